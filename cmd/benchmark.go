@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/leotaku/kojirou/cmd/formats"
+	epubpkg "github.com/leotaku/kojirou/cmd/formats/epub"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/output"
+	"github.com/leotaku/kojirou/cmd/formats/pdf"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+	md "github.com/leotaku/kojirou/mangadex"
+	"github.com/spf13/cobra"
+)
+
+var (
+	benchmarkChaptersArg int
+	benchmarkPagesArg    int
+	benchmarkWidthArg    int
+	benchmarkHeightArg   int
+	benchmarkFormatsArg  string
+)
+
+var benchmarkCmd = &cobra.Command{
+	Use:    "benchmark [flags..]",
+	Short:  "Measure generation throughput on synthetic data",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		selectedFormats, err := formats.ParseFormats(benchmarkFormatsArg)
+		if err != nil {
+			return fmt.Errorf("invalid formats: %w", err)
+		}
+
+		return runBenchmark(cmd.OutOrStdout(), selectedFormats)
+	},
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if benchmarkChaptersArg <= 0 || benchmarkPagesArg <= 0 {
+			return fmt.Errorf("chapters and pages must be positive")
+		}
+		if benchmarkWidthArg <= 0 || benchmarkHeightArg <= 0 {
+			return fmt.Errorf("width and height must be positive")
+		}
+
+		return nil
+	},
+	DisableFlagsInUseLine: true,
+}
+
+func runBenchmark(w io.Writer, selectedFormats []formats.FormatType) error {
+	pages := benchmarkChaptersArg * benchmarkPagesArg
+	fmt.Fprintf(w, "Synthetic volume: %v chapters, %v pages, %vx%v pixels\n",
+		benchmarkChaptersArg, pages, benchmarkWidthArg, benchmarkHeightArg,
+	)
+
+	for _, format := range selectedFormats {
+		// Every format is given its own freshly generated volume, so
+		// that measurements do not share any allocations.
+		manga := testhelpers.CreateSyntheticManga(
+			benchmarkChaptersArg,
+			benchmarkPagesArg,
+			benchmarkWidthArg,
+			benchmarkHeightArg,
+		)
+
+		start := time.Now()
+		peak, err := measurePeakMemory(func() error {
+			return benchmarkFormat(manga, format)
+		})
+		if err != nil {
+			return fmt.Errorf("%v: %w", format, err)
+		}
+		elapsed := time.Since(start)
+
+		fmt.Fprintf(w, "%-5v %8.2f pages/sec %8.2f MiB peak (%v)\n",
+			format,
+			float64(pages)/elapsed.Seconds(),
+			float64(peak)/(1024*1024),
+			elapsed.Round(time.Millisecond),
+		)
+	}
+
+	return nil
+}
+
+func benchmarkFormat(manga md.Manga, format formats.FormatType) error {
+	widepagePolicy := kindle.WidepagePolicy(widepageArg)
+
+	var outputFormat output.FormatOutput
+	switch format {
+	case formats.FormatMobi:
+		mobi := kindle.GenerateMOBI(manga, widepagePolicy, autocropArg, leftToRightArg)
+		outputFormat = &output.MobiOutput{Book: &mobi}
+	case formats.FormatEpub, formats.FormatKepub:
+		epub, cleanup, err := epubpkg.GenerateEPUBProd(manga, widepagePolicy, autocropArg, leftToRightArg)
+		if cleanup != nil {
+			defer cleanup()
+		}
+		if err != nil {
+			return fmt.Errorf("generate: %w", err)
+		}
+		if format == formats.FormatEpub {
+			outputFormat = &output.EpubOutput{Epub: epub}
+		} else {
+			outputFormat = &output.KepubOutput{Epub: epub}
+		}
+	case formats.FormatPdf:
+		doc := pdf.GeneratePDF(manga, widepagePolicy, autocropArg, leftToRightArg)
+		outputFormat = &output.PdfOutput{Document: &doc}
+	}
+
+	if _, err := outputFormat.GetBytes(); err != nil {
+		return fmt.Errorf("get bytes: %w", err)
+	}
+
+	return nil
+}
+
+// measurePeakMemory runs fn while periodically sampling the heap and
+// returns the highest number of allocated heap bytes observed.
+func measurePeakMemory(fn func() error) (uint64, error) {
+	runtime.GC()
+
+	var (
+		peak  uint64
+		stats runtime.MemStats
+		wg    sync.WaitGroup
+	)
+	sample := func() {
+		runtime.ReadMemStats(&stats)
+		if stats.HeapAlloc > peak {
+			peak = stats.HeapAlloc
+		}
+	}
+
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				sample()
+			}
+		}
+	}()
+
+	err := fn()
+	close(done)
+	wg.Wait()
+	sample()
+
+	return peak, err
+}
+
+func init() {
+	benchmarkCmd.Flags().IntVarP(&benchmarkChaptersArg, "chapters", "C", 4, "number of synthetic chapters")
+	benchmarkCmd.Flags().IntVarP(&benchmarkPagesArg, "pages", "P", 20, "number of synthetic pages per chapter")
+	benchmarkCmd.Flags().IntVarP(&benchmarkWidthArg, "width", "W", 1000, "width of synthetic pages")
+	benchmarkCmd.Flags().IntVarP(&benchmarkHeightArg, "height", "H", 1500, "height of synthetic pages")
+	benchmarkCmd.Flags().StringVarP(&benchmarkFormatsArg, "file-type", "t", "mobi,epub,kepub", "output file type(s) to benchmark")
+	benchmarkCmd.Flags().SortFlags = false
+	rootCmd.AddCommand(benchmarkCmd)
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBenchmarkCommand(t *testing.T) {
+	out := new(bytes.Buffer)
+	rootCmd.SetOut(out)
+	rootCmd.SetArgs([]string{
+		"benchmark",
+		"--chapters", "1",
+		"--pages", "2",
+		"--width", "100",
+		"--height", "150",
+		"--file-type", "mobi,epub,kepub",
+	})
+	defer rootCmd.SetOut(nil)
+	defer rootCmd.SetArgs(nil)
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("benchmark failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected header and 3 format lines, got %d:\n%s", len(lines), out.String())
+	}
+	if !strings.Contains(lines[0], "2 pages") {
+		t.Errorf("header does not report page count: %q", lines[0])
+	}
+	for i, format := range []string{"mobi", "epub", "kepub"} {
+		line := lines[i+1]
+		if !strings.HasPrefix(line, format) {
+			t.Errorf("expected line for %v, got %q", format, line)
+		}
+		if !strings.Contains(line, "pages/sec") || !strings.Contains(line, "MiB peak") {
+			t.Errorf("line for %v does not report throughput: %q", format, line)
+		}
+	}
+}
@@ -1,10 +1,17 @@
 package cmd
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/bmaupin/go-epub"
 	"github.com/leotaku/kojirou/cmd/filter"
@@ -13,14 +20,17 @@ import (
 	"github.com/leotaku/kojirou/cmd/formats/download"
 	epubpkg "github.com/leotaku/kojirou/cmd/formats/epub"
 	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/logging"
 	"github.com/leotaku/kojirou/cmd/formats/output"
+	"github.com/leotaku/kojirou/cmd/formats/pdf"
 	"github.com/leotaku/kojirou/cmd/formats/progress"
 	md "github.com/leotaku/kojirou/mangadex"
+	"github.com/leotaku/kojirou/mangadex/api"
 	"golang.org/x/text/language"
 )
 
-func run() error {
-	manga, err := download.MangadexSkeleton(identifierArg)
+func run() (err error) {
+	manga, err := getSkeleton()
 	if err != nil {
 		return fmt.Errorf("skeleton: %w", err)
 	}
@@ -29,26 +39,71 @@ func run() error {
 	if err != nil {
 		return fmt.Errorf("chapters: %w", err)
 	}
-	*manga = manga.WithChapters(chapters)
+	*manga = withoutEmptyVolumes(manga.WithChapters(chapters))
+
+	// Snapshot the selection before anything is downloaded
+	if exportMetadataArg != "" {
+		if err := disk.WriteDump(exportMetadataArg, manga.Info, chapters); err != nil {
+			return fmt.Errorf("export metadata: %w", err)
+		}
+	}
 
 	// Parse formats early to validate user input
-	selectedFormats, err := formats.ParseFormats(FormatsArg)
+	selectedFormats, err := selectFormats("")
 	if err != nil {
 		return fmt.Errorf("invalid formats: %w", err)
 	}
 
-	// Print summary and exit if dry run
-	formats.PrintSummary(manga)
+	if streaming() && len(selectedFormats) != 1 {
+		return fmt.Errorf("out: streaming to stdout requires a single format")
+	}
+
+	// Print summary and exit if dry run, the summary would corrupt a
+	// book streamed to stdout
+	if logging.Enabled(logging.LevelNormal) && (!streaming() || dryRunArg) {
+		formats.PrintSummary(manga)
+	}
 	if dryRunArg {
 		return nil
 	}
+	if streaming() && !combineArg && len(manga.Volumes) != 1 {
+		return fmt.Errorf("out: streaming to stdout requires a single volume or --combine")
+	}
+	if err := checkBookIdentifier(*manga); err != nil {
+		return err
+	}
+
+	// Write the report regardless of whether generating succeeded
+	if reportArg != "" {
+		runReport.reset()
+		defer func() {
+			if rerr := runReport.write(reportArg); rerr != nil {
+				err = errors.Join(err, fmt.Errorf("report: %w", rerr))
+			}
+		}()
+	}
+
+	// Continue where an interrupted run with the same selection stopped
+	resumeState = nil
+	if stateFileArg != "" {
+		resumeState, err = loadRunState(stateFileArg, stateKey(*manga, selectedFormats))
+		if err != nil {
+			return fmt.Errorf("state: %w", err)
+		}
+		defer func() {
+			if err == nil {
+				err = resumeState.finish()
+			}
+			resumeState = nil
+		}()
+	}
 
 	// Log format selection
 	formatStrings := make([]string, len(selectedFormats))
 	for i, format := range selectedFormats {
 		formatStrings[i] = string(format)
 	}
-	fmt.Printf("Generating formats: %s\n", strings.Join(formatStrings, ", "))
+	logging.Infof("Generating formats: %s", strings.Join(formatStrings, ", "))
 
 	covers, err := getCovers(manga)
 	if err != nil {
@@ -57,15 +112,126 @@ func run() error {
 	*manga = manga.WithCovers(covers)
 
 	dir := kindle.NewNormalizedDirectory(outArg, manga.Info.Title, kindleFolderModeArg)
-	for _, volume := range manga.Sorted() {
-		if err := HandleVolume(*manga, volume, dir); err != nil {
-			return fmt.Errorf("volume %v: %w", volume.Info.Identifier, err)
+	if outputDirPerVolumeArg {
+		dir = dir.WithVolumeFolders()
+	}
+	if err := dir.Validate(); err != nil {
+		return fmt.Errorf("output: %w", err)
+	}
+	if combineArg {
+		return HandleCombined(*manga, dir)
+	}
+
+	return handleVolumes(*manga, dir, volumeWorkersArg)
+}
+
+// stdoutPath is the output path that streams the book to stdout
+const stdoutPath = "-"
+
+// stdout receives books streamed to stdout, replaced in tests
+var stdout io.Writer = os.Stdout
+
+// streaming reports whether the book is streamed to stdout instead of
+// being written to the output directory
+func streaming() bool {
+	return outArg == stdoutPath
+}
+
+// runRecursive generates every series found below the disk directory as
+// if it was passed with --disk on its own, each into its own folder of the
+// output directory. A failing series does not stop the others.
+func runRecursive() error {
+	series, err := disk.DiscoverSeries(diskArg)
+	if err != nil {
+		return fmt.Errorf("disk: %w", err)
+	}
+	if len(series) == 0 {
+		return fmt.Errorf("disk: no series found in '%v'", diskArg)
+	}
+
+	root := diskArg
+	defer func() { diskArg = root }()
+	errs := make([]error, 0)
+	for _, dir := range series {
+		diskArg = dir
+		if err := run(); err != nil {
+			errs = append(errs, fmt.Errorf("series '%v': %w", dir, err))
 		}
 	}
 
+	return errors.Join(errs...)
+}
+
+// checkBookIdentifier rejects --identifier when more than one book is
+// written, since all books would share the identifier and readers such as
+// calibre would take them for copies of the same book
+func checkBookIdentifier(manga md.Manga) error {
+	if bookIdentifierArg != "" && !combineArg && len(manga.Volumes) != 1 {
+		return fmt.Errorf("identifier: requires a single volume or --combine")
+	}
+
 	return nil
 }
 
+// withoutEmptyVolumes removes volumes that have no chapters left after
+// filtering, which would otherwise fail to generate
+func withoutEmptyVolumes(manga md.Manga) md.Manga {
+	manga, empty := manga.WithoutEmptyVolumes()
+	for _, volID := range empty {
+		logging.Infof("Volume %v: skipped, no chapters left after filtering", volID)
+	}
+
+	return manga
+}
+
+// handleVolumes processes all volumes of the manga using the given number
+// of concurrent workers. A failing volume does not stop the others, instead
+// all errors are summarized and returned together once every volume is done.
+func handleVolumes(manga md.Manga, dir kindle.NormalizedDirectory, workers int) error {
+	volumes := manga.Sorted()
+	workers = max(1, min(workers, len(volumes)))
+	if workers > 1 {
+		// Concurrent progress bars would overwrite each other
+		progress.SetStatic(true)
+		defer progress.SetStatic(false)
+	}
+
+	errs := make([]error, len(volumes))
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				volume := volumes[idx]
+				if err := HandleVolume(manga, volume, dir); err != nil {
+					errs[idx] = fmt.Errorf("volume %v: %w", volume.Info.Identifier, err)
+				}
+			}
+		}()
+	}
+	for idx := range volumes {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+
+	failed := make([]string, 0)
+	for idx, err := range errs {
+		if err != nil {
+			failed = append(failed, volumes[idx].Info.Identifier.String())
+		}
+	}
+	if len(failed) > 0 {
+		logging.Infof("Volumes: %v succeeded, %v failed (%v)",
+			len(volumes)-len(failed), len(failed), strings.Join(failed, ", "),
+		)
+	}
+
+	return errors.Join(errs...)
+}
+
 // sanitizePOSIXName replaces or removes characters not allowed in POSIX file and folder names
 func sanitizePOSIXName(name string) string {
 	// Remove or replace problematic characters
@@ -80,29 +246,96 @@ func sanitizePOSIXName(name string) string {
 	return name
 }
 
-// 6. Report consolidated status at the end
+// bookNames describes how a single generated book is labeled and named
+type bookNames struct {
+	// label identifies the book in progress output
+	label string
+	// title is the title embedded in the generated book
+	title string
+	// file is the base name of the written files
+	file string
+	// kobo is the base name of the written files in Kobo folder mode
+	kobo string
+	// volume identifies the book in reports, empty for combined books
+	volume string
+}
+
+// HandleVolume generates and writes all selected formats for a single volume
 func HandleVolume(skeleton md.Manga, volume md.Volume, dir kindle.NormalizedDirectory) error {
-	// Create a titled progress bar with volume information
-	p := progress.TitledProgress(fmt.Sprintf("Volume: %v", volume.Info.Identifier))
+	volumeName := volume.Info.Identifier.StringFilled(fillVolumeNumberArg, 0, false)
+	return handleBook(skeleton, volume.Sorted(), dir, bookNames{
+		label:  fmt.Sprintf("Volume: %v", volume.Info.Identifier),
+		title:  fmt.Sprintf("%v: %v", skeleton.Info.Title, volumeName),
+		file:   volume.Info.Identifier.StringFilled(4, 0, false),
+		kobo:   fmt.Sprintf("%s v%s", sanitizePOSIXName(skeleton.Info.Title), sanitizePOSIXName(volumeName)),
+		volume: volume.Info.Identifier.String(),
+	})
+}
 
-	// Get selected formats
-	selectedFormats, err := formats.ParseFormats(FormatsArg)
+// HandleCombined generates and writes all selected formats for a single
+// book containing every volume of the manga, named by the series title
+func HandleCombined(skeleton md.Manga, dir kindle.NormalizedDirectory) error {
+	chapters := make(md.ChapterList, 0)
+	for _, volume := range skeleton.Sorted() {
+		chapters = append(chapters, volume.Sorted()...)
+	}
+
+	return handleBook(skeleton, chapters, dir, bookNames{
+		label: "Combined",
+		title: skeleton.Info.Title,
+		file:  sanitizePOSIXName(skeleton.Info.Title),
+		kobo:  sanitizePOSIXName(skeleton.Info.Title),
+	})
+}
+
+// 6. Report consolidated status at the end
+func handleBook(skeleton md.Manga, chapters md.ChapterList, dir kindle.NormalizedDirectory, names bookNames) (err error) {
+	// Create a titled progress bar with book information
+	p := progress.TitledProgress(names.label)
+
+	// Get selected formats, which may differ between volumes
+	selectedFormats, err := selectFormats(names.volume)
 	if err != nil {
 		p.Cancel(fmt.Sprintf("Format selection error: %v", err))
 		return fmt.Errorf("parse formats: %w", err)
 	}
+	if len(selectedFormats) == 0 {
+		logging.Verbosef("%v: skipped, no formats selected", names.label)
+		p.Cancel("Skipped (no formats selected)")
+		return nil
+	}
+
+	// Track which formats succeeded and failed
+	formatStatus := make(map[formats.FormatType]string)
+	defer func() { reportBook(skeleton, dir, names, selectedFormats, formatStatus, err) }()
+
+	// Skip books that an interrupted run already completed
+	if !slices.ContainsFunc(selectedFormats, func(format formats.FormatType) bool {
+		return !resumeState.done(names.file, format)
+	}) {
+		for _, format := range selectedFormats {
+			formatStatus[format] = "Skipped (completed before)"
+		}
+		logging.Verbosef("%v: skipped, completed before", names.label)
+		p.Cancel("Skipped (completed before)")
+		return nil
+	}
 
 	// Check if we can skip the entire volume processing
-	if !forceArg {
+	if !forceArg && !streaming() {
 		allExist := true
 		for _, format := range selectedFormats {
-			if !dir.HasWithExtension(volume.Info.Identifier, string(format)) {
+			if !outputExists(bookPath(skeleton, dir, names, format), format, chapters) {
 				allExist = false
 				break
 			}
 		}
 
 		if allExist {
+			for _, format := range selectedFormats {
+				formatStatus[format] = "Skipped (all formats exist)"
+			}
+			logging.Verbosef("%v: skipped, all formats exist", names.label)
 			p.Cancel("Skipped (all formats exist)")
 			return nil
 		}
@@ -110,22 +343,57 @@ func HandleVolume(skeleton md.Manga, volume md.Volume, dir kindle.NormalizedDire
 
 	// Load pages (shared operation for all formats)
 	p.SetFormat("pages")
-	pages, err := getPages(volume, p)
+	pages, err := getPages(chapters, p)
 	if err != nil {
 		return fmt.Errorf("pages: %w", err)
 	}
 	p.SetFormat("")
 
-	mangaForVolume := skeleton.WithChapters(volume.Sorted()).WithPages(pages)
+	if autoLevelsArg {
+		for i := range pages {
+			pages[i].Image = kindle.AutoLevels(pages[i].Image, autoLevelsClipArg)
+		}
+	}
 
-	// Common formatting for title
-	title := fmt.Sprintf("%v: %v",
-		skeleton.Info.Title,
-		volume.Info.Identifier.StringFilled(fillVolumeNumberArg, 0, false),
-	)
+	mangaForVolume := skeleton.WithChapters(chapters).WithPages(pages)
+	if n := minPages(); n > 0 {
+		var short md.ChapterList
+		mangaForVolume, short = mangaForVolume.WithoutShortChapters(n)
+		for _, chapter := range short {
+			logging.Infof("%v: skipped chapter %v, it has %v pages", names.label, chapter.Info.Identifier, len(chapter.Pages))
+		}
+		if len(mangaForVolume.Volumes) == 0 {
+			p.Cancel("Error: no pages")
+			return fmt.Errorf("pages: all chapters were skipped")
+		}
+	}
+	if !noCoversArg {
+		spread := kindle.CoverSpread(coverSpreadArg)
+		mangaForVolume = spread.Apply(mangaForVolume, leftToRightArg, widepageThresholdArg)
+	}
+	if coverFromFirstPage && !noCoversArg {
+		mangaForVolume = mangaForVolume.WithFallbackCovers()
+	}
+	if keep := pagesFilter(); keep != nil {
+		mangaForVolume = mangaForVolume.WithPagesFiltered(keep)
+		if len(mangaForVolume.Volumes) == 0 {
+			p.Cancel("Error: no pages")
+			return fmt.Errorf("pages: no pages selected")
+		}
+	}
 
-	// Track which formats succeeded and failed
-	formatStatus := make(map[formats.FormatType]string)
+	// Chapters that were left out are listed in the book, so that it is
+	// not taken to be incomplete when checked against them later
+	written := make(map[string]bool)
+	for _, chapter := range mangaForVolume.Chapters() {
+		written[formats.ChapterKey(chapter.Info)] = true
+	}
+	skipped := make([]string, 0)
+	for _, chapter := range chapters {
+		if key := formats.ChapterKey(chapter.Info); !written[key] {
+			skipped = append(skipped, key)
+		}
+	}
 
 	// Common parameters for all formats
 	widepagePolicy := kindle.WidepagePolicy(widepageArg)
@@ -133,21 +401,46 @@ func HandleVolume(skeleton md.Manga, volume md.Volume, dir kindle.NormalizedDire
 	// Create a shared EPUB for both EPUB and KEPUB formats
 	var sharedEpub *epub.Epub
 	needsEpub := false
+	outputs := make([]string, 0, len(selectedFormats))
 	for _, format := range selectedFormats {
 		if format == formats.FormatEpub || format == formats.FormatKepub {
 			needsEpub = true
-			break
+		}
+		if !streaming() {
+			outputs = append(outputs, bookPath(skeleton, dir, names, format))
 		}
 	}
 
-	if needsEpub {
+	// Fail before writing anything if the book will not fit on disk
+	if err := preflightDiskSpace(mangaForVolume, needsEpub && !inMemoryArg, outputs); err != nil {
+		p.Cancel("Error: disk space")
+		return err
+	}
+
+	var sharedArchive []byte
+	if needsEpub && inMemoryArg {
+		var epubErr error
+		sharedArchive, epubErr = epubpkg.GenerateEPUBInMemory(
+			mangaForVolume,
+			widepagePolicy,
+			autocropArg,
+			leftToRightArg,
+			epubOptions(),
+		)
+		if epubErr != nil {
+			p.Cancel("Error generating EPUB base")
+			return fmt.Errorf("generate epub base: %w", epubErr)
+		}
+		p.SetFormat("")
+	} else if needsEpub {
 		var epubErr error
 		var cleanup func()
-		sharedEpub, cleanup, epubErr = epubpkg.GenerateEPUBProd(
+		sharedEpub, cleanup, epubErr = epubpkg.GenerateEPUBProdWithOptions(
 			mangaForVolume,
 			widepagePolicy,
 			autocropArg,
 			leftToRightArg,
+			epubOptions(),
 		)
 		if epubErr != nil {
 			p.Cancel("Error generating EPUB base")
@@ -165,15 +458,22 @@ func HandleVolume(skeleton md.Manga, volume md.Volume, dir kindle.NormalizedDire
 		formatStrings[i] = string(f)
 	}
 	summaryProgress := progress.MultiFormatStatusProgress(
-		fmt.Sprintf("Formats - %v", volume.Info.Identifier),
+		fmt.Sprintf("Formats - %v", names.label),
 		formatStrings,
 	)
 	defer summaryProgress.Done()
 
 	// Process each format with format-specific progress reporting
 	for _, format := range selectedFormats {
+		if resumeState.done(names.file, format) {
+			logging.Verbosef("%v: skipped %v, completed before", names.label, format)
+			formatStatus[format] = "Skipped (completed before)"
+			summaryProgress.FormatCompleted(string(format), "Skipped")
+			continue
+		}
 		// Skip if the format already exists and we're not forcing regeneration
-		if !forceArg && dir.HasWithExtension(volume.Info.Identifier, string(format)) {
+		if !forceArg && !streaming() && outputExists(bookPath(skeleton, dir, names, format), format, chapters) {
+			logging.Verbosef("%v: skipped %v, already exists", names.label, format)
 			formatStatus[format] = "Skipped (already exists)"
 			summaryProgress.FormatCompleted(string(format), "Skipped")
 			continue
@@ -189,31 +489,54 @@ func HandleVolume(skeleton md.Manga, volume md.Volume, dir kindle.NormalizedDire
 
 		switch format {
 		case formats.FormatMobi:
-			mobi := kindle.GenerateMOBI(
+			mobi := kindle.GenerateMOBIWithOptions(
 				mangaForVolume,
 				widepagePolicy,
 				autocropArg,
 				leftToRightArg,
+				kindle.Options{
+					Pages: kindle.PageOptions{
+						WideThreshold: widepageThresholdArg,
+						SplitGutters:  splitGuttersArg,
+						SplitOverlap:  splitOverlapArg,
+					},
+					Dither:      ditherArg,
+					PageNumbers: pageNumbers(),
+				},
 			)
 			mobi.RightToLeft = !leftToRightArg
-			mobi.Title = title
-			outputFormat = &output.MobiOutput{Book: &mobi}
+			mobi.Title = names.title
+			outputFormat = &output.MobiOutput{Book: &mobi, Comic: kindleComicArg}
 
 		case formats.FormatEpub:
 			// We already generated the EPUB above
-			outputFormat = &output.EpubOutput{Epub: sharedEpub}
+			outputFormat = &output.EpubOutput{
+				Epub:            sharedEpub,
+				Archive:         sharedArchive,
+				ExternalIDs:     skeleton.Info.ExternalIDs,
+				Modified:        sourceDateArg.Time,
+				CoverPage:       coverAsPageArg,
+				Generator:       generator(),
+				StripMetadata:   stripMetadataArg,
+				EpubVersion:     epubVersionArg,
+				SkippedChapters: skipped,
+			}
 
 		case formats.FormatKepub:
 			// Kobo folder mode: output KEPUBs to KoboBooks/<Series Title>/
 			if koboFolderModeArg {
-				seriesTitle := sanitizePOSIXName(skeleton.Info.Title)
-				outputDir := path.Join("KoboBooks", seriesTitle)
-				if err := os.MkdirAll(outputDir, 0755); err != nil {
+				outputPath := bookPath(skeleton, dir, names, format)
+				if err := os.MkdirAll(path.Dir(outputPath), 0755); err != nil {
 					return fmt.Errorf("failed to create KoboBooks output dir: %w", err)
 				}
-				volumeName := sanitizePOSIXName(volume.Info.Identifier.StringFilled(fillVolumeNumberArg, 0, false))
-				filename := fmt.Sprintf("%s v%s.kepub.epub", seriesTitle, volumeName)
-				outputPath := path.Join(outputDir, filename)
+				outputFormat = &output.KepubOutput{
+					Epub:               sharedEpub,
+					Modified:           sourceDateArg.Time,
+					Generator:          generator(),
+					StripMetadata:      stripMetadataArg,
+					StripMediaOverlays: stripMediaOverlaysArg,
+					SkippedChapters:    skipped,
+				}
 				data, err := outputFormat.GetBytes()
 				if err != nil {
 					return fmt.Errorf("get bytes: %w", err)
@@ -221,22 +544,92 @@ func HandleVolume(skeleton md.Manga, volume md.Volume, dir kindle.NormalizedDire
 				if err := os.WriteFile(outputPath, data, 0644); err != nil {
 					return fmt.Errorf("write KEPUB: %w", err)
 				}
+				if verifyArg {
+					if err := formats.VerifyFile(outputPath, format); err != nil {
+						return fmt.Errorf("verify KEPUB: %w", err)
+					}
+				}
+				if writeChecksumsArg {
+					if err := formats.WriteChecksum(outputPath); err != nil {
+						return fmt.Errorf("KEPUB: %w", err)
+					}
+				}
+				if postProcessArg != "" {
+					if err := postProcess(runContext, postProcessArg, outputPath, postProcessTimeoutArg); err != nil {
+						return fmt.Errorf("post-process KEPUB: %w", err)
+					}
+				}
+				if err := resumeState.complete(names.file, format); err != nil {
+					return fmt.Errorf("state: %w", err)
+				}
+				logging.Verbosef("%v: wrote %v", names.label, outputPath)
 				formatStatus[format] = "Success"
 				formatProgress.Done()
 				summaryProgress.FormatCompleted(string(format), "Success")
 				continue
 			}
 			// We already generated the EPUB above, use it for KEPUB
-			outputFormat = &output.KepubOutput{Epub: sharedEpub}
+			outputFormat = &output.KepubOutput{
+				Epub:               sharedEpub,
+				Modified:           sourceDateArg.Time,
+				Generator:          generator(),
+				StripMetadata:      stripMetadataArg,
+				StripMediaOverlays: stripMediaOverlaysArg,
+				SkippedChapters:    skipped,
+			}
+
+		case formats.FormatPdf:
+			doc := pdf.GeneratePDFWithOptions(mangaForVolume, kindle.PageOptions{
+				Policy:        widepagePolicy,
+				WideThreshold: widepageThresholdArg,
+				AutoCrop:      autocropArg,
+				LeftToRight:   leftToRightArg,
+			})
+			doc.Title = names.title
+			outputFormat = &output.PdfOutput{Document: &doc}
+		}
+
+		if streaming() {
+			if _, err := outputFormat.WriteTo(formatProgress.NewProxyWriter(stdout)); err != nil {
+				formatStatus[format] = fmt.Sprintf("Error: %v", err)
+				formatProgress.CancelWithFormat(string(format), "Error")
+				summaryProgress.FormatCompleted(string(format), "Error")
+			} else {
+				formatStatus[format] = "Success"
+				formatProgress.Done()
+				summaryProgress.FormatCompleted(string(format), "Success")
+			}
+			continue
 		}
 
 		// Write the format to disk
-		if err := dir.WriteFormat(volume.Info.Identifier, outputFormat, formatProgress); err != nil {
+		err := dir.WriteNamedFormat(names.file, outputFormat, formatProgress)
+		filename := dir.PathNamed(names.file, outputFormat.Extension())
+		if err == nil && verifyArg {
+			if verr := formats.VerifyFile(filename, format); verr != nil {
+				err = fmt.Errorf("verify: %w", verr)
+			}
+		}
+		if err == nil && writeChecksumsArg {
+			err = formats.WriteChecksum(filename)
+		}
+		if err == nil && postProcessArg != "" {
+			if perr := postProcess(runContext, postProcessArg, filename, postProcessTimeoutArg); perr != nil {
+				err = fmt.Errorf("post-process: %w", perr)
+			}
+		}
+		if err == nil {
+			if serr := resumeState.complete(names.file, format); serr != nil {
+				err = fmt.Errorf("state: %w", serr)
+			}
+		}
+		if err != nil {
 			formatStatus[format] = fmt.Sprintf("Error: %v", err)
 			formatProgress.CancelWithFormat(string(format), "Error")
 			summaryProgress.FormatCompleted(string(format), "Error")
 			formatErr = err
 		} else {
+			logging.Verbosef("%v: wrote %v", names.label, filename)
 			formatStatus[format] = "Success"
 			formatProgress.Done()
 			summaryProgress.FormatCompleted(string(format), "Success")
@@ -252,6 +645,14 @@ func HandleVolume(skeleton md.Manga, volume md.Volume, dir kindle.NormalizedDire
 	// Reset format indicator before final status
 	p.SetFormat("")
 
+	if pageFilenamesArg && !streaming() {
+		filename := dir.PathNamed(names.file, formats.PageFilenamesExtension)
+		if err := formats.WritePageFilenames(filename, mangaForVolume); err != nil {
+			p.Cancel("Error: page filenames")
+			return fmt.Errorf("page filenames: %w", err)
+		}
+	}
+
 	// Check if any format failed
 	var errorFormats []string
 	for format, status := range formatStatus {
@@ -270,15 +671,137 @@ func HandleVolume(skeleton md.Manga, volume md.Volume, dir kindle.NormalizedDire
 	return nil
 }
 
-func getChapters(manga md.Manga) (md.ChapterList, error) {
-	chapters, err := download.MangadexChapters(manga.Info.ID)
+// selectFormats returns the formats selected by the user for the named
+// volume, or for any volume if the name is empty, including the
+// intermediate EPUB of KEPUB output if it should be kept
+func selectFormats(volume string) ([]formats.FormatType, error) {
+	scoped, err := formats.ParseScopedFormats(FormatsArg)
 	if err != nil {
-		return nil, fmt.Errorf("mangadex: %w", err)
+		return nil, err
+	}
+	selected := scoped.All()
+	if volume != "" {
+		selected = scoped.For(md.NewIdentifier(volume))
+	}
+	if keepEpubArg && slices.Contains(selected, formats.FormatKepub) && !slices.Contains(selected, formats.FormatEpub) {
+		selected = append(selected, formats.FormatEpub)
+	}
+
+	return selected, nil
+}
+
+// bookPath returns the path the given format of a book is written to
+func bookPath(skeleton md.Manga, dir kindle.NormalizedDirectory, names bookNames, format formats.FormatType) string {
+	if koboFolderModeArg && format == formats.FormatKepub {
+		seriesTitle := sanitizePOSIXName(skeleton.Info.Title)
+		return path.Join("KoboBooks", seriesTitle, names.kobo+"."+format.Extension())
+	}
+
+	return dir.PathNamed(names.file, format.Extension())
+}
+
+// reportBook adds the outputs of a book to the run report. Formats without
+// a status were never reached, so they share the error of the whole book.
+func reportBook(skeleton md.Manga, dir kindle.NormalizedDirectory, names bookNames, selected []formats.FormatType, statuses map[formats.FormatType]string, err error) {
+	if reportArg == "" {
+		return
+	}
+	for _, format := range selected {
+		filename := bookPath(skeleton, dir, names, format)
+		status, ok := statuses[format]
+		switch {
+		case !ok && err != nil:
+			runReport.add(names.volume, format, filename, statusError, err)
+		case strings.HasPrefix(status, "Error: "):
+			runReport.add(names.volume, format, filename, statusError, errors.New(strings.TrimPrefix(status, "Error: ")))
+		case strings.HasPrefix(status, "Skipped") || !ok:
+			runReport.add(names.volume, format, filename, statusSkipped, nil)
+		default:
+			runReport.add(names.volume, format, filename, statusGenerated, nil)
+		}
+	}
+}
+
+// outputExists reports whether the output of the given format was already
+// written to the given path. With --check-existing, outputs that are not
+// valid, do not match their checksum sidecar or lack any of the given
+// chapters that they do not list as skipped are treated as missing, where
+// MOBI and PDF outputs are only checked for their structure since they do
+// not name their chapters. With
+// --overwrite-older, outputs written before the newest of the given
+// chapters was published are treated as missing.
+func outputExists(filename string, format formats.FormatType, chapters md.ChapterList) bool {
+	if filename == "" {
+		return false
+	}
+	info, err := os.Stat(filename)
+	if err != nil || info.IsDir() {
+		return false
+	}
+
+	if overwriteOlderArg && info.ModTime().Before(latestPublished(chapters)) {
+		return false
+	}
+	if checkExistingArg {
+		return formats.VerifyFile(filename, format) == nil &&
+			formats.VerifyChapters(filename, format, chapters) == nil &&
+			formats.VerifyChecksum(filename) == nil
+	}
+
+	return true
+}
+
+// latestPublished returns the publication time of the newest chapter
+func latestPublished(chapters md.ChapterList) time.Time {
+	latest := time.Time{}
+	for _, chapter := range chapters {
+		if chapter.Info.Published.After(latest) {
+			latest = chapter.Info.Published
+		}
+	}
+
+	return latest
+}
+
+func getSkeleton() (*md.Manga, error) {
+	if recursiveArg {
+		return disk.LoadSkeleton(diskArg)
+	}
+	if dumpArg == "" {
+		return download.MangadexSkeleton(identifierArg)
+	}
+
+	manga, _, err := disk.LoadDump(dumpArg)
+	if err != nil {
+		return nil, fmt.Errorf("dump: %w", err)
+	} else if manga.Info.ID != identifierArg {
+		return nil, fmt.Errorf("dump: contains manga %v, not %v", manga.Info.ID, identifierArg)
+	}
+
+	return manga, nil
+}
+
+func getChapters(manga md.Manga) (md.ChapterList, error) {
+	var chapters md.ChapterList
+	var err error
+	switch {
+	case recursiveArg:
+		// Series found on disk are generated from disk only
+	case dumpArg != "":
+		_, chapters, err = disk.LoadDump(dumpArg)
+		if err != nil {
+			return nil, fmt.Errorf("dump: %w", err)
+		}
+	default:
+		chapters, err = download.MangadexChapters(manga.Info.ID)
+		if err != nil {
+			return nil, fmt.Errorf("mangadex: %w", err)
+		}
 	}
 
 	if diskArg != "" {
 		p := progress.VanishingProgress("Disk...")
-		diskChapters, err := disk.LoadChapters(diskArg, language.Make(languageArg), p)
+		diskChapters, err := disk.LoadChapters(diskArg, parseLanguages(languageArg)[0], p)
 		if err != nil {
 			p.Cancel("Error")
 			return nil, fmt.Errorf("disk: %w", err)
@@ -287,11 +810,23 @@ func getChapters(manga md.Manga) (md.ChapterList, error) {
 		chapters = append(chapters, diskChapters...)
 	}
 
+	// Ensure chapters without a volume still produce output
+	chapters = chapters.CollectVolumeless()
+
+	// Regroup before filtering and removing duplicates, so that all
+	// versions of a chapter end up in the same volume
+	chapters = filter.VolumeStrategy(volumeStrategyArg).Apply(chapters)
+
 	chapters, err = filterAndSortFromFlags(chapters)
 	if err != nil {
 		return nil, fmt.Errorf("filter: %w", err)
 	}
 
+	// Ensure chapters from preferred groups win over the ranking
+	if preferGroupsArg != "" {
+		chapters = filter.SortByPreferredGroups(chapters, strings.Split(preferGroupsArg, ","))
+	}
+
 	// Ensure chapters from disk are preferred
 	if diskArg != "" {
 		chapters = chapters.SortBy(func(a md.ChapterInfo, b md.ChapterInfo) bool {
@@ -299,17 +834,35 @@ func getChapters(manga md.Manga) (md.ChapterList, error) {
 		})
 	}
 
-	return filter.RemoveDuplicates(chapters), nil
+	// Remember what chapters from disk replace, to fill in their gaps
+	if diskArg != "" {
+		diskAlternates = findDiskAlternates(chapters)
+	}
+
+	chapters = filter.RemoveDuplicates(chapters)
+	logging.Debugf("Selected %v chapters", len(chapters))
+
+	return chapters, nil
 }
 
 func getCovers(manga *md.Manga) (md.ImageList, error) {
-	p := progress.VanishingProgress("Covers")
-	covers, err := download.MangadexCovers(manga, p)
-	if err != nil {
-		p.Cancel("Error")
-		return nil, fmt.Errorf("mangadex: %w", err)
+	if noCoversArg {
+		return md.ImageList{}, nil
+	}
+
+	// Dumps do not contain covers, so volumes use custom covers or their
+	// first page instead
+	covers := make(md.ImageList, 0)
+	if dumpArg == "" && !recursiveArg {
+		p := progress.VanishingProgress("Covers")
+		downloaded, err := download.MangadexCovers(manga, coverLocaleArg, p)
+		if err != nil {
+			p.Cancel("Error")
+			return nil, fmt.Errorf("mangadex: %w", err)
+		}
+		p.Done()
+		covers = downloaded
 	}
-	p.Done()
 
 	// Covers from disk should automatically be preferred, because
 	// they appear later in the list and thus should override the
@@ -325,37 +878,72 @@ func getCovers(manga *md.Manga) (md.ImageList, error) {
 		covers = append(covers, diskCovers...)
 	}
 
+	// Custom covers are appended last, so they override all others.
+	if coverArg != "" {
+		customCovers, err := disk.LoadCustomCovers(coverArg, manga.Keys())
+		if err != nil {
+			return nil, fmt.Errorf("custom: %w", err)
+		}
+		covers = append(covers, customCovers...)
+	}
+
 	return covers, nil
 }
 
-func getPages(volume md.Volume, p progress.CliProgress) (md.ImageList, error) {
-	mangadexPages, err := download.MangadexPages(volume.Sorted().FilterBy(func(ci md.ChapterInfo) bool {
+func getPages(chapters md.ChapterList, p progress.CliProgress) (md.ImageList, error) {
+	p.ShowRate()
+	remote := chapters.FilterBy(func(ci md.ChapterInfo) bool {
 		return ci.GroupNames.String() != "Filesystem"
-	}), download.DataSaverPolicy(dataSaverArg), p)
+	})
+	mangadexPages, err := loadRemotePages(remote, p)
 	if err != nil {
 		p.Cancel("Error")
 		return nil, fmt.Errorf("mangadex: %w", err)
 	}
-	diskPages, err := disk.LoadPages(volume.Sorted().FilterBy(func(ci md.ChapterInfo) bool {
+	diskPages, err := disk.LoadPages(chapters.FilterBy(func(ci md.ChapterInfo) bool {
 		return ci.GroupNames.String() == "Filesystem"
 	}), p)
 	if err != nil {
 		p.Cancel("Error")
 		return nil, fmt.Errorf("disk: %w", err)
 	}
+	if len(diskAlternates) > 0 {
+		diskPages, err = fillDiskChapters(diskPages, loadRemotePages, p)
+		if err != nil {
+			p.Cancel("Error")
+			return nil, fmt.Errorf("fill: %w", err)
+		}
+	}
 	p.Done()
 
 	return append(mangadexPages, diskPages...), nil
 }
 
+// loadRemotePages loads the pages of chapters not on disk, from the dump
+// if one is used and from MangaDex otherwise
+func loadRemotePages(cl md.ChapterList, p progress.Progress) (md.ImageList, error) {
+	if dumpArg != "" {
+		return disk.LoadDumpPages(dumpArg, cl, p)
+	}
+
+	return download.MangadexPages(cl, download.DataSaverPolicy(dataSaverArg), p)
+}
+
 func filterAndSortFromFlags(cl md.ChapterList) (md.ChapterList, error) {
 	if languageArg != "" {
-		lang := language.Make(languageArg)
-		cl = filter.FilterByLanguage(cl, lang)
+		langs := parseLanguages(languageArg)
+		if len(langs) == 1 {
+			cl = filter.FilterByLanguage(cl, langs[0])
+		} else {
+			cl = filter.FilterByLanguagePriority(cl, langs)
+		}
 	}
 	if groupsFilter != "" {
 		cl = filter.FilterByRegex(cl, "GroupNames", groupsFilter)
 	}
+	if excludeGroupsFilter != "" {
+		cl = filter.FilterByExcludedGroups(cl, strings.Split(excludeGroupsFilter, ","))
+	}
 	if volumesFilter != "" {
 		ranges := filter.ParseRanges(volumesFilter)
 		cl = filter.FilterByIdentifier(cl, "VolumeIdentifier", ranges)
@@ -364,12 +952,19 @@ func filterAndSortFromFlags(cl md.ChapterList) (md.ChapterList, error) {
 		ranges := filter.ParseRanges(chaptersFilter)
 		cl = filter.FilterByIdentifier(cl, "Identifier", ranges)
 	}
+	if minPagesArg > 0 {
+		cl = filter.FilterByMinPages(cl, minPagesArg)
+	}
 
 	switch rankArg {
 	case "newest":
 		cl = filter.SortByNewest(cl)
 	case "newest-total":
 		cl = filter.SortByNewestGroup(cl)
+	case "oldest":
+		cl = filter.SortByOldest(cl)
+	case "oldest-total":
+		cl = filter.SortByOldestGroup(cl)
 	case "views":
 		cl = filter.SortByViews(cl)
 	case "views-total":
@@ -382,3 +977,95 @@ func filterAndSortFromFlags(cl md.ChapterList) (md.ChapterList, error) {
 
 	return cl, nil
 }
+
+// minPages returns the number of pages chapters need to be included in
+// generated books, zero if all chapters are included
+func minPages() int {
+	if skipEmptyChaptersArg {
+		return max(minPagesArg, 1)
+	}
+
+	return minPagesArg
+}
+
+// pagesFilter returns whether pages are included in generated books given
+// their position in their chapter, nil if all pages are included
+func pagesFilter() func(page int) bool {
+	if pagesArg == "" && previewArg == 0 {
+		return nil
+	}
+
+	ranges := filter.ParseRanges(pagesArg)
+	return func(page int) bool {
+		if previewArg > 0 && page > previewArg {
+			return false
+		}
+
+		return pagesArg == "" || ranges.Contains(md.NewIdentifier(strconv.Itoa(page)))
+	}
+}
+
+func parseLanguages(s string) []language.Tag {
+	result := make([]language.Tag, 0)
+	for _, lang := range strings.Split(s, ",") {
+		result = append(result, language.Make(strings.TrimSpace(lang)))
+	}
+
+	return result
+}
+
+// getCredentials returns the MangaDex credentials given by flags or the
+// environment, or nil for anonymous access. Flags take precedence over
+// the KOJIROU_CLIENT_ID and KOJIROU_USERNAME environment variables.
+func getCredentials() (*api.Credentials, error) {
+	credentials := api.Credentials{
+		ClientID:     cmp.Or(clientIDArg, os.Getenv("KOJIROU_CLIENT_ID")),
+		ClientSecret: os.Getenv("KOJIROU_CLIENT_SECRET"),
+		Username:     cmp.Or(usernameArg, os.Getenv("KOJIROU_USERNAME")),
+		Password:     os.Getenv("KOJIROU_PASSWORD"),
+	}
+	switch {
+	case credentials == api.Credentials{}:
+		return nil, nil
+	case credentials.ClientID == "" || credentials.ClientSecret == "":
+		return nil, fmt.Errorf("client ID and secret are both required")
+	case credentials.Username == "" || credentials.Password == "":
+		return nil, fmt.Errorf("username and password are both required")
+	}
+
+	return &credentials, nil
+}
+
+// pageNumbers returns the page number overlay configured by flags
+func pageNumbers() kindle.PageNumbers {
+	return kindle.PageNumbers{
+		Enabled: pageNumbersArg,
+		Corner:  kindle.Corner(pageNumberCornerArg),
+		Opacity: pageNumberOpacityArg,
+	}
+}
+
+func epubOptions() epubpkg.Options {
+	margin := epubpkg.Margin(pageMarginArg)
+	margin.Color = marginColorArg.Color
+
+	return epubpkg.Options{
+		CSS:               customCSS,
+		ReplaceCSS:        replaceCSSArg,
+		ChapterTitlePages: chapterTitlePagesArg,
+		NoVolumeSections:  noVolumeSectionArg,
+		ChapterTitle:      chapterTitleArg,
+		NavGroupSize:      navGroupSizeArg,
+		VolumeNumberWidth: fillVolumeNumberArg,
+		PageNumbers:       pageNumbers(),
+		WideThreshold:     widepageThresholdArg,
+		SplitGutters:      splitGuttersArg,
+		SplitOverlap:      splitOverlapArg,
+		SVGPages:          svgPagesArg,
+		PageFilenames:     pageFilenamesArg,
+		PagePadding:       pagePadArg,
+		Margin:            margin,
+		Identifier:        bookIdentifierArg,
+		Verbose:           logging.Enabled(logging.LevelDebug),
+	}
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"archive/zip"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/disk"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/progress"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+	"golang.org/x/text/language"
+)
+
+// createDiskSeries writes a series in the disk loader layout, where
+// layout maps volume names to chapter names, and loads its skeleton.
+func createDiskSeries(t *testing.T, layout map[string][]string) string {
+	t.Helper()
+
+	root := filepath.Join(t.TempDir(), "Disk Series")
+	for volume, chapters := range layout {
+		for _, chapter := range chapters {
+			dir := filepath.Join(root, volume, chapter)
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatalf("failed to create chapter dir: %v", err)
+			}
+			for _, page := range []string{"01.png", "02.png"} {
+				f, err := os.Create(filepath.Join(dir, page))
+				if err != nil {
+					t.Fatalf("failed to create page: %v", err)
+				}
+				if err := png.Encode(f, testhelpers.CreateTestImage(200, 300, color.White)); err != nil {
+					t.Fatalf("failed to encode page: %v", err)
+				}
+				f.Close()
+			}
+		}
+	}
+
+	return root
+}
+
+func TestHandleCombined(t *testing.T) {
+	root := createDiskSeries(t, map[string][]string{
+		"1": {"1", "2"},
+		"2": {"3"},
+	})
+	skeleton, err := disk.LoadSkeleton(root)
+	if err != nil {
+		t.Fatalf("failed to load skeleton: %v", err)
+	}
+	chapters, err := disk.LoadChapters(root, language.English, progress.VanishingProgress("Disk..."))
+	if err != nil {
+		t.Fatalf("failed to load chapters: %v", err)
+	}
+	manga := skeleton.WithChapters(chapters)
+
+	origFormatsArg := FormatsArg
+	defer func() { FormatsArg = origFormatsArg }()
+	FormatsArg = "epub"
+
+	outDir := t.TempDir()
+	dir := kindle.NewNormalizedDirectory(outDir, manga.Info.Title, false)
+	if err := HandleCombined(manga, dir); err != nil {
+		t.Fatalf("HandleCombined() failed: %v", err)
+	}
+
+	entries, err := os.ReadDir(outDir)
+	if err != nil {
+		t.Fatalf("failed to list output: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "Disk Series.epub" {
+		t.Fatalf("expected single combined output, got %v", entries)
+	}
+
+	r, err := zip.OpenReader(filepath.Join(outDir, entries[0].Name()))
+	if err != nil {
+		t.Fatalf("failed to open combined EPUB: %v", err)
+	}
+	defer r.Close()
+
+	sections := make([]string, 0)
+	for _, f := range r.File {
+		if name := filepath.Base(f.Name); strings.HasPrefix(name, "chapter-") {
+			sections = append(sections, name)
+		}
+	}
+	for _, want := range []string{"chapter-1-1.xhtml", "chapter-1-2.xhtml", "chapter-2-3.xhtml"} {
+		found := false
+		for _, section := range sections {
+			found = found || section == want
+		}
+		if !found {
+			t.Errorf("combined EPUB missing %v, got %v", want, sections)
+		}
+	}
+}
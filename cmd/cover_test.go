@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"archive/zip"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/disk"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/progress"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+	md "github.com/leotaku/kojirou/mangadex"
+	"golang.org/x/text/language"
+)
+
+func TestCustomCover(t *testing.T) {
+	root := createDiskSeries(t, map[string][]string{
+		"1": {"1"},
+	})
+	skeleton, err := disk.LoadSkeleton(root)
+	if err != nil {
+		t.Fatalf("failed to load skeleton: %v", err)
+	}
+	chapters, err := disk.LoadChapters(root, language.English, progress.VanishingProgress("Disk..."))
+	if err != nil {
+		t.Fatalf("failed to load chapters: %v", err)
+	}
+	manga := skeleton.WithChapters(chapters)
+
+	coverPath := filepath.Join(t.TempDir(), "cover.png")
+	f, err := os.Create(coverPath)
+	if err != nil {
+		t.Fatalf("failed to create cover: %v", err)
+	}
+	if err := png.Encode(f, testhelpers.CreateTestImage(120, 180, color.Black)); err != nil {
+		t.Fatalf("failed to encode cover: %v", err)
+	}
+	f.Close()
+
+	covers, err := disk.LoadCustomCovers(coverPath, manga.Keys())
+	if err != nil {
+		t.Fatalf("LoadCustomCovers() failed: %v", err)
+	}
+	manga = manga.WithCovers(covers)
+
+	origFormatsArg := FormatsArg
+	defer func() { FormatsArg = origFormatsArg }()
+	FormatsArg = "epub"
+
+	outDir := t.TempDir()
+	dir := kindle.NewNormalizedDirectory(outDir, manga.Info.Title, false)
+	for _, volume := range manga.Sorted() {
+		if err := HandleVolume(manga, volume, dir); err != nil {
+			t.Fatalf("HandleVolume() failed: %v", err)
+		}
+	}
+
+	r, err := zip.OpenReader(dir.Path(manga.Keys()[0], "epub"))
+	if err != nil {
+		t.Fatalf("failed to open EPUB: %v", err)
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if filepath.Base(f.Name) != "cover-1.jpg" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open cover: %v", err)
+		}
+		defer rc.Close()
+		img, _, err := image.Decode(rc)
+		if err != nil {
+			t.Fatalf("failed to decode cover: %v", err)
+		}
+		if img.Bounds().Dx() != 120 || img.Bounds().Dy() != 180 {
+			t.Errorf("cover has size %v, want 120x180", img.Bounds().Size())
+		}
+		if r, g, b, _ := img.At(60, 90).RGBA(); r > 0x1000 || g > 0x1000 || b > 0x1000 {
+			t.Errorf("cover is not the custom image, got color %v", img.At(60, 90))
+		}
+		return
+	}
+	t.Fatal("EPUB does not contain cover-1.jpg")
+}
+
+func TestLoadCustomCoversTemplate(t *testing.T) {
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "vol-2.png"))
+	if err != nil {
+		t.Fatalf("failed to create cover: %v", err)
+	}
+	if err := png.Encode(f, testhelpers.CreateTestImage(10, 10, color.Black)); err != nil {
+		t.Fatalf("failed to encode cover: %v", err)
+	}
+	f.Close()
+
+	volumes := []md.Identifier{md.NewIdentifier("1"), md.NewIdentifier("2")}
+	covers, err := disk.LoadCustomCovers(filepath.Join(dir, "vol-{volume}.png"), volumes)
+	if err != nil {
+		t.Fatalf("LoadCustomCovers() failed: %v", err)
+	}
+	if len(covers) != 1 || covers[0].VolumeIdentifier != volumes[1] {
+		t.Errorf("expected single cover for volume 2, got %v", covers)
+	}
+}
+
+func TestSelectCovers(t *testing.T) {
+	manga := loadDiskSeries(t, map[string][]string{
+		"1": {"1"},
+		"2": {"2"},
+		"3": {"3"},
+	})
+	manga.Info.OriginalLanguage = "ja"
+
+	cover := func(volume, locale string) md.Path {
+		return md.Path{
+			DataURL:          volume + "-" + locale + ".jpg",
+			VolumeIdentifier: md.NewWithFallback(volume, "Special"),
+			Locale:           locale,
+		}
+	}
+	covers := md.PathList{
+		cover("1", "ja"),
+		cover("1", "en"),
+		cover("2", "ko"),
+		cover("2", "ja"),
+		cover("3", "ko"),
+		cover("3", "fr"),
+		cover("4", "en"),
+	}
+
+	for _, tc := range []struct {
+		locale string
+		want   []string
+	}{
+		{"en", []string{"1-en.jpg", "2-ja.jpg", "3-ko.jpg"}},
+		{"ko", []string{"1-ja.jpg", "2-ko.jpg", "3-ko.jpg"}},
+		{"", []string{"1-ja.jpg", "2-ja.jpg", "3-ko.jpg"}},
+	} {
+		selected := manga.SelectCovers(covers, tc.locale)
+		if len(selected) != len(tc.want) {
+			t.Fatalf("locale %q: got %v covers, want %v", tc.locale, len(selected), len(tc.want))
+		}
+		for i, want := range tc.want {
+			if selected[i].DataURL != want {
+				t.Errorf("locale %q: volume %v has cover %v, want %v", tc.locale, selected[i].VolumeIdentifier, selected[i].DataURL, want)
+			}
+		}
+	}
+}
+
+func TestNoCovers(t *testing.T) {
+	root := createDiskSeries(t, map[string][]string{
+		"1": {"1"},
+	})
+	f, err := os.Create(filepath.Join(root, "1.png"))
+	if err != nil {
+		t.Fatalf("failed to create cover: %v", err)
+	}
+	if err := png.Encode(f, testhelpers.CreateTestImage(120, 180, color.Black)); err != nil {
+		t.Fatalf("failed to encode cover: %v", err)
+	}
+	f.Close()
+
+	origDiskArg, origNoCoversArg, origFormatsArg := diskArg, noCoversArg, FormatsArg
+	defer func() { diskArg, noCoversArg, FormatsArg = origDiskArg, origNoCoversArg, origFormatsArg }()
+	diskArg, noCoversArg, FormatsArg = root, true, "epub"
+
+	manga := loadDiskSeries(t, map[string][]string{
+		"1": {"1"},
+	})
+	covers, err := getCovers(&manga)
+	if err != nil {
+		t.Fatalf("getCovers() failed: %v", err)
+	}
+	if len(covers) != 0 {
+		t.Fatalf("expected no covers, got %v", len(covers))
+	}
+
+	dir := kindle.NewNormalizedDirectory(t.TempDir(), manga.Info.Title, false)
+	for _, volume := range manga.Sorted() {
+		if err := HandleVolume(manga, volume, dir); err != nil {
+			t.Fatalf("HandleVolume() failed: %v", err)
+		}
+	}
+
+	r, err := zip.OpenReader(dir.Path(manga.Keys()[0], "epub"))
+	if err != nil {
+		t.Fatalf("failed to open EPUB: %v", err)
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if strings.HasPrefix(filepath.Base(f.Name), "cover") {
+			t.Errorf("EPUB contains cover %v", f.Name)
+		}
+		if filepath.Ext(f.Name) != ".opf" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open package: %v", err)
+		}
+		opf, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("failed to read package: %v", err)
+		}
+		if strings.Contains(string(opf), "cover-image") {
+			t.Errorf("package declares a cover image")
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package crop
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+const (
+	// gutterSearchRatio is the part of the width searched for a gutter on
+	// each side of the center
+	gutterSearchRatio = 0.1
+	// gutterDeviationLimit is the highest standard deviation of gray
+	// levels of a column that still counts as an empty gutter
+	gutterDeviationLimit = 6.0
+	// contentDeviationLimit is the lowest standard deviation of gray
+	// levels of some column that each page must reach to count as content
+	contentDeviationLimit = 24.0
+	// contentSamples is the number of columns sampled on each page
+	contentSamples = 32
+)
+
+// FindGutter finds the gutter of a wide image that consists of two pages
+// stitched together. The gutter is an empty column near the center, with
+// content on both sides of it. Genuine spreads have content crossing the
+// center, so no gutter is found for them.
+func FindGutter(img image.Image) (int, bool) {
+	if !ShouldSplit(img) {
+		return 0, false
+	}
+
+	bounds := img.Bounds()
+	center := bounds.Min.X + bounds.Dx()/2
+	radius := int(float64(bounds.Dx()) * gutterSearchRatio)
+
+	// Find the run of empty columns closest to the center
+	start, end, found := 0, 0, false
+	for offset := 0; offset <= radius && !found; offset++ {
+		for _, x := range []int{center - offset, center + offset} {
+			if x >= bounds.Min.X && x < bounds.Max.X && columnDeviation(img, x) <= gutterDeviationLimit {
+				start, end, found = x, x+1, true
+				break
+			}
+		}
+	}
+	if !found {
+		return 0, false
+	}
+	for start > bounds.Min.X && columnDeviation(img, start-1) <= gutterDeviationLimit {
+		start--
+	}
+	for end < bounds.Max.X && columnDeviation(img, end) <= gutterDeviationLimit {
+		end++
+	}
+
+	// Blank pages next to a page are not stitched pages
+	if !hasContent(img, bounds.Min.X, start) || !hasContent(img, end, bounds.Max.X) {
+		return 0, false
+	}
+
+	return (start + end) / 2, true
+}
+
+func hasContent(img image.Image, from, to int) bool {
+	if to <= from {
+		return false
+	}
+	step := max(1, (to-from)/contentSamples)
+	for x := from; x < to; x += step {
+		if columnDeviation(img, x) >= contentDeviationLimit {
+			return true
+		}
+	}
+
+	return false
+}
+
+func columnDeviation(img image.Image, x int) float64 {
+	bounds := img.Bounds()
+	sum, squares := 0.0, 0.0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		v := float64(color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+		sum += v
+		squares += v * v
+	}
+	n := float64(bounds.Dy())
+	mean := sum / n
+
+	return math.Sqrt(max(0, squares/n-mean*mean))
+}
@@ -5,20 +5,43 @@ import (
 	"image"
 )
 
-const aspectRatioLimit = 1.2
+// DefaultWideThreshold is the aspect ratio, width divided by height, above
+// which pages are considered wide
+const DefaultWideThreshold = 1.2
 
 func ShouldSplit(img image.Image) bool {
+	return IsWide(img, DefaultWideThreshold)
+}
+
+// IsWide reports whether the aspect ratio of the image, width divided by
+// height, is above the given threshold.
+func IsWide(img image.Image, threshold float64) bool {
 	size := img.Bounds().Size()
-	aspectRatio := float32(size.X) / float32(size.Y)
+	aspectRatio := float64(size.X) / float64(size.Y)
 
-	return aspectRatio > aspectRatioLimit
+	return aspectRatio > threshold
 }
 
 func Split(img image.Image) (image.Image, image.Image, error) {
 	bounds := img.Bounds()
 
-	left := image.Rect(bounds.Min.X, bounds.Min.Y, bounds.Min.X+bounds.Dx()/2, bounds.Max.Y)
-	right := image.Rect(bounds.Min.X+bounds.Dx()/2, bounds.Min.Y, bounds.Max.X, bounds.Max.Y)
+	return SplitAt(img, bounds.Min.X+bounds.Dx()/2)
+}
+
+// SplitAt splits the image into the parts left and right of the column x.
+// The column x itself belongs to the right part.
+func SplitAt(img image.Image, x int) (image.Image, image.Image, error) {
+	return SplitOverlapping(img, x, 0)
+}
+
+// SplitOverlapping splits the image like SplitAt, but extends both parts
+// by overlap columns past x, so that art crossing the split is not cut.
+func SplitOverlapping(img image.Image, x, overlap int) (image.Image, image.Image, error) {
+	bounds := img.Bounds()
+	overlap = max(overlap, 0)
+
+	left := image.Rect(bounds.Min.X, bounds.Min.Y, min(x+overlap, bounds.Max.X), bounds.Max.Y)
+	right := image.Rect(max(x-overlap, bounds.Min.X), bounds.Min.Y, bounds.Max.X, bounds.Max.Y)
 
 	if img, ok := img.(SubImager); !ok {
 		return nil, nil, fmt.Errorf("image does not support cropping")
@@ -2,9 +2,16 @@ package cmd
 
 import (
 	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/leotaku/kojirou/cmd/filter"
 	"github.com/leotaku/kojirou/cmd/formats/download"
+	"github.com/leotaku/kojirou/cmd/formats/epub"
 	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/logging"
 )
 
 type DataSaverPolicyArg download.DataSaverPolicy
@@ -17,6 +24,8 @@ func (p *DataSaverPolicyArg) String() string {
 		return "prefer"
 	case download.DataSaverPolicyFallback:
 		return "fallback"
+	case download.DataSaverPolicyAuto:
+		return "auto"
 	default:
 		panic("unreachable")
 	}
@@ -30,8 +39,10 @@ func (p *DataSaverPolicyArg) Set(v string) error {
 		*p = DataSaverPolicyArg(download.DataSaverPolicyPrefer)
 	case "fallback":
 		*p = DataSaverPolicyArg(download.DataSaverPolicyFallback)
+	case "auto":
+		*p = DataSaverPolicyArg(download.DataSaverPolicyAuto)
 	default:
-		return fmt.Errorf(`must be one of: "no", "prefer", or "fallback"`)
+		return fmt.Errorf(`must be one of: "no", "prefer", "fallback", or "auto"`)
 	}
 
 	return nil
@@ -80,3 +91,261 @@ func (p *WidepagePolicyArg) Set(v string) error {
 func (p *WidepagePolicyArg) Type() string {
 	return "wide-page policy"
 }
+
+type VerbosityArg logging.Level
+
+func (v *VerbosityArg) String() string {
+	switch logging.Level(*v) {
+	case logging.LevelQuiet:
+		return "quiet"
+	case logging.LevelNormal:
+		return "normal"
+	case logging.LevelVerbose:
+		return "verbose"
+	case logging.LevelDebug:
+		return "debug"
+	default:
+		panic("unreachable")
+	}
+}
+
+func (v *VerbosityArg) Set(s string) error {
+	switch s {
+	case "quiet":
+		*v = VerbosityArg(logging.LevelQuiet)
+	case "normal":
+		*v = VerbosityArg(logging.LevelNormal)
+	case "verbose":
+		*v = VerbosityArg(logging.LevelVerbose)
+	case "debug":
+		*v = VerbosityArg(logging.LevelDebug)
+	default:
+		return fmt.Errorf(`must be one of: "quiet", "normal", "verbose", or "debug"`)
+	}
+
+	return nil
+}
+
+func (v *VerbosityArg) Type() string {
+	return "verbosity"
+}
+
+type PageMarginArg epub.Margin
+
+func (m *PageMarginArg) String() string {
+	if m.Percent > 0 {
+		return strconv.FormatFloat(m.Percent, 'f', -1, 64) + "%"
+	}
+
+	return strconv.Itoa(m.Pixels)
+}
+
+func (m *PageMarginArg) Set(v string) error {
+	if percent, ok := strings.CutSuffix(v, "%"); ok {
+		n, err := strconv.ParseFloat(percent, 64)
+		if err != nil || n < 0 || n >= 50 {
+			return fmt.Errorf(`must be a percentage from 0%% up to 50%%, e.g. "5%%"`)
+		}
+		m.Pixels, m.Percent = 0, n
+	} else {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf(`must be a number of pixels or a percentage, e.g. "20" or "5%%"`)
+		}
+		m.Pixels, m.Percent = n, 0
+	}
+
+	return nil
+}
+
+func (m *PageMarginArg) Type() string {
+	return "margin"
+}
+
+type MarginColorArg struct {
+	color.Color
+}
+
+func (c *MarginColorArg) String() string {
+	if c.Color == color.Black {
+		return "black"
+	}
+
+	return "white"
+}
+
+func (c *MarginColorArg) Set(v string) error {
+	switch v {
+	case "white":
+		c.Color = color.White
+	case "black":
+		c.Color = color.Black
+	default:
+		return fmt.Errorf(`must be one of: "white" or "black"`)
+	}
+
+	return nil
+}
+
+func (c *MarginColorArg) Type() string {
+	return "color"
+}
+
+type CornerArg kindle.Corner
+
+func (c *CornerArg) String() string {
+	switch kindle.Corner(*c) {
+	case kindle.CornerBottomLeft:
+		return "bottom-left"
+	case kindle.CornerTopRight:
+		return "top-right"
+	case kindle.CornerTopLeft:
+		return "top-left"
+	default:
+		return "bottom-right"
+	}
+}
+
+func (c *CornerArg) Set(v string) error {
+	switch v {
+	case "bottom-right":
+		*c = CornerArg(kindle.CornerBottomRight)
+	case "bottom-left":
+		*c = CornerArg(kindle.CornerBottomLeft)
+	case "top-right":
+		*c = CornerArg(kindle.CornerTopRight)
+	case "top-left":
+		*c = CornerArg(kindle.CornerTopLeft)
+	default:
+		return fmt.Errorf(`must be one of: "bottom-right", "bottom-left", "top-right" or "top-left"`)
+	}
+
+	return nil
+}
+
+func (c *CornerArg) Type() string {
+	return "corner"
+}
+
+type CoverSpreadArg kindle.CoverSpread
+
+func (c *CoverSpreadArg) String() string {
+	switch kindle.CoverSpread(*c) {
+	case kindle.CoverSpreadUseAsCover:
+		return "use-as-cover"
+	case kindle.CoverSpreadSplitFrontBack:
+		return "split-front-back"
+	case kindle.CoverSpreadKeepSpread:
+		return "keep-spread"
+	default:
+		return "off"
+	}
+}
+
+func (c *CoverSpreadArg) Set(v string) error {
+	switch v {
+	case "off":
+		*c = CoverSpreadArg(kindle.CoverSpreadOff)
+	case "use-as-cover":
+		*c = CoverSpreadArg(kindle.CoverSpreadUseAsCover)
+	case "split-front-back":
+		*c = CoverSpreadArg(kindle.CoverSpreadSplitFrontBack)
+	case "keep-spread":
+		*c = CoverSpreadArg(kindle.CoverSpreadKeepSpread)
+	default:
+		return fmt.Errorf(`must be one of: "off", "use-as-cover", "split-front-back" or "keep-spread"`)
+	}
+
+	return nil
+}
+
+func (c *CoverSpreadArg) Type() string {
+	return "mode"
+}
+
+type VolumeStrategyArg filter.VolumeStrategy
+
+func (v *VolumeStrategyArg) String() string {
+	switch filter.VolumeStrategy(*v) {
+	case filter.VolumeStrategyByChapterRange:
+		return "by-chapter-range"
+	case filter.VolumeStrategySingle:
+		return "single"
+	default:
+		return "metadata"
+	}
+}
+
+func (v *VolumeStrategyArg) Set(s string) error {
+	switch s {
+	case "metadata":
+		*v = VolumeStrategyArg(filter.VolumeStrategyMetadata)
+	case "by-chapter-range":
+		*v = VolumeStrategyArg(filter.VolumeStrategyByChapterRange)
+	case "single":
+		*v = VolumeStrategyArg(filter.VolumeStrategySingle)
+	default:
+		return fmt.Errorf(`must be one of: "metadata", "by-chapter-range" or "single"`)
+	}
+
+	return nil
+}
+
+func (v *VolumeStrategyArg) Type() string {
+	return "strategy"
+}
+
+type DiskSpaceCheckArg string
+
+func (d *DiskSpaceCheckArg) String() string {
+	if *d == "" {
+		return diskSpaceWarn
+	}
+
+	return string(*d)
+}
+
+func (d *DiskSpaceCheckArg) Set(v string) error {
+	switch v {
+	case diskSpaceWarn, diskSpaceFail, diskSpaceOff:
+		*d = DiskSpaceCheckArg(v)
+	default:
+		return fmt.Errorf(`must be one of: "warn", "fail" or "off"`)
+	}
+
+	return nil
+}
+
+func (d *DiskSpaceCheckArg) Type() string {
+	return "mode"
+}
+
+type SourceDateArg struct {
+	time.Time
+}
+
+func (d *SourceDateArg) String() string {
+	if d.IsZero() {
+		return ""
+	}
+
+	return d.UTC().Format(time.RFC3339)
+}
+
+func (d *SourceDateArg) Set(v string) error {
+	if seconds, err := strconv.ParseInt(v, 10, 64); err == nil {
+		d.Time = time.Unix(seconds, 0)
+	} else if t, err := time.Parse(time.RFC3339, v); err == nil {
+		d.Time = t
+	} else if t, err := time.Parse(time.DateOnly, v); err == nil {
+		d.Time = t
+	} else {
+		return fmt.Errorf(`must be a date, a RFC 3339 timestamp or Unix seconds, e.g. "2024-01-31"`)
+	}
+
+	return nil
+}
+
+func (d *SourceDateArg) Type() string {
+	return "date"
+}
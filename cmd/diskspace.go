@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/leotaku/kojirou/cmd/formats/logging"
+	"github.com/leotaku/kojirou/cmd/formats/util"
+	md "github.com/leotaku/kojirou/mangadex"
+)
+
+// Modes of the disk space check before generating books
+const (
+	diskSpaceWarn = "warn"
+	diskSpaceFail = "fail"
+	diskSpaceOff  = "off"
+)
+
+// estimatedBytesPerPixel is a generous estimate of the size of pages after
+// encoding, so that the check errs on the side of caution
+const estimatedBytesPerPixel = 0.5
+
+// tempCopies is how many copies of the pages of EPUB and KEPUB output are
+// kept in temporary files at once: the encoded pages, the book written by
+// go-epub and the KEPUB conversion
+const tempCopies = 3
+
+// statFS returns the free space and an identifier of the filesystem of
+// the given path. It is replaced in tests to simulate full disks.
+var statFS = statFilesystem
+
+// preflightDiskSpace warns or fails, depending on --disk-space-check, if
+// the book is estimated to not fit into the temporary directory or the
+// given output files
+func preflightDiskSpace(manga md.Manga, temp bool, outputs []string) error {
+	if diskSpaceCheckArg.String() == diskSpaceOff {
+		return nil
+	}
+
+	err := checkDiskSpace(estimateBookSize(manga), temp, outputs)
+	switch {
+	case err == nil || errors.Is(err, errors.ErrUnsupported):
+		return nil
+	case diskSpaceCheckArg.String() == diskSpaceFail:
+		return err
+	default:
+		logging.Infof("warning: %v", err)
+		return nil
+	}
+}
+
+// estimateBookSize returns the estimated size of a book containing all
+// pages of the given manga
+func estimateBookSize(manga md.Manga) uint64 {
+	pixels := 0
+	for _, chapter := range manga.Chapters() {
+		for _, page := range chapter.Pages {
+			pixels += page.Bounds().Dx() * page.Bounds().Dy()
+		}
+	}
+
+	return uint64(float64(pixels) * estimatedBytesPerPixel)
+}
+
+// checkDiskSpace checks that the filesystems of the temporary directory
+// and the given output files have enough free space for a book of the
+// given size. Outputs on the same filesystem add up.
+func checkDiskSpace(size uint64, temp bool, outputs []string) error {
+	type filesystem struct {
+		path         string
+		free, needed uint64
+	}
+	filesystems := make([]*filesystem, 0)
+	byDevice := make(map[uint64]*filesystem)
+	add := func(path string, needed uint64) error {
+		path = existingParent(path)
+		free, device, err := statFS(path)
+		if err != nil {
+			return fmt.Errorf("stat %v: %w", path, err)
+		}
+		fs, ok := byDevice[device]
+		if !ok {
+			fs = &filesystem{path: path, free: free}
+			byDevice[device] = fs
+			filesystems = append(filesystems, fs)
+		}
+		fs.needed += needed
+		return nil
+	}
+
+	if temp {
+		if err := add(cmp.Or(util.TempDir(), os.TempDir()), size*tempCopies); err != nil {
+			return err
+		}
+	}
+	for _, output := range outputs {
+		if err := add(output, size); err != nil {
+			return err
+		}
+	}
+
+	problems := make([]string, 0)
+	for _, fs := range filesystems {
+		if fs.needed > fs.free {
+			problems = append(problems, fmt.Sprintf("%v needs about %v, but only %v are free", fs.path, formatSize(fs.needed), formatSize(fs.free)))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("not enough disk space: %v", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// existingParent returns the given path or its closest ancestor that
+// exists, since outputs are checked before their directories are created
+func existingParent(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+// formatSize formats a number of bytes for humans, e.g. "1.5 GiB"
+func formatSize(bytes uint64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%v B", bytes)
+	}
+	div, exp := uint64(unit), 0
+	for n := bytes / unit; n >= unit && exp < 3; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGT"[exp])
+}
@@ -0,0 +1,11 @@
+//go:build !(linux || darwin || freebsd)
+
+package cmd
+
+import "errors"
+
+// statFilesystem is not supported on this platform, so the disk space
+// check is skipped
+func statFilesystem(path string) (free uint64, device uint64, err error) {
+	return 0, 0, errors.ErrUnsupported
+}
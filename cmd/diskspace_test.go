@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+)
+
+func TestDiskSpacePreflight(t *testing.T) {
+	manga := loadDiskSeries(t, map[string][]string{"1": {"1"}})
+	volume := manga.Sorted()[0]
+
+	origFormatsArg, origDiskSpaceCheckArg, origStatFS := FormatsArg, diskSpaceCheckArg, statFS
+	defer func() { FormatsArg, diskSpaceCheckArg, statFS = origFormatsArg, origDiskSpaceCheckArg, origStatFS }()
+	FormatsArg = "epub"
+	stats := 0
+	statFS = func(path string) (uint64, uint64, error) {
+		stats++
+		return 1024, 1, nil
+	}
+
+	for _, tc := range []struct {
+		mode    string
+		fail    bool
+		checked bool
+	}{
+		{diskSpaceFail, true, true},
+		{diskSpaceWarn, false, true},
+		{diskSpaceOff, false, false},
+	} {
+		if err := diskSpaceCheckArg.Set(tc.mode); err != nil {
+			t.Fatalf("Set() failed: %v", err)
+		}
+		stats = 0
+		dir := kindle.NewNormalizedDirectory(t.TempDir(), manga.Info.Title, false)
+		err := HandleVolume(manga, volume, dir)
+		switch {
+		case tc.fail && (err == nil || !strings.Contains(err.Error(), "not enough disk space")):
+			t.Errorf("%v: expected disk space error, got %v", tc.mode, err)
+		case tc.fail && dir.HasWithExtension(volume.Info.Identifier, "epub"):
+			t.Errorf("%v: output was written despite missing disk space", tc.mode)
+		case !tc.fail && err != nil:
+			t.Errorf("%v: HandleVolume() failed: %v", tc.mode, err)
+		case !tc.fail && !dir.HasWithExtension(volume.Info.Identifier, "epub"):
+			t.Errorf("%v: output was not written", tc.mode)
+		}
+		if checked := stats > 0; checked != tc.checked {
+			t.Errorf("%v: checked disk space is %v, want %v", tc.mode, checked, tc.checked)
+		}
+	}
+}
+
+func TestCheckDiskSpace(t *testing.T) {
+	origStatFS := statFS
+	defer func() { statFS = origStatFS }()
+
+	// Outputs on the same filesystem need space for both books
+	a, b := t.TempDir(), t.TempDir()
+	devices := map[string]uint64{a: 1, b: 1}
+	statFS = func(path string) (uint64, uint64, error) {
+		return 1500, devices[path], nil
+	}
+	outputs := []string{filepath.Join(a, "0001.epub"), filepath.Join(b, "0001.epub")}
+	if err := checkDiskSpace(1000, false, outputs); err == nil {
+		t.Error("expected error for outputs on a full filesystem")
+	}
+
+	devices[b] = 2
+	if err := checkDiskSpace(1000, false, outputs); err != nil {
+		t.Errorf("checkDiskSpace() failed for separate filesystems: %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+//go:build linux || darwin || freebsd
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// statFilesystem returns the space available to unprivileged users on
+// the filesystem of the given path and an identifier of the filesystem
+func statFilesystem(path string) (free uint64, device uint64, err error) {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return 0, 0, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, fmt.Errorf("stat: unsupported file info")
+	}
+
+	return uint64(fs.Bavail) * uint64(fs.Bsize), uint64(st.Dev), nil
+}
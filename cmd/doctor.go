@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/leotaku/kojirou/cmd/formats/download"
+	"github.com/leotaku/kojirou/mangadex/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	doctorOutArg     string
+	doctorTempDirArg string
+	doctorJSONArg    bool
+)
+
+// doctorPingURL is requested to check that MangaDex is reachable. It is
+// replaced in tests to avoid network access.
+var doctorPingURL = api.APIBaseURL.JoinPath("ping").String()
+
+// doctorTimeout limits how long the network check may take
+const doctorTimeout = 10 * time.Second
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor [flags..]",
+	Short: "Check the environment for problems before long runs",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		report := runDoctor(cmd.Context())
+		if doctorJSONArg {
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			return enc.Encode(report)
+		}
+		report.print(cmd.OutOrStdout())
+
+		return nil
+	},
+	// Flags of the root command do not apply to the environment checks,
+	// except that the network is checked like downloads reach it
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := download.SetProxy(proxyArg); err != nil {
+			return fmt.Errorf("proxy: %w", err)
+		}
+
+		return nil
+	},
+	DisableFlagsInUseLine: true,
+}
+
+// doctorCodec reports whether images of a format can be decoded
+type doctorCodec struct {
+	Name      string `json:"name"`
+	Available bool   `json:"available"`
+}
+
+// doctorCheck reports whether a requirement of a run is met
+type doctorCheck struct {
+	Path  string `json:"path"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// doctorSpace reports the free space of a filesystem
+type doctorSpace struct {
+	doctorCheck
+	Free uint64 `json:"free,omitempty"`
+}
+
+// doctorReport is the result of all checks of the doctor command
+type doctorReport struct {
+	Codecs  []doctorCodec `json:"codecs"`
+	Output  doctorCheck   `json:"output"`
+	Network doctorCheck   `json:"network"`
+	Temp    doctorSpace   `json:"temp"`
+}
+
+// webpSample is a 1x1 lossless WebP image, which the standard library
+// cannot encode
+var webpSample, _ = base64.StdEncoding.DecodeString("UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA==")
+
+// codecSamples returns small images of every format that pages are
+// commonly stored in
+func codecSamples() map[string][]byte {
+	img := image.NewGray(image.Rect(0, 0, 1, 1))
+	samples := map[string][]byte{"webp": webpSample}
+	for name, encode := range map[string]func(io.Writer, image.Image) error{
+		"jpeg": func(w io.Writer, img image.Image) error { return jpeg.Encode(w, img, nil) },
+		"png":  png.Encode,
+		"gif":  func(w io.Writer, img image.Image) error { return gif.Encode(w, img, nil) },
+	} {
+		buf := new(bytes.Buffer)
+		if err := encode(buf, img); err == nil {
+			samples[name] = buf.Bytes()
+		}
+	}
+
+	return samples
+}
+
+func runDoctor(ctx context.Context) doctorReport {
+	report := doctorReport{}
+
+	// Decoders are registered by imports, so only decoding tells whether a
+	// format is supported
+	samples := codecSamples()
+	for _, name := range []string{"jpeg", "png", "webp", "gif"} {
+		_, format, err := image.DecodeConfig(bytes.NewReader(samples[name]))
+		report.Codecs = append(report.Codecs, doctorCodec{
+			Name:      name,
+			Available: err == nil && format == name,
+		})
+	}
+
+	report.Output = checkWritable(doctorOutArg)
+	report.Network = checkReachable(ctx, doctorPingURL)
+
+	tempDir := doctorTempDirArg
+	if tempDir == "" {
+		tempDir = os.TempDir()
+	}
+	report.Temp = doctorSpace{doctorCheck: checkWritable(tempDir)}
+	if report.Temp.OK {
+		if free, _, err := statFS(tempDir); err != nil {
+			report.Temp.OK = false
+			report.Temp.Error = fmt.Sprintf("stat: %v", err)
+		} else {
+			report.Temp.Free = free
+		}
+	}
+
+	return report
+}
+
+// checkWritable checks that files can be created in the given directory,
+// which is the working directory if empty
+func checkWritable(dir string) doctorCheck {
+	if dir == "" {
+		dir = "."
+	}
+	check := doctorCheck{Path: dir}
+	f, err := os.CreateTemp(dir, ".kojirou-doctor-*")
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	f.Close()
+	check.OK = os.Remove(f.Name()) == nil
+
+	return check
+}
+
+// checkReachable checks that the given URL responds successfully
+func checkReachable(ctx context.Context, url string) doctorCheck {
+	check := doctorCheck{Path: url}
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	resp, err := download.Client().Do(req)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		check.Error = fmt.Sprintf("status: %v", resp.Status)
+		return check
+	}
+	check.OK = true
+
+	return check
+}
+
+func (r doctorReport) print(w io.Writer) {
+	fmt.Fprintln(w, "Codecs:")
+	for _, codec := range r.Codecs {
+		fmt.Fprintf(w, "  %-5v %v\n", codec.Name, describe(codec.Available, "available", "unavailable"))
+	}
+	fmt.Fprintln(w, "Checks:")
+	for _, check := range []struct {
+		name  string
+		check doctorCheck
+		ok    string
+	}{
+		{"output", r.Output, "writable"},
+		{"network", r.Network, "reachable"},
+		{"temp", r.Temp.doctorCheck, fmt.Sprintf("writable, %v free", formatSize(r.Temp.Free))},
+	} {
+		result := check.ok
+		if !check.check.OK {
+			result = "error: " + check.check.Error
+		}
+		fmt.Fprintf(w, "  %-7v %v (%v)\n", check.name, result, check.check.Path)
+	}
+}
+
+func describe(ok bool, yes, no string) string {
+	if ok {
+		return yes
+	}
+
+	return no
+}
+
+func init() {
+	doctorCmd.Flags().StringVarP(&doctorOutArg, "out", "o", "", "output directory to check")
+	doctorCmd.Flags().StringVarP(&doctorTempDirArg, "temp-dir", "", "", "directory for temporary files to check, defaults to $TMPDIR")
+	doctorCmd.Flags().BoolVarP(&doctorJSONArg, "json", "", false, "print the results as JSON")
+	doctorCmd.Flags().StringVarP(&proxyArg, "proxy", "", "", "HTTP, HTTPS or SOCKS5 proxy URL for the network check")
+	doctorCmd.Flags().SortFlags = false
+	rootCmd.AddCommand(doctorCmd)
+}
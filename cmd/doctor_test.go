@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/download"
+)
+
+func TestDoctorCommand(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("pong")) //nolint:errcheck
+	}))
+	defer server.Close()
+
+	origPingURL, origOutArg, origTempDirArg, origJSONArg := doctorPingURL, doctorOutArg, doctorTempDirArg, doctorJSONArg
+	defer func() {
+		doctorPingURL, doctorOutArg, doctorTempDirArg, doctorJSONArg = origPingURL, origOutArg, origTempDirArg, origJSONArg
+	}()
+	doctorPingURL = server.URL
+
+	out := new(bytes.Buffer)
+	rootCmd.SetOut(out)
+	rootCmd.SetArgs([]string{"doctor", "--json", "--out", t.TempDir(), "--temp-dir", t.TempDir()})
+	defer rootCmd.SetOut(nil)
+	defer rootCmd.SetArgs(nil)
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("doctor failed: %v", err)
+	}
+
+	report := doctorReport{}
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("failed to parse output: %v\n%s", err, out.String())
+	}
+	available := make(map[string]bool)
+	for _, codec := range report.Codecs {
+		available[codec.Name] = codec.Available
+	}
+	for _, name := range []string{"jpeg", "png", "gif"} {
+		if !available[name] {
+			t.Errorf("registered decoder %v is reported as unavailable", name)
+		}
+	}
+	if _, ok := available["webp"]; !ok {
+		t.Errorf("webp decoder is not reported")
+	}
+	for name, check := range map[string]doctorCheck{
+		"output":  report.Output,
+		"network": report.Network,
+		"temp":    report.Temp.doctorCheck,
+	} {
+		if !check.OK {
+			t.Errorf("%v check failed: %v", name, check.Error)
+		}
+	}
+
+	// The same results are printed for humans without --json
+	out.Reset()
+	report.print(out)
+	if !strings.Contains(out.String(), "jpeg  available") {
+		t.Errorf("text output does not report decoders:\n%s", out.String())
+	}
+}
+
+func TestDoctorProxy(t *testing.T) {
+	proxied := make(chan string, 1)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied <- r.URL.String()
+		w.Write([]byte("pong")) //nolint:errcheck
+	}))
+	defer proxy.Close()
+
+	origPingURL, origJSONArg, origProxyArg := doctorPingURL, doctorJSONArg, proxyArg
+	defer func() {
+		doctorPingURL, doctorJSONArg, proxyArg = origPingURL, origJSONArg, origProxyArg
+		download.SetProxy("") //nolint:errcheck
+	}()
+	doctorPingURL = "http://mangadex.invalid/ping"
+
+	out := new(bytes.Buffer)
+	rootCmd.SetOut(out)
+	rootCmd.SetArgs([]string{"doctor", "--json", "--proxy", proxy.URL})
+	defer rootCmd.SetOut(nil)
+	defer rootCmd.SetArgs(nil)
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("doctor failed: %v", err)
+	}
+	report := doctorReport{}
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("failed to parse output: %v\n%s", err, out.String())
+	}
+	if !report.Network.OK {
+		t.Errorf("network check failed: %v", report.Network.Error)
+	}
+	select {
+	case url := <-proxied:
+		if url != doctorPingURL {
+			t.Errorf("proxy received %v, want %v", url, doctorPingURL)
+		}
+	default:
+		t.Error("network check did not use the proxy")
+	}
+}
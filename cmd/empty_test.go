@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/disk"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/progress"
+	"golang.org/x/text/language"
+)
+
+func TestSkipEmptyChapters(t *testing.T) {
+	root := createDiskSeries(t, map[string][]string{"1": {"1", "2", "3"}})
+	skeleton, err := disk.LoadSkeleton(root)
+	if err != nil {
+		t.Fatalf("failed to load skeleton: %v", err)
+	}
+	chapters, err := disk.LoadChapters(root, language.English, progress.VanishingProgress("Disk..."))
+	if err != nil {
+		t.Fatalf("failed to load chapters: %v", err)
+	}
+	manga := skeleton.WithChapters(chapters)
+	volume := manga.Sorted()[0]
+
+	// Chapter 2 lost its pages, like a chapter deleted from MangaDex
+	for _, page := range []string{"01.png", "02.png"} {
+		if err := os.Remove(filepath.Join(root, "1", "2", page)); err != nil {
+			t.Fatalf("failed to remove page: %v", err)
+		}
+	}
+
+	origFormatsArg, origSkipEmptyChaptersArg := FormatsArg, skipEmptyChaptersArg
+	defer func() { FormatsArg, skipEmptyChaptersArg = origFormatsArg, origSkipEmptyChaptersArg }()
+	FormatsArg = "epub,mobi"
+
+	skipEmptyChaptersArg = false
+	dir := kindle.NewNormalizedDirectory(t.TempDir(), manga.Info.Title, false)
+	if err := HandleVolume(manga, volume, dir); err == nil {
+		t.Fatal("HandleVolume() succeeded with empty chapter")
+	}
+
+	skipEmptyChaptersArg = true
+	dir = kindle.NewNormalizedDirectory(t.TempDir(), manga.Info.Title, false)
+	if err := HandleVolume(manga, volume, dir); err != nil {
+		t.Fatalf("HandleVolume() failed: %v", err)
+	}
+	if !dir.HasWithExtension(volume.Info.Identifier, "azw3") {
+		t.Error("MOBI output was not written")
+	}
+
+	r, err := zip.OpenReader(dir.Path(volume.Info.Identifier, "epub"))
+	if err != nil {
+		t.Fatalf("failed to open EPUB: %v", err)
+	}
+	defer r.Close()
+	for _, name := range []string{"EPUB/package.opf", "EPUB/nav.xhtml"} {
+		rc, err := r.Open(name)
+		if err != nil {
+			t.Fatalf("failed to open %v: %v", name, err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("failed to read %v: %v", name, err)
+		}
+
+		for _, chapter := range []string{"chapter-1-1.xhtml", "chapter-1-3.xhtml"} {
+			if !strings.Contains(string(content), chapter) {
+				t.Errorf("%v is missing %v", name, chapter)
+			}
+		}
+		if strings.Contains(string(content), "chapter-1-2.xhtml") {
+			t.Errorf("%v still references the empty chapter", name)
+		}
+	}
+}
+
+func TestMinPages(t *testing.T) {
+	root := createDiskSeries(t, map[string][]string{"1": {"1", "2"}})
+	skeleton, err := disk.LoadSkeleton(root)
+	if err != nil {
+		t.Fatalf("failed to load skeleton: %v", err)
+	}
+	chapters, err := disk.LoadChapters(root, language.English, progress.VanishingProgress("Disk..."))
+	if err != nil {
+		t.Fatalf("failed to load chapters: %v", err)
+	}
+	manga := skeleton.WithChapters(chapters)
+	volume := manga.Sorted()[0]
+
+	// Chapter 2 is a single page advertisement
+	if err := os.Remove(filepath.Join(root, "1", "2", "02.png")); err != nil {
+		t.Fatalf("failed to remove page: %v", err)
+	}
+
+	origFormatsArg, origMinPagesArg := FormatsArg, minPagesArg
+	defer func() { FormatsArg, minPagesArg = origFormatsArg, origMinPagesArg }()
+	FormatsArg = "epub"
+
+	for _, tc := range []struct {
+		minPages int
+		kept     []string
+		dropped  []string
+	}{
+		{0, []string{"chapter-1-1.xhtml", "chapter-1-2.xhtml"}, nil},
+		{2, []string{"chapter-1-1.xhtml"}, []string{"chapter-1-2.xhtml"}},
+	} {
+		minPagesArg = tc.minPages
+		dir := kindle.NewNormalizedDirectory(t.TempDir(), manga.Info.Title, false)
+		if err := HandleVolume(manga, volume, dir); err != nil {
+			t.Fatalf("HandleVolume() failed: %v", err)
+		}
+
+		r, err := zip.OpenReader(dir.Path(volume.Info.Identifier, "epub"))
+		if err != nil {
+			t.Fatalf("failed to open EPUB: %v", err)
+		}
+		files := make(map[string]bool)
+		for _, f := range r.File {
+			files[filepath.Base(f.Name)] = true
+		}
+		r.Close()
+
+		for _, name := range tc.kept {
+			if !files[name] {
+				t.Errorf("min pages %v: %v was dropped", tc.minPages, name)
+			}
+		}
+		for _, name := range tc.dropped {
+			if files[name] {
+				t.Errorf("min pages %v: %v was kept", tc.minPages, name)
+			}
+		}
+	}
+
+	// Volumes without any long enough chapter fail
+	minPagesArg = 3
+	dir := kindle.NewNormalizedDirectory(t.TempDir(), manga.Info.Title, false)
+	if err := HandleVolume(manga, volume, dir); err == nil {
+		t.Error("HandleVolume() succeeded without chapters")
+	}
+}
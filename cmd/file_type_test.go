@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+)
+
+func TestFileTypePerVolume(t *testing.T) {
+	manga := loadDiskSeries(t, map[string][]string{"1": {"1"}, "2": {"2"}, "3": {"3"}})
+
+	origFormatsArg := FormatsArg
+	defer func() { FormatsArg = origFormatsArg }()
+	FormatsArg = "-1:mobi,2-:epub,3:kepub"
+
+	dir := kindle.NewNormalizedDirectory(t.TempDir(), manga.Info.Title, false)
+	if err := handleVolumes(manga, dir, 1); err != nil {
+		t.Fatalf("handleVolumes() failed: %v", err)
+	}
+
+	for _, volume := range manga.Sorted() {
+		want := map[string]bool{
+			"azw3":       volume.Info.Identifier.String() == "1",
+			"epub":       volume.Info.Identifier.String() != "1",
+			"kepub.epub": volume.Info.Identifier.String() == "3",
+		}
+		for extension, written := range want {
+			_, err := os.Stat(dir.Path(volume.Info.Identifier, extension))
+			if written && err != nil {
+				t.Errorf("volume %v: %v was not written: %v", volume.Info.Identifier, extension, err)
+			} else if !written && err == nil {
+				t.Errorf("volume %v: %v was written", volume.Info.Identifier, extension)
+			}
+		}
+	}
+}
@@ -3,6 +3,7 @@ package filter
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
 
 	md "github.com/leotaku/kojirou/mangadex"
@@ -17,6 +18,30 @@ func FilterByLanguage(cl md.ChapterList, lang language.Tag) md.ChapterList {
 	})
 }
 
+func FilterByLanguagePriority(cl md.ChapterList, langs []language.Tag) md.ChapterList {
+	priority := func(lang language.Tag) int {
+		for i, l := range langs {
+			if l == lang {
+				return i
+			}
+		}
+		return -1
+	}
+
+	best := make(map[md.Identifier]int)
+	for _, c := range cl {
+		p := priority(c.Info.Language)
+		if val, ok := best[c.Info.Identifier]; p >= 0 && (!ok || p < val) {
+			best[c.Info.Identifier] = p
+		}
+	}
+
+	return cl.FilterBy(func(c md.ChapterInfo) bool {
+		p := priority(c.Language)
+		return p >= 0 && p == best[c.Identifier]
+	})
+}
+
 func FilterByRegex(cl md.ChapterList, field string, pattern string) md.ChapterList {
 	return cl.FilterBy(func(ci md.ChapterInfo) bool {
 		v := reflect.ValueOf(ci).FieldByName(field).Interface()
@@ -24,6 +49,31 @@ func FilterByRegex(cl md.ChapterList, field string, pattern string) md.ChapterLi
 	})
 }
 
+func FilterByExcludedGroups(cl md.ChapterList, names []string) md.ChapterList {
+	excluded := make(map[string]struct{})
+	for _, name := range names {
+		excluded[strings.ToLower(strings.TrimSpace(name))] = struct{}{}
+	}
+
+	return cl.FilterBy(func(ci md.ChapterInfo) bool {
+		for _, group := range ci.GroupNames {
+			if _, ok := excluded[strings.ToLower(strings.TrimSpace(group))]; ok {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// FilterByMinPages removes chapters with fewer than the given number of
+// pages, such as one page advertisements. Chapters with an unknown number
+// of pages are kept.
+func FilterByMinPages(cl md.ChapterList, minPages int) md.ChapterList {
+	return cl.FilterBy(func(ci md.ChapterInfo) bool {
+		return ci.Pages == 0 || ci.Pages >= minPages
+	})
+}
+
 func FilterByIdentifier(cl md.ChapterList, field string, ranges Ranges) md.ChapterList {
 	return cl.FilterBy(func(ci md.ChapterInfo) bool {
 		v := reflect.ValueOf(ci).FieldByName(field).Interface()
@@ -55,6 +105,25 @@ func SortByNewestGroup(cl md.ChapterList) md.ChapterList {
 	})
 }
 
+func SortByOldest(cl md.ChapterList) md.ChapterList {
+	return cl.SortBy(func(a, b md.ChapterInfo) bool {
+		return a.Published.Before(b.Published)
+	})
+}
+
+func SortByOldestGroup(cl md.ChapterList) md.ChapterList {
+	groupRanking := make(map[string]time.Time)
+	for _, c := range cl {
+		if val, ok := groupRanking[gid(c.Info)]; !ok || c.Info.Published.Before(val) {
+			groupRanking[gid(c.Info)] = c.Info.Published
+		}
+	}
+
+	return cl.SortBy(func(a, b md.ChapterInfo) bool {
+		return groupRanking[gid(a)].Before(groupRanking[gid(b)])
+	})
+}
+
 func SortByViews(cl md.ChapterList) md.ChapterList {
 	return cl.SortBy(func(a, b md.ChapterInfo) bool {
 		return a.Views > b.Views
@@ -83,6 +152,29 @@ func SortByMost(cl md.ChapterList) md.ChapterList {
 	})
 }
 
+func SortByPreferredGroups(cl md.ChapterList, names []string) md.ChapterList {
+	priority := make(map[string]int)
+	for i, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := priority[name]; !ok {
+			priority[name] = i
+		}
+	}
+	rank := func(ci md.ChapterInfo) int {
+		result := len(names)
+		for _, group := range ci.GroupNames {
+			if p, ok := priority[strings.ToLower(strings.TrimSpace(group))]; ok && p < result {
+				result = p
+			}
+		}
+		return result
+	}
+
+	return cl.SortBy(func(a, b md.ChapterInfo) bool {
+		return rank(a) < rank(b)
+	})
+}
+
 func RemoveDuplicates(cl md.ChapterList) md.ChapterList {
 	return cl.CollapseBy(func(c md.ChapterInfo) interface{} {
 		return struct {
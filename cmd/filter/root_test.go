@@ -0,0 +1,145 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	md "github.com/leotaku/kojirou/mangadex"
+	"golang.org/x/text/language"
+)
+
+func chapter(id string, groups ...string) md.Chapter {
+	return md.Chapter{
+		Info: md.ChapterInfo{
+			Identifier:       md.NewIdentifier(id),
+			VolumeIdentifier: md.NewIdentifier("1"),
+			GroupNames:       groups,
+		},
+	}
+}
+
+func identifiers(cl md.ChapterList) []string {
+	result := make([]string, 0)
+	for _, c := range cl {
+		result = append(result, c.Info.Identifier.String()+"/"+c.Info.GroupNames.String())
+	}
+
+	return result
+}
+
+func assertIdentifiers(t *testing.T, cl md.ChapterList, want ...string) {
+	t.Helper()
+	got := identifiers(cl)
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestFilterByExcludedGroups(t *testing.T) {
+	cl := md.ChapterList{
+		chapter("1", "A"),
+		chapter("2", "B"),
+		chapter("3", "C"),
+		chapter("4", "A", "B"),
+		chapter("5", "C"),
+	}
+
+	assertIdentifiers(t, FilterByExcludedGroups(cl, []string{"B"}),
+		"1/A", "3/C", "5/C",
+	)
+	assertIdentifiers(t, FilterByExcludedGroups(cl, []string{" b ", "c"}),
+		"1/A",
+	)
+	assertIdentifiers(t, FilterByExcludedGroups(cl, nil),
+		"1/A", "2/B", "3/C", "4/A and B", "5/C",
+	)
+}
+
+func TestSortByPreferredGroups(t *testing.T) {
+	cl := md.ChapterList{
+		chapter("4", "A"),
+		chapter("5", "A"),
+		chapter("5", "B"),
+		chapter("6", "C"),
+	}
+
+	assertIdentifiers(t, RemoveDuplicates(SortByPreferredGroups(cl, []string{"b"})),
+		"5/B", "4/A", "6/C",
+	)
+	assertIdentifiers(t, RemoveDuplicates(SortByPreferredGroups(cl, []string{"A", "B"})),
+		"4/A", "5/A", "6/C",
+	)
+}
+
+func TestSortByOldest(t *testing.T) {
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	published := func(c md.Chapter, days int) md.Chapter {
+		c.Info.Published = base.AddDate(0, 0, days)
+		return c
+	}
+	cl := md.ChapterList{
+		published(chapter("1", "A"), 5),
+		published(chapter("1", "B"), 1),
+		published(chapter("2", "A"), 3),
+		published(chapter("2", "C"), 3),
+		published(chapter("3", "C"), 0),
+	}
+
+	assertIdentifiers(t, SortByOldest(cl),
+		"3/C", "1/B", "2/A", "2/C", "1/A",
+	)
+	assertIdentifiers(t, SortByOldestGroup(cl),
+		"3/C", "2/C", "1/B", "2/A", "1/A",
+	)
+	assertIdentifiers(t, RemoveDuplicates(SortByOldestGroup(cl)),
+		"3/C", "2/C", "1/B",
+	)
+}
+
+func TestFilterByLanguagePriority(t *testing.T) {
+	translated := func(c md.Chapter, lang language.Tag) md.Chapter {
+		c.Info.Language = lang
+		return c
+	}
+	cl := md.ChapterList{
+		translated(chapter("1", "A"), language.Spanish),
+		translated(chapter("1", "B"), language.English),
+		translated(chapter("2", "C"), language.Spanish),
+		translated(chapter("3", "D"), language.French),
+	}
+
+	assertIdentifiers(t, FilterByLanguagePriority(cl, []language.Tag{language.English, language.Spanish}),
+		"1/B", "2/C",
+	)
+	assertIdentifiers(t, FilterByLanguagePriority(cl, []language.Tag{language.English}),
+		"1/B",
+	)
+}
+
+func TestFilterByMinPages(t *testing.T) {
+	withPages := func(c md.Chapter, pages int) md.Chapter {
+		c.Info.Pages = pages
+		return c
+	}
+	cl := md.ChapterList{
+		withPages(chapter("1", "A"), 20),
+		withPages(chapter("1", "Ads"), 1),
+		withPages(chapter("2", "A"), 3),
+		withPages(chapter("3", "Disk"), 0),
+	}
+
+	assertIdentifiers(t, FilterByMinPages(cl, 3),
+		"1/A", "2/A", "3/Disk",
+	)
+	assertIdentifiers(t, FilterByMinPages(cl, 4),
+		"1/A", "3/Disk",
+	)
+	assertIdentifiers(t, FilterByMinPages(cl, 0),
+		"1/A", "1/Ads", "2/A", "3/Disk",
+	)
+}
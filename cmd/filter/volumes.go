@@ -0,0 +1,143 @@
+package filter
+
+import (
+	"sort"
+
+	md "github.com/leotaku/kojirou/mangadex"
+)
+
+// VolumeStrategy decides how chapters are grouped into volumes, since the
+// volume metadata of MangaDex does not always agree with the numbering of
+// chapters.
+type VolumeStrategy int
+
+const (
+	// VolumeStrategyMetadata groups chapters by their volume metadata
+	VolumeStrategyMetadata VolumeStrategy = iota
+	// VolumeStrategyByChapterRange groups chapters into the consecutive
+	// ranges of chapter numbers that most of the volume metadata agrees
+	// on, correcting chapters whose volume does not fit their number
+	VolumeStrategyByChapterRange
+	// VolumeStrategySingle groups all chapters into a single volume
+	VolumeStrategySingle
+)
+
+// Apply returns the chapters with their volumes assigned by the strategy.
+//
+// For VolumeStrategyByChapterRange, the volume of every chapter number is
+// the one most of its chapters are labeled with. Of these, the longest run
+// of volumes that does not decrease with the chapter number is kept. Other
+// chapters, as well as chapters without a volume that are numbered between
+// chapters with one, join the volume of the chapter before them.
+func (s VolumeStrategy) Apply(cl md.ChapterList) md.ChapterList {
+	var volumeOf func(md.ChapterInfo) (md.Identifier, bool)
+	switch s {
+	case VolumeStrategyByChapterRange:
+		volumes := chapterRangeVolumes(cl)
+		volumeOf = func(ci md.ChapterInfo) (md.Identifier, bool) {
+			vol, ok := volumes[ci.Identifier]
+			return vol, ok
+		}
+	case VolumeStrategySingle:
+		volumeOf = func(md.ChapterInfo) (md.Identifier, bool) {
+			return md.NewIdentifier("1"), true
+		}
+	default:
+		return cl
+	}
+
+	result := make(md.ChapterList, 0, len(cl))
+	for _, val := range cl {
+		if vol, ok := volumeOf(val.Info); ok {
+			val.Info.VolumeIdentifier = vol
+		}
+		result = append(result, val)
+	}
+
+	return result
+}
+
+// chapterRangeVolumes maps the numbers of chapters to their volume by
+// chapter range, leaving out chapters that keep their volume
+func chapterRangeVolumes(cl md.ChapterList) map[md.Identifier]md.Identifier {
+	votes := make(map[md.Identifier]map[md.Identifier]int)
+	unlabeled := make([]md.Identifier, 0)
+	for _, c := range cl {
+		chapID, volID := c.Info.Identifier, c.Info.VolumeIdentifier
+		switch {
+		case chapID.IsSpecial():
+		case volID.IsSpecial():
+			unlabeled = append(unlabeled, chapID)
+		default:
+			if votes[chapID] == nil {
+				votes[chapID] = make(map[md.Identifier]int)
+			}
+			votes[chapID][volID]++
+		}
+	}
+
+	chapters := make([]md.Identifier, 0, len(votes))
+	for chapID := range votes {
+		chapters = append(chapters, chapID)
+	}
+	sort.Slice(chapters, func(i, j int) bool { return chapters[i].Less(chapters[j]) })
+	labels := make([]md.Identifier, len(chapters))
+	for i, chapID := range chapters {
+		count := 0
+		for volID, n := range votes[chapID] {
+			if n > count || n == count && volID.Less(labels[i]) {
+				labels[i], count = volID, n
+			}
+		}
+	}
+
+	// Find the longest run of non-decreasing volumes, preferring earlier
+	// chapters between runs of the same length
+	length := make([]int, len(labels))
+	previous := make([]int, len(labels))
+	last := -1
+	for i := range labels {
+		length[i], previous[i] = 1, -1
+		for j := range i {
+			if labels[j].LessOrEqual(labels[i]) && length[j]+1 > length[i] {
+				length[i], previous[i] = length[j]+1, j
+			}
+		}
+		if last < 0 || length[i] > length[last] {
+			last = i
+		}
+	}
+	kept := make([]bool, len(labels))
+	for i := last; i >= 0; i = previous[i] {
+		kept[i] = true
+	}
+
+	result := make(map[md.Identifier]md.Identifier)
+	for i, chapID := range chapters {
+		switch {
+		case kept[i]:
+			result[chapID] = labels[i]
+		case i > 0:
+			if vol, ok := result[chapters[i-1]]; ok {
+				result[chapID] = vol
+			}
+		}
+	}
+	// Chapters before the first kept chapter join its volume
+	for i := len(chapters) - 1; i >= 0; i-- {
+		if _, ok := result[chapters[i]]; !ok && i+1 < len(chapters) {
+			result[chapters[i]] = result[chapters[i+1]]
+		}
+	}
+	for _, chapID := range unlabeled {
+		if _, ok := result[chapID]; ok || len(chapters) == 0 {
+			continue
+		}
+		if first, final := chapters[0], chapters[len(chapters)-1]; first.Less(chapID) && chapID.Less(final) {
+			before := sort.Search(len(chapters), func(i int) bool { return chapID.Less(chapters[i]) }) - 1
+			result[chapID] = result[chapters[before]]
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,92 @@
+package filter
+
+import (
+	"maps"
+	"testing"
+
+	md "github.com/leotaku/kojirou/mangadex"
+)
+
+// mislabeledChapters returns chapters whose volume metadata disagrees with
+// their numbering: chapter 5 is labeled with a later volume, one group
+// puts chapter 7 into the previous volume and chapters 8 and 11 have no
+// volume at all
+func mislabeledChapters() md.ChapterList {
+	cl := make(md.ChapterList, 0)
+	for _, tc := range []struct {
+		chapter string
+		volume  md.Identifier
+		groups  []string
+	}{
+		{"1", md.NewIdentifier("1"), nil},
+		{"2", md.NewIdentifier("1"), nil},
+		{"3", md.NewIdentifier("1"), nil},
+		{"4", md.NewIdentifier("1"), nil},
+		{"5", md.NewIdentifier("3"), nil},
+		{"6", md.NewIdentifier("2"), nil},
+		{"7", md.NewIdentifier("2"), []string{"A"}},
+		{"7", md.NewIdentifier("2"), []string{"B"}},
+		{"7", md.NewIdentifier("1"), []string{"C"}},
+		{"8", md.OneshotIdentifier(), nil},
+		{"9", md.NewIdentifier("2"), nil},
+		{"10", md.NewIdentifier("3"), nil},
+		{"11", md.OneshotIdentifier(), nil},
+	} {
+		c := chapter(tc.chapter, tc.groups...)
+		c.Info.VolumeIdentifier = tc.volume
+		cl = append(cl, c)
+	}
+
+	return cl
+}
+
+// volumes returns the volume of every chapter and group
+func volumes(cl md.ChapterList) map[string]string {
+	result := make(map[string]string)
+	for _, c := range cl {
+		result[c.Info.Identifier.String()+"/"+c.Info.GroupNames.String()] = c.Info.VolumeIdentifier.String()
+	}
+
+	return result
+}
+
+func TestVolumeStrategy(t *testing.T) {
+	metadata := volumes(mislabeledChapters())
+	byRange := maps.Clone(metadata)
+	byRange["5/Unknown"], byRange["7/C"], byRange["8/Unknown"] = "1", "2", "2"
+	single := maps.Clone(metadata)
+	for key := range single {
+		single[key] = "1"
+	}
+
+	for _, tt := range []struct {
+		name     string
+		strategy VolumeStrategy
+		want     map[string]string
+	}{
+		{"metadata", VolumeStrategyMetadata, metadata},
+		{"by-chapter-range", VolumeStrategyByChapterRange, byRange},
+		{"single", VolumeStrategySingle, single},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := volumes(tt.strategy.Apply(mislabeledChapters())); !maps.Equal(got, tt.want) {
+				t.Errorf("got volumes %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVolumeStrategyLeadingChapters(t *testing.T) {
+	cl := make(md.ChapterList, 0)
+	for _, id := range []string{"1", "2", "3", "4"} {
+		c := chapter(id)
+		c.Info.VolumeIdentifier = md.NewIdentifier("2")
+		cl = append(cl, c)
+	}
+	cl[0].Info.VolumeIdentifier = md.NewIdentifier("5")
+
+	got := volumes(VolumeStrategyByChapterRange.Apply(cl))
+	if want := map[string]string{"1/Unknown": "2", "2/Unknown": "2", "3/Unknown": "2", "4/Unknown": "2"}; !maps.Equal(got, want) {
+		t.Errorf("got volumes %v, want %v", got, want)
+	}
+}
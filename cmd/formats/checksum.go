@@ -0,0 +1,70 @@
+package formats
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ChecksumExtension is appended to the name of a file to form its checksum sidecar.
+const ChecksumExtension = ".sha256"
+
+// WriteChecksum writes a SHA-256 sidecar file next to the given file.
+//
+// The sidecar uses the format of sha256sum, so it can be verified with
+// "sha256sum -c" from within the containing directory.
+func WriteChecksum(filename string) error {
+	sum, err := fileChecksum(filename)
+	if err != nil {
+		return fmt.Errorf("checksum: %w", err)
+	}
+
+	content := fmt.Sprintf("%v  %v\n", sum, filepath.Base(filename))
+	if err := os.WriteFile(filename+ChecksumExtension, []byte(content), 0644); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyChecksum checks the given file against its SHA-256 sidecar file.
+// Files without a sidecar are considered valid.
+func VerifyChecksum(filename string) error {
+	sidecar, err := os.ReadFile(filename + ChecksumExtension)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("read sidecar: %w", err)
+	}
+
+	want, _, _ := strings.Cut(string(sidecar), " ")
+	got, err := fileChecksum(filename)
+	if err != nil {
+		return fmt.Errorf("checksum: %w", err)
+	} else if got != want {
+		return fmt.Errorf("checksum mismatch: got %v, want %v", got, want)
+	}
+
+	return nil
+}
+
+func fileChecksum(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("read: %w", err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,31 @@
+package formats
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteChecksum(t *testing.T) {
+	filename := writeOutput(t, FormatEpub)
+	if err := WriteChecksum(filename); err != nil {
+		t.Fatalf("WriteChecksum() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	sidecar, err := os.ReadFile(filename + ChecksumExtension)
+	if err != nil {
+		t.Fatalf("failed to read sidecar: %v", err)
+	}
+
+	sum := sha256.Sum256(data)
+	want := hex.EncodeToString(sum[:]) + "  " + filepath.Base(filename) + "\n"
+	if string(sidecar) != want {
+		t.Errorf("sidecar content %q, want %q", sidecar, want)
+	}
+}
@@ -0,0 +1,74 @@
+package disk
+
+import (
+	"fmt"
+	"os"
+	"path"
+)
+
+// DiscoverSeries finds all series directories in the given folder tree, in
+// lexical order. A series directory follows the layout that LoadChapters
+// expects, series/volume/chapter/pages, so it is recognized by a chapter
+// directory containing files. Directories of other names are searched for
+// series at any depth, but series directories are not searched further.
+// Chapter archives count as chapter directories containing files.
+func DiscoverSeries(directory string) ([]string, error) {
+	result := make([]string, 0)
+	ok, err := isSeries(directory)
+	if err != nil {
+		return nil, err
+	} else if ok {
+		return append(result, directory), nil
+	}
+
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, fmt.Errorf("list '%v': %w", directory, err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		series, err := DiscoverSeries(path.Join(directory, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, series...)
+	}
+
+	return result, nil
+}
+
+func isSeries(directory string) (bool, error) {
+	volumes, err := os.ReadDir(directory)
+	if err != nil {
+		return false, fmt.Errorf("list '%v': %w", directory, err)
+	}
+	for _, volume := range volumes {
+		if !volume.IsDir() {
+			continue
+		}
+		chapters, err := os.ReadDir(path.Join(directory, volume.Name()))
+		if err != nil {
+			return false, fmt.Errorf("list '%v': %w", volume.Name(), err)
+		}
+		for _, chapter := range chapters {
+			if chapter.Type().IsRegular() && isCBR(chapter.Name()) {
+				return true, nil
+			} else if !chapter.IsDir() {
+				continue
+			}
+			pages, err := os.ReadDir(path.Join(directory, volume.Name(), chapter.Name()))
+			if err != nil {
+				return false, fmt.Errorf("list '%v': %w", chapter.Name(), err)
+			}
+			for _, page := range pages {
+				if page.Type().IsRegular() {
+					return true, nil
+				}
+			}
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,99 @@
+package disk
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/leotaku/kojirou/cmd/formats/progress"
+	md "github.com/leotaku/kojirou/mangadex"
+)
+
+// Files of a MangaDex JSON dump. Besides these, the dump contains a
+// directory of page images for every chapter, named after the chapter ID.
+const (
+	DumpMangaFile    = "manga.json"
+	DumpChaptersFile = "chapters.json"
+)
+
+// LoadDump loads the manga and its chapters from a MangaDex JSON dump. The
+// pages of the chapters are loaded separately by LoadDumpPages.
+func LoadDump(directory string) (*md.Manga, md.ChapterList, error) {
+	manga, err := os.ReadFile(path.Join(directory, DumpMangaFile))
+	if err != nil {
+		return nil, nil, fmt.Errorf("read: %w", err)
+	}
+	chapters, err := os.ReadFile(path.Join(directory, DumpChaptersFile))
+	if err != nil {
+		return nil, nil, fmt.Errorf("read: %w", err)
+	}
+
+	skeleton, cl, err := md.ParseDump(manga, chapters)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, chapter := range cl {
+		if _, err := os.Stat(path.Join(directory, chapter.Info.ID)); err != nil {
+			return nil, nil, fmt.Errorf("chapter %v: missing images: %w", chapter.Info.Identifier, err)
+		}
+	}
+
+	return skeleton, cl, nil
+}
+
+// LoadDumpPages loads the pages of the given chapters from a MangaDex JSON
+// dump, in the order of their filenames.
+func LoadDumpPages(directory string, cl md.ChapterList, p progress.Progress) (md.ImageList, error) {
+	result := make(md.ImageList, 0)
+	for _, chap := range cl {
+		chapterDir := path.Join(directory, chap.Info.ID)
+		entries, err := os.ReadDir(chapterDir)
+		if err != nil {
+			return nil, fmt.Errorf("list '%v': %w", chap.Info.Identifier, err)
+		}
+		if chap.Info.Pages != 0 && len(entries) != chap.Info.Pages {
+			return nil, fmt.Errorf("chapter %v: expected %v pages, found %v", chap.Info.Identifier, chap.Info.Pages, len(entries))
+		}
+
+		p.Increase(len(entries))
+		for id, entry := range entries {
+			p.Add(1)
+
+			img, err := decodeImage(path.Join(chapterDir, entry.Name()))
+			if err != nil {
+				return nil, fmt.Errorf("page '%v': %w", entry.Name(), err)
+			}
+
+			result = append(result, md.Image{
+				Image:             img,
+				Filename:          entry.Name(),
+				ImageIdentifier:   id,
+				ChapterIdentifier: chap.Info.Identifier,
+				VolumeIdentifier:  chap.Info.VolumeIdentifier,
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// WriteDump writes the manga and the given chapters as a MangaDex JSON dump
+// without any pages, which can be loaded again by LoadDump once the pages
+// have been added.
+func WriteDump(directory string, info md.MangaInfo, cl md.ChapterList) error {
+	manga, chapters, err := md.ExportDump(info, cl)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if err := os.WriteFile(path.Join(directory, DumpMangaFile), manga, 0644); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	if err := os.WriteFile(path.Join(directory, DumpChaptersFile), chapters, 0644); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return nil
+}
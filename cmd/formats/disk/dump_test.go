@@ -0,0 +1,271 @@
+package disk
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leotaku/kojirou/cmd/formats/progress"
+	md "github.com/leotaku/kojirou/mangadex"
+	"golang.org/x/text/language"
+)
+
+const dumpManga = `{
+  "result": "ok",
+  "response": "entity",
+  "data": {
+    "id": "manga-1",
+    "type": "manga",
+    "attributes": {
+      "title": {"en": "Dumped Manga"},
+      "originalLanguage": "ja",
+      "links": {"mal": "1234", "raw": "https://example.com"}
+    },
+    "relationships": [
+      {"id": "author-1", "type": "author", "attributes": {"name": "Writer"}},
+      {"id": "artist-1", "type": "artist", "attributes": {"name": "Painter"}},
+      {"id": "cover-1", "type": "cover_art"}
+    ]
+  }
+}`
+
+const dumpChapters = `{
+  "result": "ok",
+  "response": "collection",
+  "data": [
+    {
+      "id": "chapter-1",
+      "type": "chapter",
+      "attributes": {"volume": "1", "chapter": "1", "title": "Start", "pages": 2, "translatedLanguage": "en"},
+      "relationships": [
+        {"id": "group-1", "type": "scanlation_group", "attributes": {"name": "Scans"}},
+        {"id": "manga-1", "type": "manga"}
+      ]
+    },
+    {
+      "id": "chapter-2",
+      "type": "chapter",
+      "attributes": {"volume": "2", "chapter": "2.5", "pages": 1, "translatedLanguage": "en"},
+      "relationships": [{"id": "group-1", "type": "scanlation_group"}]
+    }
+  ],
+  "limit": 500,
+  "offset": 0,
+  "total": 2
+}`
+
+// writeDump writes a dump where every page is as wide as its number
+func writeDump(t *testing.T, manga, chapters string, pages map[string]int) string {
+	t.Helper()
+
+	root := t.TempDir()
+	for name, content := range map[string]string{DumpMangaFile: manga, DumpChaptersFile: chapters} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %v: %v", name, err)
+		}
+	}
+	for chapter, count := range pages {
+		if err := os.MkdirAll(filepath.Join(root, chapter), 0755); err != nil {
+			t.Fatalf("failed to create chapter: %v", err)
+		}
+		for i := 1; i <= count; i++ {
+			f, err := os.Create(filepath.Join(root, chapter, string(rune('0'+i))+".png"))
+			if err != nil {
+				t.Fatalf("failed to create page: %v", err)
+			}
+			if err := png.Encode(f, image.NewGray(image.Rect(0, 0, i, 10))); err != nil {
+				t.Fatalf("failed to encode page: %v", err)
+			}
+			f.Close()
+		}
+	}
+
+	return root
+}
+
+func TestLoadDump(t *testing.T) {
+	root := writeDump(t, dumpManga, dumpChapters, map[string]int{"chapter-1": 2, "chapter-2": 1})
+	skeleton, chapters, err := LoadDump(root)
+	if err != nil {
+		t.Fatalf("LoadDump() failed: %v", err)
+	}
+
+	info := skeleton.Info
+	if info.ID != "manga-1" || info.Title != "Dumped Manga" || info.OriginalLanguage != "ja" {
+		t.Errorf("unexpected manga info: %+v", info)
+	}
+	if !slices.Equal(info.Authors, []string{"Writer"}) || !slices.Equal(info.Artists, []string{"Painter"}) {
+		t.Errorf("unexpected authors %v and artists %v", info.Authors, info.Artists)
+	}
+	if info.ExternalIDs["myanimelist"] != "1234" || len(info.ExternalIDs) != 1 {
+		t.Errorf("unexpected external identifiers: %v", info.ExternalIDs)
+	}
+
+	pages, err := LoadDumpPages(root, chapters, progress.VanishingProgress("Dump..."))
+	if err != nil {
+		t.Fatalf("LoadDumpPages() failed: %v", err)
+	}
+	manga := skeleton.WithChapters(chapters).WithPages(pages)
+
+	volumes := manga.Sorted()
+	if len(volumes) != 2 {
+		t.Fatalf("expected 2 volumes, got %v", len(volumes))
+	}
+	for i, want := range []struct {
+		volume  string
+		chapter string
+		title   string
+		widths  []int
+	}{
+		{"1", "1", "Start", []int{1, 2}},
+		{"2", "2.5", "", []int{1}},
+	} {
+		volume := volumes[i]
+		if volume.Info.Identifier != md.NewIdentifier(want.volume) {
+			t.Errorf("volume %v: got identifier %v", want.volume, volume.Info.Identifier)
+		}
+		chapters := volume.Sorted()
+		if len(chapters) != 1 {
+			t.Fatalf("volume %v: expected 1 chapter, got %v", want.volume, len(chapters))
+		}
+		chapter := chapters[0]
+		if chapter.Info.Identifier != md.NewIdentifier(want.chapter) || chapter.Info.Title != want.title {
+			t.Errorf("volume %v: unexpected chapter %v %q", want.volume, chapter.Info.Identifier, chapter.Info.Title)
+		}
+		if chapter.Info.Language != language.English || chapter.Info.GroupNames.String() != "Scans" {
+			t.Errorf("volume %v: unexpected language %v or groups %v", want.volume, chapter.Info.Language, chapter.Info.GroupNames)
+		}
+		widths := make([]int, 0)
+		for _, page := range chapter.Sorted() {
+			widths = append(widths, page.Bounds().Dx())
+		}
+		if !slices.Equal(widths, want.widths) {
+			t.Errorf("volume %v: expected pages %v, got %v", want.volume, want.widths, widths)
+		}
+	}
+}
+
+func TestLoadDumpInvalid(t *testing.T) {
+	for name, tc := range map[string]struct {
+		manga    string
+		chapters string
+		pages    map[string]int
+		err      string
+	}{
+		"malformed": {
+			manga: `{"data": `, chapters: dumpChapters,
+			pages: map[string]int{"chapter-1": 2, "chapter-2": 1},
+			err:   "manga",
+		},
+		"wrong type": {
+			manga: strings.Replace(dumpManga, `"type": "manga"`, `"type": "author"`, 1), chapters: dumpChapters,
+			pages: map[string]int{"chapter-1": 2, "chapter-2": 1},
+			err:   "expected type 'manga'",
+		},
+		"missing title": {
+			manga: strings.Replace(dumpManga, `{"en": "Dumped Manga"}`, `{}`, 1), chapters: dumpChapters,
+			pages: map[string]int{"chapter-1": 2, "chapter-2": 1},
+			err:   "missing title",
+		},
+		"chapter without ID": {
+			manga: dumpManga, chapters: strings.Replace(dumpChapters, `"id": "chapter-2"`, `"id": ""`, 1),
+			pages: map[string]int{"chapter-1": 2},
+			err:   "chapter without ID",
+		},
+		"missing images": {
+			manga: dumpManga, chapters: dumpChapters,
+			pages: map[string]int{"chapter-1": 2},
+			err:   "missing images",
+		},
+	} {
+		root := writeDump(t, tc.manga, tc.chapters, tc.pages)
+		if _, _, err := LoadDump(root); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%v: expected error containing %q, got %v", name, tc.err, err)
+		}
+	}
+
+	// Page counts are only known once the chapter is loaded
+	root := writeDump(t, dumpManga, dumpChapters, map[string]int{"chapter-1": 1, "chapter-2": 1})
+	_, chapters, err := LoadDump(root)
+	if err != nil {
+		t.Fatalf("LoadDump() failed: %v", err)
+	}
+	if _, err := LoadDumpPages(root, chapters, progress.VanishingProgress("Dump...")); err == nil {
+		t.Errorf("expected error for missing pages")
+	}
+}
+
+func TestWriteDump(t *testing.T) {
+	info := md.MangaInfo{
+		Title:            "Exported Manga",
+		Authors:          []string{"Writer", "Cowriter"},
+		Artists:          []string{},
+		ID:               "manga-1",
+		OriginalLanguage: "ko",
+		ExternalIDs:      map[string]string{"anilist": "42", "isbn": "978-3-16-148410-0"},
+	}
+	published := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	chapters := make(md.ChapterList, 0)
+	for _, tc := range []struct {
+		id      string
+		volume  md.Identifier
+		chapter md.Identifier
+		title   string
+		groups  []string
+		lang    language.Tag
+	}{
+		{"chapter-4", md.OneshotIdentifier(), md.NewIdentifier("Extra"), "Bonus", []string{"Scans", "Others"}, language.Korean},
+		{"chapter-3", md.NewWithFallback("", "Special"), md.UnknownIdentifier(), "", []string{}, language.English},
+		{"chapter-2", md.NewIdentifier("1"), md.NewIdentifier("7.05"), "", []string{"Others"}, language.English},
+		{"chapter-1", md.NewIdentifier("1"), md.NewIdentifier("7"), "Start", []string{"Scans"}, language.English},
+	} {
+		chapters = append(chapters, md.Chapter{
+			Info: md.ChapterInfo{
+				Title:            tc.title,
+				Language:         tc.lang,
+				GroupNames:       tc.groups,
+				Published:        published,
+				ID:               tc.id,
+				Pages:            len(chapters) + 1,
+				Identifier:       tc.chapter,
+				VolumeIdentifier: tc.volume,
+			},
+			Pages: make(map[int]image.Image),
+		})
+	}
+
+	root := t.TempDir()
+	if err := WriteDump(root, info, chapters); err != nil {
+		t.Fatalf("WriteDump() failed: %v", err)
+	}
+	for _, chapter := range chapters {
+		if err := os.Mkdir(filepath.Join(root, chapter.Info.ID), 0755); err != nil {
+			t.Fatalf("failed to create chapter: %v", err)
+		}
+	}
+
+	skeleton, imported, err := LoadDump(root)
+	if err != nil {
+		t.Fatalf("LoadDump() failed: %v", err)
+	}
+	if !reflect.DeepEqual(skeleton.Info, info) {
+		t.Errorf("manga info does not round-trip:\n got %+v\nwant %+v", skeleton.Info, info)
+	}
+	if len(skeleton.Volumes) != 0 {
+		t.Errorf("expected an empty skeleton, got %v volumes", len(skeleton.Volumes))
+	}
+	if len(imported) != len(chapters) {
+		t.Fatalf("expected %v chapters, got %v", len(chapters), len(imported))
+	}
+	for i := range chapters {
+		if !reflect.DeepEqual(imported[i], chapters[i]) {
+			t.Errorf("chapter does not round-trip:\n got %+v\nwant %+v", imported[i].Info, chapters[i].Info)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package disk
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/progress"
+	"github.com/leotaku/kojirou/cmd/formats/util"
+	"golang.org/x/text/language"
+)
+
+// writeAnimatedPage writes a GIF page with one frame for each of the given
+// colors, where every frame after the first only covers the left half
+func writeAnimatedPage(t *testing.T, filename string, colors []color.Color) {
+	t.Helper()
+
+	palette := color.Palette{color.White, color.Black}
+	for _, c := range colors {
+		palette = append(palette, c)
+	}
+	g := &gif.GIF{Config: image.Config{ColorModel: palette, Width: 20, Height: 10}}
+	for i, c := range colors {
+		bounds := image.Rect(0, 0, 20, 10)
+		if i > 0 {
+			bounds = image.Rect(0, 0, 10, 10)
+		}
+		frame := image.NewPaletted(bounds, palette)
+		for j := range frame.Pix {
+			frame.Pix[j] = uint8(palette.Index(c))
+		}
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, 10)
+		g.Disposal = append(g.Disposal, gif.DisposalNone)
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatalf("failed to create page: %v", err)
+	}
+	defer f.Close()
+	if err := gif.EncodeAll(f, g); err != nil {
+		t.Fatalf("failed to encode page: %v", err)
+	}
+}
+
+func TestAnimatedGIF(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	green := color.RGBA{G: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "1", "1"), 0755); err != nil {
+		t.Fatalf("failed to create chapter: %v", err)
+	}
+	writeAnimatedPage(t, filepath.Join(root, "1", "1", "1.gif"), []color.Color{red, green, blue})
+
+	chapters, err := LoadChapters(root, language.English, progress.VanishingProgress("Disk..."))
+	if err != nil {
+		t.Fatalf("LoadChapters() failed: %v", err)
+	}
+
+	defer util.SetGIFFrame(0)
+	for _, tc := range []struct {
+		frame       int
+		left, right color.Color
+	}{
+		{0, red, red},
+		{1, green, red},
+		{2, blue, red},
+		// Pages with fewer frames use their last frame
+		{5, blue, red},
+	} {
+		util.SetGIFFrame(tc.frame)
+		pages, err := LoadPages(chapters, progress.VanishingProgress("Disk..."))
+		if err != nil {
+			t.Fatalf("LoadPages() failed: %v", err)
+		}
+		if len(pages) != 1 {
+			t.Fatalf("frame %v: expected a single page, got %v", tc.frame, len(pages))
+		}
+
+		img := pages[0].Image
+		if _, ok := img.(*image.Paletted); ok {
+			t.Errorf("frame %v: page is still a paletted GIF frame", tc.frame)
+		}
+		if img.Bounds() != image.Rect(0, 0, 20, 10) {
+			t.Errorf("frame %v: unexpected bounds %v", tc.frame, img.Bounds())
+		}
+		for _, px := range []struct {
+			x    int
+			want color.Color
+		}{{2, tc.left}, {17, tc.right}} {
+			if got := color.RGBAModel.Convert(img.At(px.x, 5)); got != px.want {
+				t.Errorf("frame %v: pixel at %v is %v, want %v", tc.frame, px.x, got, px.want)
+			}
+		}
+	}
+}
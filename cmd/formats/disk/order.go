@@ -0,0 +1,101 @@
+package disk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// ReadingOrderFile is the name of the optional sidecar in the root of a
+// series directory that overrides the natural order of its content.
+const ReadingOrderFile = "reading-order.json"
+
+// readingOrder overrides the natural order of chapters and pages loaded
+// from disk. Chapters are named by their path relative to the series
+// directory, e.g. "01/02" for chapter 02 of volume 01, and pages by the
+// path of their file, e.g. "01/02/03.png".
+type readingOrder struct {
+	// Chapters maps chapter directories to the identifiers that they are
+	// sorted by instead of their names
+	Chapters map[string]string `json:"chapters"`
+	// Pages lists the page files of chapters in reading order, files of
+	// the chapter that are not listed are left out
+	Pages map[string][]string `json:"pages"`
+	// Exclude lists chapters and pages that are left out
+	Exclude []string `json:"exclude"`
+}
+
+// loadReadingOrder reads the reading order sidecar of the given series
+// directory. Series without a sidecar use the natural order.
+func loadReadingOrder(directory string) (*readingOrder, error) {
+	data, err := os.ReadFile(path.Join(directory, ReadingOrderFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return new(readingOrder), nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	order := new(readingOrder)
+	if err := json.Unmarshal(data, order); err != nil {
+		return nil, fmt.Errorf("parse: %w", err)
+	}
+
+	return order, nil
+}
+
+// validate checks that all chapters and pages named by the reading order
+// exist in the given series directory
+func (o *readingOrder) validate(directory string) error {
+	names := slices.Clone(o.Exclude)
+	for chapter := range o.Chapters {
+		names = append(names, chapter)
+	}
+	for chapter, pages := range o.Pages {
+		names = append(names, chapter)
+		for _, page := range pages {
+			names = append(names, path.Join(chapter, page))
+		}
+	}
+
+	for _, name := range names {
+		// Pages of chapter archives are checked when the archive is read
+		if archive, _, ok := strings.Cut(name, CBRExtension+"/"); ok {
+			name = archive + CBRExtension
+		}
+		if _, err := os.Stat(path.Join(directory, name)); err != nil {
+			return fmt.Errorf("'%v' does not exist", name)
+		}
+	}
+
+	return nil
+}
+
+// excluded reports whether the given chapter or page is left out
+func (o *readingOrder) excluded(name string) bool {
+	return slices.Contains(o.Exclude, name)
+}
+
+// chapterName returns the name that the given chapter is identified by
+func (o *readingOrder) chapterName(chapter, name string) string {
+	if override, ok := o.Chapters[chapter]; ok {
+		return override
+	}
+
+	return name
+}
+
+// pages returns the page files of the given chapter in reading order
+func (o *readingOrder) pages(chapter string, files []string) []string {
+	if listed, ok := o.Pages[chapter]; ok {
+		files = listed
+	}
+
+	return slices.DeleteFunc(slices.Clone(files), func(file string) bool {
+		return o.excluded(path.Join(chapter, file))
+	})
+}
@@ -0,0 +1,93 @@
+package disk
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/progress"
+	"golang.org/x/text/language"
+)
+
+// writeSeries writes a series where every page is as wide as its number
+func writeSeries(t *testing.T, pages map[string]int, sidecar string) string {
+	t.Helper()
+
+	root := t.TempDir()
+	for chapter, count := range pages {
+		if err := os.MkdirAll(filepath.Join(root, chapter), 0755); err != nil {
+			t.Fatalf("failed to create chapter: %v", err)
+		}
+		for i := 1; i <= count; i++ {
+			f, err := os.Create(filepath.Join(root, chapter, string(rune('0'+i))+".png"))
+			if err != nil {
+				t.Fatalf("failed to create page: %v", err)
+			}
+			if err := png.Encode(f, image.NewGray(image.Rect(0, 0, i, 10))); err != nil {
+				t.Fatalf("failed to encode page: %v", err)
+			}
+			f.Close()
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, ReadingOrderFile), []byte(sidecar), 0644); err != nil {
+		t.Fatalf("failed to write sidecar: %v", err)
+	}
+
+	return root
+}
+
+func TestReadingOrder(t *testing.T) {
+	root := writeSeries(t, map[string]int{"1/1": 3, "1/2": 1, "1/extra": 2}, `{
+		"chapters": {"1/extra": "1.5"},
+		"pages": {"1/1": ["3.png", "2.png", "1.png"]},
+		"exclude": ["1/2", "1/extra/2.png"]
+	}`)
+
+	chapters, err := LoadChapters(root, language.English, progress.VanishingProgress("Disk..."))
+	if err != nil {
+		t.Fatalf("LoadChapters() failed: %v", err)
+	}
+	ids := make([]string, 0)
+	for _, chapter := range chapters {
+		ids = append(ids, chapter.Info.Identifier.String())
+	}
+	if strings.Join(ids, ",") != "1,1.5" {
+		t.Fatalf("got chapters %v, want 1 and 1.5", ids)
+	}
+
+	pages, err := LoadPages(chapters, progress.VanishingProgress("Disk..."))
+	if err != nil {
+		t.Fatalf("LoadPages() failed: %v", err)
+	}
+	widths := make(map[string][]int)
+	for _, page := range pages {
+		id := page.ChapterIdentifier.String()
+		for len(widths[id]) <= page.ImageIdentifier {
+			widths[id] = append(widths[id], 0)
+		}
+		widths[id][page.ImageIdentifier] = page.Image.Bounds().Dx()
+	}
+	if got := widths["1"]; len(got) != 3 || got[0] != 3 || got[1] != 2 || got[2] != 1 {
+		t.Errorf("chapter 1 has page widths %v, want reversed order", got)
+	}
+	if got := widths["1.5"]; len(got) != 1 || got[0] != 1 {
+		t.Errorf("chapter 1.5 has page widths %v, want excluded second page", got)
+	}
+}
+
+func TestReadingOrderValidation(t *testing.T) {
+	for _, sidecar := range []string{
+		`{"chapters": {"1/9": "9"}}`,
+		`{"pages": {"1/1": ["1.png", "9.png"]}}`,
+		`{"exclude": ["2"]}`,
+		`{"exclude": "1/1"}`,
+	} {
+		root := writeSeries(t, map[string]int{"1/1": 1}, sidecar)
+		if _, err := LoadChapters(root, language.English, progress.VanishingProgress("Disk...")); err == nil {
+			t.Errorf("LoadChapters() accepted invalid sidecar %v", sidecar)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package disk
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// exifSegment returns a JPEG APP1 segment with the given orientation
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8, // header
+		0, 1, // one entry
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, byte(orientation >> 8), byte(orientation), 0, 0, // orientation
+		0, 0, 0, 0, // no next IFD
+	}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	length := len(payload) + 2
+
+	return append([]byte{0xFF, 0xE1, byte(length >> 8), byte(length)}, payload...)
+}
+
+func TestDecodeImageOrientation(t *testing.T) {
+	// Stored sideways, with red on the left and blue on the right
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for x := 0; x < 40; x++ {
+		for y := 0; y < 20; y++ {
+			if x < 20 {
+				img.Set(x, y, color.RGBA{255, 0, 0, 255})
+			} else {
+				img.Set(x, y, color.RGBA{0, 0, 255, 255})
+			}
+		}
+	}
+	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, img, nil); err != nil {
+		t.Fatalf("failed to encode JPEG: %v", err)
+	}
+	data := append(append([]byte{0xFF, 0xD8}, exifSegment(6)...), buf.Bytes()[2:]...)
+
+	filename := filepath.Join(t.TempDir(), "page.jpg")
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatalf("failed to write JPEG: %v", err)
+	}
+	if orientation := exifOrientation(data); orientation != 6 {
+		t.Fatalf("expected orientation 6, got %v", orientation)
+	}
+
+	decoded, err := decodeImage(filename)
+	if err != nil {
+		t.Fatalf("decodeImage() failed: %v", err)
+	}
+	if size := decoded.Bounds().Size(); size != image.Pt(20, 40) {
+		t.Fatalf("expected upright size 20x40, got %vx%v", size.X, size.Y)
+	}
+
+	// Rotating clockwise moves the left half to the top
+	for _, tc := range []struct {
+		y   int
+		red bool
+	}{{5, true}, {35, false}} {
+		r, _, b, _ := decoded.At(10, tc.y).RGBA()
+		if (r > b) != tc.red {
+			t.Errorf("unexpected color at row %v: red %v, blue %v", tc.y, r, b)
+		}
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// A 2x1 image with distinct pixels A and B
+	a, b := color.NRGBA{1, 0, 0, 255}, color.NRGBA{2, 0, 0, 255}
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, a)
+	img.Set(1, 0, b)
+
+	for orientation, expected := range map[int][]color.Color{
+		1: {a, b}, 2: {b, a}, 3: {b, a}, 4: {a, b},
+		5: {a, b}, 6: {a, b}, 7: {b, a}, 8: {b, a},
+	} {
+		oriented := orient(img, orientation)
+		pixels := make([]color.Color, 0)
+		bounds := oriented.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				pixels = append(pixels, color.NRGBAModel.Convert(oriented.At(x, y)))
+			}
+		}
+		if len(pixels) != 2 || pixels[0] != expected[0] || pixels[1] != expected[1] {
+			t.Errorf("orientation %v: expected %v, got %v", orientation, expected, pixels)
+		}
+		if (orientation >= 5) != (bounds.Dy() == 2) {
+			t.Errorf("orientation %v: unexpected size %v", orientation, bounds.Size())
+		}
+	}
+}
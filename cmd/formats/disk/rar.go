@@ -0,0 +1,111 @@
+package disk
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/nwaples/rardecode/v2"
+)
+
+// CBRExtension is the extension of RAR archives that hold the pages of a
+// chapter in place of a chapter directory
+const CBRExtension = ".cbr"
+
+// rarEntry is a file stored in a RAR archive
+type rarEntry struct {
+	Name string
+	Data []byte
+}
+
+// isCBR reports whether the given file is a chapter archive
+func isCBR(name string) bool {
+	return strings.EqualFold(path.Ext(name), CBRExtension)
+}
+
+// readRAR reads all files of a RAR archive, in the order in which they are
+// stored. Entries are read in a single pass, which solid archives require
+// since every entry depends on the ones before it.
+func readRAR(filename string) ([]rarEntry, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	r, err := rardecode.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	entries := make([]rarEntry, 0)
+	for {
+		header, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("read: %w", err)
+		}
+		if header.IsDir {
+			continue
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("entry '%v': %w", header.Name, err)
+		}
+		entries = append(entries, rarEntry{Name: header.Name, Data: data})
+	}
+
+	return entries, nil
+}
+
+// naturalLess compares names so that runs of digits are ordered by their
+// numeric value, which sorts "2.png" before "10.png"
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		da, db := digitPrefix(a), digitPrefix(b)
+		if da != "" && db != "" {
+			na, nb := strings.TrimLeft(da, "0"), strings.TrimLeft(db, "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			} else if na != nb {
+				return na < nb
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+
+	return len(a) < len(b)
+}
+
+func digitPrefix(s string) string {
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+
+	return s[:end]
+}
+
+// sortNatural sorts the entries of an archive in natural order of their
+// names
+func sortNatural(entries []rarEntry) {
+	slices.SortStableFunc(entries, func(a, b rarEntry) int {
+		switch {
+		case naturalLess(a.Name, b.Name):
+			return -1
+		case naturalLess(b.Name, a.Name):
+			return 1
+		default:
+			return 0
+		}
+	})
+}
@@ -0,0 +1,184 @@
+package disk
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/progress"
+	"github.com/nwaples/rardecode/v2"
+	"golang.org/x/text/language"
+)
+
+// rarFile is a file of a test archive
+type rarFile struct {
+	name string
+	data []byte
+}
+
+// encodeRAR5 encodes files as a RAR 5 archive with the store method,
+// starting with a header of the given type
+func encodeRAR5(mainType uint64, files []rarFile) []byte {
+	buf := bytes.NewBufferString("Rar!\x1a\x07\x01\x00")
+	vint := func(b []byte, v uint64) []byte {
+		for v >= 0x80 {
+			b = append(b, byte(v)|0x80)
+			v >>= 7
+		}
+		return append(b, byte(v))
+	}
+	block := func(fields []byte, data []byte) {
+		header := vint(nil, uint64(len(fields)))
+		header = append(header, fields...)
+		buf.Write(binary.LittleEndian.AppendUint32(nil, crc32.ChecksumIEEE(header)))
+		buf.Write(header)
+		buf.Write(data)
+	}
+
+	block(vint(vint(vint(nil, mainType), 0), 0), nil)
+	for _, file := range files {
+		// File header with data, followed by the size of the data, a
+		// checksum, the unpacked size, attributes and the checksum itself
+		fields := vint(vint(nil, 2), 0x0002)
+		fields = vint(fields, uint64(len(file.data)))
+		fields = vint(fields, 0x0004)
+		fields = vint(fields, uint64(len(file.data)))
+		fields = vint(fields, 0x20)
+		fields = binary.LittleEndian.AppendUint32(fields, crc32.ChecksumIEEE(file.data))
+		// Store method and Windows as host
+		fields = vint(fields, 0)
+		fields = vint(fields, 0)
+		fields = vint(fields, uint64(len(file.name)))
+		fields = append(fields, file.name...)
+		block(fields, file.data)
+	}
+	block(vint(vint(vint(nil, 5), 0), 0), nil)
+
+	return buf.Bytes()
+}
+
+// pageFiles returns pages in shuffled order, where every page is as wide
+// as its number
+func pageFiles(t *testing.T) []rarFile {
+	t.Helper()
+
+	files := make([]rarFile, 0)
+	for _, name := range []string{"pages/10.png", "pages/2.png", "pages/01.png", "pages/3.png"} {
+		width, _ := strconv.Atoi(strings.TrimSuffix(path.Base(name), ".png"))
+		buf := new(bytes.Buffer)
+		if err := png.Encode(buf, image.NewGray(image.Rect(0, 0, width, 10))); err != nil {
+			t.Fatalf("failed to encode page: %v", err)
+		}
+		files = append(files, rarFile{name: name, data: buf.Bytes()})
+	}
+
+	return files
+}
+
+func TestLoadCBR(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "1"), 0755); err != nil {
+		t.Fatalf("failed to create volume: %v", err)
+	}
+	archive := encodeRAR5(1, pageFiles(t))
+	if err := os.WriteFile(filepath.Join(root, "1", "2.cbr"), archive, 0644); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+
+	if series, err := DiscoverSeries(root); err != nil || len(series) != 1 {
+		t.Fatalf("DiscoverSeries() = %v, %v, want the series", series, err)
+	}
+	chapters, err := LoadChapters(root, language.English, progress.VanishingProgress("Disk..."))
+	if err != nil {
+		t.Fatalf("LoadChapters() failed: %v", err)
+	}
+	if len(chapters) != 1 || chapters[0].Info.Identifier.String() != "2" {
+		t.Fatalf("got chapters %v, want chapter 2", chapters)
+	}
+	if groups := chapters[0].Info.GroupNames; len(groups) != 1 || groups[0] != "Filesystem" {
+		t.Errorf("got groups %v, want Filesystem", groups)
+	}
+
+	pages, err := LoadPages(chapters, progress.VanishingProgress("Pages..."))
+	if err != nil {
+		t.Fatalf("LoadPages() failed: %v", err)
+	}
+	widths := make([]int, 0)
+	for _, page := range pages {
+		widths = append(widths, page.Image.Bounds().Dx())
+	}
+	if !slices.Equal(widths, []int{1, 2, 3, 10}) {
+		t.Errorf("got page widths %v, want 1, 2, 3 and 10", widths)
+	}
+}
+
+// The archives in testdata hold a text file compressed by RAR 5, taken
+// from a two-part archive of the archives test suite and joined into a
+// single volume. The solid archive stores it twice, where the second entry
+// continues the decoder state of the first.
+func TestReadRAR(t *testing.T) {
+	const want = "b1040e9bde2125471abc00773c7c589c32ee879354dd188a919988f70b84ea19"
+
+	for filename, names := range map[string][]string{
+		"testdata/compressed.cbr": {"test.txt"},
+		"testdata/solid.cbr":      {"1.txt", "2.txt"},
+	} {
+		entries, err := readRAR(filename)
+		if err != nil {
+			t.Fatalf("%v: readRAR() failed: %v", filename, err)
+		}
+		got := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			got = append(got, entry.Name)
+			if sum := fmt.Sprintf("%x", sha256.Sum256(entry.Data)); sum != want {
+				t.Errorf("%v: %v has checksum %v, want %v", filename, entry.Name, sum, want)
+			}
+		}
+		if !slices.Equal(got, names) {
+			t.Errorf("%v: got entries %v, want %v", filename, got, names)
+		}
+	}
+}
+
+func TestReadRARErrors(t *testing.T) {
+	solid, err := os.ReadFile("testdata/solid.cbr")
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		archive []byte
+		want    error
+	}{
+		{"encrypted headers", encodeRAR5(4, pageFiles(t)), rardecode.ErrArchiveEncrypted},
+		{"truncated", solid[:len(solid)/2], io.ErrUnexpectedEOF},
+		{"not an archive", []byte("PK\x03\x04"), rardecode.ErrNoSig},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "chapter.cbr")
+			if err := os.WriteFile(filename, tt.archive, 0644); err != nil {
+				t.Fatalf("failed to write archive: %v", err)
+			}
+			_, err := readRAR(filename)
+			if err == nil {
+				t.Fatal("readRAR() succeeded, want error")
+			} else if !errors.Is(err, tt.want) {
+				t.Errorf("readRAR() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
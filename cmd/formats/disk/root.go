@@ -10,8 +10,11 @@ import (
 	"io/fs"
 	"os"
 	"path"
+	"strings"
 
+	"github.com/leotaku/kojirou/cmd/formats/icc"
 	"github.com/leotaku/kojirou/cmd/formats/progress"
+	"github.com/leotaku/kojirou/cmd/formats/util"
 	md "github.com/leotaku/kojirou/mangadex"
 	"golang.org/x/text/language"
 )
@@ -28,6 +31,14 @@ func LoadSkeleton(directory string) (*md.Manga, error) {
 }
 
 func LoadChapters(directory string, lang language.Tag, p progress.Progress) (md.ChapterList, error) {
+	order, err := loadReadingOrder(directory)
+	if err != nil {
+		return nil, fmt.Errorf("reading order: %w", err)
+	}
+	if err := order.validate(directory); err != nil {
+		return nil, fmt.Errorf("reading order: %w", err)
+	}
+
 	result := make(md.ChapterList, 0)
 	volumes, err := os.ReadDir(directory)
 	if err != nil {
@@ -42,14 +53,20 @@ func LoadChapters(directory string, lang language.Tag, p progress.Progress) (md.
 			return nil, fmt.Errorf("list '%v': %w", directory, err)
 		}
 		for _, chapter := range chapters {
-			if !chapter.IsDir() {
+			name := path.Join(volume.Name(), chapter.Name())
+			if !chapter.IsDir() && !isCBR(chapter.Name()) || order.excluded(name) {
 				continue
 			}
 			p.Increase(1)
 			p.Add(1)
 
+			// Chapter archives are named like chapter directories
+			title := strings.TrimSuffix(chapter.Name(), path.Ext(chapter.Name()))
+			if chapter.IsDir() {
+				title = chapter.Name()
+			}
 			info := md.ChapterInfo{
-				Identifier:       md.NewIdentifier(chapter.Name()),
+				Identifier:       md.NewIdentifier(order.chapterName(name, title)),
 				VolumeIdentifier: md.NewIdentifier(volume.Name()),
 				GroupNames:       []string{"Filesystem"},
 				Language:         lang,
@@ -68,26 +85,35 @@ func LoadChapters(directory string, lang language.Tag, p progress.Progress) (md.
 func LoadPages(cl md.ChapterList, p progress.Progress) (md.ImageList, error) {
 	result := make(md.ImageList, 0)
 	for _, chap := range cl {
-		pages, err := os.ReadDir(chap.Info.ID)
+		files, read, err := listChapter(chap.Info.ID)
 		if err != nil {
 			return nil, fmt.Errorf("list '%v': %w", chap.Info.Identifier, err)
 		}
 
+		// Chapters are stored in volume directories of the series directory
+		volumeDir := path.Dir(chap.Info.ID)
+		order, err := loadReadingOrder(path.Dir(volumeDir))
+		if err != nil {
+			return nil, fmt.Errorf("reading order: %w", err)
+		}
+		pages := order.pages(path.Join(path.Base(volumeDir), path.Base(chap.Info.ID)), files)
+
 		p.Increase(len(pages))
 		for id, page := range pages {
 			p.Add(1)
 
-			f, err := os.Open(path.Join(chap.Info.ID, page.Name()))
+			data, err := read(page)
 			if err != nil {
-				return nil, err
+				return nil, fmt.Errorf("page '%v': %w", page, err)
 			}
-			img, _, err := image.Decode(f)
+			img, err := decodeImageData(data)
 			if err != nil {
-				return nil, err
+				return nil, fmt.Errorf("page '%v': %w", page, err)
 			}
 
 			result = append(result, md.Image{
 				Image:             img,
+				Filename:          page,
 				ImageIdentifier:   id,
 				ChapterIdentifier: chap.Info.Identifier,
 				VolumeIdentifier:  chap.Info.VolumeIdentifier,
@@ -98,6 +124,48 @@ func LoadPages(cl md.ChapterList, p progress.Progress) (md.ImageList, error) {
 	return result, nil
 }
 
+// listChapter lists the page files of a chapter directory or archive,
+// returning a function that reads a page file by name
+func listChapter(chapter string) ([]string, func(string) ([]byte, error), error) {
+	if isCBR(chapter) {
+		entries, err := readRAR(chapter)
+		if err != nil {
+			return nil, nil, err
+		}
+		sortNatural(entries)
+		files := make([]string, 0, len(entries))
+		data := make(map[string][]byte, len(entries))
+		for _, entry := range entries {
+			files = append(files, entry.Name)
+			data[entry.Name] = entry.Data
+		}
+
+		return files, func(name string) ([]byte, error) {
+			if content, ok := data[name]; ok {
+				return content, nil
+			}
+			return nil, fs.ErrNotExist
+		}, nil
+	}
+
+	entries, err := os.ReadDir(chapter)
+	if err != nil {
+		return nil, nil, err
+	}
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		files = append(files, entry.Name())
+	}
+
+	return files, func(name string) ([]byte, error) {
+		data, err := os.ReadFile(path.Join(chapter, name))
+		if err != nil {
+			return nil, fmt.Errorf("open: %w", err)
+		}
+		return data, nil
+	}, nil
+}
+
 func LoadCovers(directory string, p progress.Progress) (md.ImageList, error) {
 	result := make(md.ImageList, 0)
 	volumes, err := os.ReadDir(directory)
@@ -125,20 +193,87 @@ func LoadCovers(directory string, p progress.Progress) (md.ImageList, error) {
 	return result, nil
 }
 
+// LoadCustomCovers loads user-provided covers for the given volumes.
+//
+// The target may be a single image file, which is used for all volumes,
+// a directory containing images named after volume identifiers, or a
+// template path in which "{volume}" is replaced by each volume identifier.
+func LoadCustomCovers(target string, volumes []md.Identifier) (md.ImageList, error) {
+	result := make(md.ImageList, 0)
+	switch info, err := os.Stat(target); {
+	case strings.Contains(target, "{volume}"):
+		for _, volume := range volumes {
+			name := strings.ReplaceAll(target, "{volume}", volume.String())
+			img, err := decodeImage(name)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf("cover for volume '%v': %w", volume, err)
+			}
+			result = append(result, md.Image{
+				Image:            img,
+				VolumeIdentifier: volume,
+			})
+		}
+	case err != nil:
+		return nil, fmt.Errorf("stat '%v': %w", target, err)
+	case info.IsDir():
+		for _, volume := range volumes {
+			img, err := readImage(target, volume.String())
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf("cover for volume '%v': %w", volume, err)
+			}
+			result = append(result, md.Image{
+				Image:            img,
+				VolumeIdentifier: volume,
+			})
+		}
+	default:
+		img, err := decodeImage(target)
+		if err != nil {
+			return nil, fmt.Errorf("cover '%v': %w", target, err)
+		}
+		for _, volume := range volumes {
+			result = append(result, md.Image{
+				Image:            img,
+				VolumeIdentifier: volume,
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// decodeImage decodes the given image file, rotating it upright according
+// to its EXIF orientation and normalizing its colors to sRGB.
+func decodeImage(filename string) (image.Image, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+
+	return decodeImageData(data)
+}
+
+// decodeImageData decodes the data of an image file like decodeImage
+func decodeImageData(data []byte) (image.Image, error) {
+	img, _, err := util.DecodeImage(data)
+	if err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+
+	return orient(icc.Normalize(img, data), exifOrientation(data)), nil
+}
+
 func readImage(directory, name string) (image.Image, error) {
 	for _, ext := range []string{".jpg", ".jpeg", ".png", ".gif"} {
-		f, err := os.Open(path.Join(directory, name+ext))
+		img, err := decodeImage(path.Join(directory, name+ext))
 		if errors.Is(err, fs.ErrNotExist) {
 			continue
-		} else if err != nil {
-			return nil, fmt.Errorf("open: %w", err)
 		} else {
-			img, _, err := image.Decode(f)
-			if err != nil {
-				return nil, fmt.Errorf("decode: %w", err)
-			} else {
-				return img, nil
-			}
+			return img, err
 		}
 	}
 
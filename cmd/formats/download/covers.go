@@ -0,0 +1,96 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/leotaku/kojirou/cmd/formats/icc"
+	"github.com/leotaku/kojirou/cmd/formats/progress"
+	"github.com/leotaku/kojirou/cmd/formats/util"
+	md "github.com/leotaku/kojirou/mangadex"
+	"golang.org/x/sync/errgroup"
+)
+
+var (
+	// coverCache is the directory downloaded covers are kept in, with a
+	// folder for every manga. Covers are not kept if it is empty.
+	coverCache string
+	// refreshCovers downloads cached covers again, replacing them
+	refreshCovers bool
+)
+
+// SetCoverCache keeps downloaded covers in the given directory, so that
+// later runs do not download them again. With refresh, cached covers are
+// downloaded again and replaced. An empty directory disables the cache.
+func SetCoverCache(directory string, refresh bool) {
+	coverCache, refreshCovers = directory, refresh
+}
+
+// cachedCovers downloads the given covers of a manga, using the cover
+// cache for covers downloaded before
+func cachedCovers(ctx context.Context, mangaID string, paths md.PathList, p progress.Progress) (md.ImageList, error) {
+	dir := filepath.Join(coverCache, mangaID)
+	results := make(md.ImageList, len(paths))
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.SetLimit(maxJobsImage)
+	p.Increase(len(paths))
+	for i, path := range paths {
+		eg.Go(func() error {
+			img, err := cachedCover(ctx, dir, path.DataURL)
+			if err != nil {
+				return fmt.Errorf("volume %v: cover: %w", path.VolumeIdentifier, err)
+			}
+			results[i] = path.WithImage(img)
+			p.Add(1)
+			return nil
+		})
+	}
+
+	return results, eg.Wait()
+}
+
+// cachedCover returns the cover at the given URL from the cache directory,
+// downloading and caching it unless it was cached before. Cover filenames
+// are unique on MangaDex, so changed covers are never found in the cache.
+func cachedCover(ctx context.Context, dir, coverURL string) (image.Image, error) {
+	u, err := url.Parse(coverURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse: %w", err)
+	}
+	filename := filepath.Join(dir, path.Base(u.Path))
+
+	if !refreshCovers {
+		if data, err := os.ReadFile(filename); err == nil {
+			if img, _, err := util.DecodeImage(data); err == nil {
+				return icc.Normalize(img, data), nil
+			}
+		}
+	}
+
+	resp, err := getResp(httpClient, ctx, coverURL)
+	if err != nil {
+		return nil, fmt.Errorf("download: %w", err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("download: %w", err)
+	}
+	img, _, err := util.DecodeImage(data)
+	if err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+
+	// Covers that cannot be cached are downloaded again next time
+	if err := os.MkdirAll(dir, 0755); err == nil {
+		_ = os.WriteFile(filename, data, 0644)
+	}
+
+	return icc.Normalize(img, data), nil
+}
@@ -0,0 +1,52 @@
+package download
+
+import (
+	"context"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/progress"
+	md "github.com/leotaku/kojirou/mangadex"
+)
+
+func TestCachedCovers(t *testing.T) {
+	hits := atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		png.Encode(w, image.NewGray(image.Rect(0, 0, 2, 3))) //nolint:errcheck
+	}))
+	defer server.Close()
+	defer SetCoverCache("", false)
+
+	paths := md.PathList{{
+		DataURL:          server.URL + "/covers/manga-1/cover.png",
+		VolumeIdentifier: md.NewIdentifier("1"),
+	}}
+	fetch := func() {
+		t.Helper()
+		covers, err := cachedCovers(context.Background(), "manga-1", paths, progress.VanishingProgress("Covers"))
+		if err != nil {
+			t.Fatalf("cachedCovers() failed: %v", err)
+		}
+		if len(covers) != 1 || covers[0].Image.Bounds().Size() != image.Pt(2, 3) {
+			t.Fatalf("unexpected covers: %v", covers)
+		}
+	}
+
+	SetCoverCache(t.TempDir(), false)
+	fetch()
+	fetch()
+	if hits.Load() != 1 {
+		t.Errorf("expected cached cover to be downloaded once, got %v downloads", hits.Load())
+	}
+
+	SetCoverCache(coverCache, true)
+	fetch()
+	if hits.Load() != 2 {
+		t.Errorf("expected refresh to download cover again, got %v downloads", hits.Load())
+	}
+}
@@ -0,0 +1,44 @@
+package download
+
+import (
+	"context"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	md "github.com/leotaku/kojirou/mangadex"
+)
+
+func TestSetProxy(t *testing.T) {
+	mu := sync.Mutex{}
+	proxied := make([]string, 0)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		proxied = append(proxied, r.URL.String())
+		mu.Unlock()
+		png.Encode(w, image.NewGray(image.Rect(0, 0, 1, 1))) //nolint:errcheck
+	}))
+	defer proxy.Close()
+
+	if err := SetProxy(proxy.URL); err != nil {
+		t.Fatalf("SetProxy() failed: %v", err)
+	}
+	defer SetProxy("") //nolint:errcheck
+
+	path := md.Path{DataURL: "http://uploads.example.invalid/data/1.png"}
+	if _, err := getImageWithPolicy(httpClient, context.Background(), path, DataSaverPolicyNo, nil); err != nil {
+		t.Fatalf("getImageWithPolicy() failed: %v", err)
+	}
+	if len(proxied) != 1 || proxied[0] != path.DataURL {
+		t.Errorf("expected request for %v through proxy, got %v", path.DataURL, proxied)
+	}
+
+	for _, proxyURL := range []string{"ftp://localhost:21", "http://", "://"} {
+		if err := SetProxy(proxyURL); err == nil {
+			t.Errorf("SetProxy(%q) succeeded, expected error", proxyURL)
+		}
+	}
+}
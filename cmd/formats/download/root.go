@@ -10,11 +10,15 @@ import (
 	_ "image/png"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/hashicorp/go-retryablehttp"
+	"github.com/leotaku/kojirou/cmd/formats/icc"
 	"github.com/leotaku/kojirou/cmd/formats/progress"
+	"github.com/leotaku/kojirou/cmd/formats/util"
 	md "github.com/leotaku/kojirou/mangadex"
+	"github.com/leotaku/kojirou/mangadex/api"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -24,20 +28,29 @@ const (
 	DataSaverPolicyNo DataSaverPolicy = iota
 	DataSaverPolicyPrefer
 	DataSaverPolicyFallback
+	DataSaverPolicyAuto
 )
 
 const (
 	maxJobsChapter = 8
 	maxJobsImage   = 16
+	// maxImageAttempts is how often incomplete images are downloaded
+	// before giving up
+	maxImageAttempts = 3
 )
 
 var (
+	transport      *http.Transport
 	httpClient     *http.Client
 	mangadexClient *md.Client
 )
 
 func init() {
+	// Proxies are read from the environment until set explicitly
+	transport = http.DefaultTransport.(*http.Transport).Clone()
+
 	retry := retryablehttp.NewClient()
+	retry.HTTPClient = &http.Client{Transport: transport}
 	retry.Logger = nil
 	retry.RetryWaitMin = time.Second * 5
 	retry.Backoff = retryablehttp.LinearJitterBackoff
@@ -47,6 +60,45 @@ func init() {
 	mangadexClient = md.NewClient().WithHTTPClient(httpClient)
 }
 
+// SetProxy routes all downloads through the proxy at the given URL, which
+// may use the "http", "https" or "socks5" scheme. An empty URL restores
+// the default of honoring the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+// environment variables.
+func SetProxy(proxyURL string) error {
+	if proxyURL == "" {
+		transport.Proxy = http.ProxyFromEnvironment
+		return nil
+	}
+
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return fmt.Errorf("parse: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf(`unsupported scheme: "%v"`, u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("missing host: %v", proxyURL)
+	}
+	transport.Proxy = http.ProxyURL(u)
+
+	return nil
+}
+
+// Client returns an HTTP client that sends requests like downloads do,
+// through the proxy set with SetProxy, but without retrying them.
+func Client() *http.Client {
+	return &http.Client{Transport: transport}
+}
+
+// SetCredentials authenticates all requests to the MangaDex API as the
+// given user, which allows access to restricted titles.
+func SetCredentials(credentials api.Credentials) {
+	mangadexClient.WithCredentials(credentials)
+}
+
 func MangadexSkeleton(mangaID string) (*md.Manga, error) {
 	return mangadexClient.FetchManga(context.TODO(), mangaID)
 }
@@ -55,7 +107,9 @@ func MangadexChapters(mangaID string) (md.ChapterList, error) {
 	return mangadexClient.FetchChapters(context.TODO(), mangaID)
 }
 
-func MangadexCovers(manga *md.Manga, p progress.Progress) (md.ImageList, error) {
+// MangadexCovers downloads a single cover for every volume of the manga,
+// preferring covers in the given locale.
+func MangadexCovers(manga *md.Manga, locale string, p progress.Progress) (md.ImageList, error) {
 	ctx, cancel := context.WithCancel(context.TODO())
 	defer cancel()
 
@@ -63,21 +117,22 @@ func MangadexCovers(manga *md.Manga, p progress.Progress) (md.ImageList, error)
 	if err != nil {
 		return nil, err
 	}
+	if coverCache != "" {
+		return cachedCovers(ctx, manga.Info.ID, manga.SelectCovers(covers, locale), p)
+	}
 
 	coverPaths := make(chan md.Path)
 	go func() {
-		for _, path := range covers {
-			if _, ok := manga.Volumes[path.VolumeIdentifier]; ok {
-				coverPaths <- path
-				p.Increase(1)
-			}
+		for _, path := range manga.SelectCovers(covers, locale) {
+			coverPaths <- path
+			p.Increase(1)
 		}
 		close(coverPaths)
 	}()
 
-	coverImages, eg := pathsToImages(coverPaths, ctx, cancel)
+	coverImages, eg := pathsToImages(coverPaths, ctx, cancel, DataSaverPolicyNo)
 
-	results := make(md.ImageList, len(covers))
+	results := make(md.ImageList, 0, len(covers))
 	for coverImage := range coverImages {
 		p.Add(1)
 		results = append(results, coverImage)
@@ -108,7 +163,7 @@ func MangadexPages(chapterList md.ChapterList, policy DataSaverPolicy, p progres
 	paths, childEg := chaptersToPaths(chapters, ctx, cancel, p)
 	eg.Go(childEg.Wait)
 
-	images, childEg := pathsToImages(paths, ctx, cancel)
+	images, childEg := pathsToImages(paths, ctx, cancel, policy)
 	eg.Go(childEg.Wait)
 
 	results := make(md.ImageList, 0)
@@ -177,8 +232,10 @@ func pathsToImages(
 	paths <-chan md.Path,
 	ctx context.Context,
 	cancel context.CancelFunc,
+	policy DataSaverPolicy,
 ) (<-chan md.Image, *errgroup.Group) {
 	ch := make(chan md.Image)
+	probe := new(throughputProbe)
 	eg, ctx := errgroup.WithContext(ctx)
 	eg.SetLimit(maxJobsImage + 1)
 
@@ -192,7 +249,7 @@ func pathsToImages(
 					return nil
 				}
 				eg.Go(func() error {
-					img, err := getImageWithPolicy(httpClient, ctx, path, DataSaverPolicyNo)
+					img, err := getImageWithPolicy(httpClient, ctx, path, policy, probe)
 					if err != nil {
 						defer cancel()
 						return fmt.Errorf("chapter %v: image %v: %w", path.ChapterIdentifier, path.ImageIdentifier, err)
@@ -217,31 +274,65 @@ func pathsToImages(
 	return ch, eg
 }
 
-func getImageWithPolicy(client *http.Client, ctx context.Context, path md.Path, policy DataSaverPolicy) (image.Image, error) {
-	resp := new(http.Response)
-	err := error(nil)
-
+func getImageWithPolicy(client *http.Client, ctx context.Context, path md.Path, policy DataSaverPolicy, probe *throughputProbe) (image.Image, error) {
+	url := path.DataURL
 	switch policy {
-	case DataSaverPolicyNo, DataSaverPolicyFallback:
-		resp, err = getResp(httpClient, ctx, path.DataURL)
+	case DataSaverPolicyAuto:
+		return getImageWithPolicy(client, ctx, path, probe.policy(), probe)
 	case DataSaverPolicyPrefer:
-		resp, err = getResp(httpClient, ctx, path.DataSaverURL)
+		url = path.DataSaverURL
+	}
+
+	img, incomplete, err := getImage(client, ctx, url, probe)
+	if err != nil && incomplete && policy == DataSaverPolicyFallback {
+		return getImageWithPolicy(client, ctx, path, DataSaverPolicyPrefer, probe)
+	}
+
+	return img, err
+}
+
+// getImage downloads and decodes the image at the given URL. Flaky
+// connections may deliver truncated images, so images that are shorter
+// than announced or do not fully decode are downloaded again, up to
+// maxImageAttempts times. It reports whether the last error was such an
+// incomplete image.
+func getImage(client *http.Client, ctx context.Context, url string, probe *throughputProbe) (image.Image, bool, error) {
+	err := error(nil)
+	for range maxImageAttempts {
+		var img image.Image
+		var incomplete bool
+		if img, incomplete, err = fetchImage(client, ctx, url, probe); err == nil {
+			return img, false, nil
+		} else if !incomplete {
+			return nil, false, err
+		}
 	}
 
+	return nil, true, err
+}
+
+func fetchImage(client *http.Client, ctx context.Context, url string, probe *throughputProbe) (image.Image, bool, error) {
+	start := time.Now()
+	resp, err := getResp(client, ctx, url)
 	if err != nil {
-		return nil, fmt.Errorf("download: %w", err)
+		return nil, false, fmt.Errorf("download: %w", err)
 	}
 
-	img, _, err := image.Decode(resp.Body)
-	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, true, fmt.Errorf("download: %w", err)
+	} else if resp.ContentLength >= 0 && int64(len(data)) != resp.ContentLength {
+		return nil, true, fmt.Errorf("download: received %v of %v bytes", len(data), resp.ContentLength)
+	}
+	probe.record(len(data), time.Since(start))
 
-	if err != nil && policy == DataSaverPolicyFallback {
-		return getImageWithPolicy(client, ctx, path, DataSaverPolicyPrefer)
-	} else if err != nil {
-		return nil, fmt.Errorf("decode: %w", err)
-	} else {
-		return img, nil
+	img, _, err := util.DecodeImage(data)
+	if err != nil {
+		return nil, true, fmt.Errorf("decode: %w", err)
 	}
+
+	return icc.Normalize(img, data), false, nil
 }
 
 func getResp(client *http.Client, ctx context.Context, url string) (*http.Response, error) {
@@ -0,0 +1,96 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	md "github.com/leotaku/kojirou/mangadex"
+)
+
+func TestTruncatedImage(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 64, 64))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 7)
+	}
+	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, img, nil); err != nil {
+		t.Fatalf("failed to encode image: %v", err)
+	}
+	full := buf.Bytes()
+	truncated := full[:len(full)/2]
+
+	for _, tc := range []struct {
+		name string
+		// failures is the number of truncated responses before the
+		// complete image is served
+		failures int
+		// announced sends the length of the complete image for truncated
+		// responses, which breaks off the connection early
+		announced bool
+		ok        bool
+	}{
+		{"complete", 0, false, true},
+		{"truncated", 1, false, true},
+		{"shorter than announced", 1, true, true},
+		{"always truncated", maxImageAttempts, false, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests > tc.failures {
+					w.Write(full) //nolint:errcheck
+				} else if tc.announced {
+					w.Header().Set("Content-Length", strconv.Itoa(len(full)))
+					w.Write(truncated) //nolint:errcheck
+				} else {
+					w.Write(truncated) //nolint:errcheck
+				}
+			}))
+			defer server.Close()
+
+			path := md.Path{DataURL: server.URL + "/data"}
+			got, err := getImageWithPolicy(server.Client(), context.TODO(), path, DataSaverPolicyNo, new(throughputProbe))
+			if !tc.ok {
+				if err == nil {
+					t.Fatal("getImageWithPolicy() succeeded, want error")
+				}
+			} else if err != nil {
+				t.Fatalf("getImageWithPolicy() failed: %v", err)
+			} else if got.Bounds() != img.Bounds() {
+				t.Errorf("expected image of bounds %v, got %v", img.Bounds(), got.Bounds())
+			}
+			if expected := min(tc.failures+1, maxImageAttempts); requests != expected {
+				t.Errorf("expected %v requests, got %v", expected, requests)
+			}
+		})
+	}
+
+	t.Run("fallback", func(t *testing.T) {
+		saver := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/data-saver" {
+				saver++
+				w.Write(full) //nolint:errcheck
+			} else {
+				w.Write(truncated) //nolint:errcheck
+			}
+		}))
+		defer server.Close()
+
+		path := md.Path{DataURL: server.URL + "/data", DataSaverURL: server.URL + "/data-saver"}
+		if _, err := getImageWithPolicy(server.Client(), context.TODO(), path, DataSaverPolicyFallback, new(throughputProbe)); err != nil {
+			t.Fatalf("getImageWithPolicy() failed: %v", err)
+		}
+		if saver != 1 {
+			t.Errorf("expected 1 data-saver download, got %v", saver)
+		}
+	})
+
+}
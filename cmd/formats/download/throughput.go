@@ -0,0 +1,55 @@
+package download
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// autoProbeImages is the number of images downloaded in full quality
+	// to measure throughput before the auto policy decides on a quality
+	autoProbeImages = 4
+	// autoThreshold is the throughput in bytes per second below which the
+	// auto policy prefers data-saver images
+	autoThreshold = 512 * 1024
+)
+
+// throughputProbe measures the throughput of the first downloaded images
+// to resolve DataSaverPolicyAuto. A nil probe is valid and always resolves
+// to full quality images.
+type throughputProbe struct {
+	mu      sync.Mutex
+	images  int
+	bytes   int
+	elapsed time.Duration
+}
+
+func (p *throughputProbe) record(n int, elapsed time.Duration) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.images < autoProbeImages {
+		p.images++
+		p.bytes += n
+		p.elapsed += elapsed
+	}
+}
+
+func (p *throughputProbe) policy() DataSaverPolicy {
+	if p == nil {
+		return DataSaverPolicyNo
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.images < autoProbeImages || p.elapsed <= 0 {
+		return DataSaverPolicyNo
+	} else if float64(p.bytes)/p.elapsed.Seconds() < autoThreshold {
+		return DataSaverPolicyPrefer
+	} else {
+		return DataSaverPolicyNo
+	}
+}
@@ -0,0 +1,59 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	md "github.com/leotaku/kojirou/mangadex"
+)
+
+func TestDataSaverPolicyAuto(t *testing.T) {
+	buf := new(bytes.Buffer)
+	png.Encode(buf, image.NewGray(image.Rect(0, 0, 1, 1))) //nolint:errcheck
+	tiny := buf.Bytes()
+	// Trailing data is ignored when decoding
+	large := append(append([]byte{}, tiny...), make([]byte, 1024*1024)...)
+
+	for _, tc := range []struct {
+		name     string
+		body     []byte
+		delay    time.Duration
+		expected DataSaverPolicy
+	}{
+		{"slow", tiny, 20 * time.Millisecond, DataSaverPolicyPrefer},
+		{"fast", large, 0, DataSaverPolicyNo},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			saver := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/data-saver" {
+					saver++
+				}
+				time.Sleep(tc.delay)
+				w.Write(tc.body) //nolint:errcheck
+			}))
+			defer server.Close()
+
+			probe := new(throughputProbe)
+			path := md.Path{DataURL: server.URL + "/data", DataSaverURL: server.URL + "/data-saver"}
+			for i := 0; i < autoProbeImages+2; i++ {
+				if _, err := getImageWithPolicy(server.Client(), context.TODO(), path, DataSaverPolicyAuto, probe); err != nil {
+					t.Fatalf("getImageWithPolicy() failed: %v", err)
+				}
+			}
+
+			if policy := probe.policy(); policy != tc.expected {
+				t.Errorf("expected policy %v, got %v", tc.expected, policy)
+			}
+			if expected := map[DataSaverPolicy]int{DataSaverPolicyPrefer: 2}[tc.expected]; saver != expected {
+				t.Errorf("expected %v data-saver downloads, got %v", expected, saver)
+			}
+		})
+	}
+}
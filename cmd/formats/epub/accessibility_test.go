@@ -0,0 +1,51 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	kepubconv "github.com/leotaku/kojirou/cmd/formats/kepubconv"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+)
+
+func TestPageAltText(t *testing.T) {
+	manga := testhelpers.CreateSyntheticManga(2, 2, 200, 300)
+	e, cleanup, err := GenerateEPUB(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true)
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		t.Fatalf("GenerateEPUB() failed: %v", err)
+	}
+
+	zipReader, err := writeEPUB(t, e)
+	if err != nil {
+		t.Fatalf("failed to write EPUB: %v", err)
+	}
+	section := readEPUBFile(t, zipReader, "EPUB/xhtml/chapter-1-2.xhtml")
+	for _, want := range []string{
+		`alt="Synthetic Manga Chapter 2 page 1"`,
+		`alt="Synthetic Manga Chapter 2 page 2"`,
+		`aria-label="Page 2"`,
+	} {
+		if !strings.Contains(section, want) {
+			t.Errorf("chapter section missing %v:\n%s", want, section)
+		}
+	}
+
+	kepubData, err := kepubconv.ConvertToKEPUB(e, "", 0)
+	if err != nil {
+		t.Fatalf("ConvertToKEPUB() failed: %v", err)
+	}
+	kepubReader, err := zip.NewReader(bytes.NewReader(kepubData), int64(len(kepubData)))
+	if err != nil {
+		t.Fatalf("failed to open KEPUB: %v", err)
+	}
+	section = readEPUBFile(t, kepubReader, "EPUB/xhtml/chapter-1-2.xhtml")
+	if !strings.Contains(section, `alt="Synthetic Manga Chapter 2 page 2"`) {
+		t.Errorf("KEPUB conversion dropped alt text:\n%s", section)
+	}
+}
@@ -0,0 +1,63 @@
+package epub
+
+import (
+	"image/color"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+)
+
+var coverItemRe = regexp.MustCompile(`<item [^>]*properties="cover-image"[^>]*>`)
+
+func TestCoverFallbackToFirstPage(t *testing.T) {
+	manga := testhelpers.CreateSyntheticManga(2, 2, 200, 300)
+	for volID, vol := range manga.Volumes {
+		vol.Cover = nil
+		manga.Volumes[volID] = vol
+	}
+
+	// Remember the first page so it can be compared against the cover
+	firstPage := manga.Sorted()[0].Sorted()[0].Sorted()[0]
+	manga = manga.WithFallbackCovers()
+	if cover := manga.Sorted()[0].Cover; cover != firstPage {
+		t.Fatalf("expected first page to be used as cover, got %v", cover)
+	}
+
+	e, cleanup, err := GenerateEPUB(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true)
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		t.Fatalf("GenerateEPUB() failed: %v", err)
+	}
+
+	zipReader, err := writeEPUB(t, e)
+	if err != nil {
+		t.Fatalf("failed to write EPUB: %v", err)
+	}
+	opf := readEPUBFile(t, zipReader, "EPUB/package.opf")
+	items := coverItemRe.FindAllString(opf, -1)
+	if len(items) != 1 {
+		t.Fatalf("expected exactly one cover-image item, got %d:\n%s", len(items), opf)
+	}
+	if !strings.Contains(items[0], "cover-1.jpg") {
+		t.Errorf("cover-image item does not reference volume cover: %s", items[0])
+	}
+}
+
+func TestCoverFallbackKeepsExistingCover(t *testing.T) {
+	manga := testhelpers.CreateSyntheticManga(1, 1, 200, 300)
+	cover := testhelpers.CreateTestImage(100, 150, color.Black)
+	for volID, vol := range manga.Volumes {
+		vol.Cover = cover
+		manga.Volumes[volID] = vol
+	}
+
+	manga = manga.WithFallbackCovers()
+	if manga.Sorted()[0].Cover != cover {
+		t.Error("explicit cover was replaced by fallback")
+	}
+}
@@ -0,0 +1,69 @@
+package epub
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+)
+
+func generateWithOptions(t *testing.T, opts Options) (css string, section string) {
+	t.Helper()
+
+	manga := testhelpers.CreateSyntheticManga(1, 1, 200, 300)
+	e, cleanup, err := GenerateEPUBWithOptions(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true, opts)
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		t.Fatalf("GenerateEPUBWithOptions() failed: %v", err)
+	}
+
+	zipReader, err := writeEPUB(t, e)
+	if err != nil {
+		t.Fatalf("failed to write EPUB: %v", err)
+	}
+
+	return readEPUBFile(t, zipReader, "EPUB/css/style.css"),
+		readEPUBFile(t, zipReader, "EPUB/xhtml/chapter-1-1.xhtml")
+}
+
+func TestCustomCSS(t *testing.T) {
+	custom := "img { border: 1px solid red; }"
+
+	t.Run("append", func(t *testing.T) {
+		css, section := generateWithOptions(t, Options{CSS: custom})
+		if !strings.Contains(css, DefaultCSS) || !strings.Contains(css, custom) {
+			t.Errorf("stylesheet does not contain default and custom CSS:\n%s", css)
+		}
+		if !strings.Contains(section, `href="../css/style.css"`) {
+			t.Errorf("chapter does not reference stylesheet:\n%s", section)
+		}
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		css, section := generateWithOptions(t, Options{CSS: custom, ReplaceCSS: true})
+		if css != custom {
+			t.Errorf("expected stylesheet to be replaced, got:\n%s", css)
+		}
+		if !strings.Contains(section, `href="../css/style.css"`) {
+			t.Errorf("chapter does not reference stylesheet:\n%s", section)
+		}
+	})
+}
+
+func TestDefaultCSSCentersPages(t *testing.T) {
+	css, _ := generateWithOptions(t, Options{})
+	for _, rule := range []string{
+		"background-color: #000",
+		"align-items: center",
+		"justify-content: center",
+		"margin: 0 auto",
+		"margin: 0; padding: 0",
+	} {
+		if !strings.Contains(css, rule) {
+			t.Errorf("default stylesheet missing %q:\n%s", rule, css)
+		}
+	}
+}
@@ -35,7 +35,10 @@ package epub
 import (
 	"archive/zip"
 	"bytes"
+	"cmp"
+	"crypto/sha1"
 	"fmt"
+	"html"
 	"image"
 	"image/jpeg"
 	"io"
@@ -43,16 +46,50 @@ import (
 	"path/filepath"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
-
-	"golang.org/x/image/draw"
+	"sync/atomic"
+	"text/template"
 
 	"github.com/bmaupin/go-epub"
 	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/progress"
+	"github.com/leotaku/kojirou/cmd/formats/util"
 	"github.com/leotaku/kojirou/mangadex"
+	"golang.org/x/text/language"
 )
 
+// tempFileCounter numbers all temporary files written by this process, so
+// that books generated concurrently never share a temporary path, even when
+// they share a temporary directory
+var tempFileCounter atomic.Uint64
+
+// tempFilePath returns a temporary path in the given directory that no
+// other book of this run uses
+func tempFilePath(tempDir, name string) string {
+	return filepath.Join(tempDir, fmt.Sprintf("%d-%s", tempFileCounter.Add(1), name))
+}
+
+// fileStore keeps an encoded file of a book until go-epub writes it and
+// returns the source go-epub reads the file from. The data is only valid
+// during the call.
+type fileStore func(name string, data []byte) (string, error)
+
+// tempFileStore stores files in the given temporary directory, recording
+// their paths so that they can be removed once the book is written
+func tempFileStore(tempDir string, paths *[]string) fileStore {
+	return func(name string, data []byte) (string, error) {
+		path := tempFilePath(tempDir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return "", err
+		}
+		*paths = append(*paths, path)
+
+		return path, nil
+	}
+}
+
 // GenerateEPUB creates an EPUB file from manga data
 //
 // This function processes manga data and converts it into a structured EPUB document,
@@ -76,77 +113,210 @@ import (
 //   - Setting correct reading direction
 //   - Generating navigation elements
 func GenerateEPUB(tempDir string, manga mangadex.Manga, widepage kindle.WidepagePolicy, crop bool, ltr bool) (*epub.Epub, func(), error) {
+	return GenerateEPUBWithOptions(tempDir, manga, widepage, crop, ltr, Options{})
+}
+
+// DefaultCSS is the stylesheet used for all generated EPUB sections.
+//
+// Pages are centered horizontally and vertically on a black background,
+// which letterboxes them on screens that do not match their aspect ratio.
+const DefaultCSS = `html, body { margin: 0; padding: 0; background-color: #000; color: #fff; }
+body { text-align: center; }
+div { display: flex; align-items: center; justify-content: center; height: 100vh; margin: 0; padding: 0; page-break-after: always; }
+img { display: block; margin: 0 auto; max-width: 100%; max-height: 100vh; height: auto; width: auto; }
+.chapter-title { flex-direction: column; }
+svg { display: block; width: 100%; height: 100vh; }`
+
+// Options configures optional aspects of EPUB generation.
+//
+// The zero value generates the same output as GenerateEPUB.
+type Options struct {
+	// CSS is a custom stylesheet appended to DefaultCSS.
+	CSS string
+	// ReplaceCSS uses CSS instead of DefaultCSS rather than appending it.
+	ReplaceCSS bool
+	// ChapterTitlePages inserts a page showing the chapter title before
+	// the pages of every chapter, which the table of contents links to in
+	// place of the first page.
+	ChapterTitlePages bool
+	// NoVolumeSections leaves out the page showing the volume title at
+	// the start of every volume. Volumes are still listed in the table of
+	// contents.
+	NoVolumeSections bool
+	// VolumeNumberWidth pads the number of volumes in their titles with
+	// leading zeros to this many digits, e.g. "Volume 01" for two.
+	VolumeNumberWidth int
+	// ChapterTitle is a text/template for the titles of chapter sections
+	// and their table of contents entries, with ChapterTitleFields as its
+	// data. Empty uses DefaultChapterTitle.
+	ChapterTitle string
+	// NavGroupSize groups the chapters of volumes with more chapters than
+	// this into nested table of contents entries. Zero disables grouping.
+	NavGroupSize int
+	// PageNumbers draws page numbers onto every page image.
+	PageNumbers kindle.PageNumbers
+	// WideThreshold is the aspect ratio above which the wide page policy
+	// considers pages wide. Zero uses the default of the kindle package.
+	WideThreshold float64
+	// SplitGutters splits wide pages that are two pages stitched together
+	// at their gutter, regardless of the wide page policy.
+	SplitGutters bool
+	// SplitOverlap extends both halves of split pages by this many pixels
+	// past the split.
+	SplitOverlap int
+	// SVGPages puts every page into a section of its own, wrapping its
+	// image in an SVG whose viewBox matches the image. Readers scale such
+	// pages like vector graphics, which keeps them crisp and letterboxed.
+	SVGPages bool
+	// PageFilenames shows the original filename of pages loaded from disk
+	// as the title of their image.
+	PageFilenames bool
+	// PagePadding pads the page numbers in the filenames of page images
+	// with leading zeros to this many digits, so that readers which sort
+	// the archive by name keep the pages in order. Zero pads to the
+	// digits of the largest page number of the book.
+	PagePadding int
+	// Margin adds a border around every page image, so that e-readers do
+	// not hide the edges of pages under their bezel.
+	Margin Margin
+	// Identifier overrides the unique identifier of the package, which is
+	// otherwise derived from the MangaDex ID, or the title, and volumes.
+	Identifier string
+	// Verbose writes debug messages about the generated sections to
+	// standard error.
+	Verbose bool
+}
+
+// DefaultChapterTitle names chapters by their title, falling back to
+// their number for chapters without one.
+const DefaultChapterTitle = `{{if .Title}}{{.Title}}{{else}}Chapter {{.Number}}{{end}}`
+
+// ChapterTitleFields are the fields available to chapter title templates.
+type ChapterTitleFields struct {
+	// Number is the chapter number, e.g. "7.5"
+	Number string
+	// Title is the chapter title, which may be empty
+	Title string
+	// Volume is the display title of the volume, e.g. "Volume 3"
+	Volume string
+}
+
+// ParseChapterTitle parses a chapter title template, using
+// DefaultChapterTitle for the empty string.
+func ParseChapterTitle(text string) (*template.Template, error) {
+	return template.New("chapter-title").Parse(cmp.Or(text, DefaultChapterTitle))
+}
+
+func (o Options) stylesheet() string {
+	switch {
+	case o.ReplaceCSS:
+		return o.CSS
+	case o.CSS != "":
+		return DefaultCSS + "\n" + o.CSS
+	default:
+		return DefaultCSS
+	}
+}
+
+// Margin describes a border added around page images.
+type Margin = kindle.Margin
+
+func (o Options) debugf(format string, args ...any) {
+	if o.Verbose {
+		progress.Println(os.Stderr, "[DEBUG] "+fmt.Sprintf(format, args...))
+	}
+}
+
+// GenerateEPUBWithOptions creates an EPUB file from manga data like
+// GenerateEPUB, but allows customizing the output with the given options.
+func GenerateEPUBWithOptions(tempDir string, manga mangadex.Manga, widepage kindle.WidepagePolicy, crop bool, ltr bool, opts Options) (*epub.Epub, func(), error) {
+	var tempPaths []string
+	e, err := generateEPUB(manga, widepage, crop, ltr, opts, tempFileStore(tempDir, &tempPaths))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	/*
+	   Cleanup function: Must be called only after the EPUB is fully written.
+	   If called before e.Write(), temp image files will be deleted too early and EPUB writing will fail.
+	*/
+	cleanup := func() {
+		for _, path := range tempPaths {
+			_ = os.Remove(path)
+		}
+	}
+
+	return e, cleanup, nil
+}
+
+// generateEPUB creates an EPUB from manga data, handing the stylesheet and
+// all encoded images to the given store, which decides where go-epub reads
+// them from
+func generateEPUB(manga mangadex.Manga, widepage kindle.WidepagePolicy, crop bool, ltr bool, opts Options, store fileStore) (*epub.Epub, error) {
 	// Basic validation
 	if manga.Info.Title == "" {
 		// Instead of error, use a default title to match test expectations
 		manga.Info.Title = "Untitled Manga"
 	}
 	if len(manga.Volumes) == 0 {
-		return nil, nil, fmt.Errorf("manga has no volumes")
+		return nil, fmt.Errorf("manga has no volumes")
 	}
 
 	e := epub.NewEpub(manga.Info.Title)
 	if len(manga.Info.Authors) > 0 {
 		e.SetAuthor(manga.Info.Authors[0])
 	}
-	// Set identifier if present
-	if manga.Info.ID != "" {
-		e.SetIdentifier(manga.Info.ID)
+	e.SetIdentifier(cmp.Or(opts.Identifier, mangaToIdentifier(manga)))
+	bookLang := mangaToLanguage(manga).String()
+	e.SetLang(bookLang)
+	cssSource, err := store("style.css", []byte(opts.stylesheet()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to write temp CSS file: %w", err)
 	}
-	// Always set language to en (default)
-	e.SetLang("en")
-	cssContent := "body { margin: 0; padding: 0; } img { display: block; max-width: 100%; height: auto; }"
-	cssTempPath := filepath.Join(tempDir, "style.css")
-	err := os.WriteFile(cssTempPath, []byte(cssContent), 0644)
+	cssHref, _ := e.AddCSS(cssSource, "style.css")
+	chapterTitleTemplate, err := ParseChapterTitle(opts.ChapterTitle)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to write temp CSS file: %w", err)
+		return nil, fmt.Errorf("chapter title: %w", err)
 	}
-	cssHref, _ := e.AddCSS(cssTempPath, "style.css")
-
-	var tempImagePaths []string
-	// Track temp CSS for cleanup
-	tempImagePaths = append(tempImagePaths, cssTempPath)
 
-	// Add covers for each volume as images
-	coverIndex := 1
-	for volID, vol := range manga.Volumes {
+	// Add the cover of the first volume that has one, covers of later
+	// volumes would not be shown anywhere
+	for _, volID := range manga.Keys() {
+		vol := manga.Volumes[volID]
 		// Validate cover dimensions
 		if vol.Cover != nil {
 			bounds := vol.Cover.Bounds()
 			if bounds.Dx() <= 0 || bounds.Dy() <= 0 || bounds.Min.X < 0 || bounds.Min.Y < 0 || bounds.Max.X <= bounds.Min.X || bounds.Max.Y <= bounds.Min.Y {
-				return nil, nil, fmt.Errorf("invalid cover image dimensions: %+v", bounds)
+				return nil, fmt.Errorf("invalid cover image dimensions: %+v", bounds)
 			}
 			coverName := fmt.Sprintf("cover-%v.jpg", volID)
-			imgPath := filepath.Join(tempDir, coverName)
-			f, err := os.Create(imgPath)
-			if err != nil {
-				return nil, nil, fmt.Errorf("failed to create temp cover image: %w", err)
+			coverBuf := new(bytes.Buffer)
+			if err := jpeg.Encode(coverBuf, vol.Cover, nil); err != nil {
+				return nil, fmt.Errorf("failed to encode cover image: %w", err)
 			}
-			err = jpeg.Encode(f, vol.Cover, nil)
-			f.Close()
+			imgSource, err := store(coverName, coverBuf.Bytes())
 			if err != nil {
-				return nil, nil, fmt.Errorf("failed to encode cover image: %w", err)
+				return nil, fmt.Errorf("failed to create temp cover image: %w", err)
 			}
 			// Add cover image to EPUB and manifest
-			imgHref, err := e.AddImage(imgPath, coverName)
+			imgHref, err := e.AddImage(imgSource, coverName)
 			if err != nil {
-				return nil, nil, fmt.Errorf("failed to add cover image: %w", err)
-			}
-			// Set as cover if first volume
-			if coverIndex == 1 {
-				e.SetCover(imgHref, "")
+				return nil, fmt.Errorf("failed to add cover image: %w", err)
 			}
-			tempImagePaths = append(tempImagePaths, imgPath)
-			coverIndex++
+			e.SetCover(imgHref, "")
+			break
 		}
 	}
 
 	// Parallel image processing worker pool
+	type imgResult struct {
+		source string
+		err    error
+	}
 	type imgJob struct {
 		img      image.Image
 		imgName  string
-		imgPath  string
-		resultCh chan error
+		resultCh chan imgResult
 	}
 
 	const maxWorkers = 4 // Tune for your CPU
@@ -162,50 +332,50 @@ func GenerateEPUB(tempDir string, manga mangadex.Manga, widepage kindle.Widepage
 			for job := range imgJobs {
 				jpegMu.Lock()
 				jpegBuf.Reset()
-				err := jpeg.Encode(jpegBuf, job.img, nil)
-				jpegMu.Unlock()
-				if err == nil {
-					f, ferr := os.Create(job.imgPath)
-					if ferr == nil {
-						_, werr := f.Write(jpegBuf.Bytes())
-						f.Close()
-						if werr != nil {
-							err = werr
-						}
-					} else {
-						err = ferr
-					}
+				result := imgResult{err: jpeg.Encode(jpegBuf, job.img, nil)}
+				if result.err == nil {
+					result.source, result.err = store(job.imgName, jpegBuf.Bytes())
 				}
-				job.resultCh <- err
+				jpegMu.Unlock()
+				job.resultCh <- result
 			}
 		}()
 	}
 
-	// Track chapters that actually had a section created
+	// Track the section path of chapters that actually had a section created
 	type chapterKey struct {
 		volID   mangadex.Identifier
 		chapKey mangadex.Identifier
 	}
-	addedChapters := make(map[chapterKey]bool)
+	chapterPaths := make(map[chapterKey]string)
+	chapterTitles := make(map[chapterKey]string)
+	volumePaths := make(map[mangadex.Identifier]string)
+	pageNumber := 0
+	imgNames := make(map[string]bool)
+	pagePadding := pagePaddingWidth(manga, opts.PagePadding)
 
 	// For each volume and chapter, add pages with deterministic image names
-	for volID, vol := range manga.Volumes {
+	for _, volID := range manga.Keys() {
+		vol := manga.Volumes[volID]
 		// Add a section for the volume at the start of the volume loop
-		volNum := volID.StringFilled(1, 0, false)
-		volTitle := "Volume " + volNum
-		volSectionHTML := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+		if !opts.NoVolumeSections {
+			volTitle := volumeTitle(volID, opts.VolumeNumberWidth)
+			volSectionHTML := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
 <html xmlns="http://www.w3.org/1999/xhtml">
 <head>
   <title>%s</title>
   <link rel="stylesheet" type="text/css" href="%s"/>
 </head>
 <body><h1>%s</h1></body>
-</html>`, volTitle, cssHref, volTitle)
-		_, _ = e.AddSection(volSectionHTML, volTitle, fmt.Sprintf("volume-%v.xhtml", volID), "volume")
+</html>`, html.EscapeString(volTitle), cssHref, html.EscapeString(volTitle))
+			if volPath, err := e.AddSection(volSectionHTML, volTitle, fmt.Sprintf("volume-%v.xhtml", volID), ""); err == nil {
+				volumePaths[volID] = volPath
+			}
+		}
 
 		// Check for empty chapters in volume
 		if len(vol.Chapters) == 0 {
-			return nil, nil, fmt.Errorf("volume %v has no chapters", volID)
+			return nil, fmt.Errorf("volume %v has no chapters", volID)
 		}
 		// Sort chapter keys to ensure deterministic chapter order
 		chapKeys := make([]mangadex.Identifier, 0, len(vol.Chapters))
@@ -215,16 +385,39 @@ func GenerateEPUB(tempDir string, manga mangadex.Manga, widepage kindle.Widepage
 		sort.Slice(chapKeys, func(i, j int) bool { return chapKeys[i].Less(chapKeys[j]) })
 		for _, chapKey := range chapKeys {
 			chap := vol.Chapters[chapKey]
-			sectionTitle := chap.Info.Title
-			if sectionTitle == "" {
-				sectionTitle = "Untitled Chapter"
+			sectionTitle, err := chapterTitle(chapterTitleTemplate, volID, chapKey, chap.Info.Title, opts.VolumeNumberWidth)
+			if err != nil {
+				return nil, fmt.Errorf("chapter title: %w", err)
 			}
+			chapterTitles[chapterKey{volID, chapKey}] = sectionTitle
 			// Check for empty pages in chapter
 			if len(chap.Pages) == 0 {
-				return nil, nil, fmt.Errorf("chapter %q has no pages", sectionTitle)
+				return nil, fmt.Errorf("chapter %q has no pages", sectionTitle)
+			}
+			// Chapters carry their own language for mixed-language volumes
+			chapLang := chapterLanguage(chap.Info, bookLang)
+			// The table of contents links the title page of chapters that
+			// have one, so that following it does not skip the page
+			titlePath := ""
+			if opts.ChapterTitlePages {
+				titleHTML := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml" xml:lang="%[3]s" lang="%[3]s">
+<head>
+  <title>%[1]s</title>
+  <link rel="stylesheet" type="text/css" href="%[2]s"/>
+</head>
+<body><div class="chapter-title"><h1>%[1]s</h1></div></body>
+</html>`, html.EscapeString(sectionTitle), cssHref, chapLang)
+				// Untitled sections are left out of the flat table of
+				// contents of go-epub
+				titleID := fmt.Sprintf("title-%v-%v.xhtml", volID, chapKey)
+				if titlePath, err = e.AddSection(titleHTML, "", titleID, ""); err != nil {
+					return nil, fmt.Errorf("failed to add title page: %w", err)
+				}
 			}
 			// Build HTML for this chapter with all images, in sorted order
 			var htmlBuilder strings.Builder
+			var svgPages []string
 			// Sort page keys to ensure deterministic order
 			pageKeys := make([]int, 0, len(chap.Pages))
 			for k := range chap.Pages {
@@ -236,72 +429,128 @@ func GenerateEPUB(tempDir string, manga mangadex.Manga, widepage kindle.Widepage
 				img := chap.Pages[k]
 				if img == nil {
 					// Return an error for nil images instead of skipping
-					return nil, nil, fmt.Errorf("nil image found in chapter %q, page %d", sectionTitle, k)
+					return nil, fmt.Errorf("nil image found in chapter %q, page %d", sectionTitle, k)
 				}
 				bounds := img.Bounds()
 				if bounds.Dx() <= 0 || bounds.Dy() <= 0 || bounds.Min.X < 0 || bounds.Min.Y < 0 || bounds.Max.X <= bounds.Min.X || bounds.Max.Y <= bounds.Min.Y {
-					return nil, nil, fmt.Errorf("invalid image dimensions in chapter %q: %+v", sectionTitle, bounds)
+					return nil, fmt.Errorf("invalid image dimensions in chapter %q: %+v", sectionTitle, bounds)
 				}
-				// Use CropAndSplit for wide page handling
-				processedImages := kindle.CropAndSplit(img, widepage, crop, ltr)
-				// Release reference to original image
-				chap.Pages[k] = nil
+				// Crop, split wide pages, scale images wider than 1600px and add margins
+				processedImages := kindle.CropAndSplitWithOptions(img, kindle.PageOptions{
+					Policy:        widepage,
+					WideThreshold: opts.WideThreshold,
+					AutoCrop:      crop,
+					LeftToRight:   ltr,
+					SplitGutters:  opts.SplitGutters,
+					SplitOverlap:  opts.SplitOverlap,
+					MaxWidth:      1600,
+					Margin:        opts.Margin,
+				})
 				for splitIdx, splitImg := range processedImages {
+					pageNumber++
+					splitImg = opts.PageNumbers.Apply(splitImg, pageNumber, imgIdx+1)
 					bounds := splitImg.Bounds()
 					if bounds.Dx() <= 0 || bounds.Dy() <= 0 || bounds.Min.X < 0 || bounds.Min.Y < 0 || bounds.Max.X <= bounds.Min.X || bounds.Max.Y <= bounds.Min.Y {
-						return nil, nil, fmt.Errorf("invalid split image dimensions in chapter %q: %+v", sectionTitle, bounds)
+						return nil, fmt.Errorf("invalid split image dimensions in chapter %q: %+v", sectionTitle, bounds)
 					}
-					// Scale image if wider than 1600px
-					if splitImg.Bounds().Dx() > 1600 {
-						splitImg = scaleImageToMaxWidth(splitImg, 1600)
-					}
-					imgName := fmt.Sprintf("page-%v-%v-%d", volID, chapKey, k)
+					imgName := fmt.Sprintf("page-%v-%v-%0*d", volID, chapKey, pagePadding, k)
 					if len(processedImages) > 1 {
-						imgName = fmt.Sprintf("%s-%d.jpg", imgName, splitIdx)
-					} else {
-						imgName = imgName + ".jpg"
+						imgName = fmt.Sprintf("%s-%d", imgName, splitIdx)
 					}
-					imgPath := filepath.Join(tempDir, imgName)
-					resultCh := make(chan error, 1)
-					imgJobs <- imgJob{img: splitImg, imgName: imgName, imgPath: imgPath, resultCh: resultCh}
-					err := <-resultCh
-					if err != nil {
-						return nil, nil, fmt.Errorf("failed to encode/write image: %w", err)
+					// Identifiers may look alike once joined, in which case the
+					// position of the page in the book keeps names unique
+					for imgNames[imgName] {
+						imgName = fmt.Sprintf("%s-p%d", imgName, pageNumber)
+					}
+					imgNames[imgName] = true
+					imgName = imgName + ".jpg"
+					resultCh := make(chan imgResult, 1)
+					imgJobs <- imgJob{img: splitImg, imgName: imgName, resultCh: resultCh}
+					result := <-resultCh
+					if result.err != nil {
+						return nil, fmt.Errorf("failed to encode/write image: %w", result.err)
 					}
-					imgHref, err := e.AddImage(imgPath, imgName)
+					imgHref, err := e.AddImage(result.source, imgName)
 					if err != nil {
-						return nil, nil, fmt.Errorf("failed to add image: %w", err)
+						return nil, fmt.Errorf("failed to add image: %w", err)
+					}
+					title := ""
+					if filename := chap.Filenames[k]; opts.PageFilenames && filename != "" {
+						title = fmt.Sprintf(" title=\"%s\"", html.EscapeString(filename))
+					}
+					alt := pageAltText(manga.Info.Title, chapKey, imgIdx+1)
+					if opts.SVGPages {
+						svgPages = append(svgPages, svgPage(imgIdx+1, imgHref, alt, bounds.Size()))
+					} else {
+						htmlBuilder.WriteString(fmt.Sprintf(
+							"<div role=\"group\" aria-label=\"Page %d\"><img src=\"%s\" alt=\"%s\"%s/></div>",
+							imgIdx+1, imgHref, alt, title,
+						))
 					}
-					htmlBuilder.WriteString(fmt.Sprintf("<div><img src=\"%s\" alt=\"Page image\"/></div>", imgHref))
-					tempImagePaths = append(tempImagePaths, imgPath)
 					// Release reference to split image
 					processedImages[splitIdx] = nil
 					imgIdx++
 				}
 			}
+			sectionID := fmt.Sprintf("chapter-%v-%v.xhtml", volID, chapKey)
+			if len(svgPages) > 0 {
+				// Every page is a section of its own, only the first of
+				// which is listed in the table of contents
+				sectionPath := ""
+				for i, page := range svgPages {
+					pageID, pageTitle := sectionID, sectionTitle
+					if i > 0 {
+						pageID, pageTitle = fmt.Sprintf("chapter-%v-%v-%d.xhtml", volID, chapKey, i+1), ""
+					}
+					pageHTML := `<?xml version="1.0" encoding="UTF-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml" xml:lang="` + chapLang + `" lang="` + chapLang + `">
+<head>
+  <title>` + html.EscapeString(sectionTitle) + `</title>
+  <link rel="stylesheet" type="text/css" href="` + cssHref + `"/>
+</head>
+<body>
+` + page + `
+</body>
+</html>`
+					pagePath, err := e.AddSection(pageHTML, pageTitle, pageID, "")
+					if err != nil {
+						return nil, fmt.Errorf("failed to add section %s: %w", pageID, err)
+					}
+					if i == 0 {
+						sectionPath = pagePath
+					}
+				}
+				opts.debugf("added %d page sections of %s at %s", len(svgPages), sectionID, sectionPath)
+				chapterPaths[chapterKey{volID, chapKey}] = cmp.Or(titlePath, sectionPath)
+				runtime.GC()
+				continue
+			}
 			if htmlBuilder.Len() == 0 {
 				htmlBuilder.WriteString("<p>(No images in this chapter)</p>")
 			}
+			// The title page already shows the heading
+			heading := ""
+			if titlePath == "" {
+				heading = "<h1>" + html.EscapeString(sectionTitle) + "</h1>"
+			}
 			// Prepend stylesheet link in a full XHTML document structure
 			sectionHTML := `<?xml version="1.0" encoding="UTF-8"?>
-<html xmlns="http://www.w3.org/1999/xhtml">
+<html xmlns="http://www.w3.org/1999/xhtml" xml:lang="` + chapLang + `" lang="` + chapLang + `">
 <head>
-  <title>` + sectionTitle + `</title>
+  <title>` + html.EscapeString(sectionTitle) + `</title>
   <link rel="stylesheet" type="text/css" href="` + cssHref + `"/>
 </head>
 <body>
-<h1>` + sectionTitle + `</h1>` + htmlBuilder.String() + `
+` + heading + htmlBuilder.String() + `
 </body>
 </html>`
-			sectionID := fmt.Sprintf("chapter-%v-%v.xhtml", volID, chapKey)
-			sectionPath, err := e.AddSection(sectionHTML, sectionTitle, sectionID, "chapter")
+			sectionPath, err := e.AddSection(sectionHTML, sectionTitle, sectionID, "")
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to add section %s: %v\n", sectionID, err)
-				return nil, nil, fmt.Errorf("failed to add section: %w", err)
+				return nil, fmt.Errorf("failed to add section %s: %w", sectionID, err)
 			}
-			fmt.Fprintf(os.Stderr, "Added section: %s at %s\n", sectionID, sectionPath)
+			opts.debugf("added section %s at %s", sectionID, sectionPath)
 			// Mark this chapter as added
-			addedChapters[chapterKey{volID, chapKey}] = true
+			chapterPaths[chapterKey{volID, chapKey}] = cmp.Or(titlePath, sectionPath)
 			// Encourage GC after each chapter
 			runtime.GC()
 		}
@@ -311,13 +560,14 @@ func GenerateEPUB(tempDir string, manga mangadex.Manga, widepage kindle.Widepage
 	close(imgJobs)
 	wg.Wait()
 
-	// After all chapters are added, generate nav.xhtml
-	// Always use nested structure for all manga (even single-volume)
+	// After all chapters are added, generate the nested table of contents.
+	// go-epub only writes a flat one, so it is written to a page of its own
+	// that replaces the navigation document and NCX when the book is output.
 	navHTML := `<?xml version="1.0" encoding="UTF-8"?>
 <!DOCTYPE html>
 <html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
   <head>
-    <title>` + manga.Info.Title + `</title>
+    <title>` + html.EscapeString(manga.Info.Title) + `</title>
   </head>
   <body>
     <nav epub:type="toc">
@@ -333,63 +583,85 @@ func GenerateEPUB(tempDir string, manga mangadex.Manga, widepage kindle.Widepage
 	// Always use nested structure for navigation
 	for _, volID := range volKeys {
 		vol := manga.Volumes[volID]
-		volNum := volID.StringFilled(1, 0, false)
-		volTitle := "Volume " + volNum
-		// Emit <li>Volume N<ol>...</ol></li> with NO indentation or newline between <li> and volume title
-		navHTML += "        <li>" + volTitle + "<ol>\n"
+		volTitle := volumeTitle(volID, opts.VolumeNumberWidth)
+		// Volumes link to their section, if they have one
+		if volPath, ok := volumePaths[volID]; ok {
+			navHTML += "        <li><a href=\"" + volPath + "\">" + html.EscapeString(volTitle) + "</a><ol>\n"
+		} else {
+			navHTML += "        <li><span>" + html.EscapeString(volTitle) + "</span><ol>\n"
+		}
 		chapKeys := make([]mangadex.Identifier, 0, len(vol.Chapters))
 		for k := range vol.Chapters {
 			chapKeys = append(chapKeys, k)
 		}
 		sort.Slice(chapKeys, func(i, j int) bool { return chapKeys[i].Less(chapKeys[j]) })
-		chapterCount := 0
+		added := make([]mangadex.Identifier, 0, len(chapKeys))
 		for _, chapKey := range chapKeys {
-			if !addedChapters[chapterKey{volID, chapKey}] {
-				continue
+			if _, ok := chapterPaths[chapterKey{volID, chapKey}]; ok {
+				added = append(added, chapKey)
 			}
-			chap := vol.Chapters[chapKey]
-			chapTitle := chap.Info.Title
-			if chapTitle == "" {
-				chapTitle = "Untitled Chapter"
+		}
+		// Bucket chapters of long volumes into groups of the configured size
+		groupSize := len(added)
+		if opts.NavGroupSize > 0 && opts.NavGroupSize < len(added) {
+			groupSize = opts.NavGroupSize
+		}
+		grouped := groupSize < len(added)
+		for start := 0; start < len(added); start += groupSize {
+			group := added[start:min(start+groupSize, len(added))]
+			indent := "            "
+			if grouped {
+				navHTML += fmt.Sprintf("            <li><span>Chapters %v–%v</span><ol>\n", group[0], group[len(group)-1])
+				indent += "    "
+			}
+			for _, chapKey := range group {
+				chapTitle := chapterTitles[chapterKey{volID, chapKey}]
+				// Section paths are relative to other sections, such as this one
+				sectionPath := chapterPaths[chapterKey{volID, chapKey}]
+				navHTML += indent + "<li><a href=\"" + sectionPath + "\">" + html.EscapeString(chapTitle) + "</a></li>\n"
+			}
+			if grouped {
+				navHTML += "            </ol></li>\n"
 			}
-			sectionID := fmt.Sprintf("chapter-%v-%v.xhtml", volID, chapKey)
-			navHTML += "            <li><a href=\"xhtml/" + sectionID + "\">" + chapTitle + "</a></li>\n"
-			chapterCount++
 		}
 		navHTML += "          </ol>\n"
 		navHTML += "        </li>\n"
 	}
-	// Optionally add navigation link at the end
-	navHTML += "        <li><a href=\"nav.xhtml\">Navigation</a></li>\n"
 	navHTML += `      </ol>
     </nav>
   </body>
 </html>
 `
-	// Add nav.xhtml as a section with nav property
-	fmt.Fprintf(os.Stderr, "[DEBUG] nav.xhtml about to be added:\n%s\n", navHTML)
-	_, _ = e.AddSection(navHTML, "Navigation", "nav.xhtml", "nav")
-	fmt.Fprintf(os.Stderr, "[DEBUG] nav.xhtml AddSection complete\n")
+	// Untitled, so that the table of contents does not list itself
+	_, _ = e.AddSection(navHTML, "", "nav.xhtml", "")
+	opts.debugf("added navigation section nav.xhtml")
 
-	/*
-	   Cleanup function: Must be called only after the EPUB is fully written.
-	   If called before e.Write(), temp image files will be deleted too early and EPUB writing will fail.
-	*/
-	cleanup := func() {
-		for _, path := range tempImagePaths {
-			_ = os.Remove(path)
-		}
-	}
+	return e, nil
+}
 
-	return e, cleanup, nil
+// svgPage returns the body of a page that shows the given image in an SVG
+// scaled to the viewport
+func svgPage(number int, href, alt string, size image.Point) string {
+	return fmt.Sprintf(
+		"<div class=\"svg-page\" role=\"group\" aria-label=\"Page %d\">"+
+			"<svg xmlns=\"http://www.w3.org/2000/svg\" xmlns:xlink=\"http://www.w3.org/1999/xlink\" version=\"1.1\" width=\"100%%\" height=\"100%%\" viewBox=\"0 0 %d %d\" preserveAspectRatio=\"xMidYMid meet\">"+
+			"<title>%s</title><image width=\"%d\" height=\"%d\" xlink:href=\"%s\"/></svg></div>",
+		number, size.X, size.Y, alt, size.X, size.Y, href,
+	)
 }
 
 func GenerateEPUBProd(manga mangadex.Manga, widepage kindle.WidepagePolicy, crop bool, ltr bool) (*epub.Epub, func(), error) {
-	tempDir, err := os.MkdirTemp("", "epub-prod-*")
+	return GenerateEPUBProdWithOptions(manga, widepage, crop, ltr, Options{})
+}
+
+// GenerateEPUBProdWithOptions is like GenerateEPUBProd, but allows
+// customizing the output with the given options.
+func GenerateEPUBProdWithOptions(manga mangadex.Manga, widepage kindle.WidepagePolicy, crop bool, ltr bool, opts Options) (*epub.Epub, func(), error) {
+	tempDir, err := os.MkdirTemp(util.TempDir(), "epub-prod-*")
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create temp dir: %w", err)
 	}
-	epubObj, cleanup, err := GenerateEPUB(tempDir, manga, widepage, crop, ltr)
+	epubObj, cleanup, err := GenerateEPUBWithOptions(tempDir, manga, widepage, crop, ltr, opts)
 	prodCleanup := func() {
 		cleanup()
 		_ = os.RemoveAll(tempDir)
@@ -397,18 +669,111 @@ func GenerateEPUBProd(manga mangadex.Manga, widepage kindle.WidepagePolicy, crop
 	return epubObj, prodCleanup, err
 }
 
-func scaleImageToMaxWidth(src image.Image, maxWidth int) image.Image {
-	bounds := src.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-	if width <= maxWidth {
-		return src
-	}
-	newWidth := maxWidth
-	newHeight := int(float64(height) * float64(maxWidth) / float64(width))
-	dst := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
-	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
-	return dst
+// chapterTitle returns the display title for a chapter of the given
+// volume by executing the chapter title template
+func chapterTitle(tmpl *template.Template, volID, chapID mangadex.Identifier, title string, width int) (string, error) {
+	var b strings.Builder
+	err := tmpl.Execute(&b, ChapterTitleFields{
+		Number: chapID.String(),
+		Title:  title,
+		Volume: volumeTitle(volID, width),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// pagePaddingWidth returns the number of digits that page numbers in the
+// filenames of page images are padded to, which is the given width or the
+// digits of the largest page number of the manga if zero.
+func pagePaddingWidth(manga mangadex.Manga, width int) int {
+	if width > 0 {
+		return width
+	}
+	largest := 0
+	for _, vol := range manga.Volumes {
+		for _, chap := range vol.Chapters {
+			for k := range chap.Pages {
+				largest = max(largest, k)
+			}
+		}
+	}
+
+	return len(strconv.Itoa(largest))
+}
+
+// volumeTitle returns the display title for a volume with its number
+// padded to the given width, using the plain name for special volumes
+// such as "Oneshot" or "Special".
+func volumeTitle(volID mangadex.Identifier, width int) string {
+	if volID.IsSpecial() {
+		return volID.String()
+	}
+
+	return "Volume " + volID.StringFilled(max(width, 1), 0, false)
+}
+
+// mangaToLanguage returns the language of most chapters as a canonical
+// BCP 47 tag, falling back to English when it cannot be determined. Ties
+// go to the language that appears first in reading order.
+func mangaToLanguage(manga mangadex.Manga) language.Tag {
+	counts := make(map[language.Tag]int)
+	dominant := language.Und
+	for _, vol := range manga.Sorted() {
+		for _, chap := range vol.Sorted() {
+			tag, err := language.BCP47.Canonicalize(chap.Info.Language)
+			if err != nil || tag == language.Und {
+				continue
+			}
+			counts[tag]++
+			if counts[tag] > counts[dominant] {
+				dominant = tag
+			}
+		}
+	}
+	if dominant == language.Und {
+		return language.English
+	}
+
+	return dominant
+}
+
+// mangaToIdentifier returns a name-based UUID URN derived from the ID, or
+// the title if it has none, and the volumes of the manga, so that repeated
+// runs produce the same identifier while every book of a series gets its
+// own.
+func mangaToIdentifier(manga mangadex.Manga) string {
+	hash := sha1.New()
+	hash.Write([]byte(cmp.Or(manga.Info.ID, manga.Info.Title)))
+	for _, idx := range manga.Keys() {
+		hash.Write([]byte{0})
+		hash.Write([]byte(idx.String()))
+	}
+	sum := hash.Sum(nil)
+
+	// Version 5 and RFC 4122 variant bits
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// chapterLanguage returns the language of a chapter as a canonical BCP 47
+// tag, or the given fallback when it cannot be determined.
+func chapterLanguage(info mangadex.ChapterInfo, fallback string) string {
+	tag, err := language.BCP47.Canonicalize(info.Language)
+	if err != nil || tag == language.Und {
+		return fallback
+	}
+
+	return tag.String()
+}
+
+// pageAltText returns descriptive alternative text for a page image,
+// escaped for use in an XHTML attribute.
+func pageAltText(series string, chapter mangadex.Identifier, page int) string {
+	return html.EscapeString(fmt.Sprintf("%v Chapter %v page %d", series, chapter, page))
 }
 
 // PatchEPUBNavManifest ensures nav.xhtml is listed with properties="nav" in the OPF manifest inside the EPUB file.
@@ -24,8 +24,8 @@ func TestEPUBMetadataHandling(t *testing.T) {
 			name:   "standard metadata",
 			modify: func(manga *md.Manga) {},
 			validate: func(t *testing.T, e *epub.Epub) {
-				if e.Identifier() != "test-manga-id" {
-					t.Errorf("Expected identifier 'test-manga-id', got %s", e.Identifier())
+				if !strings.HasPrefix(e.Identifier(), "urn:uuid:") {
+					t.Errorf("Expected identifier derived from 'test-manga-id', got %s", e.Identifier())
 				}
 				if e.Title() != "Test Manga" {
 					t.Errorf("Expected title 'Test Manga', got %s", e.Title())
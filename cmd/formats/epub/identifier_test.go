@@ -0,0 +1,79 @@
+package epub
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+	md "github.com/leotaku/kojirou/mangadex"
+)
+
+var identifierRe = regexp.MustCompile(`<dc:identifier id="pub-id">([^<]*)</dc:identifier>`)
+
+func packageIdentifier(t *testing.T, manga md.Manga, opts Options) string {
+	t.Helper()
+
+	e, cleanup, err := GenerateEPUBWithOptions(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true, opts)
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		t.Fatalf("GenerateEPUBWithOptions() failed: %v", err)
+	}
+	zipReader, err := writeEPUB(t, e)
+	if err != nil {
+		t.Fatalf("failed to write EPUB: %v", err)
+	}
+	match := identifierRe.FindStringSubmatch(readEPUBFile(t, zipReader, "EPUB/package.opf"))
+	if match == nil {
+		t.Fatalf("package.opf has no identifier")
+	}
+
+	return match[1]
+}
+
+func TestIdentifier(t *testing.T) {
+	manga := testhelpers.CreateSyntheticManga(1, 1, 20, 30)
+	manga.Info.ID = ""
+
+	first, second := packageIdentifier(t, manga, Options{}), packageIdentifier(t, manga, Options{})
+	if first != second {
+		t.Errorf("identifiers differ between runs: %q and %q", first, second)
+	}
+	if !regexp.MustCompile(`^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(first) {
+		t.Errorf("identifier is not a name-based UUID URN: %q", first)
+	}
+
+	manga.Info.Title = "Other Manga"
+	other := packageIdentifier(t, manga, Options{})
+	if other == first {
+		t.Errorf("different series share the identifier %q", other)
+	}
+
+	manga.Info.ID = "mangadex-id"
+	if id := packageIdentifier(t, manga, Options{}); id == "mangadex-id" || id == other {
+		t.Errorf("identifier is not derived from the MangaDex ID, got %q", id)
+	}
+	if id := packageIdentifier(t, manga, Options{Identifier: "isbn:9780000000000"}); id != "isbn:9780000000000" {
+		t.Errorf("identifier override was not honored, got %q", id)
+	}
+}
+
+func TestIdentifierPerVolume(t *testing.T) {
+	manga := testhelpers.CreateTestManga()
+	manga.Info.ID = "mangadex-id"
+
+	ids := make(map[string]bool)
+	for _, volume := range manga.Sorted() {
+		book := manga
+		book.Volumes = map[md.Identifier]md.Volume{volume.Info.Identifier: volume}
+		ids[packageIdentifier(t, book, Options{})] = true
+	}
+	if combined := packageIdentifier(t, manga, Options{}); ids[combined] {
+		t.Errorf("combined book shares the identifier %q of a volume", combined)
+	}
+	if len(ids) != 2 {
+		t.Errorf("volumes of the same manga share identifiers: %v", ids)
+	}
+}
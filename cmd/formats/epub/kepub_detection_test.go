@@ -0,0 +1,51 @@
+package epub
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmaupin/go-epub"
+	kepubconv "github.com/leotaku/kojirou/cmd/formats/kepubconv"
+)
+
+func TestIsKEPUB(t *testing.T) {
+	// The word "kobo" appears in metadata and text without any Kobo markup
+	e := epub.NewEpub("Reading on my kobo")
+	e.SetAuthor("kobo fan")
+	if _, err := e.AddSection(`<p class="kobo-note">Bought from the kobo store</p>`, "kobo", "kobo.xhtml", ""); err != nil {
+		t.Fatalf("AddSection() failed: %v", err)
+	}
+
+	epubPath := filepath.Join(t.TempDir(), "plain.epub")
+	if err := e.Write(epubPath); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	data, err := kepubconv.ConvertToKEPUB(e, "", 0)
+	if err != nil {
+		t.Fatalf("ConvertToKEPUB() failed: %v", err)
+	}
+	kepubPath := filepath.Join(t.TempDir(), "book.kepub.epub")
+	if err := os.WriteFile(kepubPath, data, 0644); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		path string
+		want bool
+	}{
+		{"mentions kobo", epubPath, false},
+		{"converted", kepubPath, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsKEPUB(tt.path)
+			if err != nil {
+				t.Fatalf("IsKEPUB() failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsKEPUB() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
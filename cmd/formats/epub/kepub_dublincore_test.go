@@ -0,0 +1,53 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/kepubconv"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+)
+
+func TestKEPUBDublinCoreMetadata(t *testing.T) {
+	manga := testhelpers.CreateSyntheticManga(1, 2, 200, 300)
+	manga.Info.Title = "Full Metadata"
+	manga.Info.Authors = []string{"Some Author"}
+	e, cleanup, err := GenerateEPUB(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, false)
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		t.Fatalf("GenerateEPUB() failed: %v", err)
+	}
+	e.SetLang("ja")
+	e.SetDescription("A book with all metadata")
+
+	data, err := kepubconv.ConvertToKEPUB(e, "Some Series", 1)
+	if err != nil {
+		t.Fatalf("ConvertToKEPUB() failed: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("failed to open KEPUB: %v", err)
+	}
+	pkg := struct {
+		Identifier  string `xml:"metadata>identifier"`
+		Title       string `xml:"metadata>title"`
+		Creator     string `xml:"metadata>creator"`
+		Language    string `xml:"metadata>language"`
+		Description string `xml:"metadata>description"`
+	}{}
+	if err := xml.Unmarshal([]byte(readEPUBFile(t, zr, "EPUB/package.opf")), &pkg); err != nil {
+		t.Fatalf("failed to parse package: %v", err)
+	}
+
+	if pkg.Title != "Full Metadata" || pkg.Creator != "Some Author" || pkg.Language != "ja" {
+		t.Errorf("unexpected title %q, author %q or language %q", pkg.Title, pkg.Creator, pkg.Language)
+	}
+	if pkg.Identifier != e.Identifier() || pkg.Description != "A book with all metadata" {
+		t.Errorf("unexpected identifier %q or description %q", pkg.Identifier, pkg.Description)
+	}
+}
@@ -0,0 +1,71 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"image/color"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/kepubconv"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+)
+
+func TestKEPUBSpineOrder(t *testing.T) {
+	manga := testhelpers.CreateSyntheticManga(2, 2, 200, 300)
+	for volID, vol := range manga.Volumes {
+		vol.Cover = testhelpers.CreateTestImage(200, 300, color.Black)
+		manga.Volumes[volID] = vol
+	}
+	e, cleanup, err := GenerateEPUB(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true)
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		t.Fatalf("GenerateEPUB() failed: %v", err)
+	}
+	// Writing the EPUB first, as for EPUB and KEPUB output, repeats the
+	// manifest and spine of later writes
+	if err := e.Write(filepath.Join(t.TempDir(), "book.epub")); err != nil {
+		t.Fatalf("failed to write EPUB: %v", err)
+	}
+
+	data, err := kepubconv.ConvertToKEPUB(e, "", 0)
+	if err != nil {
+		t.Fatalf("ConvertToKEPUB() failed: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("failed to open KEPUB: %v", err)
+	}
+	pkg := struct {
+		Items []struct {
+			ID string `xml:"id,attr"`
+		} `xml:"manifest>item"`
+		Spine []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"spine>itemref"`
+	}{}
+	if err := xml.Unmarshal([]byte(readEPUBFile(t, zr, "EPUB/package.opf")), &pkg); err != nil {
+		t.Fatalf("failed to parse package: %v", err)
+	}
+
+	spine := make([]string, 0)
+	for _, ref := range pkg.Spine {
+		spine = append(spine, ref.IDRef)
+	}
+	want := []string{"cover.xhtml", "volume-1.xhtml", "chapter-1-1.xhtml", "chapter-1-2.xhtml", "nav.xhtml"}
+	if !slices.Equal(spine, want) {
+		t.Errorf("got spine %v, want %v", spine, want)
+	}
+	ids := make(map[string]bool)
+	for _, it := range pkg.Items {
+		if ids[it.ID] {
+			t.Errorf("manifest item %v is listed more than once", it.ID)
+		}
+		ids[it.ID] = true
+	}
+}
@@ -3,11 +3,13 @@ package epub
 import (
 	"archive/zip"
 	"bytes"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 
 	"golang.org/x/net/html"
@@ -227,61 +229,41 @@ func CheckForKoboSpanID(n *html.Node) bool {
 	return false
 }
 
-// IsKEPUB checks if an EPUB file has been converted to KEPUB format
-func IsKEPUB(filePath string) (bool, error) {
-	// Open the EPUB file
-	reader, err := os.Open(filePath)
-	if err != nil {
-		return false, fmt.Errorf("failed to open file: %w", err)
-	}
-	defer reader.Close()
-
-	// Get file info to determine size
-	info, err := reader.Stat()
-	if err != nil {
-		return false, fmt.Errorf("failed to get file info: %w", err)
-	}
+// koboNamespace is the XML namespace of Kobo extensions
+const koboNamespace = "http://kobobooks.com/ns/kobo"
 
-	// Open as ZIP
-	r, err := zip.NewReader(reader, info.Size())
+// IsKEPUB checks if an EPUB file has been converted to KEPUB format. Only
+// actual Kobo markers count, which are the Kobo content type in the
+// package document and Kobo spans or the Kobo namespace in content
+// documents, so that books merely mentioning Kobo are not mistaken for
+// KEPUB files.
+func IsKEPUB(filePath string) (bool, error) {
+	r, err := zip.OpenReader(filePath)
 	if err != nil {
 		return false, fmt.Errorf("failed to open as ZIP: %w", err)
 	}
+	defer r.Close()
 
-	// Check for Kobo-specific features
 	for _, f := range r.File {
-		// Check OPF files for Kobo metadata
-		if strings.HasSuffix(f.Name, ".opf") {
+		switch {
+		case strings.HasSuffix(f.Name, ".opf"):
 			rc, err := f.Open()
 			if err != nil {
 				continue
 			}
-
 			content, err := io.ReadAll(rc)
 			rc.Close()
-			if err != nil {
-				continue
-			}
-
-			if strings.Contains(string(content), "kobo") {
+			if err == nil && hasKoboContentType(content) {
 				return true, nil
 			}
-		}
-
-		// Check HTML files for Kobo spans
-		if strings.HasSuffix(f.Name, ".html") || strings.HasSuffix(f.Name, ".xhtml") {
+		case strings.HasSuffix(f.Name, ".html") || strings.HasSuffix(f.Name, ".xhtml"):
 			rc, err := f.Open()
 			if err != nil {
 				continue
 			}
-
-			content, err := io.ReadAll(rc)
+			doc, err := html.Parse(rc)
 			rc.Close()
-			if err != nil {
-				continue
-			}
-
-			if strings.Contains(string(content), "kobo") {
+			if err == nil && hasKoboMarkup(doc) {
 				return true, nil
 			}
 		}
@@ -290,6 +272,49 @@ func IsKEPUB(filePath string) (bool, error) {
 	return false, nil
 }
 
+// hasKoboContentType reports whether a package document declares a Kobo
+// content type in its metadata
+func hasKoboContentType(opf []byte) bool {
+	pkg := struct {
+		Metas []struct {
+			Name     string `xml:"name,attr"`
+			Property string `xml:"property,attr"`
+		} `xml:"metadata>meta"`
+	}{}
+	if err := xml.Unmarshal(opf, &pkg); err != nil {
+		return false
+	}
+	for _, meta := range pkg.Metas {
+		if meta.Name == "kobo:content-type" || meta.Property == "kobo:content-type" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasKoboMarkup reports whether a content document contains Kobo spans or
+// declares the Kobo namespace on its root element
+func hasKoboMarkup(n *html.Node) bool {
+	if n.Type == html.ElementNode {
+		for _, attr := range n.Attr {
+			switch {
+			case n.Data == "span" && attr.Key == "class" && slices.Contains(strings.Fields(attr.Val), "koboSpan"):
+				return true
+			case n.Data == "html" && attr.Val == koboNamespace && (attr.Key == "xmlns:kobo" || attr.Namespace == "xmlns" && attr.Key == "kobo"):
+				return true
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if hasKoboMarkup(c) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // findContentFiles is a test-local copy for test helpers
 func findContentFiles(extractDir string) ([]string, error) {
 	var contentFiles []string
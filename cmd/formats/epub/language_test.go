@@ -0,0 +1,86 @@
+package epub
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+	md "github.com/leotaku/kojirou/mangadex"
+	"golang.org/x/text/language"
+)
+
+// withLanguages assigns the given languages to the chapters of the manga
+// in reading order
+func withLanguages(manga md.Manga, langs ...language.Tag) md.Manga {
+	i := 0
+	for _, vol := range manga.Sorted() {
+		for _, chap := range vol.Sorted() {
+			chap.Info.Language = langs[i%len(langs)]
+			manga.Volumes[vol.Info.Identifier].Chapters[chap.Info.Identifier] = chap
+			i++
+		}
+	}
+
+	return manga
+}
+
+func TestEPUBLanguage(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		langs    []language.Tag
+		expected string
+	}{
+		{"japanese", []language.Tag{language.Japanese, language.Japanese, language.English}, "ja"},
+		{"regional", []language.Tag{language.MustParse("pt-br")}, "pt-BR"},
+		{"undetermined", []language.Tag{language.Und}, "en"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			manga := withLanguages(testhelpers.CreateSyntheticManga(3, 1, 20, 30), tc.langs...)
+			e, cleanup, err := GenerateEPUB(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true)
+			if cleanup != nil {
+				defer cleanup()
+			}
+			if err != nil {
+				t.Fatalf("GenerateEPUB() failed: %v", err)
+			}
+			zipReader, err := writeEPUB(t, e)
+			if err != nil {
+				t.Fatalf("failed to write EPUB: %v", err)
+			}
+
+			opf := readEPUBFile(t, zipReader, "EPUB/package.opf")
+			if expected := "<dc:language>" + tc.expected + "</dc:language>"; !strings.Contains(opf, expected) {
+				t.Errorf("package.opf does not contain %q", expected)
+			}
+		})
+	}
+}
+
+func TestChapterLanguage(t *testing.T) {
+	manga := withLanguages(testhelpers.CreateSyntheticManga(3, 1, 20, 30), language.Japanese, language.English, language.Und)
+	e, cleanup, err := GenerateEPUBWithOptions(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true, Options{ChapterTitlePages: true})
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		t.Fatalf("GenerateEPUBWithOptions() failed: %v", err)
+	}
+	zipReader, err := writeEPUB(t, e)
+	if err != nil {
+		t.Fatalf("failed to write EPUB: %v", err)
+	}
+
+	// Chapters without a language use the language of the book
+	for _, tc := range []struct{ file, expected string }{
+		{"chapter-1-1.xhtml", "ja"},
+		{"title-1-1.xhtml", "ja"},
+		{"chapter-1-2.xhtml", "en"},
+		{"chapter-1-3.xhtml", "ja"},
+	} {
+		xhtml := readEPUBFile(t, zipReader, "EPUB/xhtml/"+tc.file)
+		if expected := `xml:lang="` + tc.expected + `" lang="` + tc.expected + `"`; !strings.Contains(xhtml, expected) {
+			t.Errorf("%v does not contain %q", tc.file, expected)
+		}
+	}
+}
@@ -307,3 +307,58 @@ func verifyMangaEPUBStructure(t *testing.T, epub *epub.Epub, expectedVolumes, ex
 		t.Error("nav.xhtml not found")
 	}
 }
+
+func TestVolumelessChaptersGenerateOneshotVolume(t *testing.T) {
+	chapters := md.ChapterList{
+		{Info: md.ChapterInfo{
+			Title:            "Prologue",
+			Identifier:       md.NewIdentifier("0"),
+			VolumeIdentifier: md.UnknownIdentifier(),
+		}},
+		{Info: md.ChapterInfo{
+			Title:            "Extra",
+			Identifier:       md.NewIdentifier("1"),
+			VolumeIdentifier: md.UnknownIdentifier(),
+		}},
+	}.CollectVolumeless()
+
+	for _, chap := range chapters {
+		if !chap.Info.VolumeIdentifier.Equal(md.OneshotIdentifier()) {
+			t.Fatalf("chapter %v not collected into oneshot volume: %v", chap.Info.Identifier, chap.Info.VolumeIdentifier)
+		}
+	}
+
+	skeleton := md.Manga{
+		Info:    md.MangaInfo{Title: "Oneshot Test"},
+		Volumes: map[md.Identifier]md.Volume{},
+	}
+	manga := skeleton.WithChapters(chapters).WithPages(md.ImageList{
+		{Image: createTestImage(800, 1200, color.White), ChapterIdentifier: md.NewIdentifier("0"), VolumeIdentifier: md.OneshotIdentifier()},
+		{Image: createTestImage(800, 1200, color.White), ChapterIdentifier: md.NewIdentifier("1"), VolumeIdentifier: md.OneshotIdentifier()},
+	})
+	if len(manga.Volumes) != 1 {
+		t.Fatalf("expected a single oneshot volume, got %d volumes", len(manga.Volumes))
+	}
+
+	e, cleanup, err := GenerateEPUB(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true)
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		t.Fatalf("GenerateEPUB() failed: %v", err)
+	}
+
+	zipReader, err := writeEPUB(t, e)
+	if err != nil {
+		t.Fatalf("failed to write EPUB: %v", err)
+	}
+	nav := readEPUBFile(t, zipReader, "EPUB/nav.xhtml")
+	if !strings.Contains(nav, ">Oneshot</a>") {
+		t.Errorf("nav.xhtml missing oneshot volume entry:\n%s", nav)
+	}
+	for _, title := range []string{"Prologue", "Extra"} {
+		if !strings.Contains(nav, title) {
+			t.Errorf("nav.xhtml missing chapter %q", title)
+		}
+	}
+}
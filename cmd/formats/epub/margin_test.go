@@ -0,0 +1,66 @@
+package epub
+
+import (
+	"image"
+	"image/color"
+	"image/jpeg"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+)
+
+func TestPageMargin(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		margin   Margin
+		expected image.Point
+		border   uint32
+	}{
+		{"none", Margin{}, image.Pt(200, 300), 0xffff},
+		{"pixels", Margin{Pixels: 10, Color: color.Black}, image.Pt(220, 320), 0},
+		{"percent", Margin{Percent: 10}, image.Pt(240, 360), 0xffff},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			manga := testhelpers.CreateSyntheticManga(1, 1, 200, 300)
+			e, cleanup, err := GenerateEPUBWithOptions(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true, Options{Margin: tc.margin})
+			if cleanup != nil {
+				defer cleanup()
+			}
+			if err != nil {
+				t.Fatalf("GenerateEPUBWithOptions() failed: %v", err)
+			}
+			zipReader, err := writeEPUB(t, e)
+			if err != nil {
+				t.Fatalf("failed to write EPUB: %v", err)
+			}
+
+			page := readEPUBFile(t, zipReader, "EPUB/images/page-1-1-0.jpg")
+			img, err := jpeg.Decode(strings.NewReader(page))
+			if err != nil {
+				t.Fatalf("failed to decode page: %v", err)
+			}
+			if size := img.Bounds().Size(); size != tc.expected {
+				t.Errorf("expected page size %v, got %v", tc.expected, size)
+			}
+
+			// Allow for JPEG compression artifacts
+			near := func(c color.Color, expected uint32) bool {
+				r, g, b, _ := c.RGBA()
+				for _, v := range []uint32{r, g, b} {
+					if int64(v)-int64(expected) > 0x800 || int64(expected)-int64(v) > 0x800 {
+						return false
+					}
+				}
+				return true
+			}
+			if corner := img.At(1, 1); !near(corner, tc.border) {
+				t.Errorf("expected border color %x, got %v", tc.border, corner)
+			}
+			if center := img.At(tc.expected.X/2, tc.expected.Y/2); !near(center, 0xffff) {
+				t.Errorf("expected white page, got %v", center)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"path"
+
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/util"
+	"github.com/leotaku/kojirou/mangadex"
+)
+
+// placeholderJPEG is the smallest image go-epub accepts as a JPEG, which
+// stands in for pages that are kept in memory while go-epub writes the book
+var placeholderJPEG = func() string {
+	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, image.NewGray(image.Rect(0, 0, 1, 1)), nil); err != nil {
+		panic(err)
+	}
+
+	return dataURL("image/jpeg", buf.Bytes())
+}()
+
+func dataURL(mediaType string, data []byte) string {
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// memoryStore keeps encoded images in memory, keyed by their filename in
+// the book. go-epub only sees a placeholder for every image, so that it
+// never writes page data to its temporary directory.
+type memoryStore map[string][]byte
+
+func (m memoryStore) store(name string, data []byte) (string, error) {
+	// Stylesheets are small enough to be passed to go-epub as they are
+	if path.Ext(name) == ".css" {
+		return dataURL("text/css", data), nil
+	}
+	m[name] = bytes.Clone(data)
+
+	return placeholderJPEG, nil
+}
+
+// GenerateEPUBInMemory creates an EPUB like GenerateEPUBWithOptions and
+// returns the written book. Images are never written to temporary files,
+// but kept in memory and written straight into the archive, which trades
+// disk writes for memory that grows with the size of the book.
+func GenerateEPUBInMemory(manga mangadex.Manga, widepage kindle.WidepagePolicy, crop bool, ltr bool, opts Options) ([]byte, error) {
+	images := make(memoryStore)
+	e, err := generateEPUB(manga, widepage, crop, ltr, opts, images.store)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	if _, err := e.WriteTo(buf); err != nil {
+		return nil, fmt.Errorf("write: %w", err)
+	}
+
+	return replaceImages(buf.Bytes(), images)
+}
+
+// replaceImages rewrites an EPUB archive with the content of every image
+// that has a filename in the given images
+func replaceImages(data []byte, images map[string][]byte) ([]byte, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for _, f := range r.File {
+		content, ok := images[path.Base(f.Name)]
+		if !ok || path.Base(path.Dir(f.Name)) != "images" {
+			if err := w.Copy(f); err != nil {
+				return nil, fmt.Errorf("copy %v: %w", f.Name, err)
+			}
+			continue
+		}
+
+		fw, err := w.CreateHeader(&zip.FileHeader{
+			Name:     f.Name,
+			Method:   util.ZipMethod(f.Name),
+			Modified: f.Modified,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("create %v: %w", f.Name, err)
+		}
+		if _, err := fw.Write(content); err != nil {
+			return nil, fmt.Errorf("write %v: %w", f.Name, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("close: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
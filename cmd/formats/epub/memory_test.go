@@ -0,0 +1,124 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"image/color"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	md "github.com/leotaku/kojirou/mangadex"
+)
+
+// modifiedMeta matches the modification date go-epub writes at write time
+var modifiedMeta = regexp.MustCompile(`<meta property="dcterms:modified">[^<]*</meta>`)
+
+// archiveContents returns the content of every file of an EPUB archive.
+// The package is normalized, as go-epub writes its manifest in random
+// order and dates it at write time.
+func archiveContents(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("failed to open EPUB: %v", err)
+	}
+	contents := make(map[string]string)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %v: %v", f.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("failed to read %v: %v", f.Name, err)
+		}
+		if path.Ext(f.Name) == ".opf" {
+			lines := strings.Split(modifiedMeta.ReplaceAllString(string(content), ""), "\n")
+			slices.Sort(lines)
+			content = []byte(strings.Join(lines, "\n"))
+		}
+		contents[f.Name] = string(content)
+	}
+
+	return contents
+}
+
+// memoryTestManga returns a small manga with covers and wide pages
+func memoryTestManga() md.Manga {
+	manga := createLargeTestManga(2, 6)
+	for id, volume := range manga.Volumes {
+		volume.Cover = createTestImage(300, 450, color.Black)
+		manga.Volumes[id] = volume
+	}
+
+	return manga
+}
+
+func TestGenerateEPUBInMemory(t *testing.T) {
+	manga := memoryTestManga()
+	opts := Options{Identifier: "memory-test"}
+
+	e, cleanup, err := GenerateEPUBWithOptions(t.TempDir(), manga, kindle.WidepagePolicySplit, false, true, opts)
+	if err != nil {
+		t.Fatalf("GenerateEPUBWithOptions() failed: %v", err)
+	}
+	defer cleanup()
+	buf := new(bytes.Buffer)
+	if _, err := e.WriteTo(buf); err != nil {
+		t.Fatalf("failed to write EPUB: %v", err)
+	}
+	want := archiveContents(t, buf.Bytes())
+
+	data, err := GenerateEPUBInMemory(manga, kindle.WidepagePolicySplit, false, true, opts)
+	if err != nil {
+		t.Fatalf("GenerateEPUBInMemory() failed: %v", err)
+	}
+	got := archiveContents(t, data)
+
+	if len(got) != len(want) {
+		t.Errorf("expected %v files, got %v", len(want), len(got))
+	}
+	images := 0
+	for name, content := range want {
+		if path.Base(path.Dir(name)) == "images" {
+			images++
+		}
+		if got[name] != content {
+			t.Errorf("%v differs from the EPUB written through temporary files", name)
+		}
+	}
+	if images == 0 {
+		t.Errorf("expected EPUB to contain images")
+	}
+}
+
+func BenchmarkEPUBWritePaths(b *testing.B) {
+	manga := createLargeTestManga(4, 10)
+
+	b.Run("temp files", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			e, cleanup, err := GenerateEPUB(b.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true)
+			if err != nil {
+				b.Fatalf("GenerateEPUB() failed: %v", err)
+			}
+			if _, err := e.WriteTo(io.Discard); err != nil {
+				b.Fatalf("WriteTo() failed: %v", err)
+			}
+			cleanup()
+		}
+	})
+	b.Run("in memory", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := GenerateEPUBInMemory(manga, kindle.WidepagePolicyPreserve, false, true, Options{}); err != nil {
+				b.Fatalf("GenerateEPUBInMemory() failed: %v", err)
+			}
+		}
+	})
+}
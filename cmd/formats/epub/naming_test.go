@@ -0,0 +1,97 @@
+package epub
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+	md "github.com/leotaku/kojirou/mangadex"
+)
+
+// collidingManga returns a manga whose page names would be the same if they
+// were only built from identifiers: a split third page of chapter 2 in
+// volume 1 and the first page of chapter 3 in volume "1-2"
+func collidingManga() md.Manga {
+	manga := md.Manga{
+		Info:    md.MangaInfo{Title: "Colliding Manga", ID: "colliding-manga-id"},
+		Volumes: map[md.Identifier]md.Volume{},
+	}
+	for _, tc := range []struct {
+		volume  md.Identifier
+		chapter md.Identifier
+		page    int
+		width   int
+	}{
+		{md.NewIdentifier("1"), md.NewIdentifier("2"), 3, 600},
+		{md.NewWithFallback("", "1-2"), md.NewIdentifier("3"), 1, 200},
+	} {
+		manga.Volumes[tc.volume] = md.Volume{
+			Info: md.VolumeInfo{Identifier: tc.volume},
+			Chapters: map[md.Identifier]md.Chapter{
+				tc.chapter: {
+					Info: md.ChapterInfo{Identifier: tc.chapter, VolumeIdentifier: tc.volume},
+					Pages: map[int]image.Image{
+						tc.page: testhelpers.CreateTestImage(tc.width, 300, color.Black),
+					},
+				},
+			},
+		}
+	}
+
+	return manga
+}
+
+func TestUniqueImageNames(t *testing.T) {
+	e, cleanup, err := GenerateEPUB(t.TempDir(), collidingManga(), kindle.WidepagePolicySplit, false, true)
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		t.Fatalf("GenerateEPUB() failed: %v", err)
+	}
+
+	zr, err := writeEPUB(t, e)
+	if err != nil {
+		t.Fatalf("failed to write EPUB: %v", err)
+	}
+	images := make(map[string]bool)
+	for _, f := range zr.File {
+		if strings.HasPrefix(f.Name, "EPUB/images/page-") {
+			images[f.Name] = true
+		}
+	}
+	if len(images) != 3 {
+		t.Errorf("expected 3 distinct page images, got %v", images)
+	}
+}
+
+func TestUniqueTempPaths(t *testing.T) {
+	// Books of a run share the temporary directory when generated in parallel
+	tempDir := t.TempDir()
+	paths := make([][]string, 4)
+	wg := sync.WaitGroup{}
+	for i := range paths {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				paths[i] = append(paths[i], tempFilePath(tempDir, "page-1-1-1-0.jpg"))
+			}
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	for _, list := range paths {
+		for _, path := range list {
+			if seen[path] {
+				t.Fatalf("temporary path %v is used twice", path)
+			}
+			seen[path] = true
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/bmaupin/go-epub"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/output"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+	"github.com/leotaku/kojirou/mangadex"
+)
+
+// writeBook writes an EPUB as it is output, with its nested table of
+// contents in place
+func writeBook(t *testing.T, e *epub.Epub) *zip.Reader {
+	t.Helper()
+
+	data, err := output.EpubOutput{Epub: e}.GetBytes()
+	if err != nil {
+		t.Fatalf("GetBytes() failed: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("failed to open EPUB: %v", err)
+	}
+
+	return zr
+}
+
+func TestNavGroupSize(t *testing.T) {
+	manga := testhelpers.CreateSyntheticManga(120, 1, 20, 30)
+	e, cleanup, err := GenerateEPUBWithOptions(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true, Options{NavGroupSize: 50})
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		t.Fatalf("GenerateEPUBWithOptions() failed: %v", err)
+	}
+	zipReader := writeBook(t, e)
+	nav := readEPUBFile(t, zipReader, "EPUB/nav.xhtml")
+
+	if count := strings.Count(nav, "<span>Chapters "); count != 3 {
+		t.Errorf("expected 3 chapter groups, got %d:\n%s", count, nav)
+	}
+	ncx := readEPUBFile(t, zipReader, "EPUB/toc.ncx")
+	if count := strings.Count(ncx, "<text>Chapters "); count != 3 {
+		t.Errorf("expected 3 chapter groups in NCX, got %d:\n%s", count, ncx)
+	}
+	for _, tc := range []struct{ chapter, group, next string }{
+		{"xhtml/chapter-1-1.xhtml", "Chapters 1–50", "Chapters 51–100"},
+		{"xhtml/chapter-1-50.xhtml", "Chapters 1–50", "Chapters 51–100"},
+		{"xhtml/chapter-1-51.xhtml", "Chapters 51–100", "Chapters 101–120"},
+		{"xhtml/chapter-1-120.xhtml", "Chapters 101–120", "</nav>"},
+	} {
+		idx := strings.Index(nav, `"`+tc.chapter+`"`)
+		if idx < 0 || idx < strings.Index(nav, tc.group) || idx > strings.Index(nav, tc.next) {
+			t.Errorf("%v is not placed in group %q", tc.chapter, tc.group)
+		}
+	}
+}
+
+var hrefRe = regexp.MustCompile(`(?:href|src)="([^"#]+)`)
+
+func TestNavLinksResolve(t *testing.T) {
+	manga := testhelpers.CreateSyntheticManga(3, 2, 20, 30)
+	e, cleanup, err := GenerateEPUBWithOptions(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true, Options{NavGroupSize: 2})
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		t.Fatalf("GenerateEPUBWithOptions() failed: %v", err)
+	}
+	zipReader := writeBook(t, e)
+	files := make(map[string]bool)
+	for _, f := range zipReader.File {
+		files[f.Name] = true
+	}
+
+	for _, navPath := range []string{"EPUB/nav.xhtml", "EPUB/toc.ncx"} {
+		nav := readEPUBFile(t, zipReader, navPath)
+		links := hrefRe.FindAllStringSubmatch(nav, -1)
+		if len(links) == 0 {
+			t.Errorf("%v has no links", navPath)
+		}
+		for _, link := range links {
+			target := path.Join(path.Dir(navPath), link[1])
+			if target == navPath {
+				t.Errorf("%v links to itself", navPath)
+			} else if !files[target] {
+				t.Errorf("%v links to missing file %v", navPath, target)
+			}
+		}
+	}
+}
+
+func TestNoVolumeSections(t *testing.T) {
+	manga := testhelpers.CreateSyntheticManga(4, 1, 20, 30)
+	chapters := manga.Chapters()
+	for i, chapter := range chapters {
+		if chapter.Info.Identifier.String() > "2" {
+			chapters[i].Info.VolumeIdentifier = mangadex.NewIdentifier("2")
+		}
+	}
+	manga = manga.WithChapters(chapters)
+	e, cleanup, err := GenerateEPUBWithOptions(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true, Options{NoVolumeSections: true})
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		t.Fatalf("GenerateEPUBWithOptions() failed: %v", err)
+	}
+	zipReader := writeBook(t, e)
+
+	for _, f := range zipReader.File {
+		if strings.HasPrefix(path.Base(f.Name), "volume-") {
+			t.Errorf("unexpected volume section %v", f.Name)
+		}
+	}
+	opf := readEPUBFile(t, zipReader, "EPUB/package.opf")
+	if count := strings.Count(opf, `<itemref idref="chapter-`); count != 4 {
+		t.Errorf("expected 4 chapters in spine, got %d:\n%s", count, opf)
+	}
+
+	nav := readEPUBFile(t, zipReader, "EPUB/nav.xhtml")
+	for _, want := range []string{
+		"<li><span>Volume 1</span><ol>", "<li><span>Volume 2</span><ol>",
+		`"xhtml/chapter-1-1.xhtml"`, `"xhtml/chapter-1-2.xhtml"`, `"xhtml/chapter-2-3.xhtml"`, `"xhtml/chapter-2-4.xhtml"`,
+	} {
+		if !strings.Contains(nav, want) {
+			t.Errorf("nav.xhtml missing %v:\n%s", want, nav)
+		}
+	}
+}
+
+func TestVolumeNumberWidth(t *testing.T) {
+	manga := testhelpers.CreateSyntheticManga(2, 1, 20, 30)
+	chapters := manga.Chapters()
+	for i, chapter := range chapters {
+		if chapter.Info.Identifier.String() == "2" {
+			chapters[i].Info.VolumeIdentifier = mangadex.NewIdentifier("1.5")
+		}
+	}
+	manga = manga.WithChapters(chapters)
+
+	for _, tc := range []struct {
+		width int
+		want  map[string]string
+	}{
+		{0, map[string]string{"1": "Volume 1", "1.5": "Volume 1.5"}},
+		{2, map[string]string{"1": "Volume 01", "1.5": "Volume 01.5"}},
+		{3, map[string]string{"1": "Volume 001", "1.5": "Volume 001.5"}},
+	} {
+		e, cleanup, err := GenerateEPUBWithOptions(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true, Options{VolumeNumberWidth: tc.width})
+		if err != nil {
+			t.Fatalf("GenerateEPUBWithOptions() failed: %v", err)
+		}
+		zipReader := writeBook(t, e)
+		cleanup()
+
+		nav := readEPUBFile(t, zipReader, "EPUB/nav.xhtml")
+		for volume, want := range tc.want {
+			if !strings.Contains(nav, ">"+want+"</a><ol>") {
+				t.Errorf("width %v: nav.xhtml missing %q:\n%s", tc.width, want, nav)
+			}
+			section := readEPUBFile(t, zipReader, "EPUB/xhtml/volume-"+volume+".xhtml")
+			if !strings.Contains(section, "<h1>"+want+"</h1>") {
+				t.Errorf("width %v: volume section missing %q:\n%s", tc.width, want, section)
+			}
+		}
+	}
+}
+
+func TestChapterTitle(t *testing.T) {
+	manga := testhelpers.CreateSyntheticManga(2, 1, 20, 30)
+	chapters := manga.Chapters()
+	for i, chapter := range chapters {
+		if chapter.Info.Identifier.String() == "2" {
+			chapters[i].Info.Title = ""
+		}
+	}
+	manga = manga.WithChapters(chapters)
+
+	for _, tc := range []struct {
+		template string
+		want     []string
+	}{
+		{"", []string{"Chapter 1", "Chapter 2"}},
+		{"{{.Volume}} #{{.Number}}{{with .Title}}: {{.}}{{end}}", []string{"Volume 1 #1: Chapter 1", "Volume 1 #2"}},
+	} {
+		e, cleanup, err := GenerateEPUBWithOptions(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true, Options{ChapterTitle: tc.template})
+		if err != nil {
+			t.Fatalf("GenerateEPUBWithOptions() failed: %v", err)
+		}
+		zipReader := writeBook(t, e)
+		cleanup()
+
+		nav := readEPUBFile(t, zipReader, "EPUB/nav.xhtml")
+		for i, want := range tc.want {
+			if !strings.Contains(nav, ">"+want+"</a>") {
+				t.Errorf("template %q: nav.xhtml missing %q:\n%s", tc.template, want, nav)
+			}
+			section := readEPUBFile(t, zipReader, fmt.Sprintf("EPUB/xhtml/chapter-1-%v.xhtml", i+1))
+			if !strings.Contains(section, "<h1>"+want+"</h1>") {
+				t.Errorf("template %q: chapter section missing %q:\n%s", tc.template, want, section)
+			}
+		}
+	}
+
+	if _, _, err := GenerateEPUBWithOptions(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true, Options{ChapterTitle: "{{.Missing}}"}); err == nil {
+		t.Error("expected error for template with unknown field")
+	}
+}
+
+func TestChapterTitleEscaped(t *testing.T) {
+	manga := testhelpers.CreateSyntheticManga(1, 1, 20, 30)
+	chapters := manga.Chapters()
+	chapters[0].Info.Title = "Cats & <Dogs>"
+	manga = manga.WithChapters(chapters)
+
+	e, cleanup, err := GenerateEPUBWithOptions(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true, Options{ChapterTitle: "{{.Title}} & more"})
+	if err != nil {
+		t.Fatalf("GenerateEPUBWithOptions() failed: %v", err)
+	}
+	zipReader := writeBook(t, e)
+	cleanup()
+
+	const want = "Cats &amp; &lt;Dogs&gt; &amp; more"
+	for name, tag := range map[string]string{
+		"EPUB/nav.xhtml":               "</a>",
+		"EPUB/xhtml/chapter-1-1.xhtml": "</h1>",
+	} {
+		content := readEPUBFile(t, zipReader, name)
+		if !strings.Contains(content, want+tag) {
+			t.Errorf("%v is missing escaped title %q:\n%s", name, want, content)
+		}
+		decoder := xml.NewDecoder(strings.NewReader(content))
+		for {
+			if _, err := decoder.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Errorf("%v is not well-formed: %v", name, err)
+				break
+			}
+		}
+	}
+}
+
+func TestNavigationNotListed(t *testing.T) {
+	manga := testhelpers.CreateSyntheticManga(2, 1, 20, 30)
+	e, cleanup, err := GenerateEPUB(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true)
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		t.Fatalf("GenerateEPUB() failed: %v", err)
+	}
+
+	// Neither the book as output nor as written by go-epub alone lists
+	// the page that holds the nested table of contents
+	written, err := writeEPUB(t, e)
+	if err != nil {
+		t.Fatalf("failed to write EPUB: %v", err)
+	}
+	for _, zipReader := range []*zip.Reader{writeBook(t, e), written} {
+		for _, name := range []string{"EPUB/nav.xhtml", "EPUB/toc.ncx"} {
+			content := readEPUBFile(t, zipReader, name)
+			if strings.Contains(content, "xhtml/nav.xhtml") || strings.Contains(content, "Navigation") {
+				t.Errorf("%v lists the navigation page:\n%s", name, content)
+			}
+		}
+	}
+
+	opf := readEPUBFile(t, writeBook(t, e), "EPUB/package.opf")
+	if strings.Contains(opf, `<itemref idref="nav.xhtml"`) {
+		t.Errorf("navigation page is part of the spine:\n%s", opf)
+	}
+}
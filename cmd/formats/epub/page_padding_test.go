@@ -0,0 +1,49 @@
+package epub
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+)
+
+var pageImageRe = regexp.MustCompile(`images/(page-1-1-[0-9]+\.jpg)`)
+
+func TestPagePadding(t *testing.T) {
+	manga := testhelpers.CreateSyntheticManga(1, 150, 20, 30)
+	for _, tt := range []struct {
+		padding int
+		first   string
+	}{
+		{0, "page-1-1-000.jpg"},
+		{5, "page-1-1-00000.jpg"},
+	} {
+		e, cleanup, err := GenerateEPUBWithOptions(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true, Options{PagePadding: tt.padding})
+		if cleanup != nil {
+			defer cleanup()
+		}
+		if err != nil {
+			t.Fatalf("GenerateEPUBWithOptions() failed: %v", err)
+		}
+		zr, err := writeEPUB(t, e)
+		if err != nil {
+			t.Fatalf("failed to write EPUB: %v", err)
+		}
+
+		pages := make([]string, 0)
+		for _, match := range pageImageRe.FindAllStringSubmatch(readEPUBFile(t, zr, "EPUB/xhtml/chapter-1-1.xhtml"), -1) {
+			pages = append(pages, match[1])
+		}
+		if len(pages) != 150 {
+			t.Fatalf("padding %v: expected 150 pages, got %v", tt.padding, len(pages))
+		}
+		if pages[0] != tt.first {
+			t.Errorf("padding %v: got first page %v, want %v", tt.padding, pages[0], tt.first)
+		}
+		if !slices.IsSorted(pages) {
+			t.Errorf("padding %v: page filenames do not sort in reading order: %v", tt.padding, pages)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package epub
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+)
+
+func TestSVGPages(t *testing.T) {
+	_, section := generateWithOptions(t, Options{SVGPages: true})
+	if !strings.Contains(section, `viewBox="0 0 200 300"`) {
+		t.Errorf("page does not have a viewBox of the image size:\n%s", section)
+	}
+	if !regexp.MustCompile(`<svg[^>]*>.*<image width="200" height="300" xlink:href="\.\./images/[^"]+\.jpg"/></svg>`).MatchString(section) {
+		t.Errorf("page does not show its image in an SVG:\n%s", section)
+	}
+	if strings.Contains(section, "<img") {
+		t.Errorf("page contains an img element:\n%s", section)
+	}
+
+	t.Run("section per page", func(t *testing.T) {
+		manga := testhelpers.CreateSyntheticManga(1, 3, 200, 300)
+		e, cleanup, err := GenerateEPUBWithOptions(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true, Options{SVGPages: true})
+		if cleanup != nil {
+			defer cleanup()
+		}
+		if err != nil {
+			t.Fatalf("GenerateEPUBWithOptions() failed: %v", err)
+		}
+		zipReader, err := writeEPUB(t, e)
+		if err != nil {
+			t.Fatalf("failed to write EPUB: %v", err)
+		}
+
+		for page, name := range []string{"chapter-1-1.xhtml", "chapter-1-1-2.xhtml", "chapter-1-1-3.xhtml"} {
+			section := readEPUBFile(t, zipReader, "EPUB/xhtml/"+name)
+			if strings.Count(section, "<svg") != 1 || !strings.Contains(section, fmt.Sprintf(`aria-label="Page %d"`, page+1)) {
+				t.Errorf("section %v does not contain page %d alone:\n%s", name, page+1, section)
+			}
+		}
+		nav := readEPUBFile(t, zipReader, "EPUB/nav.xhtml")
+		if strings.Contains(nav, "chapter-1-1-2.xhtml") {
+			t.Errorf("navigation lists pages after the first:\n%s", nav)
+		}
+	})
+}
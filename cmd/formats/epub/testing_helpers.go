@@ -3,6 +3,7 @@ package epub
 import (
 	"archive/zip"
 	"bytes"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
@@ -34,3 +35,28 @@ func writeEPUB(t *testing.T, e *epub.Epub) (*zip.Reader, error) {
 	// Return a zip reader for inspection
 	return zip.NewReader(bytes.NewReader(data), int64(len(data)))
 }
+
+// readEPUBFile returns the contents of the named file inside an EPUB archive
+func readEPUBFile(t *testing.T, zr *zip.Reader, name string) string {
+	t.Helper()
+
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", name, err)
+		}
+		defer rc.Close()
+
+		content, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		return string(content)
+	}
+
+	t.Fatalf("file not found in EPUB: %s", name)
+	return ""
+}
@@ -0,0 +1,61 @@
+package epub
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+)
+
+var navLinkRe = regexp.MustCompile(`href="(xhtml/[^"]+)"`)
+
+func TestChapterTitlePages(t *testing.T) {
+	generate := func(opts Options) (nav string, opf string, title string) {
+		manga := testhelpers.CreateSyntheticManga(2, 1, 200, 300)
+		e, cleanup, err := GenerateEPUBWithOptions(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true, opts)
+		if cleanup != nil {
+			defer cleanup()
+		}
+		if err != nil {
+			t.Fatalf("GenerateEPUBWithOptions() failed: %v", err)
+		}
+		zipReader := writeBook(t, e)
+		if opts.ChapterTitlePages {
+			title = readEPUBFile(t, zipReader, "EPUB/xhtml/title-1-2.xhtml")
+		}
+
+		return readEPUBFile(t, zipReader, "EPUB/nav.xhtml"), readEPUBFile(t, zipReader, "EPUB/package.opf"), title
+	}
+
+	plainNav, _, _ := generate(Options{})
+	nav, opf, title := generate(Options{ChapterTitlePages: true})
+
+	if !strings.Contains(title, "<h1>Chapter 2</h1>") || !strings.Contains(title, `class="chapter-title"`) {
+		t.Errorf("title page does not show chapter title:\n%s", title)
+	}
+	if strings.Count(title, "Chapter 2</h") != 1 {
+		t.Errorf("title page repeats its heading:\n%s", title)
+	}
+
+	// Title pages precede their chapter in the reading order
+	titleIdx := strings.Index(opf, `<itemref idref="title-1-2.xhtml"`)
+	chapterIdx := strings.Index(opf, `<itemref idref="chapter-1-2.xhtml"`)
+	if titleIdx < 0 || chapterIdx < 0 || titleIdx > chapterIdx {
+		t.Errorf("title page is not placed before its chapter in the spine:\n%s", opf)
+	}
+
+	// Chapters are linked at their title page instead of their first page
+	plainLinks := navLinkRe.FindAllStringSubmatch(plainNav, -1)
+	links := navLinkRe.FindAllStringSubmatch(nav, -1)
+	if len(links) != len(plainLinks) {
+		t.Fatalf("nav has %d links, want %d", len(links), len(plainLinks))
+	}
+	for i, link := range links {
+		want := strings.Replace(plainLinks[i][1], "xhtml/chapter-", "xhtml/title-", 1)
+		if link[1] != want {
+			t.Errorf("nav links to %v, want %v", link[1], want)
+		}
+	}
+}
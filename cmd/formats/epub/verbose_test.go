@@ -0,0 +1,48 @@
+package epub
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+)
+
+func TestVerboseOutput(t *testing.T) {
+	generate := func(opts Options) string {
+		oldStderr := os.Stderr
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatalf("failed to create pipe: %v", err)
+		}
+		os.Stderr = w
+		defer func() {
+			os.Stderr = oldStderr
+		}()
+
+		manga := testhelpers.CreateSyntheticManga(2, 1, 20, 30)
+		_, cleanup, err := GenerateEPUBWithOptions(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true, opts)
+		if cleanup != nil {
+			defer cleanup()
+		}
+		w.Close()
+		if err != nil {
+			t.Fatalf("GenerateEPUBWithOptions() failed: %v", err)
+		}
+		out, _ := io.ReadAll(r)
+
+		return string(out)
+	}
+
+	if out := generate(Options{}); out != "" {
+		t.Errorf("unexpected output at default verbosity:\n%s", out)
+	}
+	out := generate(Options{Verbose: true})
+	for _, expected := range []string{"[DEBUG] added section chapter-1-1.xhtml", "[DEBUG] added navigation section"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("verbose output does not contain %q:\n%s", expected, out)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package formats
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	md "github.com/leotaku/kojirou/mangadex"
+)
+
+// PageFilenamesExtension is the extension of the sidecar that maps the
+// pages of a book to their original filenames.
+const PageFilenamesExtension = "pages.json"
+
+// PageFilename maps a page of a book to the file it was loaded from.
+type PageFilename struct {
+	Volume  string `json:"volume"`
+	Chapter string `json:"chapter"`
+	// Page is the position of the page in its chapter, starting at one
+	Page int    `json:"page"`
+	File string `json:"file"`
+}
+
+// PageFilenames lists the original filenames of all pages of the manga
+// in reading order. Pages without a filename are left out.
+func PageFilenames(manga md.Manga) []PageFilename {
+	result := make([]PageFilename, 0)
+	for _, volume := range manga.Sorted() {
+		for _, chapter := range volume.Sorted() {
+			for i, key := range chapter.Keys() {
+				if file := chapter.Filenames[key]; file != "" {
+					result = append(result, PageFilename{
+						Volume:  volume.Info.Identifier.String(),
+						Chapter: chapter.Info.Identifier.String(),
+						Page:    i + 1,
+						File:    file,
+					})
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// WritePageFilenames writes the original filenames of all pages of the
+// manga as a JSON sidecar to the given path.
+func WritePageFilenames(filename string, manga md.Manga) error {
+	data, err := json.MarshalIndent(PageFilenames(manga), "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("directory: %w", err)
+	}
+	if err := os.WriteFile(filename, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/bmaupin/go-epub"
+	"github.com/leotaku/kojirou/cmd/formats/util"
 	"github.com/leotaku/mobi"
 )
 
@@ -21,6 +22,8 @@ const (
 	FormatEpub FormatType = "epub"
 	// FormatKepub represents the Kobo-specific EPUB format
 	FormatKepub FormatType = "kepub"
+	// FormatPdf represents a PDF document with one page image per page
+	FormatPdf FormatType = "pdf"
 )
 
 // String returns the string representation of the format type
@@ -28,6 +31,18 @@ func (f FormatType) String() string {
 	return string(f)
 }
 
+// Extension returns the file extension of written files of this format (without dot)
+func (f FormatType) Extension() string {
+	switch f {
+	case FormatMobi:
+		return "azw3"
+	case FormatKepub:
+		return "kepub.epub"
+	default:
+		return string(f)
+	}
+}
+
 // FormatOutput represents the output of a format generator
 type FormatOutput interface {
 	// Extension returns the file extension for this format (without dot)
@@ -63,7 +78,7 @@ func (e EpubOutput) Extension() string {
 }
 
 func (e EpubOutput) Bytes() ([]byte, error) {
-	tempFile, err := os.CreateTemp("", "epub-*.epub")
+	tempFile, err := os.CreateTemp(util.TempDir(), "epub-*.epub")
 	if err != nil {
 		return nil, fmt.Errorf("create temp file: %w", err)
 	}
@@ -90,7 +105,7 @@ func (k KepubOutput) Extension() string {
 
 func (k KepubOutput) Bytes() ([]byte, error) {
 	// TODO: Convert EPUB to Kobo EPUB format
-	tempFile, err := os.CreateTemp("", "kepub-*.epub")
+	tempFile, err := os.CreateTemp(util.TempDir(), "kepub-*.epub")
 	if err != nil {
 		return nil, fmt.Errorf("create temp file: %w", err)
 	}
@@ -114,7 +129,7 @@ func ParseFormats(formatStr string) ([]FormatType, error) {
 	for _, part := range parts {
 		format := FormatType(strings.TrimSpace(strings.ToLower(part)))
 		switch format {
-		case FormatMobi, FormatEpub, FormatKepub:
+		case FormatMobi, FormatEpub, FormatKepub, FormatPdf:
 			formats = append(formats, format)
 		default:
 			return nil, fmt.Errorf("unsupported format: %s", part)
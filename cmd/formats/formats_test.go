@@ -36,6 +36,12 @@ func TestParseFormats(t *testing.T) {
 			want:    []FormatType{FormatMobi, FormatEpub, FormatKepub},
 			wantErr: false,
 		},
+		{
+			name:    "pdf",
+			input:   "pdf,mobi",
+			want:    []FormatType{FormatPdf, FormatMobi},
+			wantErr: false,
+		},
 		{
 			name:    "invalid format",
 			input:   "mobi,invalid",
@@ -0,0 +1,130 @@
+// Package icc handles ICC color profiles embedded in source images
+package icc
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/leotaku/kojirou/cmd/formats/logging"
+)
+
+var (
+	convert bool
+	// reported remembers dropped profiles, so each is only logged once
+	reported sync.Map
+)
+
+// SetConvert enables converting images with embedded non-sRGB profiles
+// to sRGB. Otherwise, such profiles are dropped and the image colors are
+// assumed to be sRGB.
+func SetConvert(enabled bool) {
+	convert = enabled
+}
+
+// Normalize returns the image decoded from the given data with its colors
+// in sRGB, converting them from the embedded profile if enabled.
+func Normalize(img image.Image, data []byte) image.Image {
+	embedded := Extract(data)
+	if embedded == nil {
+		return img
+	}
+
+	profile, err := Parse(embedded)
+	if err != nil {
+		report("unsupported", err.Error())
+		return img
+	} else if profile.IsSRGB() {
+		return img
+	} else if !convert {
+		report("dropped", profile.Description)
+		return img
+	}
+
+	return profile.Convert(img)
+}
+
+func report(reason, detail string) {
+	if _, loaded := reported.LoadOrStore(reason+detail, true); !loaded {
+		logging.Infof("Color profile %v, colors may be shifted: %v", reason, detail)
+	}
+}
+
+// Extract returns the ICC profile embedded in the given JPEG or PNG data,
+// or nil if there is none.
+func Extract(data []byte) []byte {
+	switch {
+	case bytes.HasPrefix(data, []byte("\xFF\xD8")):
+		return extractJPEG(data)
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return extractPNG(data)
+	default:
+		return nil
+	}
+}
+
+func extractJPEG(data []byte) []byte {
+	// Profiles may be split into numbered chunks across segments
+	chunks := make(map[int][]byte)
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF; {
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if marker == 0xDA || i+2+length > len(data) {
+			break
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE2 && len(segment) > 14 && bytes.HasPrefix(segment, []byte("ICC_PROFILE\x00")) {
+			chunks[int(segment[12])] = segment[14:]
+		}
+		i += 2 + length
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	keys := make([]int, 0, len(chunks))
+	for key := range chunks {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	result := make([]byte, 0)
+	for _, key := range keys {
+		result = append(result, chunks[key]...)
+	}
+
+	return result
+}
+
+func extractPNG(data []byte) []byte {
+	for i := 8; i+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		kind := string(data[i+4 : i+8])
+		if kind == "IDAT" || i+12+length > len(data) {
+			break
+		}
+		if kind == "iCCP" {
+			// Profile name, null separator and compression method
+			chunk := data[i+8 : i+8+length]
+			name := bytes.IndexByte(chunk, 0)
+			if name < 0 || name+2 > len(chunk) {
+				return nil
+			}
+			r, err := zlib.NewReader(bytes.NewReader(chunk[name+2:]))
+			if err != nil {
+				return nil
+			}
+			profile, err := io.ReadAll(r)
+			if err != nil {
+				return nil
+			}
+			return profile
+		}
+		i += 12 + length
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package icc
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+var adobeRGB = [3][3]float64{
+	{0.6097559, 0.2052401, 0.1492240},
+	{0.3111145, 0.6256714, 0.0632141},
+	{0.0194702, 0.0608902, 0.7445396},
+}
+
+// buildProfile returns a minimal RGB profile with the given colorants
+// and a simple gamma curve for all channels
+func buildProfile(description string, colorants [3][3]float64, gamma float64) []byte {
+	fixed := func(v float64) []byte {
+		return binary.BigEndian.AppendUint32(nil, uint32(int32(v*65536)))
+	}
+	type tag struct {
+		signature string
+		content   []byte
+	}
+	tags := []tag{}
+	desc := append([]byte("desc\x00\x00\x00\x00"), binary.BigEndian.AppendUint32(nil, uint32(len(description)))...)
+	tags = append(tags, tag{"desc", append(desc, description...)})
+	for i, channel := range []string{"r", "g", "b"} {
+		xyz := []byte("XYZ \x00\x00\x00\x00")
+		for j := range colorants {
+			xyz = append(xyz, fixed(colorants[j][i])...)
+		}
+		trc := append([]byte("curv\x00\x00\x00\x00\x00\x00\x00\x01"), byte(gamma), byte((gamma-float64(int(gamma)))*256))
+		tags = append(tags, tag{channel + "XYZ", xyz}, tag{channel + "TRC", trc})
+	}
+
+	header := make([]byte, 128)
+	copy(header[16:], "RGB ")
+	copy(header[20:], "XYZ ")
+	copy(header[36:], "acsp")
+	table := binary.BigEndian.AppendUint32(nil, uint32(len(tags)))
+	data := []byte{}
+	offset := 128 + 4 + 12*len(tags)
+	for _, tag := range tags {
+		table = append(table, tag.signature...)
+		table = binary.BigEndian.AppendUint32(table, uint32(offset+len(data)))
+		table = binary.BigEndian.AppendUint32(table, uint32(len(tag.content)))
+		data = append(data, tag.content...)
+		data = append(data, make([]byte, (4-len(tag.content)%4)%4)...)
+	}
+	profile := append(append(header, table...), data...)
+	binary.BigEndian.PutUint32(profile, uint32(len(profile)))
+
+	return profile
+}
+
+// tagPNG inserts an iCCP chunk with the given profile into PNG data
+func tagPNG(data, profile []byte) []byte {
+	compressed := new(bytes.Buffer)
+	w := zlib.NewWriter(compressed)
+	w.Write(profile) //nolint:errcheck
+	w.Close()
+	content := append([]byte("iCCP"), append([]byte("test\x00\x00"), compressed.Bytes()...)...)
+
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(content)-4))
+	chunk = append(chunk, content...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(content))
+
+	// Insert directly after the IHDR chunk
+	ihdr := 8 + 12 + int(binary.BigEndian.Uint32(data[8:]))
+	return append(append(append([]byte{}, data[:ihdr]...), chunk...), data[ihdr:]...)
+}
+
+func TestNormalize(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{128, 128, 128, 255})
+	img.SetNRGBA(1, 0, color.NRGBA{100, 150, 100, 255})
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+
+	SetConvert(true)
+	defer SetConvert(false)
+	data := tagPNG(buf.Bytes(), buildProfile("Adobe RGB (1998)", adobeRGB, 2.2))
+	decoded, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to decode tagged PNG: %v", err)
+	}
+	converted := Normalize(decoded, data)
+	if converted == decoded {
+		t.Fatalf("image with Adobe RGB profile was not converted")
+	}
+
+	// Neutral colors stay neutral, saturated colors become more saturated
+	gray := color.NRGBAModel.Convert(converted.At(0, 0)).(color.NRGBA)
+	if gray.R != gray.G || gray.G != gray.B || gray.R < 125 || gray.R > 131 {
+		t.Errorf("gray was not preserved: %v", gray)
+	}
+	green := color.NRGBAModel.Convert(converted.At(1, 0)).(color.NRGBA)
+	if int(green.G)-int(green.R) <= 50 {
+		t.Errorf("green was not saturated: %v", green)
+	}
+
+	// Images tagged as sRGB are used as they are
+	data = tagPNG(buf.Bytes(), buildProfile("sRGB IEC61966-2.1", srgbColorants, 2.2))
+	if Normalize(decoded, data) != decoded {
+		t.Errorf("image with sRGB profile was converted")
+	}
+
+	// Profiles are dropped without conversion
+	SetConvert(false)
+	data = tagPNG(buf.Bytes(), buildProfile("Adobe RGB (1998)", adobeRGB, 2.2))
+	if Normalize(decoded, data) != decoded {
+		t.Errorf("image was converted with conversion disabled")
+	}
+}
+
+func TestExtractJPEG(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, image.NewGray(image.Rect(0, 0, 1, 1)), nil); err != nil {
+		t.Fatalf("failed to encode JPEG: %v", err)
+	}
+	profile := buildProfile("Adobe RGB (1998)", adobeRGB, 2.2)
+
+	// Split the profile across two segments, stored out of order
+	data := []byte{0xFF, 0xD8}
+	for _, chunk := range []struct {
+		seq     byte
+		content []byte
+	}{{2, profile[100:]}, {1, profile[:100]}} {
+		segment := append([]byte("ICC_PROFILE\x00"), chunk.seq, 2)
+		segment = append(segment, chunk.content...)
+		data = append(data, 0xFF, 0xE2, byte((len(segment)+2)>>8), byte(len(segment)+2))
+		data = append(data, segment...)
+	}
+	data = append(data, buf.Bytes()[2:]...)
+
+	if extracted := Extract(data); !bytes.Equal(extracted, profile) {
+		t.Errorf("extracted profile does not match embedded profile")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(data)); err != nil {
+		t.Errorf("tagged JPEG is invalid: %v", err)
+	}
+}
@@ -0,0 +1,256 @@
+package icc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"unicode/utf16"
+)
+
+// Profile is an RGB color profile described by colorant primaries and
+// tone reproduction curves, which covers the profiles commonly embedded
+// in images such as Adobe RGB or Display P3.
+type Profile struct {
+	Description string
+	// toSRGB converts linear profile RGB to linear sRGB
+	toSRGB [3][3]float64
+	curves [3]curve
+}
+
+// srgbColorants are the sRGB primaries adapted to the D50 illuminant used
+// by ICC profiles, as columns of red, green and blue.
+var srgbColorants = [3][3]float64{
+	{0.4360747, 0.3850649, 0.1430804},
+	{0.2225045, 0.7168786, 0.0606169},
+	{0.0139322, 0.0971045, 0.7141733},
+}
+
+// Parse parses an ICC profile. Only RGB profiles using colorants and
+// tone reproduction curves are supported.
+func Parse(data []byte) (*Profile, error) {
+	if len(data) < 132 || string(data[36:40]) != "acsp" {
+		return nil, fmt.Errorf("not an ICC profile")
+	}
+	if space := string(data[16:20]); space != "RGB " {
+		return nil, fmt.Errorf("unsupported color space: %q", strings.TrimSpace(space))
+	}
+
+	tags := make(map[string][]byte)
+	count := int(binary.BigEndian.Uint32(data[128:]))
+	for i := 0; i < count && 132+i*12+12 <= len(data); i++ {
+		entry := data[132+i*12:]
+		offset := int(binary.BigEndian.Uint32(entry[4:]))
+		size := int(binary.BigEndian.Uint32(entry[8:]))
+		if offset+size <= len(data) {
+			tags[string(entry[:4])] = data[offset : offset+size]
+		}
+	}
+
+	p := &Profile{Description: parseDescription(tags["desc"])}
+	colorants := [3][3]float64{}
+	for i, channel := range []string{"r", "g", "b"} {
+		xyz, ok := parseXYZ(tags[channel+"XYZ"])
+		if !ok {
+			return nil, fmt.Errorf("missing %vXYZ colorant", channel)
+		}
+		for j := range xyz {
+			colorants[j][i] = xyz[j]
+		}
+		curve, err := parseCurve(tags[channel+"TRC"])
+		if err != nil {
+			return nil, fmt.Errorf("%vTRC: %w", channel, err)
+		}
+		p.curves[i] = curve
+	}
+	p.toSRGB = multiply(invert(srgbColorants), colorants)
+
+	return p, nil
+}
+
+// IsSRGB reports whether the profile describes sRGB closely enough for
+// images to be used without conversion.
+func (p *Profile) IsSRGB() bool {
+	if strings.Contains(p.Description, "sRGB") {
+		return true
+	}
+	for i := range p.toSRGB {
+		for j := range p.toSRGB[i] {
+			identity := 0.0
+			if i == j {
+				identity = 1
+			}
+			if math.Abs(p.toSRGB[i][j]-identity) > 0.01 {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Convert returns a copy of the image with its colors converted from the
+// profile to sRGB.
+func (p *Profile) Convert(img image.Image) image.Image {
+	linear := [3][256]float64{}
+	for i, curve := range p.curves {
+		for v := range linear[i] {
+			linear[i][v] = curve(float64(v) / 255)
+		}
+	}
+
+	b := img.Bounds()
+	dst := image.NewNRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			rgb := [3]float64{linear[0][c.R], linear[1][c.G], linear[2][c.B]}
+			out := [3]uint8{}
+			for i, row := range p.toSRGB {
+				out[i] = encodeSRGB(row[0]*rgb[0] + row[1]*rgb[1] + row[2]*rgb[2])
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{out[0], out[1], out[2], c.A})
+		}
+	}
+
+	return dst
+}
+
+// curve maps an encoded channel value in [0, 1] to its linear value
+type curve func(float64) float64
+
+func parseCurve(tag []byte) (curve, error) {
+	if len(tag) < 12 {
+		return nil, fmt.Errorf("missing curve")
+	}
+
+	switch string(tag[:4]) {
+	case "curv":
+		n := int(binary.BigEndian.Uint32(tag[8:]))
+		if len(tag) < 12+2*n {
+			return nil, fmt.Errorf("truncated curve")
+		}
+		switch n {
+		case 0:
+			return func(v float64) float64 { return v }, nil
+		case 1:
+			gamma := float64(binary.BigEndian.Uint16(tag[12:])) / 256
+			return func(v float64) float64 { return math.Pow(v, gamma) }, nil
+		default:
+			table := make([]float64, n)
+			for i := range table {
+				table[i] = float64(binary.BigEndian.Uint16(tag[12+2*i:])) / 65535
+			}
+			return func(v float64) float64 {
+				pos := v * float64(n-1)
+				i := min(int(pos), n-2)
+				return table[i] + (table[i+1]-table[i])*(pos-float64(i))
+			}, nil
+		}
+	case "para":
+		params := make([]float64, 7)
+		fn := binary.BigEndian.Uint16(tag[8:])
+		required := []int{1, 3, 4, 5, 7}
+		if int(fn) >= len(required) || len(tag) < 12+4*required[fn] {
+			return nil, fmt.Errorf("unsupported parametric curve")
+		}
+		for i := 0; i < required[fn]; i++ {
+			params[i] = s15Fixed16(tag[12+4*i:])
+		}
+		g, a, b, c, d, e, f := params[0], params[1], params[2], params[3], params[4], params[5], params[6]
+		switch fn {
+		case 1:
+			d = -b / a
+		case 2:
+			d, e, f = -b/a, c, c
+			c = 0
+		case 3:
+		case 0:
+			a, d = 1, math.Inf(-1)
+		}
+		return func(v float64) float64 {
+			if v >= d {
+				return math.Pow(max(a*v+b, 0), g) + e
+			}
+			return c*v + f
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported curve type: %q", tag[:4])
+}
+
+func parseXYZ(tag []byte) ([3]float64, bool) {
+	if len(tag) < 20 || string(tag[:4]) != "XYZ " {
+		return [3]float64{}, false
+	}
+
+	return [3]float64{s15Fixed16(tag[8:]), s15Fixed16(tag[12:]), s15Fixed16(tag[16:])}, true
+}
+
+func parseDescription(tag []byte) string {
+	switch {
+	case len(tag) >= 12 && string(tag[:4]) == "desc":
+		n := int(binary.BigEndian.Uint32(tag[8:]))
+		if len(tag) >= 12+n {
+			return strings.TrimRight(string(tag[12:12+n]), "\x00")
+		}
+	case len(tag) >= 28 && string(tag[:4]) == "mluc":
+		// Use the first localized record
+		length := int(binary.BigEndian.Uint32(tag[20:]))
+		offset := int(binary.BigEndian.Uint32(tag[24:]))
+		if offset+length <= len(tag) {
+			units := make([]uint16, length/2)
+			for i := range units {
+				units[i] = binary.BigEndian.Uint16(tag[offset+2*i:])
+			}
+			return string(utf16.Decode(units))
+		}
+	}
+
+	return ""
+}
+
+func s15Fixed16(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
+
+func encodeSRGB(v float64) uint8 {
+	v = min(max(v, 0), 1)
+	if v <= 0.0031308 {
+		v *= 12.92
+	} else {
+		v = 1.055*math.Pow(v, 1/2.4) - 0.055
+	}
+
+	return uint8(math.Round(v * 255))
+}
+
+func multiply(a, b [3][3]float64) (result [3][3]float64) {
+	for i := range result {
+		for j := range result[i] {
+			for k := range a[i] {
+				result[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+
+	return result
+}
+
+func invert(m [3][3]float64) (result [3][3]float64) {
+	det := m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+	for i := range result {
+		for j := range result[i] {
+			// Cofactor of the transposed position
+			r0, r1 := (j+1)%3, (j+2)%3
+			c0, c1 := (i+1)%3, (i+2)%3
+			result[i][j] = (m[r0][c0]*m[r1][c1] - m[r0][c1]*m[r1][c0]) / det
+		}
+	}
+
+	return result
+}
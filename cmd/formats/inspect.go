@@ -0,0 +1,226 @@
+package formats
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	epubpkg "github.com/leotaku/kojirou/cmd/formats/epub"
+)
+
+// BookInfo describes the metadata and structure of an ebook file
+type BookInfo struct {
+	Format      FormatType `json:"format"`
+	Title       string     `json:"title"`
+	Authors     []string   `json:"authors"`
+	Language    string     `json:"language"`
+	Series      string     `json:"series,omitempty"`
+	SeriesIndex string     `json:"seriesIndex,omitempty"`
+	// Spine is the number of documents in the reading order, which MOBI
+	// files do not have
+	Spine     int    `json:"spine"`
+	Images    int    `json:"images"`
+	Direction string `json:"direction"`
+	// Kobo reports whether the Kobo enhancements of KEPUB are present
+	Kobo bool `json:"kobo"`
+}
+
+// InspectFile reads the metadata and structure of an EPUB, KEPUB or MOBI
+// file. The format is detected from the content of the file.
+func InspectFile(filename string) (BookInfo, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return BookInfo{}, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	header := make([]byte, 78)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return BookInfo{}, fmt.Errorf("read header: %w", err)
+	}
+	switch {
+	case bytes.HasPrefix(header, []byte("PK")):
+		return inspectEPUB(filename)
+	case string(header[60:68]) == "BOOKMOBI":
+		return inspectMOBI(filename)
+	default:
+		return BookInfo{}, fmt.Errorf("unsupported file: not an EPUB, KEPUB or MOBI")
+	}
+}
+
+func inspectEPUB(filename string) (BookInfo, error) {
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		return BookInfo{}, fmt.Errorf("open archive: %w", err)
+	}
+	defer r.Close()
+
+	files := make(map[string]*zip.File)
+	for _, f := range r.File {
+		files[f.Name] = f
+	}
+	containerFile, ok := files["META-INF/container.xml"]
+	if !ok {
+		return BookInfo{}, fmt.Errorf("container: missing")
+	}
+	data, err := readZipFile(containerFile)
+	if err != nil {
+		return BookInfo{}, fmt.Errorf("container: %w", err)
+	}
+	container := struct {
+		Rootfiles []struct {
+			FullPath string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}{}
+	if err := xml.Unmarshal(data, &container); err != nil {
+		return BookInfo{}, fmt.Errorf("container: %w", err)
+	} else if len(container.Rootfiles) == 0 {
+		return BookInfo{}, fmt.Errorf("container: no rootfile")
+	}
+	opfFile, ok := files[container.Rootfiles[0].FullPath]
+	if !ok {
+		return BookInfo{}, fmt.Errorf("package: missing '%v'", container.Rootfiles[0].FullPath)
+	}
+	data, err = readZipFile(opfFile)
+	if err != nil {
+		return BookInfo{}, fmt.Errorf("package: %w", err)
+	}
+
+	pkg := struct {
+		Titles    []string `xml:"metadata>title"`
+		Creators  []string `xml:"metadata>creator"`
+		Languages []string `xml:"metadata>language"`
+		Metas     []struct {
+			Name     string `xml:"name,attr"`
+			Property string `xml:"property,attr"`
+			Content  string `xml:"content,attr"`
+			Value    string `xml:",chardata"`
+		} `xml:"metadata>meta"`
+		Items []struct {
+			MediaType string `xml:"media-type,attr"`
+		} `xml:"manifest>item"`
+		Spine struct {
+			Direction string     `xml:"page-progression-direction,attr"`
+			Items     []struct{} `xml:"itemref"`
+		} `xml:"spine"`
+	}{}
+	if err := xml.Unmarshal(data, &pkg); err != nil {
+		return BookInfo{}, fmt.Errorf("package: %w", err)
+	}
+
+	info := BookInfo{
+		Format:    FormatEpub,
+		Authors:   append(make([]string, 0), pkg.Creators...),
+		Spine:     len(pkg.Spine.Items),
+		Direction: pkg.Spine.Direction,
+	}
+	if len(pkg.Titles) > 0 {
+		info.Title = pkg.Titles[0]
+	}
+	if len(pkg.Languages) > 0 {
+		info.Language = pkg.Languages[0]
+	}
+	for _, meta := range pkg.Metas {
+		// Metadata is either written as content or text of the element
+		value := strings.TrimSpace(meta.Content + meta.Value)
+		switch meta.Name + meta.Property {
+		case "calibre:series", "belongs-to-collection":
+			info.Series = value
+		case "calibre:series_index", "group-position":
+			info.SeriesIndex = value
+		case "page-progression-direction":
+			info.Direction = value
+		}
+	}
+	for _, item := range pkg.Items {
+		if strings.HasPrefix(item.MediaType, "image/") {
+			info.Images++
+		}
+	}
+	if info.Direction == "" || info.Direction == "default" {
+		info.Direction = "ltr"
+	}
+
+	info.Kobo, err = epubpkg.IsKEPUB(filename)
+	if err != nil {
+		return BookInfo{}, fmt.Errorf("kobo: %w", err)
+	}
+	if info.Kobo {
+		info.Format = FormatKepub
+	}
+
+	return info, nil
+}
+
+// EXTH record types of MOBI metadata
+const (
+	exthAuthor                   = 100
+	exthTitle                    = 503
+	exthCountResources           = 125
+	exthLanguage                 = 524
+	exthPageProgressionDirection = 527
+)
+
+func inspectMOBI(filename string) (BookInfo, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return BookInfo{}, fmt.Errorf("read: %w", err)
+	}
+
+	// The first record holds the PalmDOC and MOBI headers, followed by
+	// the EXTH header with all metadata
+	if len(data) < 86 || binary.BigEndian.Uint16(data[76:78]) == 0 {
+		return BookInfo{}, fmt.Errorf("no records")
+	}
+	record := int(binary.BigEndian.Uint32(data[78:82]))
+	if record+24 > len(data) || string(data[record+16:record+20]) != "MOBI" {
+		return BookInfo{}, fmt.Errorf("missing MOBI header")
+	}
+	exth := record + 16 + int(binary.BigEndian.Uint32(data[record+20:record+24]))
+	if exth+12 > len(data) || string(data[exth:exth+4]) != "EXTH" {
+		return BookInfo{}, fmt.Errorf("missing EXTH header")
+	}
+
+	info := BookInfo{
+		Format:    FormatMobi,
+		Title:     strings.TrimRight(string(data[:32]), "\x00"),
+		Authors:   make([]string, 0),
+		Direction: "ltr",
+	}
+	count := int(binary.BigEndian.Uint32(data[exth+8 : exth+12]))
+	offset := exth + 12
+	for range count {
+		if offset+8 > len(data) {
+			return BookInfo{}, fmt.Errorf("truncated EXTH header")
+		}
+		kind := binary.BigEndian.Uint32(data[offset : offset+4])
+		length := int(binary.BigEndian.Uint32(data[offset+4 : offset+8]))
+		if length < 8 || offset+length > len(data) {
+			return BookInfo{}, fmt.Errorf("truncated EXTH record %v", kind)
+		}
+		value := data[offset+8 : offset+length]
+		offset += length
+
+		switch kind {
+		case exthAuthor:
+			info.Authors = append(info.Authors, string(value))
+		case exthTitle:
+			info.Title = string(value)
+		case exthLanguage:
+			info.Language = string(value)
+		case exthPageProgressionDirection:
+			info.Direction = string(value)
+		case exthCountResources:
+			if len(value) == 4 {
+				info.Images = int(binary.BigEndian.Uint32(value))
+			}
+		}
+	}
+
+	return info, nil
+}
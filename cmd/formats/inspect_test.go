@@ -0,0 +1,91 @@
+package formats
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	epubpkg "github.com/leotaku/kojirou/cmd/formats/epub"
+	"github.com/leotaku/kojirou/cmd/formats/kepubconv"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+)
+
+func TestInspectKEPUB(t *testing.T) {
+	manga := testhelpers.CreateSyntheticManga(2, 3, 200, 300)
+	e, cleanup, err := epubpkg.GenerateEPUB(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, false)
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		t.Fatalf("GenerateEPUB() failed: %v", err)
+	}
+	data, err := kepubconv.ConvertToKEPUB(e, "Synthetic Series", 2)
+	if err != nil {
+		t.Fatalf("ConvertToKEPUB() failed: %v", err)
+	}
+	filename := filepath.Join(t.TempDir(), "book.kepub.epub")
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatalf("failed to write KEPUB: %v", err)
+	}
+
+	info, err := InspectFile(filename)
+	if err != nil {
+		t.Fatalf("InspectFile() failed: %v", err)
+	}
+	if info.Format != FormatKepub || !info.Kobo {
+		t.Errorf("expected KEPUB with Kobo enhancements, got %v with kobo %v", info.Format, info.Kobo)
+	}
+	if info.Title != "Synthetic Manga" || !slices.Equal(info.Authors, []string{"Test Author"}) {
+		t.Errorf("unexpected title %q or authors %v", info.Title, info.Authors)
+	}
+	// Volume and chapter sections, and the navigation document
+	if info.Language != "en" || info.Spine != 4 {
+		t.Errorf("unexpected language %q or spine %v", info.Language, info.Spine)
+	}
+	if info.Series != "Synthetic Series" || info.SeriesIndex != "2.0" {
+		t.Errorf("unexpected series %q with index %q", info.Series, info.SeriesIndex)
+	}
+	if info.Images != 6 {
+		t.Errorf("expected 6 images, got %v", info.Images)
+	}
+	if info.Direction != "rtl" {
+		t.Errorf("expected right-to-left reading, got %q", info.Direction)
+	}
+}
+
+func TestInspectFile(t *testing.T) {
+	for _, tc := range []struct {
+		format    FormatType
+		title     string
+		language  string
+		spine     int
+		direction string
+	}{
+		// Volume and chapter sections
+		{FormatEpub, "Synthetic Manga", "en", 2, "ltr"},
+		{FormatMobi, "Synthetic Manga: 1", "en", 0, "rtl"},
+	} {
+		info, err := InspectFile(writeOutput(t, tc.format))
+		if err != nil {
+			t.Fatalf("%v: InspectFile() failed: %v", tc.format, err)
+		}
+		if info.Format != tc.format || info.Kobo {
+			t.Errorf("%v: detected as %v with kobo %v", tc.format, info.Format, info.Kobo)
+		}
+		if info.Title != tc.title || !slices.Equal(info.Authors, []string{"Test Author"}) {
+			t.Errorf("%v: unexpected title %q or authors %v", tc.format, info.Title, info.Authors)
+		}
+		if info.Language != tc.language || info.Spine != tc.spine {
+			t.Errorf("%v: unexpected language %q or spine %v", tc.format, info.Language, info.Spine)
+		}
+		if info.Images != 2 || info.Direction != tc.direction {
+			t.Errorf("%v: unexpected %v images read %v", tc.format, info.Images, info.Direction)
+		}
+	}
+
+	if _, err := InspectFile(writeOutput(t, FormatPdf)); err == nil {
+		t.Errorf("expected error for PDF file")
+	}
+}
@@ -0,0 +1,112 @@
+package kepubconv
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/leotaku/kojirou/cmd/formats/util"
+)
+
+// convertArchive converts the EPUB archive to a KEPUB in memory. The result
+// is the same as that of convertDirectory, without writing the contents of
+// the book to disk and reading them back twice.
+func convertArchive(epubData []byte, seriesTitle string, seriesIndex float64, opts Options) ([]byte, error) {
+	files, err := readArchive(epubData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract EPUB: %w", err)
+	}
+
+	if opts.StripMediaOverlays {
+		if err := stripArchiveOverlays(files); err != nil {
+			return nil, fmt.Errorf("failed to process EPUB for Kobo: failed to strip media overlays: %w", err)
+		}
+	}
+	for name, data := range files {
+		switch strings.ToLower(path.Ext(name)) {
+		case ".opf":
+			output, err := koboOPF(data, seriesTitle, seriesIndex)
+			if err != nil {
+				return nil, fmt.Errorf("failed to process EPUB for Kobo: %w", err)
+			}
+			files[name] = output
+		case ".html", ".xhtml":
+			files[name] = addKoboAttributes(data)
+		}
+	}
+
+	kepubData, err := writeArchive(files)
+	if err != nil {
+		return nil, fmt.Errorf("failed to package KEPUB: %w", err)
+	}
+
+	return kepubData, nil
+}
+
+// readArchive reads all files of a zip archive by their cleaned names
+func readArchive(data []byte) (map[string][]byte, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open EPUB file: %w", err)
+	}
+
+	files := make(map[string][]byte, len(r.File))
+	for _, file := range r.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file in archive: %w", err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file contents: %w", err)
+		}
+		files[path.Clean(file.Name)] = content
+	}
+
+	return files, nil
+}
+
+// writeArchive packages files as a KEPUB archive. Files are written in the
+// order in which packageKEPUB walks an extracted directory.
+func writeArchive(files map[string][]byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+	if err := writeMimetype(zipWriter); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		if name != "mimetype" {
+			names = append(names, name)
+		}
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		return slices.Compare(strings.Split(a, "/"), strings.Split(b, "/"))
+	})
+	for _, name := range names {
+		w, err := zipWriter.CreateHeader(&zip.FileHeader{
+			Name:   name,
+			Method: util.ZipMethod(name),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := zipWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,137 @@
+package kepubconv
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmaupin/go-epub"
+	"github.com/leotaku/kojirou/cmd/formats/util"
+)
+
+// writeTestEPUB writes a book with a cover and the given number of pages
+// as an EPUB file
+func writeTestEPUB(tb testing.TB, pages int) string {
+	tb.Helper()
+
+	dir := tb.TempDir()
+	imagePath := filepath.Join(dir, "page.png")
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 800, 1200))); err != nil {
+		tb.Fatalf("failed to encode image: %v", err)
+	}
+	if err := os.WriteFile(imagePath, buf.Bytes(), 0644); err != nil {
+		tb.Fatalf("failed to write image: %v", err)
+	}
+
+	e := epub.NewEpub("Test")
+	e.SetAuthor("Test Author")
+	cover, err := e.AddImage(imagePath, "cover.png")
+	if err != nil {
+		tb.Fatalf("failed to add cover: %v", err)
+	}
+	e.SetCover(cover, "")
+	for i := range pages {
+		src, err := e.AddImage(imagePath, fmt.Sprintf("page-%04d.png", i))
+		if err != nil {
+			tb.Fatalf("failed to add page: %v", err)
+		}
+		body := fmt.Sprintf(`<div><img src="%v" alt=""/></div>`, src)
+		if _, err := e.AddSection(body, fmt.Sprintf("Page %v", i), "", ""); err != nil {
+			tb.Fatalf("failed to add section: %v", err)
+		}
+	}
+
+	filename := filepath.Join(dir, "book.epub")
+	if err := e.Write(filename); err != nil {
+		tb.Fatalf("failed to write EPUB: %v", err)
+	}
+
+	return filename
+}
+
+func TestConvertArchive(t *testing.T) {
+	overlayBook := filepath.Join(t.TempDir(), "overlays.epub")
+	if err := packageKEPUB(writeOverlayBook(t), overlayBook); err != nil {
+		t.Fatalf("failed to write EPUB: %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		filename string
+		opts     Options
+	}{
+		"pages":    {writeTestEPUB(t, 3), Options{}},
+		"overlays": {overlayBook, Options{StripMediaOverlays: true}},
+	} {
+		data, err := os.ReadFile(tc.filename)
+		if err != nil {
+			t.Fatalf("%v: failed to read EPUB: %v", name, err)
+		}
+		want, err := convertDirectory(tc.filename, t.TempDir(), "Series", 2, tc.opts)
+		if err != nil {
+			t.Fatalf("%v: convertDirectory() failed: %v", name, err)
+		}
+		got, err := convertArchive(data, "Series", 2, tc.opts)
+		if err != nil {
+			t.Fatalf("%v: convertArchive() failed: %v", name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%v: in-memory conversion differs from conversion on disk", name)
+		}
+	}
+}
+
+func TestConvertInMemory(t *testing.T) {
+	// Conversion in memory succeeds without a directory for temporary files
+	temp := filepath.Join(t.TempDir(), "temp")
+	if err := os.Mkdir(temp, 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := util.SetTempDir(temp); err != nil {
+		t.Fatalf("SetTempDir() failed: %v", err)
+	}
+	defer util.SetTempDir("")
+	if err := os.Remove(temp); err != nil {
+		t.Fatalf("failed to remove directory: %v", err)
+	}
+
+	e := epub.NewEpub("Test")
+	if _, err := e.AddSection("<p>Page</p>", "Page", "", ""); err != nil {
+		t.Fatalf("failed to add section: %v", err)
+	}
+	if _, err := ConvertToKEPUBWithOptions(e, "Series", 1, Options{}); err != nil {
+		t.Errorf("ConvertToKEPUBWithOptions() failed: %v", err)
+	}
+	if _, err := ConvertToKEPUBWithOptions(e, "Series", 1, Options{ExtractToDisk: true}); err == nil {
+		t.Error("ConvertToKEPUBWithOptions() extracted to disk without a directory")
+	}
+}
+
+func BenchmarkConvert(b *testing.B) {
+	filename := writeTestEPUB(b, 50)
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		b.Fatalf("failed to read EPUB: %v", err)
+	}
+
+	b.Run("memory", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := convertArchive(data, "", 0, Options{}); err != nil {
+				b.Fatalf("convertArchive() failed: %v", err)
+			}
+		}
+	})
+	b.Run("disk", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := convertDirectory(filename, b.TempDir(), "", 0, Options{}); err != nil {
+				b.Fatalf("convertDirectory() failed: %v", err)
+			}
+		}
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"os"
 	"path/filepath"
@@ -22,8 +23,40 @@ import (
 // KEPUBExtension is the standard extension for Kobo KEPUB files
 const KEPUBExtension = ".kepub.epub"
 
+// epubMimetype is the content of the mimetype file of EPUB and KEPUB files
+const epubMimetype = "application/epub+zip"
+
+var (
+	// packageRe matches the start tag of package documents
+	packageRe = regexp.MustCompile(`(?s)<package([^>]*)>`)
+	// metaRe matches package metadata, capturing its property or name
+	metaRe = regexp.MustCompile(`<meta[^>]+(?:property|name)="([^"]+)"[^>]*/?>`)
+	// metadataCloseRe matches the end tag of package metadata
+	metadataCloseRe = regexp.MustCompile(`(?s)(</metadata>)`)
+)
+
+// Options configures optional steps of the KEPUB conversion.
+//
+// The zero value converts books like ConvertToKEPUB.
+type Options struct {
+	// StripMediaOverlays removes SMIL media overlays, which image-only
+	// books have no use for and which may confuse Kobo devices, along
+	// with all references to them.
+	StripMediaOverlays bool
+	// ExtractToDisk converts the book in a temporary directory rather than
+	// in memory, which needs less memory for very large books.
+	ExtractToDisk bool
+}
+
 // ConvertToKEPUB transforms a standard EPUB object into a Kobo-compatible KEPUB.
 func ConvertToKEPUB(epubBook *epub.Epub, seriesTitle string, seriesIndex float64) ([]byte, error) {
+	return ConvertToKEPUBWithOptions(epubBook, seriesTitle, seriesIndex, Options{})
+}
+
+// ConvertToKEPUBWithOptions transforms a standard EPUB object into a
+// Kobo-compatible KEPUB like ConvertToKEPUB, with optional steps
+// configured by the given options.
+func ConvertToKEPUBWithOptions(epubBook *epub.Epub, seriesTitle string, seriesIndex float64, opts Options) ([]byte, error) {
 	var retErr error
 	// Input validation
 	if epubBook == nil {
@@ -33,8 +66,18 @@ func ConvertToKEPUB(epubBook *epub.Epub, seriesTitle string, seriesIndex float64
 		return nil, errors.New("empty EPUB: no content sections found")
 	}
 
+	// Step 1: Write the EPUB, converting it in memory unless asked to
+	// extract it to disk
+	if !opts.ExtractToDisk {
+		var buf bytes.Buffer
+		if _, err := epubBook.WriteTo(&buf); err != nil {
+			return nil, fmt.Errorf("failed to write EPUB: %w", err)
+		}
+		return convertArchive(buf.Bytes(), seriesTitle, seriesIndex, opts)
+	}
+
 	// Create a temporary directory for processing
-	tempDir, err := os.MkdirTemp("", "kepub-conversion")
+	tempDir, err := os.MkdirTemp(util.TempDir(), "kepub-conversion")
 	if err != nil {
 		return nil, fmt.Errorf("failed to create temp directory: %w", err)
 	}
@@ -60,13 +103,22 @@ func ConvertToKEPUB(epubBook *epub.Epub, seriesTitle string, seriesIndex float64
 		}
 	}
 
-	// Step 1: Write the EPUB to a temporary file
 	epubPath := filepath.Join(tempDir, "original.epub")
 	err = epubBook.Write(epubPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to write EPUB to temp file: %w", err)
 	}
+	kepubData, err := convertDirectory(epubPath, tempDir, seriesTitle, seriesIndex, opts)
+	if err != nil {
+		return nil, err
+	}
 
+	return kepubData, retErr
+}
+
+// convertDirectory converts the EPUB file to a KEPUB by extracting it into
+// the given temporary directory
+func convertDirectory(epubPath, tempDir string, seriesTitle string, seriesIndex float64, opts Options) ([]byte, error) {
 	// Step 2: Extract EPUB contents to a directory
 	extractDir := filepath.Join(tempDir, "extracted")
 	if err := os.MkdirAll(extractDir, 0755); err != nil {
@@ -78,7 +130,7 @@ func ConvertToKEPUB(epubBook *epub.Epub, seriesTitle string, seriesIndex float64
 	}
 
 	// Step 3: Process EPUB contents for Kobo compatibility
-	if err := processEPUBForKobo(extractDir, seriesTitle, seriesIndex); err != nil {
+	if err := processEPUBForKobo(extractDir, seriesTitle, seriesIndex, opts); err != nil {
 		return nil, fmt.Errorf("failed to process EPUB for Kobo: %w", err)
 	}
 
@@ -102,11 +154,7 @@ func ConvertToKEPUB(epubBook *epub.Epub, seriesTitle string, seriesIndex float64
 		return nil, fmt.Errorf("failed to read KEPUB data: %w", err)
 	}
 
-	// Clean up: Remove debug output directory if it exists
-	debugOutdir := "/home/felix/src/kojirou/kepub_debug_tmp"
-	_ = os.RemoveAll(debugOutdir)
-
-	return kepubData, retErr
+	return kepubData, nil
 }
 
 // extractEPUB extracts the contents of an EPUB file to a specified directory.
@@ -152,7 +200,13 @@ func extractEPUB(epubPath, extractDir string) error {
 }
 
 // processEPUBForKobo modifies the contents of an extracted EPUB directory for Kobo compatibility.
-func processEPUBForKobo(extractDir string, seriesTitle string, seriesIndex float64) error {
+func processEPUBForKobo(extractDir string, seriesTitle string, seriesIndex float64, opts Options) error {
+	if opts.StripMediaOverlays {
+		if err := stripMediaOverlays(extractDir); err != nil {
+			return fmt.Errorf("failed to strip media overlays: %w", err)
+		}
+	}
+
 	// 1. Inject Kobo-specific metadata into OPF files (recursive)
 	opfFiles := []string{}
 	if err := filepath.Walk(extractDir, func(path string, info os.FileInfo, err error) error {
@@ -168,11 +222,9 @@ func processEPUBForKobo(extractDir string, seriesTitle string, seriesIndex float
 		if err != nil {
 			return fmt.Errorf("failed to read OPF file: %w", err)
 		}
-		output := injectKoboMetadata(data, seriesTitle, seriesIndex)
-		// --- Ensure cover image is first in manifest and referenced in metadata ---
-		output, err = ensureKoboCoverInOPF(output)
+		output, err := koboOPF(data, seriesTitle, seriesIndex)
 		if err != nil {
-			return fmt.Errorf("failed to ensure Kobo cover in OPF: %w", err)
+			return err
 		}
 		if err := os.WriteFile(opfFile, output, 0644); err != nil {
 			return fmt.Errorf("failed to write modified OPF file: %w", err)
@@ -203,11 +255,22 @@ func processEPUBForKobo(extractDir string, seriesTitle string, seriesIndex float
 	return nil
 }
 
+// koboOPF applies the Kobo enhancements to a package document
+func koboOPF(data []byte, seriesTitle string, seriesIndex float64) ([]byte, error) {
+	output := injectKoboMetadata(data, seriesTitle, seriesIndex)
+	// --- Ensure cover image is first in manifest and referenced in metadata ---
+	output, err := ensureKoboCoverInOPF(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to ensure Kobo cover in OPF: %w", err)
+	}
+
+	return output, nil
+}
+
 // injectKoboMetadata adds Kobo-specific metadata to the OPF XML content.
 func injectKoboMetadata(data []byte, seriesTitle string, seriesIndex float64) []byte {
 	opf := string(data)
 	// 1. Inject Kobo/rendition namespaces into <package ...>
-	packageRe := regexp.MustCompile(`(?s)<package([^>]*)>`)
 	opf = packageRe.ReplaceAllStringFunc(opf, func(pkgTag string) string {
 		// Always add Kobo/rendition namespaces if not present
 		if !strings.Contains(pkgTag, "xmlns:rendition") {
@@ -249,7 +312,6 @@ func injectKoboMetadata(data []byte, seriesTitle string, seriesIndex float64) []
 
 	// Check which metadata is already present
 	present := map[string]bool{}
-	metaRe := regexp.MustCompile(`<meta[^>]+(?:property|name)="([^"]+)"[^>]*/?>`)
 	for _, m := range metaRe.FindAllStringSubmatch(opf, -1) {
 		present[m[1]] = true
 	}
@@ -269,7 +331,6 @@ func injectKoboMetadata(data []byte, seriesTitle string, seriesIndex float64) []
 	}
 
 	// Insert the new metadata before closing </metadata> tag
-	metadataCloseRe := regexp.MustCompile(`(?s)(</metadata>)`)
 	if metaInsert.Len() > 0 {
 		opf = metadataCloseRe.ReplaceAllString(opf, metaInsert.String()+"$1")
 	}
@@ -417,32 +478,10 @@ func packageKEPUB(extractDir, kepubPath string) error {
 	zipWriter := zip.NewWriter(outFile)
 	defer zipWriter.Close()
 
-	// 1. Write mimetype file first, uncompressed
-	mimetypePath := filepath.Join(extractDir, "mimetype")
-	mimetypeInfo, err := os.Stat(mimetypePath)
-	if err != nil {
-		return fmt.Errorf("mimetype file missing: %w", err)
-	}
-	mimetypeHeader, err := zip.FileInfoHeader(mimetypeInfo)
-	if err != nil {
-		return fmt.Errorf("failed to create mimetype header: %w", err)
-	}
-	mimetypeHeader.Name = "mimetype"
-	mimetypeHeader.Method = zip.Store // No compression
-
-	mimetypeWriter, err := zipWriter.CreateHeader(mimetypeHeader)
-	if err != nil {
-		return fmt.Errorf("failed to create mimetype entry: %w", err)
-	}
-	mimetypeFile, err := os.Open(mimetypePath)
-	if err != nil {
-		return fmt.Errorf("failed to open mimetype: %w", err)
-	}
-	if _, err := io.Copy(mimetypeWriter, mimetypeFile); err != nil {
-		mimetypeFile.Close()
-		return fmt.Errorf("failed to write mimetype: %w", err)
+	// 1. Write mimetype file first
+	if err := writeMimetype(zipWriter); err != nil {
+		return err
 	}
-	mimetypeFile.Close()
 
 	// 2. Write all other files (skip mimetype)
 	err = filepath.Walk(extractDir, func(path string, info os.FileInfo, err error) error {
@@ -462,7 +501,11 @@ func packageKEPUB(extractDir, kepubPath string) error {
 		}
 		defer file.Close()
 
-		w, err := zipWriter.Create(relPath)
+		relPath = filepath.ToSlash(relPath)
+		w, err := zipWriter.CreateHeader(&zip.FileHeader{
+			Name:   relPath,
+			Method: util.ZipMethod(relPath),
+		})
 		if err != nil {
 			return err
 		}
@@ -476,191 +519,23 @@ func packageKEPUB(extractDir, kepubPath string) error {
 	return nil
 }
 
-// ensureKoboCoverInOPF ensures the cover image is the first item in the manifest and referenced in <meta name="cover" content="cover"/>.
-func ensureKoboCoverInOPF(opfData []byte) ([]byte, error) {
-	type item struct {
-		ID         string `xml:"id,attr"`
-		Href       string `xml:"href,attr"`
-		MediaType  string `xml:"media-type,attr"`
-		Properties string `xml:"properties,attr,omitempty"`
-	}
-
-	type manifest struct {
-		XMLName xml.Name `xml:"manifest"`
-		Items   []item   `xml:"item"`
-	}
-
-	type meta struct {
-		Name     string `xml:"name,attr,omitempty"`
-		Content  string `xml:"content,attr,omitempty"`
-		Property string `xml:"property,attr,omitempty"`
-	}
-
-	type metadata struct {
-		XMLName xml.Name `xml:"metadata"`
-		Metas   []meta   `xml:"meta"`
-	}
-
-	type opfPackage struct {
-		XMLName  xml.Name `xml:"package"`
-		Metadata metadata `xml:"metadata"`
-		Manifest manifest `xml:"manifest"`
-	}
-
-	var pkg opfPackage
-	if err := xml.Unmarshal(opfData, &pkg); err != nil {
-		return opfData, err
-	}
-
-	// Find cover image using strict priority order:
-	coverIdx := -1
-	// 1. Properties contains "cover-image"
-	for i, it := range pkg.Manifest.Items {
-		if strings.Contains(it.Properties, "cover-image") && strings.HasPrefix(it.MediaType, "image/") {
-			coverIdx = i
-			break
-		}
-	}
-	// 2. id="cover" and image/*
-	if coverIdx == -1 {
-		for i, it := range pkg.Manifest.Items {
-			if it.ID == "cover" && strings.HasPrefix(it.MediaType, "image/") {
-				coverIdx = i
-				break
-			}
-		}
-	}
-	// 3. href contains 'cover' and image/*
-	if coverIdx == -1 {
-		for i, it := range pkg.Manifest.Items {
-			if strings.Contains(strings.ToLower(it.Href), "cover") && strings.HasPrefix(it.MediaType, "image/") {
-				coverIdx = i
-				break
-			}
-		}
-	}
-	// 4. meta[name=cover] content reference
-	if coverIdx == -1 {
-		var coverId string
-		for _, m := range pkg.Metadata.Metas {
-			if m.Name == "cover" {
-				coverId = m.Content
-				break
-			}
-		}
-		if coverId != "" {
-			for i, it := range pkg.Manifest.Items {
-				if it.ID == coverId && strings.HasPrefix(it.MediaType, "image/") {
-					coverIdx = i
-					break
-				}
-			}
-		}
-	}
-	// 5. first image/* item
-	if coverIdx == -1 {
-		for i, it := range pkg.Manifest.Items {
-			if strings.HasPrefix(it.MediaType, "image/") {
-				coverIdx = i
-				break
-			}
-		}
-	}
-
-	// Always move the cover to the first position if found
-	if coverIdx >= 0 && len(pkg.Manifest.Items) > 0 {
-		coverItem := pkg.Manifest.Items[coverIdx]
-		// Remove the cover item from its current position
-		pkg.Manifest.Items = append(pkg.Manifest.Items[:coverIdx], pkg.Manifest.Items[coverIdx+1:]...)
-		// Insert at the front
-		pkg.Manifest.Items = append([]item{coverItem}, pkg.Manifest.Items...)
-	}
-
-	// Ensure cover id is "cover" and has cover-image property
-	if len(pkg.Manifest.Items) > 0 {
-		pkg.Manifest.Items[0].ID = "cover"
-		if pkg.Manifest.Items[0].Properties == "" {
-			pkg.Manifest.Items[0].Properties = "cover-image"
-		} else if !strings.Contains(pkg.Manifest.Items[0].Properties, "cover-image") {
-			pkg.Manifest.Items[0].Properties += " cover-image"
-		}
-	}
-
-	// Ensure <meta name="cover" content="cover"/> exists
-	hasCoverMeta := false
-	for _, m := range pkg.Metadata.Metas {
-		if m.Name == "cover" && m.Content == "cover" {
-			hasCoverMeta = true
-			break
-		}
-	}
-	if !hasCoverMeta {
-		pkg.Metadata.Metas = append([]meta{{Name: "cover", Content: "cover"}}, pkg.Metadata.Metas...)
-	}
-
-	// Marshal back to XML with proper formatting
-	out, err := xml.MarshalIndent(pkg, "", "  ")
+// writeMimetype writes the mimetype file, which has to be the first file
+// of the archive, uncompressed and without extra fields. KEPUBs keep the
+// EPUB mimetype, as Kobo devices only recognize them by their extension.
+func writeMimetype(zipWriter *zip.Writer) error {
+	mimetypeWriter, err := zipWriter.CreateRaw(&zip.FileHeader{
+		Name:               "mimetype",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE([]byte(epubMimetype)),
+		CompressedSize64:   uint64(len(epubMimetype)),
+		UncompressedSize64: uint64(len(epubMimetype)),
+	})
 	if err != nil {
-		return opfData, err
-	}
-
-	// Add XML declaration and remove unnecessary whitespace
-	out = append([]byte(xml.Header), out...)
-	out = regexp.MustCompile(`>\s+<`).ReplaceAll(out, []byte(">\n<"))
-
-	// --- Manual metadata serialization to guarantee all <meta> are escaped ---
-	metadataStart := []byte("<metadata>")
-	metadataEnd := []byte("</metadata>")
-	var metaItems []string
-	for _, m := range pkg.Metadata.Metas {
-		attrs := []string{}
-		if m.Name != "" {
-			attrs = append(attrs, "name=\""+xmlEscape(m.Name)+"\"")
-		}
-		if m.Property != "" {
-			attrs = append(attrs, "property=\""+xmlEscape(m.Property)+"\"")
-		}
-		if m.Content != "" {
-			attrs = append(attrs, "content=\""+xmlEscape(m.Content)+"\"")
-		}
-		metaItems = append(metaItems, "  <meta "+strings.Join(attrs, " ")+"/>")
-	}
-	metadataBlock := string(metadataStart) + "\n" + strings.Join(metaItems, "\n") + "\n" + string(metadataEnd)
-	out = regexp.MustCompile(`<metadata[\s\S]*?</metadata>`).ReplaceAll(out, []byte(metadataBlock))
-
-	// --- Manual manifest serialization to guarantee <item id="cover" ...> is first ---
-	manifestStart := []byte("<manifest>")
-	manifestEnd := []byte("</manifest>")
-	var manifestItems []string
-	for _, it := range pkg.Manifest.Items {
-		attrs := []string{
-			"id=\"" + xmlEscape(it.ID) + "\"",
-			"href=\"" + xmlEscape(it.Href) + "\"",
-			"media-type=\"" + xmlEscape(it.MediaType) + "\"",
-		}
-		if it.Properties != "" {
-			attrs = append(attrs, "properties=\""+xmlEscape(it.Properties)+"\"")
-		}
-		manifestItems = append(manifestItems, "  <item "+strings.Join(attrs, " ")+"/>")
-	}
-	// Reorder so <item id="cover" ...> is first
-	coverIdx = -1
-	for i, line := range manifestItems {
-		if strings.Contains(line, "id=\"cover\"") {
-			coverIdx = i
-			break
-		}
+		return fmt.Errorf("failed to create mimetype entry: %w", err)
 	}
-	if coverIdx > 0 {
-		cover := manifestItems[coverIdx]
-		manifestItems = append(manifestItems[:coverIdx], manifestItems[coverIdx+1:]...)
-		manifestItems = append([]string{cover}, manifestItems...)
+	if _, err := io.WriteString(mimetypeWriter, epubMimetype); err != nil {
+		return fmt.Errorf("failed to write mimetype: %w", err)
 	}
-	manifestBlock := string(manifestStart) + "\n" + strings.Join(manifestItems, "\n") + "\n" + string(manifestEnd)
-	out = regexp.MustCompile(`<manifest[\s\S]*?</manifest>`).ReplaceAll(out, []byte(manifestBlock))
 
-	// Write debug output for inspection
-	// _ = os.WriteFile("/home/felix/src/kojirou/cmd/formats/epub/_debug_last_opf.xml", out, 0644)
-
-	return out, nil
+	return nil
 }
@@ -0,0 +1,79 @@
+package kepubconv
+
+import "testing"
+
+// testOPF is a package document as written by go-epub for a volume
+const testOPF = `<?xml version="1.0" encoding="UTF-8"?>
+<package version="3.0" unique-identifier="pub-id" xmlns="http://www.idpf.org/2007/opf">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="pub-id">urn:uuid:0b1e0c52-0a8d-4c6e-9b3a-1f4f8a1c2d3e</dc:identifier>
+    <dc:title>Some Title</dc:title>
+    <dc:language>en</dc:language>
+    <dc:creator id="creator">Some Author</dc:creator>
+    <meta refines="#creator" property="role" scheme="marc:relators" id="role">aut</meta>
+    <meta property="dcterms:modified">2024-01-01T00:00:00Z</meta>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"></item>
+    <item id="style.css" href="css/style.css" media-type="text/css"></item>
+    <item id="image0001.png" href="images/image0001.png" media-type="image/png"></item>
+    <item id="cover.png" href="images/cover.png" media-type="image/png"></item>
+    <item id="cover.xhtml" href="xhtml/cover.xhtml" media-type="application/xhtml+xml"></item>
+    <item id="chapter-1.xhtml" href="xhtml/chapter-1.xhtml" media-type="application/xhtml+xml"></item>
+    <item id="chapter-2.xhtml" href="xhtml/chapter-2.xhtml" media-type="application/xhtml+xml"></item>
+  </manifest>
+  <spine>
+    <itemref idref="chapter-1.xhtml"></itemref>
+    <itemref idref="cover.xhtml"></itemref>
+    <itemref idref="chapter-2.xhtml"></itemref>
+    <itemref idref="nav"></itemref>
+  </spine>
+</package>`
+
+// testKoboOPF is testOPF after the Kobo enhancements of ConvertToKEPUB
+const testKoboOPF = `<?xml version="1.0" encoding="UTF-8"?>
+<package version="3.0" unique-identifier="pub-id" xmlns="http://www.idpf.org/2007/opf" xmlns:rendition="http://www.idpf.org/vocab/rendition/#" xmlns:kobo="http://kobobooks.com/ns/kobo">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <meta name="cover" content="cover"/>
+    <dc:identifier id="pub-id">urn:uuid:0b1e0c52-0a8d-4c6e-9b3a-1f4f8a1c2d3e</dc:identifier>
+    <dc:title>Some Title</dc:title>
+    <dc:language>en</dc:language>
+    <dc:creator id="creator">Some Author</dc:creator>
+    <meta refines="#creator" property="role" scheme="marc:relators" id="role">aut</meta>
+    <meta property="dcterms:modified">2024-01-01T00:00:00Z</meta>
+  <meta property="kobo:content-type" content="comic"/><meta property="kobo:epub-version" content="3.0"/><meta property="rendition:layout" content="pre-paginated"/><meta property="rendition:orientation" content="portrait"/><meta property="rendition:spread" content="none"/><meta property="rendition:flow" content="paginated"/><meta property="page-progression-direction" content="rtl"/><meta name="calibre:series" content="Some Series"/><meta name="calibre:series_index" content="3.0"/></metadata>
+  <manifest>
+    <item id="cover" href="images/cover.png" media-type="image/png" properties="cover-image"></item>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"></item>
+    <item id="style.css" href="css/style.css" media-type="text/css"></item>
+    <item id="image0001.png" href="images/image0001.png" media-type="image/png"></item>
+    <item id="cover.xhtml" href="xhtml/cover.xhtml" media-type="application/xhtml+xml"></item>
+    <item id="chapter-1.xhtml" href="xhtml/chapter-1.xhtml" media-type="application/xhtml+xml"></item>
+    <item id="chapter-2.xhtml" href="xhtml/chapter-2.xhtml" media-type="application/xhtml+xml"></item>
+  </manifest>
+  <spine>
+    <itemref idref="cover.xhtml"></itemref>
+    <itemref idref="chapter-1.xhtml"></itemref>
+    <itemref idref="chapter-2.xhtml"></itemref>
+    <itemref idref="nav"></itemref>
+  </spine>
+</package>`
+
+func TestKoboOPF(t *testing.T) {
+	got, err := ensureKoboCoverInOPF(injectKoboMetadata([]byte(testOPF), "Some Series", 3))
+	if err != nil {
+		t.Fatalf("ensureKoboCoverInOPF() failed: %v", err)
+	}
+	if string(got) != testKoboOPF {
+		t.Errorf("got package document:\n%s\nwant:\n%s", got, testKoboOPF)
+	}
+}
+
+func BenchmarkKoboOPF(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := ensureKoboCoverInOPF(injectKoboMetadata([]byte(testOPF), "Some Series", 3)); err != nil {
+			b.Fatalf("ensureKoboCoverInOPF() failed: %v", err)
+		}
+	}
+}
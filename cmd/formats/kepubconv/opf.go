@@ -0,0 +1,398 @@
+package kepubconv
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// opfElement is an element of a package document, located by its offsets
+// so that it can be edited without touching the rest of the document
+type opfElement struct {
+	name xml.Name
+	// start and end are the offsets of the whole element, tagEnd is the
+	// end of its start tag
+	start, tagEnd, end int
+	// attrs are the attributes of the element without a namespace prefix
+	attrs map[string]string
+}
+
+// selfClosing reports whether the element was written as <name/>
+func (e opfElement) selfClosing() bool {
+	return e.tagEnd == e.end
+}
+
+// qualifiedName returns the name of the element as written, including
+// its namespace prefix
+func (e opfElement) qualifiedName() string {
+	if e.name.Space == "" {
+		return e.name.Local
+	}
+	return e.name.Space + ":" + e.name.Local
+}
+
+// opfDocument holds the elements of a package document that Kobo
+// conversion edits. Documents without some of them are valid, which leaves
+// the corresponding fields empty.
+type opfDocument struct {
+	data     []byte
+	metadata *opfElement
+	metas    []opfElement
+	manifest *opfElement
+	items    []opfElement
+	spine    *opfElement
+	itemrefs []opfElement
+}
+
+// parseOPF locates the elements of a package document. Elements are
+// matched by their local name, so that prefixed namespaces like <opf:item>
+// are found as well.
+func parseOPF(data []byte) (opfDocument, error) {
+	doc := opfDocument{data: data}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	stack := make([]opfElement, 0)
+	root := false
+	for {
+		start := int(dec.InputOffset())
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return doc, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if len(stack) == 0 {
+				if root || t.Name.Local != "package" {
+					return doc, fmt.Errorf("unexpected root element <%v>", t.Name.Local)
+				}
+				root = true
+			}
+			el := opfElement{name: t.Name, start: start, tagEnd: int(dec.InputOffset()), attrs: make(map[string]string)}
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "" {
+					el.attrs[attr.Name.Local] = attr.Value
+				}
+			}
+			stack = append(stack, el)
+		case xml.EndElement:
+			if len(stack) == 0 || stack[len(stack)-1].name != t.Name {
+				return doc, fmt.Errorf("unexpected </%v>", t.Name.Local)
+			}
+			el := stack[len(stack)-1]
+			el.end = int(dec.InputOffset())
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				continue
+			}
+			switch stack[len(stack)-1].name.Local + "/" + el.name.Local {
+			case "package/metadata":
+				doc.metadata = &el
+			case "metadata/meta":
+				doc.metas = append(doc.metas, el)
+			case "package/manifest":
+				doc.manifest = &el
+			case "manifest/item":
+				doc.items = append(doc.items, el)
+			case "package/spine":
+				doc.spine = &el
+			case "spine/itemref":
+				doc.itemrefs = append(doc.itemrefs, el)
+			}
+		}
+	}
+	if len(stack) > 0 {
+		return doc, fmt.Errorf("unclosed <%v>", stack[len(stack)-1].name.Local)
+	} else if !root {
+		return doc, fmt.Errorf("no root element")
+	}
+
+	return doc, nil
+}
+
+// text returns the source of the element
+func (d opfDocument) text(el opfElement) string {
+	return string(d.data[el.start:el.end])
+}
+
+// indent returns the whitespace that the line of the given offset starts
+// with
+func (d opfDocument) indent(offset int) string {
+	lineStart := bytes.LastIndexByte(d.data[:offset], '\n') + 1
+	line := d.data[lineStart:offset]
+	return string(line[:len(line)-len(bytes.TrimLeft(line, " \t"))])
+}
+
+// opfEdit replaces the source between two offsets of a document
+type opfEdit struct {
+	start, end int
+	text       string
+}
+
+// remove returns an edit that removes the element along with the
+// whitespace before it on its line
+func (d opfDocument) remove(el opfElement) opfEdit {
+	start := el.start
+	for start > 0 && (d.data[start-1] == ' ' || d.data[start-1] == '\t') {
+		start--
+	}
+	if start > 0 && d.data[start-1] == '\n' {
+		start--
+	}
+
+	return opfEdit{start: start, end: el.end}
+}
+
+// apply returns the document with the given non-overlapping edits
+func (d opfDocument) apply(edits []opfEdit) []byte {
+	slices.SortStableFunc(edits, func(a, b opfEdit) int {
+		if a.start != b.start {
+			return a.start - b.start
+		}
+		return a.end - b.end
+	})
+
+	result := make([]byte, 0, len(d.data))
+	offset := 0
+	for _, edit := range edits {
+		result = append(result, d.data[offset:edit.start]...)
+		result = append(result, edit.text...)
+		offset = edit.end
+	}
+
+	return append(result, d.data[offset:]...)
+}
+
+// attrRes match the attributes that Kobo conversion sets
+var attrRes = map[string]*regexp.Regexp{
+	"id":         attrRe("id"),
+	"properties": attrRe("properties"),
+	"content":    attrRe("content"),
+	"refines":    attrRe("refines"),
+	"idref":      attrRe("idref"),
+}
+
+// attrRe returns a regexp that matches the named attribute of a start tag,
+// capturing the whitespace before it
+func attrRe(name string) *regexp.Regexp {
+	return regexp.MustCompile(`(\s)` + regexp.QuoteMeta(name) + `\s*=\s*("[^"]*"|'[^']*')`)
+}
+
+// setAttr returns the start tag with the attribute set to the given value,
+// adding the attribute if the tag does not have it yet
+func setAttr(tag, name, value string) string {
+	attr := name + `="` + xmlEscape(value) + `"`
+	re, ok := attrRes[name]
+	if !ok {
+		re = attrRe(name)
+	}
+	if loc := re.FindStringSubmatchIndex(tag); loc != nil {
+		return tag[:loc[3]] + attr + tag[loc[1]:]
+	}
+	end := strings.TrimSuffix(strings.TrimSuffix(tag, ">"), "/")
+	return strings.TrimRight(end, " \t\r\n") + " " + attr + tag[len(end):]
+}
+
+// coverItem returns the index of the cover image among the manifest items,
+// or -1 if the book has no images. The cover is the first image marked as
+// cover, with the ID "cover", named after the cover, referenced by the
+// cover meta element or just the first image, in this order.
+func coverItem(items []item, coverMeta string) int {
+	isImage := func(it item) bool { return strings.HasPrefix(it.MediaType, "image/") }
+	for _, match := range []func(item) bool{
+		func(it item) bool { return strings.Contains(it.Properties, "cover-image") },
+		func(it item) bool { return it.ID == "cover" },
+		func(it item) bool { return strings.Contains(strings.ToLower(it.Href), "cover") },
+		func(it item) bool { return coverMeta != "" && it.ID == coverMeta },
+		func(it item) bool { return true },
+	} {
+		if i := slices.IndexFunc(items, func(it item) bool { return isImage(it) && match(it) }); i >= 0 {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// ensureKoboCoverInOPF ensures the cover image is the first item in the
+// manifest, has the ID "cover" and is referenced by <meta name="cover"/>,
+// and that the spine is in reading order.
+//
+// The package document is edited in place, so that all elements and
+// attributes not involved are kept as they are. Documents that cannot be
+// parsed are returned unchanged.
+func ensureKoboCoverInOPF(opfData []byte) ([]byte, error) {
+	doc, err := parseOPF(opfData)
+	if err != nil || doc.manifest == nil {
+		return opfData, nil
+	}
+	edits := make([]opfEdit, 0)
+
+	// Remove items whose ID was already used, which appear when a book is
+	// written more than once
+	elements := make([]opfElement, 0, len(doc.items))
+	items := make([]item, 0, len(doc.items))
+	seen := make(map[string]bool)
+	for _, el := range doc.items {
+		if seen[el.attrs["id"]] {
+			edits = append(edits, doc.remove(el))
+			continue
+		}
+		seen[el.attrs["id"]] = true
+		elements = append(elements, el)
+		items = append(items, item{
+			ID:         el.attrs["id"],
+			Href:       el.attrs["href"],
+			MediaType:  el.attrs["media-type"],
+			Properties: el.attrs["properties"],
+		})
+	}
+
+	coverMetas := slices.DeleteFunc(slices.Clone(doc.metas), func(el opfElement) bool {
+		return el.attrs["name"] != "cover"
+	})
+	coverMeta := ""
+	if len(coverMetas) > 0 {
+		coverMeta = coverMetas[0].attrs["content"]
+	}
+
+	// Move the cover to the front of the manifest, naming it "cover" unless
+	// another item already uses that ID
+	renamed := map[string]string{}
+	if coverIdx := coverItem(items, coverMeta); coverIdx >= 0 {
+		cover, el := items[coverIdx], elements[coverIdx]
+		if cover.ID != "cover" && !seen["cover"] {
+			renamed[cover.ID] = "cover"
+			items[coverIdx].ID = "cover"
+		}
+		properties := strings.Fields(cover.Properties)
+		if !slices.Contains(properties, "cover-image") {
+			properties = append(properties, "cover-image")
+		}
+		tag := string(opfData[el.start:el.tagEnd])
+		tag = setAttr(setAttr(tag, "id", items[coverIdx].ID), "properties", strings.Join(properties, " "))
+		text := tag + string(opfData[el.tagEnd:el.end])
+
+		if coverIdx == 0 {
+			edits = append(edits, opfEdit{start: el.start, end: el.end, text: text})
+		} else {
+			first := elements[0]
+			edits = append(edits,
+				opfEdit{start: first.start, end: first.start, text: text + "\n" + doc.indent(first.start)},
+				doc.remove(el),
+			)
+			it := items[coverIdx]
+			items = slices.Insert(slices.Delete(items, coverIdx, coverIdx+1), 0, it)
+		}
+
+		// Point exactly one cover meta element at the cover
+		switch {
+		case len(coverMetas) > 0:
+			el := coverMetas[0]
+			tag := setAttr(string(opfData[el.start:el.tagEnd]), "content", items[0].ID)
+			edits = append(edits, opfEdit{start: el.start, end: el.tagEnd, text: tag})
+			for _, el := range coverMetas[1:] {
+				edits = append(edits, doc.remove(el))
+			}
+		case doc.metadata != nil:
+			edits = append(edits, doc.insertMeta(items[0].ID))
+		}
+	}
+
+	// Keep references to the renamed cover intact
+	for _, el := range doc.metas {
+		if to, ok := renamed[strings.TrimPrefix(el.attrs["refines"], "#")]; ok {
+			tag := setAttr(string(opfData[el.start:el.tagEnd]), "refines", "#"+to)
+			edits = append(edits, opfEdit{start: el.start, end: el.tagEnd, text: tag})
+		}
+	}
+
+	if doc.spine != nil {
+		spineEdits, err := doc.orderSpine(items, renamed)
+		if err != nil {
+			return opfData, fmt.Errorf("spine: %w", err)
+		}
+		edits = append(edits, spineEdits...)
+	}
+
+	return doc.apply(edits), nil
+}
+
+// insertMeta returns an edit that adds a cover meta element as the first
+// child of the metadata element, using the namespace prefix of the latter
+func (d opfDocument) insertMeta(coverID string) opfEdit {
+	el := *d.metadata
+	meta := "meta"
+	if el.name.Space != "" {
+		meta = el.name.Space + ":meta"
+	}
+	indent := d.indent(el.start)
+	text := "\n" + indent + "  " + `<` + meta + ` name="cover" content="` + xmlEscape(coverID) + `"/>`
+	if !el.selfClosing() {
+		return opfEdit{start: el.tagEnd, end: el.tagEnd, text: text}
+	}
+
+	// Metadata written as <metadata/> has to be opened first
+	tag := string(d.data[el.start:el.tagEnd])
+	tag = strings.TrimRight(strings.TrimSuffix(strings.TrimSuffix(tag, ">"), "/"), " \t\r\n") + ">"
+	return opfEdit{start: el.start, end: el.end, text: tag + text + "\n" + indent + "</" + el.qualifiedName() + ">"}
+}
+
+// orderSpine returns the edits that put the spine into reading order as
+// described by orderSpine, and validates the result
+func (d opfDocument) orderSpine(items []item, renamed map[string]string) ([]opfEdit, error) {
+	refs := make([]itemref, 0, len(d.itemrefs))
+	for _, el := range d.itemrefs {
+		idref := el.attrs["idref"]
+		if to, ok := renamed[idref]; ok {
+			idref = to
+		}
+		refs = append(refs, itemref{IDRef: idref})
+	}
+	ordered := orderSpine(refs, items)
+	if err := validateSpine(ordered, items); err != nil {
+		return nil, err
+	}
+
+	// The ordered spine keeps the first of repeated entries and moves at
+	// most the cover page to the front
+	kept := make([]int, 0, len(ordered))
+	seen := make(map[string]bool)
+	for i, ref := range refs {
+		if !seen[ref.IDRef] && slices.Contains(ordered, ref) {
+			kept = append(kept, i)
+		}
+		seen[ref.IDRef] = true
+	}
+	moved := -1
+	if refs[kept[0]] != ordered[0] {
+		moved = slices.Index(refs, ordered[0])
+	}
+
+	edits := make([]opfEdit, 0)
+	for i, el := range d.itemrefs {
+		text := d.text(el)
+		if refs[i].IDRef != el.attrs["idref"] {
+			text = setAttr(string(d.data[el.start:el.tagEnd]), "idref", refs[i].IDRef) + string(d.data[el.tagEnd:el.end])
+		}
+		switch {
+		case !slices.Contains(kept, i):
+			edits = append(edits, d.remove(el))
+		case i == moved:
+			start := d.itemrefs[kept[0]].start
+			edits = append(edits,
+				opfEdit{start: start, end: start, text: text + "\n" + d.indent(start)},
+				d.remove(el),
+			)
+		case text != d.text(el):
+			edits = append(edits, opfEdit{start: el.start, end: el.end, text: text})
+		}
+	}
+
+	return edits, nil
+}
@@ -0,0 +1,133 @@
+package kepubconv
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEnsureKoboCoverInOPF(t *testing.T) {
+	tests := []struct {
+		name string
+		opf  string
+		want []string
+	}{
+		{
+			name: "element-rich",
+			opf: `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="pub-id" xml:lang="ja" prefix="rendition: http://www.idpf.org/vocab/rendition/#">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="pub-id">urn:uuid:0b1e0c52-0a8d-4c6e-9b3a-1f4f8a1c2d3e</dc:identifier>
+    <dc:title>Some Title</dc:title>
+    <dc:creator id="author">Some Author</dc:creator>
+    <meta refines="#author" property="role" scheme="marc:relators">aut</meta>
+    <dc:language>ja</dc:language>
+    <meta property="dcterms:modified">2024-01-01T00:00:00Z</meta>
+    <meta property="rendition:layout">pre-paginated</meta>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="page-1" href="xhtml/page-1.xhtml" media-type="application/xhtml+xml"/>
+    <item id="cover-page" href="xhtml/cover.xhtml" media-type="application/xhtml+xml"/>
+    <item id="image-cover" href="images/cover.jpg" media-type="image/jpeg" data-custom="kept"/>
+    <item id="page-1" href="xhtml/page-1.xhtml" media-type="application/xhtml+xml"/>
+  </manifest>
+  <spine toc="ncx" page-progression-direction="rtl">
+    <itemref idref="page-1" properties="page-spread-left"/>
+    <itemref idref="cover-page" linear="yes"/>
+    <itemref idref="page-1"/>
+  </spine>
+  <guide>
+    <reference type="cover" title="Cover" href="xhtml/cover.xhtml"/>
+  </guide>
+  <!-- custom comment -->
+</package>
+`,
+			want: []string{
+				`unique-identifier="pub-id" xml:lang="ja" prefix="rendition: http://www.idpf.org/vocab/rendition/#"`,
+				`<dc:identifier id="pub-id">urn:uuid:0b1e0c52-0a8d-4c6e-9b3a-1f4f8a1c2d3e</dc:identifier>`,
+				`<dc:title>Some Title</dc:title>`,
+				`<dc:creator id="author">Some Author</dc:creator>`,
+				`<meta refines="#author" property="role" scheme="marc:relators">aut</meta>`,
+				`<dc:language>ja</dc:language>`,
+				`<meta property="dcterms:modified">2024-01-01T00:00:00Z</meta>`,
+				`<meta property="rendition:layout">pre-paginated</meta>`,
+				`<meta name="cover" content="cover"/>`,
+				"<manifest>\n    " + `<item id="cover" href="images/cover.jpg" media-type="image/jpeg" data-custom="kept" properties="cover-image"/>` + "\n    " + `<item id="nav"`,
+				`<spine toc="ncx" page-progression-direction="rtl">` + "\n    " +
+					`<itemref idref="cover-page" linear="yes"/>` + "\n    " +
+					`<itemref idref="page-1" properties="page-spread-left"/>` + "\n  </spine>",
+				`<reference type="cover" title="Cover" href="xhtml/cover.xhtml"/>`,
+				`<!-- custom comment -->`,
+			},
+		},
+		{
+			name: "namespaced",
+			opf: `<?xml version="1.0" encoding="UTF-8"?>
+<opf:package xmlns:opf="http://www.idpf.org/2007/opf" xmlns:dc="http://purl.org/dc/elements/1.1/" version="2.0" unique-identifier="id">
+  <opf:metadata>
+    <dc:title>Namespaced</dc:title>
+    <opf:meta name="cover" content="img"/>
+    <opf:meta name="cover" content="img"/>
+  </opf:metadata>
+  <opf:manifest>
+    <opf:item id="page" href="page.xhtml" media-type="application/xhtml+xml"/>
+    <opf:item id="img" href="img.png" media-type="image/png"/>
+  </opf:manifest>
+  <opf:spine>
+    <opf:itemref idref="page"/>
+  </opf:spine>
+</opf:package>
+`,
+			want: []string{
+				`<opf:package xmlns:opf="http://www.idpf.org/2007/opf" xmlns:dc="http://purl.org/dc/elements/1.1/" version="2.0" unique-identifier="id">`,
+				`<dc:title>Namespaced</dc:title>` + "\n    " + `<opf:meta name="cover" content="cover"/>` + "\n  </opf:metadata>",
+				"<opf:manifest>\n    " + `<opf:item id="cover" href="img.png" media-type="image/png" properties="cover-image"/>` + "\n    " + `<opf:item id="page"`,
+				`<opf:itemref idref="page"/>`,
+			},
+		},
+		{
+			name: "no metadata",
+			opf: `<package version="3.0"><metadata/><manifest>` +
+				`<item id="page" href="page.xhtml" media-type="application/xhtml+xml"/>` +
+				`<item id="cover" href="cover.jpg" media-type="image/jpeg"/>` +
+				`</manifest></package>`,
+			want: []string{
+				`<metadata>` + "\n  " + `<meta name="cover" content="cover"/>` + "\n</metadata>",
+				`<item id="cover" href="cover.jpg" media-type="image/jpeg" properties="cover-image"/>`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ensureKoboCoverInOPF([]byte(tt.opf))
+			if err != nil {
+				t.Fatalf("ensureKoboCoverInOPF() failed: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(got), want) {
+					t.Errorf("result does not contain %q:\n%s", want, got)
+				}
+			}
+			if n := strings.Count(string(got), `name="cover"`); n != 1 {
+				t.Errorf("got %v cover meta elements, want 1", n)
+			}
+		})
+	}
+}
+
+func TestEnsureKoboCoverInOPFUnchanged(t *testing.T) {
+	for name, opf := range map[string]string{
+		"malformed":   `<package><metadata><dc:title>Broken</metadata></package>`,
+		"truncated":   `<package><manifest><item id="cover" href="cover.jpg" media-type="image/jpeg"/>`,
+		"no package":  `<container><rootfiles/></container>`,
+		"no manifest": `<package version="3.0"><metadata><dc:title>Only</dc:title></metadata></package>`,
+	} {
+		got, err := ensureKoboCoverInOPF([]byte(opf))
+		if err != nil {
+			t.Errorf("%v: ensureKoboCoverInOPF() failed: %v", name, err)
+		} else if string(got) != opf {
+			t.Errorf("%v: got %q, want original %q", name, got, opf)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package kepubconv
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// smilMediaType is the media type of SMIL media overlay documents
+const smilMediaType = "application/smil+xml"
+
+var (
+	// mediaOverlayAttrRe matches media overlay references of manifest items
+	mediaOverlayAttrRe = regexp.MustCompile(`\s+media-overlay="[^"]*"`)
+	// mediaMetaRe matches package metadata of media overlays, such as
+	// their duration and narrator
+	mediaMetaRe = regexp.MustCompile(`(?s)\s*<meta[^>]*property="media:[^"]*"[^>]*(/>|>.*?</meta>)`)
+)
+
+// stripMediaOverlays removes all SMIL media overlays of an extracted EPUB
+// directory. Overlays are removed from the manifest and spine of every
+// package document, which also loses their metadata and the references
+// of other items, before the SMIL files themselves are deleted.
+func stripMediaOverlays(extractDir string) error {
+	overlays := make(map[string]bool)
+	if err := filepath.Walk(extractDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".smil":
+			overlays[path] = true
+		case ".opf":
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("read %v: %w", filepath.Base(path), err)
+			}
+			stripped, files, err := stripPackageOverlays(data)
+			if err != nil {
+				return fmt.Errorf("parse %v: %w", filepath.Base(path), err)
+			}
+			for _, file := range files {
+				overlays[filepath.Join(filepath.Dir(path), filepath.FromSlash(file))] = true
+			}
+			if err := os.WriteFile(path, stripped, 0644); err != nil {
+				return fmt.Errorf("write %v: %w", filepath.Base(path), err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for path := range overlays {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// stripArchiveOverlays removes all SMIL media overlays of a book read
+// into memory like stripMediaOverlays
+func stripArchiveOverlays(files map[string][]byte) error {
+	overlays := make(map[string]bool)
+	for name, data := range files {
+		switch strings.ToLower(path.Ext(name)) {
+		case ".smil":
+			overlays[name] = true
+		case ".opf":
+			stripped, refs, err := stripPackageOverlays(data)
+			if err != nil {
+				return fmt.Errorf("parse %v: %w", path.Base(name), err)
+			}
+			for _, ref := range refs {
+				overlays[path.Join(path.Dir(name), ref)] = true
+			}
+			files[name] = stripped
+		}
+	}
+
+	for name := range overlays {
+		delete(files, name)
+	}
+
+	return nil
+}
+
+// stripPackageOverlays removes media overlays from a package document,
+// returning the document and the files of the removed overlays relative
+// to it
+func stripPackageOverlays(opf []byte) ([]byte, []string, error) {
+	pkg := struct {
+		Items []struct {
+			ID        string `xml:"id,attr"`
+			Href      string `xml:"href,attr"`
+			MediaType string `xml:"media-type,attr"`
+		} `xml:"manifest>item"`
+	}{}
+	if err := xml.Unmarshal(opf, &pkg); err != nil {
+		return nil, nil, err
+	}
+
+	result := mediaMetaRe.ReplaceAllString(mediaOverlayAttrRe.ReplaceAllString(string(opf), ""), "")
+	files := make([]string, 0)
+	for _, item := range pkg.Items {
+		if item.MediaType != smilMediaType && !strings.EqualFold(filepath.Ext(item.Href), ".smil") {
+			continue
+		}
+		files = append(files, item.Href)
+		for element, attr := range map[string]string{"item": "id", "itemref": "idref"} {
+			re := regexp.MustCompile(`\s*<` + element + `\s[^>]*` + attr + `="` + regexp.QuoteMeta(item.ID) + `"[^>]*/>`)
+			result = re.ReplaceAllString(result, "")
+		}
+	}
+
+	return []byte(result), files, nil
+}
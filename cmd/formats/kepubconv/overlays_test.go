@@ -0,0 +1,100 @@
+package kepubconv
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const overlayOPF = `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="id">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="id">test</dc:identifier>
+    <dc:title>Test</dc:title>
+    <meta property="media:duration" refines="#overlay">0:00:05</meta>
+    <meta property="media:duration">0:00:05</meta>
+  </metadata>
+  <manifest>
+    <item id="image" href="images/page.jpg" media-type="image/jpeg"/>
+    <item id="page" href="xhtml/page.xhtml" media-type="application/xhtml+xml" media-overlay="overlay"/>
+    <item id="overlay" href="smil/page.smil" media-type="application/smil+xml"/>
+  </manifest>
+  <spine>
+    <itemref idref="page"/>
+  </spine>
+</package>`
+
+// writeOverlayBook writes an extracted book with a page that has a media
+// overlay
+func writeOverlayBook(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"mimetype":             "application/epub+zip",
+		"EPUB/package.opf":     overlayOPF,
+		"EPUB/images/page.jpg": "",
+		"EPUB/xhtml/page.xhtml": `<html xmlns="http://www.w3.org/1999/xhtml"><head><title>Page</title></head>` +
+			`<body><img src="../images/page.jpg" alt=""/></body></html>`,
+		"EPUB/smil/page.smil": `<smil xmlns="http://www.w3.org/ns/SMIL" version="3.0"><body/></smil>`,
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %v: %v", name, err)
+		}
+	}
+
+	return dir
+}
+
+func TestStripMediaOverlays(t *testing.T) {
+	for _, strip := range []bool{false, true} {
+		dir := writeOverlayBook(t)
+		if err := processEPUBForKobo(dir, "", 0, Options{StripMediaOverlays: strip}); err != nil {
+			t.Fatalf("processEPUBForKobo() failed: %v", err)
+		}
+
+		_, err := os.Stat(filepath.Join(dir, "EPUB", "smil", "page.smil"))
+		if exists := err == nil; exists == strip {
+			t.Errorf("strip %v: SMIL file exists: %v", strip, exists)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "EPUB", "package.opf"))
+		if err != nil {
+			t.Fatalf("failed to read package: %v", err)
+		}
+		opf := string(data)
+		if !strip {
+			if !strings.Contains(opf, "smil/page.smil") {
+				t.Errorf("SMIL was removed from manifest without stripping:\n%s", opf)
+			}
+			continue
+		}
+
+		for _, reference := range []string{"smil", "media-overlay", "media:duration"} {
+			if strings.Contains(opf, reference) {
+				t.Errorf("package still references %q:\n%s", reference, opf)
+			}
+		}
+		pkg := struct {
+			Items []struct {
+				Href string `xml:"href,attr"`
+			} `xml:"manifest>item"`
+		}{}
+		if err := xml.Unmarshal(data, &pkg); err != nil {
+			t.Fatalf("failed to parse package: %v", err)
+		}
+		if len(pkg.Items) != 2 {
+			t.Errorf("expected 2 manifest items, got %v:\n%s", len(pkg.Items), opf)
+		}
+		for _, item := range pkg.Items {
+			if _, err := os.Stat(filepath.Join(dir, "EPUB", filepath.FromSlash(item.Href))); err != nil {
+				t.Errorf("manifest item %v does not exist: %v", item.Href, err)
+			}
+		}
+	}
+}
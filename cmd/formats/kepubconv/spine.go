@@ -0,0 +1,89 @@
+package kepubconv
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// item is an entry of the manifest of a package document
+type item struct {
+	ID         string
+	Href       string
+	MediaType  string
+	Properties string
+}
+
+// itemref is an entry of the spine, referring to a manifest item
+type itemref struct {
+	IDRef string
+}
+
+// orderSpine returns the spine in reading order. Entries that repeat an
+// earlier entry or refer to no manifest item are removed, and the cover
+// page is moved to the front. All other entries keep their order.
+func orderSpine(items []itemref, manifest []item) []itemref {
+	known := make(map[string]item)
+	for _, it := range manifest {
+		known[it.ID] = it
+	}
+
+	result := make([]itemref, 0, len(items))
+	seen := make(map[string]bool)
+	for _, ref := range items {
+		if _, ok := known[ref.IDRef]; ok && !seen[ref.IDRef] {
+			seen[ref.IDRef] = true
+			result = append(result, ref)
+		}
+	}
+	if i := slices.IndexFunc(result, func(ref itemref) bool { return isCoverPage(known[ref.IDRef]) }); i > 0 {
+		cover := result[i]
+		result = slices.Insert(slices.Delete(result, i, i+1), 0, cover)
+	}
+
+	return result
+}
+
+// validateSpine checks that the spine lists every content document at
+// most once and starts with the cover page, if the book has one
+func validateSpine(items []itemref, manifest []item) error {
+	known := make(map[string]item)
+	for _, it := range manifest {
+		known[it.ID] = it
+	}
+
+	if len(items) == 0 {
+		return fmt.Errorf("no content documents")
+	}
+	seen := make(map[string]bool)
+	for i, ref := range items {
+		it, ok := known[ref.IDRef]
+		switch {
+		case !ok:
+			return fmt.Errorf("entry '%v' refers to no manifest item", ref.IDRef)
+		case seen[ref.IDRef]:
+			return fmt.Errorf("entry '%v' is listed more than once", ref.IDRef)
+		case !isContentDocument(it):
+			return fmt.Errorf("entry '%v' is not a content document", ref.IDRef)
+		case i > 0 && isCoverPage(it):
+			return fmt.Errorf("cover page '%v' is not the first entry", ref.IDRef)
+		}
+		seen[ref.IDRef] = true
+	}
+
+	return nil
+}
+
+// isContentDocument reports whether the manifest item can be listed in the
+// spine
+func isContentDocument(it item) bool {
+	return it.MediaType == "application/xhtml+xml" || it.MediaType == "image/svg+xml"
+}
+
+// isCoverPage reports whether the manifest item is the page that shows the
+// cover, which is named "cover" by go-epub and most other tools
+func isCoverPage(it item) bool {
+	name := strings.ToLower(path.Base(it.Href))
+	return isContentDocument(it) && strings.HasPrefix(name, "cover")
+}
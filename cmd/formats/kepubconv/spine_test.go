@@ -0,0 +1,40 @@
+package kepubconv
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestOrderSpine(t *testing.T) {
+	manifest := []item{
+		{ID: "page-1", Href: "xhtml/page-1.xhtml", MediaType: "application/xhtml+xml"},
+		{ID: "page-2", Href: "xhtml/page-2.xhtml", MediaType: "application/xhtml+xml"},
+		{ID: "cover-page", Href: "xhtml/cover.xhtml", MediaType: "application/xhtml+xml"},
+		{ID: "cover", Href: "images/cover.jpg", MediaType: "image/jpeg"},
+	}
+	items := []itemref{{IDRef: "page-1"}, {IDRef: "cover-page"}, {IDRef: "missing"}, {IDRef: "page-2"}, {IDRef: "page-1"}}
+
+	spine := orderSpine(items, manifest)
+	got := make([]string, 0)
+	for _, ref := range spine {
+		got = append(got, ref.IDRef)
+	}
+	if want := []string{"cover-page", "page-1", "page-2"}; !slices.Equal(got, want) {
+		t.Errorf("got spine %v, want %v", got, want)
+	}
+	if err := validateSpine(spine, manifest); err != nil {
+		t.Errorf("validateSpine() failed on ordered spine: %v", err)
+	}
+
+	for name, invalid := range map[string][]itemref{
+		"empty":     {},
+		"missing":   {{IDRef: "page-1"}, {IDRef: "missing"}},
+		"duplicate": {{IDRef: "page-1"}, {IDRef: "page-1"}},
+		"image":     {{IDRef: "cover"}},
+		"cover":     {{IDRef: "page-1"}, {IDRef: "cover-page"}},
+	} {
+		if err := validateSpine(invalid, manifest); err == nil {
+			t.Errorf("%v: validateSpine() succeeded, want error", name)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package kindle
+
+import (
+	"image"
+	"maps"
+
+	"github.com/leotaku/kojirou/cmd/crop"
+	"github.com/leotaku/kojirou/mangadex"
+)
+
+// CoverSpread decides how wraparound covers are arranged. These are wide
+// covers, or wide first pages of volumes without a cover, that show the
+// back of the volume next to its front.
+type CoverSpread int
+
+const (
+	// CoverSpreadOff keeps wide covers and first pages as they are
+	CoverSpreadOff CoverSpread = iota
+	// CoverSpreadUseAsCover uses the whole spread as cover, removing it
+	// from the pages if it was the first page
+	CoverSpreadUseAsCover
+	// CoverSpreadSplitFrontBack uses the front half as cover and moves the
+	// back half to the end of the volume
+	CoverSpreadSplitFrontBack
+	// CoverSpreadKeepSpread uses the front half as cover and shows the
+	// whole spread as the first page
+	CoverSpreadKeepSpread
+)
+
+// Apply arranges the wraparound covers of all volumes. Spreads are
+// recognized by an aspect ratio above the given threshold, or
+// crop.DefaultWideThreshold if zero. The front of a spread is its right
+// half for left-to-right reading and its left half otherwise.
+func (s CoverSpread) Apply(manga mangadex.Manga, ltr bool, threshold float64) mangadex.Manga {
+	if s == CoverSpreadOff {
+		return manga
+	}
+	if threshold <= 0 {
+		threshold = crop.DefaultWideThreshold
+	}
+
+	vols := make(map[mangadex.Identifier]mangadex.Volume, len(manga.Volumes))
+	for volID, vol := range manga.Volumes {
+		vols[volID] = s.applyVolume(vol, ltr, threshold)
+	}
+
+	return mangadex.Manga{
+		Info:    manga.Info,
+		Volumes: vols,
+	}
+}
+
+func (s CoverSpread) applyVolume(vol mangadex.Volume, ltr bool, threshold float64) mangadex.Volume {
+	chapKeys := vol.Keys()
+	if len(chapKeys) == 0 {
+		return vol
+	}
+	first, last := chapKeys[0], chapKeys[len(chapKeys)-1]
+
+	spread, fromPage := vol.Cover, false
+	if spread == nil {
+		if keys := vol.Chapters[first].Keys(); len(keys) > 0 {
+			spread, fromPage = vol.Chapters[first].Pages[keys[0]], true
+		}
+	}
+	if spread == nil || !crop.IsWide(spread, threshold) {
+		return vol
+	}
+	left, right, err := crop.Split(spread)
+	if err != nil {
+		return vol
+	}
+	front, back := left, right
+	if ltr {
+		front, back = right, left
+	}
+
+	// Volumes keep the spread as page if it is their only page
+	onlyPage := len(chapKeys) == 1 && len(vol.Chapters[first].Pages) == 1
+	vol.Chapters = maps.Clone(vol.Chapters)
+	switch s {
+	case CoverSpreadUseAsCover:
+		vol.Cover = spread
+		if fromPage && !onlyPage {
+			removeFirstPage(vol.Chapters, first)
+		}
+	case CoverSpreadSplitFrontBack:
+		vol.Cover = front
+		vol.Chapters[last] = withPage(vol.Chapters[last], back, false)
+		if fromPage {
+			removeFirstPage(vol.Chapters, first)
+		}
+	case CoverSpreadKeepSpread:
+		vol.Cover = front
+		if !fromPage {
+			vol.Chapters[first] = withPage(vol.Chapters[first], spread, true)
+		}
+	}
+
+	return vol
+}
+
+// removeFirstPage removes the first page of the given chapter, and the
+// chapter itself if it has no pages left
+func removeFirstPage(chapters map[mangadex.Identifier]mangadex.Chapter, chapID mangadex.Identifier) {
+	chap := chapters[chapID]
+	keys := chap.Keys()
+	if len(keys) <= 1 {
+		delete(chapters, chapID)
+		return
+	}
+	chap.Pages, chap.Filenames = maps.Clone(chap.Pages), maps.Clone(chap.Filenames)
+	delete(chap.Pages, keys[0])
+	delete(chap.Filenames, keys[0])
+	chapters[chapID] = chap
+}
+
+// withPage returns the chapter with the page added before its first or
+// after its last page
+func withPage(chap mangadex.Chapter, page image.Image, before bool) mangadex.Chapter {
+	key := 0
+	if keys := chap.Keys(); len(keys) > 0 && before {
+		key = keys[0] - 1
+	} else if len(keys) > 0 {
+		key = keys[len(keys)-1] + 1
+	}
+	pages := make(map[int]image.Image, len(chap.Pages)+1)
+	maps.Copy(pages, chap.Pages)
+	pages[key] = page
+	chap.Pages = pages
+
+	return chap
+}
@@ -0,0 +1,90 @@
+package kindle
+
+import (
+	"fmt"
+	"image"
+	"testing"
+
+	"github.com/leotaku/kojirou/mangadex"
+)
+
+// describePage describes a page by its width and the color of its center
+func describePage(img image.Image) string {
+	if img == nil {
+		return "none"
+	}
+	b := img.Bounds()
+	name := "gray"
+	switch r, _, bl, _ := img.At(b.Min.X+b.Dx()/2, b.Min.Y+b.Dy()/2).RGBA(); {
+	case r > 0 && bl == 0:
+		name = "red"
+	case bl > 0 && r == 0:
+		name = "blue"
+	}
+
+	return fmt.Sprint(b.Dx(), " ", name)
+}
+
+// spreadVolume returns a manga with a volume of two gray pages, led by the
+// wide spread unless it is the cover
+func spreadVolume(cover bool) mangadex.Manga {
+	gray := image.NewGray(image.Rect(0, 0, 140, 200))
+	chap := mangadex.Chapter{
+		Info:  mangadex.ChapterInfo{Identifier: mangadex.NewIdentifier("1")},
+		Pages: map[int]image.Image{1: gray, 2: gray},
+	}
+	vol := mangadex.Volume{
+		Info:     mangadex.VolumeInfo{Identifier: mangadex.NewIdentifier("1")},
+		Chapters: map[mangadex.Identifier]mangadex.Chapter{chap.Info.Identifier: chap},
+	}
+	if cover {
+		vol.Cover = widePage()
+	} else {
+		chap.Pages[0] = widePage()
+	}
+
+	return mangadex.Manga{Volumes: map[mangadex.Identifier]mangadex.Volume{vol.Info.Identifier: vol}}
+}
+
+func TestCoverSpread(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		spread CoverSpread
+		cover  bool
+		ltr    bool
+		want   string
+	}{
+		{"off", CoverSpreadOff, false, false, "none [400 blue 140 gray 140 gray]"},
+		{"use page as cover", CoverSpreadUseAsCover, false, false, "400 blue [140 gray 140 gray]"},
+		{"use cover as cover", CoverSpreadUseAsCover, true, false, "400 blue [140 gray 140 gray]"},
+		{"split page right-to-left", CoverSpreadSplitFrontBack, false, false, "200 red [140 gray 140 gray 200 blue]"},
+		{"split page left-to-right", CoverSpreadSplitFrontBack, false, true, "200 blue [140 gray 140 gray 200 red]"},
+		{"split cover", CoverSpreadSplitFrontBack, true, false, "200 red [140 gray 140 gray 200 blue]"},
+		{"keep page spread", CoverSpreadKeepSpread, false, false, "200 red [400 blue 140 gray 140 gray]"},
+		{"keep cover spread", CoverSpreadKeepSpread, true, true, "200 blue [400 blue 140 gray 140 gray]"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			manga := tc.spread.Apply(spreadVolume(tc.cover), tc.ltr, 0)
+			vol := manga.Sorted()[0]
+			pages := make([]string, 0)
+			for _, chap := range vol.Sorted() {
+				for _, page := range chap.Sorted() {
+					pages = append(pages, describePage(page))
+				}
+			}
+			if got := fmt.Sprint(describePage(vol.Cover), " ", pages); got != tc.want {
+				t.Errorf("expected cover and pages %v, got %v", tc.want, got)
+			}
+		})
+	}
+
+	t.Run("narrow cover", func(t *testing.T) {
+		manga := spreadVolume(false)
+		vol := manga.Volumes[mangadex.NewIdentifier("1")]
+		vol.Cover = image.NewGray(image.Rect(0, 0, 140, 200))
+		manga.Volumes[vol.Info.Identifier] = vol
+		if got := describePage(CoverSpreadSplitFrontBack.Apply(manga, false, 0).Sorted()[0].Cover); got != "140 gray" {
+			t.Errorf("expected narrow cover to be kept, got %v", got)
+		}
+	})
+}
@@ -2,8 +2,11 @@ package kindle
 
 import (
 	"image"
+	"image/color"
+	"math"
 
 	"github.com/leotaku/kojirou/cmd/crop"
+	"golang.org/x/image/draw"
 )
 
 type WidepagePolicy int
@@ -15,9 +18,74 @@ const (
 	WidepagePolicySplitAndPreserve
 )
 
+// PageOptions configures how CropAndSplitWithOptions processes pages.
+//
+// The zero value preserves pages as they are.
+type PageOptions struct {
+	// Policy decides whether wide pages are split into their halves.
+	Policy WidepagePolicy
+	// WideThreshold is the aspect ratio, width divided by height, above
+	// which the policy considers pages wide. Zero uses
+	// crop.DefaultWideThreshold.
+	WideThreshold float64
+	// AutoCrop crops whitespace from the borders of pages.
+	AutoCrop bool
+	// LeftToRight orders the halves of split pages for reading from left
+	// to right instead of right to left.
+	LeftToRight bool
+	// SplitGutters splits wide pages that are two pages stitched together
+	// at their gutter, regardless of the policy. Spreads without a clear
+	// gutter are left to the policy.
+	SplitGutters bool
+	// SplitOverlap extends both halves of split pages by this many pixels
+	// past the split, so that art at the gutter is not cut.
+	SplitOverlap int
+	// MaxWidth and MaxHeight scale down larger pages to fit, preserving
+	// their aspect ratio. Zero leaves the respective dimension unlimited.
+	MaxWidth  int
+	MaxHeight int
+	// Margin adds a border around every page after scaling.
+	Margin Margin
+}
+
+// Margin describes a border added around page images.
+type Margin struct {
+	// Pixels is the width of the border on every side.
+	Pixels int
+	// Percent is the width of the border on every side relative to the
+	// width and height of the page. It is used instead of Pixels if set.
+	Percent float64
+	// Color is the color of the border, white if nil.
+	Color color.Color
+}
+
 // CropAndSplit processes an image for manga pages, applying optional cropping and page splitting
+//
+// It is equivalent to CropAndSplitWithOptions with only the policy,
+// cropping and reading direction configured.
 func CropAndSplit(img image.Image, widepage WidepagePolicy, autocrop bool, ltr bool) []image.Image {
-	if autocrop {
+	return CropAndSplitWithOptions(img, PageOptions{
+		Policy:      widepage,
+		AutoCrop:    autocrop,
+		LeftToRight: ltr,
+	})
+}
+
+// CropAndSplitWithOptions processes an image for manga pages, returning
+// one or more pages depending on the wide page policy. Pages are cropped
+// first, then split, scaled and finally surrounded by the margin.
+func CropAndSplitWithOptions(img image.Image, opts PageOptions) []image.Image {
+	pages := cropAndSplit(img, opts)
+	for i, page := range pages {
+		pages[i] = opts.Margin.apply(fit(page, opts.MaxWidth, opts.MaxHeight))
+	}
+
+	return pages
+}
+
+func cropAndSplit(img image.Image, opts PageOptions) []image.Image {
+	widepage, ltr := opts.Policy, opts.LeftToRight
+	if opts.AutoCrop {
 		croppedImg, err := crop.Crop(img, crop.Bounds(img))
 		if err != nil {
 			panic("unsupported image type for splitting")
@@ -25,8 +93,27 @@ func CropAndSplit(img image.Image, widepage WidepagePolicy, autocrop bool, ltr b
 		img = croppedImg
 	}
 
-	if widepage != WidepagePolicyPreserve && crop.ShouldSplit(img) {
-		left, right, err := crop.Split(img)
+	if opts.SplitGutters {
+		if x, ok := crop.FindGutter(img); ok {
+			left, right, err := crop.SplitOverlapping(img, x, opts.SplitOverlap)
+			if err != nil {
+				panic("unsupported image type for splitting")
+			}
+			if ltr {
+				return []image.Image{left, right}
+			}
+			return []image.Image{right, left}
+		}
+	}
+
+	// For odd widths, the center column belongs to the right half
+	threshold := opts.WideThreshold
+	if threshold <= 0 {
+		threshold = crop.DefaultWideThreshold
+	}
+	if widepage != WidepagePolicyPreserve && crop.IsWide(img, threshold) {
+		bounds := img.Bounds()
+		left, right, err := crop.SplitOverlapping(img, bounds.Min.X+bounds.Dx()/2, opts.SplitOverlap)
 		if err != nil {
 			panic("unsupported image type for splitting")
 		}
@@ -52,3 +139,47 @@ func CropAndSplit(img image.Image, widepage WidepagePolicy, autocrop bool, ltr b
 
 	return []image.Image{img}
 }
+
+// fit scales the image down to the given maximum dimensions, preserving
+// its aspect ratio. Zero leaves the respective dimension unlimited.
+func fit(img image.Image, maxWidth, maxHeight int) image.Image {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if maxWidth > 0 && width > maxWidth {
+		width, height = maxWidth, int(float64(height)*float64(maxWidth)/float64(width))
+	}
+	if maxHeight > 0 && height > maxHeight {
+		width, height = int(float64(width)*float64(maxHeight)/float64(height)), maxHeight
+	}
+	if width == b.Dx() && height == b.Dy() {
+		return img
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, max(1, width), max(1, height)))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+
+	return dst
+}
+
+// apply composites the image onto a canvas enlarged by the margin
+func (m Margin) apply(img image.Image) image.Image {
+	b := img.Bounds()
+	x, y := m.Pixels, m.Pixels
+	if m.Percent > 0 {
+		x = int(math.Round(float64(b.Dx()) * m.Percent / 100))
+		y = int(math.Round(float64(b.Dy()) * m.Percent / 100))
+	}
+	if x <= 0 && y <= 0 {
+		return img
+	}
+	background := m.Color
+	if background == nil {
+		background = color.White
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, b.Dx()+2*x, b.Dy()+2*y))
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	draw.Draw(canvas, b.Sub(b.Min).Add(image.Pt(x, y)), img, b.Min, draw.Over)
+
+	return canvas
+}
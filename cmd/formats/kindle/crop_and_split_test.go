@@ -0,0 +1,198 @@
+package kindle
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// widePage returns a wide page with a red left half and blue right half
+func widePage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 400; x++ {
+			if x < 200 {
+				img.Set(x, y, color.RGBA{255, 0, 0, 255})
+			} else {
+				img.Set(x, y, color.RGBA{0, 0, 255, 255})
+			}
+		}
+	}
+
+	return img
+}
+
+func TestCropAndSplitWithOptions(t *testing.T) {
+	red := color.RGBAModel.Convert(color.RGBA{255, 0, 0, 255})
+	blue := color.RGBAModel.Convert(color.RGBA{0, 0, 255, 255})
+
+	for _, tc := range []struct {
+		name    string
+		opts    PageOptions
+		sizes   []image.Point
+		centers []color.Color
+	}{
+		{"preserve", PageOptions{}, []image.Point{{400, 200}}, nil},
+		{"split right-to-left", PageOptions{Policy: WidepagePolicySplit}, []image.Point{{200, 200}, {200, 200}}, []color.Color{blue, red}},
+		{"split left-to-right", PageOptions{Policy: WidepagePolicySplit, LeftToRight: true}, []image.Point{{200, 200}, {200, 200}}, []color.Color{red, blue}},
+		{"max width", PageOptions{MaxWidth: 100}, []image.Point{{100, 50}}, nil},
+		{"max height", PageOptions{MaxHeight: 100}, []image.Point{{200, 100}}, nil},
+		{"max both", PageOptions{MaxWidth: 200, MaxHeight: 50}, []image.Point{{100, 50}}, nil},
+		{"split and scale", PageOptions{Policy: WidepagePolicySplit, MaxHeight: 100}, []image.Point{{100, 100}, {100, 100}}, []color.Color{blue, red}},
+		{"margin after scaling", PageOptions{MaxWidth: 100, Margin: Margin{Pixels: 10}}, []image.Point{{120, 70}}, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pages := CropAndSplitWithOptions(widePage(), tc.opts)
+			if len(pages) != len(tc.sizes) {
+				t.Fatalf("expected %v pages, got %v", len(tc.sizes), len(pages))
+			}
+			for i, page := range pages {
+				if size := page.Bounds().Size(); size != tc.sizes[i] {
+					t.Errorf("page %v: expected size %v, got %v", i, tc.sizes[i], size)
+				}
+				if tc.centers == nil {
+					continue
+				}
+				b := page.Bounds()
+				center := color.RGBAModel.Convert(page.At(b.Min.X+b.Dx()/2, b.Min.Y+b.Dy()/2))
+				if center != tc.centers[i] {
+					t.Errorf("page %v: expected color %v, got %v", i, tc.centers[i], center)
+				}
+			}
+		})
+	}
+}
+
+func TestCropAndSplitLegacy(t *testing.T) {
+	// Pages are described by their width and the color of their center
+	for _, tc := range []struct {
+		policy   WidepagePolicy
+		ltr      bool
+		expected []string
+	}{
+		{WidepagePolicyPreserve, false, []string{"400 blue"}},
+		{WidepagePolicySplit, false, []string{"200 blue", "200 red"}},
+		{WidepagePolicySplit, true, []string{"200 red", "200 blue"}},
+		{WidepagePolicyPreserveAndSplit, false, []string{"400 blue", "200 blue", "200 red"}},
+		{WidepagePolicySplitAndPreserve, true, []string{"200 red", "200 blue", "400 blue"}},
+	} {
+		pages := CropAndSplit(widePage(), tc.policy, true, tc.ltr)
+		described := make([]string, 0)
+		for _, page := range pages {
+			b := page.Bounds()
+			r, _, _, _ := page.At(b.Min.X+b.Dx()/2, b.Min.Y+b.Dy()/2).RGBA()
+			name := "blue"
+			if r > 0 {
+				name = "red"
+			}
+			described = append(described, fmt.Sprint(b.Dx(), " ", name))
+		}
+		if fmt.Sprint(described) != fmt.Sprint(tc.expected) {
+			t.Errorf("policy %v, ltr %v: expected %v, got %v", tc.policy, tc.ltr, tc.expected, described)
+		}
+	}
+}
+
+// stripedPage returns a wide page with horizontal stripes, leaving the
+// columns of the given gutter white
+func stripedPage(gutter image.Rectangle) image.Image {
+	img := image.NewGray(image.Rect(0, 0, 400, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 400; x++ {
+			if (y/10)%2 == 0 || image.Pt(x, y).In(gutter) {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+
+	return img
+}
+
+func TestSplitGutters(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		gutter image.Rectangle
+		policy WidepagePolicy
+		ltr    bool
+		widths []int
+	}{
+		{"stitched right-to-left", image.Rect(190, 0, 220, 200), WidepagePolicyPreserve, false, []int{195, 205}},
+		{"stitched left-to-right", image.Rect(190, 0, 220, 200), WidepagePolicyPreserve, true, []int{205, 195}},
+		{"stitched pages are not preserved", image.Rect(190, 0, 220, 200), WidepagePolicyPreserveAndSplit, true, []int{205, 195}},
+		{"spread without gutter", image.Rectangle{}, WidepagePolicyPreserve, false, []int{400}},
+		{"spread with partial gutter", image.Rect(190, 0, 220, 150), WidepagePolicyPreserve, false, []int{400}},
+		{"gutter far from the center", image.Rect(20, 0, 60, 200), WidepagePolicyPreserve, false, []int{400}},
+		{"blank page", image.Rect(190, 0, 400, 200), WidepagePolicyPreserve, false, []int{400}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pages := CropAndSplitWithOptions(stripedPage(tc.gutter), PageOptions{
+				Policy:       tc.policy,
+				LeftToRight:  tc.ltr,
+				SplitGutters: true,
+			})
+			widths := make([]int, 0)
+			for _, page := range pages {
+				widths = append(widths, page.Bounds().Dx())
+			}
+			if fmt.Sprint(widths) != fmt.Sprint(tc.widths) {
+				t.Errorf("expected pages of widths %v, got %v", tc.widths, widths)
+			}
+		})
+	}
+}
+
+func TestSplitOverlap(t *testing.T) {
+	// The center column of odd widths always belongs to the right half
+	img := image.NewRGBA(image.Rect(0, 0, 401, 200))
+	for _, tc := range []struct {
+		ltr     bool
+		overlap int
+		halves  []image.Rectangle
+	}{
+		{false, 0, []image.Rectangle{image.Rect(200, 0, 401, 200), image.Rect(0, 0, 200, 200)}},
+		{true, 0, []image.Rectangle{image.Rect(0, 0, 200, 200), image.Rect(200, 0, 401, 200)}},
+		{false, 8, []image.Rectangle{image.Rect(192, 0, 401, 200), image.Rect(0, 0, 208, 200)}},
+		{true, 8, []image.Rectangle{image.Rect(0, 0, 208, 200), image.Rect(192, 0, 401, 200)}},
+		{true, 1000, []image.Rectangle{image.Rect(0, 0, 401, 200), image.Rect(0, 0, 401, 200)}},
+	} {
+		pages := CropAndSplitWithOptions(img, PageOptions{
+			Policy:       WidepagePolicySplit,
+			LeftToRight:  tc.ltr,
+			SplitOverlap: tc.overlap,
+		})
+		halves := make([]image.Rectangle, 0)
+		for _, page := range pages {
+			halves = append(halves, page.Bounds())
+		}
+		if fmt.Sprint(halves) != fmt.Sprint(tc.halves) {
+			t.Errorf("ltr %v, overlap %v: expected halves %v, got %v", tc.ltr, tc.overlap, tc.halves, halves)
+		}
+	}
+}
+
+func TestWideThreshold(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		width     int
+		threshold float64
+		pages     int
+	}{
+		{"below default", 119, 0, 1},
+		{"at default", 120, 0, 1},
+		{"above default", 121, 0, 2},
+		{"below custom", 149, 1.5, 1},
+		{"above custom", 151, 1.5, 2},
+		{"portrait above lowered", 91, 0.9, 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pages := CropAndSplitWithOptions(image.NewGray(image.Rect(0, 0, tc.width, 100)), PageOptions{
+				Policy:        WidepagePolicySplit,
+				WideThreshold: tc.threshold,
+			})
+			if len(pages) != tc.pages {
+				t.Errorf("%vx100 with threshold %v: expected %v pages, got %v", tc.width, tc.threshold, tc.pages, len(pages))
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package kindle
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// GrayLevels is the number of gray levels shown by Kindle e-ink displays.
+const GrayLevels = 16
+
+var grayPalette = func() color.Palette {
+	palette := make(color.Palette, GrayLevels)
+	for i := range palette {
+		palette[i] = color.Gray{Y: uint8(i * 255 / (GrayLevels - 1))}
+	}
+	return palette
+}()
+
+// Dither converts a page to the gray levels of Kindle displays using
+// Floyd–Steinberg error diffusion, which avoids visible banding in
+// gradients compared to rounding every pixel to the nearest level.
+func Dither(img image.Image) *image.Gray {
+	b := img.Bounds()
+	paletted := image.NewPaletted(b, grayPalette)
+	draw.FloydSteinberg.Draw(paletted, b, img, b.Min)
+
+	gray := image.NewGray(b)
+	draw.Draw(gray, b, paletted, b.Min, draw.Src)
+
+	return gray
+}
@@ -0,0 +1,76 @@
+package kindle
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// transitions counts horizontally adjacent pixels with different values
+func transitions(img *image.Gray) int {
+	count := 0
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X + 1; x < b.Max.X; x++ {
+			if img.GrayAt(x, y) != img.GrayAt(x-1, y) {
+				count++
+			}
+		}
+	}
+
+	return count
+}
+
+func TestDither(t *testing.T) {
+	gradient := image.NewRGBA(image.Rect(0, 0, 256, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 256; x++ {
+			gradient.Set(x, y, color.RGBA{uint8(x), uint8(x), uint8(x), 255})
+		}
+	}
+
+	// Rounding every pixel to the nearest level produces bands
+	quantized := image.NewGray(gradient.Bounds())
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 256; x++ {
+			quantized.Set(x, y, grayPalette.Convert(gradient.At(x, y)))
+		}
+	}
+
+	dithered := Dither(gradient)
+	levels := make(map[uint8]bool)
+	for _, v := range dithered.Pix {
+		levels[v] = true
+	}
+	if len(levels) > GrayLevels {
+		t.Errorf("expected at most %v gray levels, got %v", GrayLevels, len(levels))
+	}
+	if plain, diffused := transitions(quantized), transitions(dithered); diffused < 4*plain {
+		t.Errorf("expected error diffusion, got %v transitions compared to %v", diffused, plain)
+	}
+
+	// Error diffusion preserves the average brightness of each region
+	for x := 0; x < 256; x += 32 {
+		sum := 0
+		for y := 0; y < 16; y++ {
+			for dx := 0; dx < 32; dx++ {
+				sum += int(dithered.GrayAt(x+dx, y).Y)
+			}
+		}
+		if average, expected := sum/(16*32), x+16; average < expected-4 || average > expected+4 {
+			t.Errorf("expected average brightness near %v at %v, got %v", expected, x, average)
+		}
+	}
+}
+
+func TestGenerateMOBIDither(t *testing.T) {
+	book := GenerateMOBIWithOptions(createTestManga(), WidepagePolicyPreserve, false, true, Options{Dither: true})
+	if len(book.Images) == 0 {
+		t.Fatal("MOBI book has no images")
+	}
+	for i, img := range book.Images {
+		if _, ok := img.(*image.Gray); !ok {
+			t.Errorf("image %v is not dithered to grayscale: %T", i, img)
+		}
+	}
+}
@@ -15,11 +15,24 @@ import (
 	md "github.com/leotaku/kojirou/mangadex"
 )
 
+// NormalizedDirectory determines where generated books are written.
+//
+// In kindle folder mode, the target is treated as the root of a Kindle
+// mounted over USB. Books are written to documents/<series>/ and cover
+// thumbnails for MOBI files to system/thumbnails/, where the device looks
+// for them when displaying its library. Reading positions are stored by the
+// device itself in .sdr sidecar folders next to each book, which are never
+// written or removed here.
 type NormalizedDirectory struct {
 	bookDirectory      string
 	thumbnailDirectory string
+	title              string
+	volumeFolders      bool
 }
 
+// NewNormalizedDirectory creates a directory for the given target and series
+// title. An empty target writes into a folder named after the series, or
+// into a local "kindle" folder in kindle folder mode.
 func NewNormalizedDirectory(target, title string, kindleFolder bool) NormalizedDirectory {
 	title = util.SanitizePOSIXName(title)
 	title = strings.ReplaceAll(title, ":", "_")
@@ -33,29 +46,77 @@ func NewNormalizedDirectory(target, title string, kindleFolder bool) NormalizedD
 		return NormalizedDirectory{
 			bookDirectory:      path.Join("kindle", "documents", title),
 			thumbnailDirectory: path.Join("kindle", "system", "thumbnails"),
+			title:              title,
 		}
 	case kindleFolder:
 		return NormalizedDirectory{
 			bookDirectory:      path.Join(target, "documents", title),
 			thumbnailDirectory: path.Join(target, "system", "thumbnails"),
+			title:              title,
 		}
 	case target == "":
 		return NormalizedDirectory{
 			bookDirectory: title,
+			title:         title,
 		}
 	default:
 		return NormalizedDirectory{
 			bookDirectory: target,
+			title:         title,
 		}
 	}
 }
 
+// Validate checks that the directory layout can be written. In kindle folder
+// mode, the target must look like a Kindle root, which means existing
+// documents and system entries have to be folders.
+func (n *NormalizedDirectory) Validate() error {
+	if n.thumbnailDirectory == "" {
+		return nil
+	}
+
+	root := path.Dir(path.Dir(n.thumbnailDirectory))
+	documents := path.Join(root, "documents")
+	if !strings.HasPrefix(n.bookDirectory, documents+"/") {
+		return fmt.Errorf("kindle: books are not written into '%v'", documents)
+	}
+	for _, dir := range []string{root, documents, path.Join(root, "system"), n.thumbnailDirectory} {
+		if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+			return fmt.Errorf("kindle: '%v' is not a directory", dir)
+		} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("kindle: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// WithVolumeFolders returns a copy of the directory that places the files
+// of every book into its own subfolder of the series folder, resulting in
+// paths like <out>/<series>/<volume>/<volume>.epub.
+func (n NormalizedDirectory) WithVolumeFolders() NormalizedDirectory {
+	if n.bookDirectory != "" && path.Base(n.bookDirectory) != n.title {
+		n.bookDirectory = path.Join(n.bookDirectory, n.title)
+	}
+	n.volumeFolders = true
+
+	return n
+}
+
+// filename returns the path of the book with the given base name and extension
+func (n *NormalizedDirectory) filename(name string, extension string) string {
+	if n.volumeFolders {
+		return path.Join(n.bookDirectory, name, name+"."+extension)
+	}
+	return path.Join(n.bookDirectory, name+"."+extension)
+}
+
 func (n *NormalizedDirectory) Has(identifier md.Identifier) bool {
 	// Check for any supported format
-	exts := []string{".azw3", ".epub", ".kepub.epub"}
-	base := identifier.StringFilled(4, 2, false)
+	exts := []string{"azw3", "epub", "kepub.epub"}
+	base := identifier.StringFilled(4, 0, false)
 	for _, ext := range exts {
-		if exists(path.Join(n.bookDirectory, base+ext)) {
+		if exists(n.filename(base, ext)) {
 			return true
 		}
 	}
@@ -64,27 +125,41 @@ func (n *NormalizedDirectory) Has(identifier md.Identifier) bool {
 
 // HasWithExtension checks if a file with the specified identifier and extension exists
 func (n *NormalizedDirectory) HasWithExtension(identifier md.Identifier, extension string) bool {
-	filename := identifier.StringFilled(4, 2, false) + "." + extension
-	return exists(path.Join(n.bookDirectory, filename))
+	return n.HasNamedWithExtension(identifier.StringFilled(4, 0, false), extension)
+}
+
+// HasNamedWithExtension checks if a file with the specified base name and extension exists
+func (n *NormalizedDirectory) HasNamedWithExtension(name string, extension string) bool {
+	return exists(n.filename(name, extension))
 }
 
 // Path returns the normalized path for a volume with the given identifier and extension
 func (n *NormalizedDirectory) Path(identifier md.Identifier, extension string) string {
+	return n.PathNamed(identifier.StringFilled(4, 0, false), extension)
+}
+
+// PathNamed returns the normalized path for a book with the given base name and extension
+func (n *NormalizedDirectory) PathNamed(name string, extension string) string {
 	if n.bookDirectory == "" {
 		return ""
 	}
-	filename := identifier.StringFilled(4, 2, false) + "." + extension
-	return path.Join(n.bookDirectory, filename)
+	return n.filename(name, extension)
 }
 
 // WriteFormat writes the output to the appropriate file based on its extension
 func (n *NormalizedDirectory) WriteFormat(identifier md.Identifier, out output.FormatOutput, p progress.Progress) error {
+	return n.WriteNamedFormat(identifier.StringFilled(4, 0, false), out, p)
+}
+
+// WriteNamedFormat writes the output to a file with the given base name
+// and the extension of the output format
+func (n *NormalizedDirectory) WriteNamedFormat(name string, out output.FormatOutput, p progress.Progress) error {
 	if n.bookDirectory == "" {
 		return fmt.Errorf("unsupported configuration: no book output")
 	}
 
 	// Get the path for this format
-	filepath := n.Path(identifier, out.Extension())
+	filepath := n.PathNamed(name, out.Extension())
 
 	f, err := create(filepath)
 	if err != nil {
@@ -92,12 +167,7 @@ func (n *NormalizedDirectory) WriteFormat(identifier md.Identifier, out output.F
 	}
 	defer f.Close()
 
-	data, err := out.GetBytes()
-	if err != nil {
-		return fmt.Errorf("get bytes: %w", err)
-	}
-
-	if _, err := p.NewProxyWriter(f).Write(data); err != nil {
+	if _, err := out.WriteTo(p.NewProxyWriter(f)); err != nil {
 		return fmt.Errorf("write: %w", err)
 	}
 
@@ -105,13 +175,17 @@ func (n *NormalizedDirectory) WriteFormat(identifier md.Identifier, out output.F
 	if mobi, ok := out.(*output.MobiOutput); ok && n.thumbnailDirectory != "" {
 		coverImage := mobi.GetCoverImage()
 		if coverImage != nil {
+			thumb, err := thumbnail(coverImage)
+			if err != nil {
+				return fmt.Errorf("thumbnail: %w", err)
+			}
 			f, err := create(path.Join(n.thumbnailDirectory, mobi.GetThumbFilename()))
 			if err != nil {
 				return fmt.Errorf("create thumbnail: %w", err)
 			}
 			defer f.Close()
 
-			if err := jpeg.Encode(p.NewProxyWriter(f), coverImage, nil); err != nil {
+			if err := jpeg.Encode(p.NewProxyWriter(f), thumb, nil); err != nil {
 				return fmt.Errorf("write thumbnail: %w", err)
 			}
 		}
@@ -139,10 +213,10 @@ func (n *NormalizedDirectory) WriteMobi(identifier md.Identifier, mobi *output.M
 func (n *NormalizedDirectory) GetExistingFormats(identifier md.Identifier) map[string]string {
 	result := make(map[string]string)
 	exts := []string{"azw3", "epub", "kepub.epub"}
-	base := identifier.StringFilled(4, 2, false)
+	base := identifier.StringFilled(4, 0, false)
 
 	for _, ext := range exts {
-		filepath := path.Join(n.bookDirectory, base+"."+ext)
+		filepath := n.filename(base, ext)
 		if exists(filepath) {
 			result[ext] = filepath
 		}
@@ -1,11 +1,15 @@
 package kindle
 
 import (
+	"image"
+	"image/jpeg"
 	"os"
 	"path"
 	"strings"
 	"testing"
 
+	"github.com/leotaku/kojirou/cmd/formats/output"
+	"github.com/leotaku/kojirou/cmd/formats/progress"
 	md "github.com/leotaku/kojirou/mangadex"
 )
 
@@ -21,19 +25,19 @@ func TestNormalizedDirectoryPath(t *testing.T) {
 
 	// Test Path method with different extensions
 	epubPath := dir.Path(identifier, "epub")
-	expectedEpubPath := path.Join(testDir, "0001.05.epub")
+	expectedEpubPath := path.Join(testDir, "0001.5.epub")
 	if epubPath != expectedEpubPath {
 		t.Errorf("Path for EPUB incorrect, got: %s, want: %s", epubPath, expectedEpubPath)
 	}
 
 	kepubPath := dir.Path(identifier, "kepub.epub")
-	expectedKepubPath := path.Join(testDir, "0001.05.kepub.epub")
+	expectedKepubPath := path.Join(testDir, "0001.5.kepub.epub")
 	if kepubPath != expectedKepubPath {
 		t.Errorf("Path for KEPUB incorrect, got: %s, want: %s", kepubPath, expectedKepubPath)
 	}
 
 	mobiPath := dir.Path(identifier, "azw3")
-	expectedMobiPath := path.Join(testDir, "0001.05.azw3")
+	expectedMobiPath := path.Join(testDir, "0001.5.azw3")
 	if mobiPath != expectedMobiPath {
 		t.Errorf("Path for MOBI incorrect, got: %s, want: %s", mobiPath, expectedMobiPath)
 	}
@@ -50,7 +54,7 @@ func TestHasWithExtension(t *testing.T) {
 	identifier := md.NewIdentifier("1.5")
 
 	// Create test file
-	epubPath := path.Join(testDir, "0001.05.epub")
+	epubPath := path.Join(testDir, "0001.5.epub")
 	err := os.WriteFile(epubPath, []byte("test"), 0644)
 	if err != nil {
 		t.Fatalf("Failed to create test file: %v", err)
@@ -81,12 +85,12 @@ func TestGetExistingFormats(t *testing.T) {
 	identifier := md.NewIdentifier("1.5")
 
 	// Create test files
-	epubPath := path.Join(testDir, "0001.05.epub")
+	epubPath := path.Join(testDir, "0001.5.epub")
 	if err := os.WriteFile(epubPath, []byte("epub test"), 0644); err != nil {
 		t.Fatalf("Failed to create EPUB test file: %v", err)
 	}
 
-	kepubPath := path.Join(testDir, "0001.05.kepub.epub")
+	kepubPath := path.Join(testDir, "0001.5.kepub.epub")
 	if err := os.WriteFile(kepubPath, []byte("kepub test"), 0644); err != nil {
 		t.Fatalf("Failed to create KEPUB test file: %v", err)
 	}
@@ -142,3 +146,101 @@ func TestPOSIXComplianceForKindlePaths(t *testing.T) {
 		t.Errorf("bookDirectory base is reserved or empty: %s", bookDirBase)
 	}
 }
+
+func TestVolumeFolders(t *testing.T) {
+	testDir := t.TempDir()
+	dir := NewNormalizedDirectory(testDir, "Test Manga", false).WithVolumeFolders()
+	identifier := md.NewIdentifier("2")
+
+	want := path.Join(testDir, "Test_Manga", "0002", "0002.epub")
+	if got := dir.Path(identifier, "epub"); got != want {
+		t.Errorf("Path in volume folder incorrect, got: %s, want: %s", got, want)
+	}
+	if dir.HasWithExtension(identifier, "epub") {
+		t.Error("HasWithExtension reported missing file")
+	}
+
+	if err := os.MkdirAll(path.Dir(want), 0755); err != nil {
+		t.Fatalf("Failed to create volume folder: %v", err)
+	}
+	if err := os.WriteFile(want, []byte("test"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if !dir.HasWithExtension(identifier, "epub") || !dir.Has(identifier) {
+		t.Error("skip detection does not find file in volume folder")
+	}
+	if got := dir.GetExistingFormats(identifier)["epub"]; got != want {
+		t.Errorf("GetExistingFormats incorrect, got: %s, want: %s", got, want)
+	}
+
+	// The series folder is not repeated when it is already the target
+	dir = NewNormalizedDirectory("", "Test Manga", false).WithVolumeFolders()
+	if got := dir.Path(identifier, "azw3"); got != path.Join("Test_Manga", "0002", "0002.azw3") {
+		t.Errorf("Path without target incorrect, got: %s", got)
+	}
+}
+
+func TestValidateKindleLayout(t *testing.T) {
+	testDir := t.TempDir()
+	dir := NewNormalizedDirectory(testDir, "Test Manga", true)
+	if err := dir.Validate(); err != nil {
+		t.Errorf("Validate failed for empty Kindle root: %v", err)
+	}
+
+	if err := os.WriteFile(path.Join(testDir, "documents"), []byte("test"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := dir.Validate(); err == nil {
+		t.Error("Validate accepted documents file in Kindle root")
+	}
+
+	dir = NewNormalizedDirectory(testDir, "Test Manga", false)
+	if err := dir.Validate(); err != nil {
+		t.Errorf("Validate failed outside of kindle folder mode: %v", err)
+	}
+}
+
+func TestKindleThumbnail(t *testing.T) {
+	testDir := t.TempDir()
+	dir := NewNormalizedDirectory(testDir, "Test Manga", true)
+
+	manga := createTestManga()
+	book := GenerateMOBI(manga, WidepagePolicyPreserve, false, true)
+	err := dir.WriteFormat(md.NewIdentifier("1"), &output.MobiOutput{Book: &book}, progress.VanishingProgress("Test"))
+	if err != nil {
+		t.Fatalf("WriteFormat failed: %v", err)
+	}
+
+	if !exists(path.Join(testDir, "documents", "Test_Manga", "0001.azw3")) {
+		t.Error("MOBI not written to documents folder")
+	}
+
+	if book.UniqueID != mangaToUniqueID(manga) {
+		t.Errorf("thumbnail is not named after unique ID of manga")
+	}
+	f, err := os.Open(path.Join(testDir, "system", "thumbnails", book.GetThumbFilename()))
+	if err != nil {
+		t.Fatalf("Failed to open thumbnail: %v", err)
+	}
+	defer f.Close()
+	cfg, err := jpeg.DecodeConfig(f)
+	if err != nil {
+		t.Fatalf("Failed to decode thumbnail: %v", err)
+	}
+	// The 1000x1500 test cover keeps its aspect ratio
+	if cfg.Width != ThumbnailWidth || cfg.Height != 495 {
+		t.Errorf("Thumbnail has size %vx%v, want %vx495", cfg.Width, cfg.Height, ThumbnailWidth)
+	}
+}
+
+func TestKindleThumbnailEmptyCover(t *testing.T) {
+	for _, bounds := range []image.Rectangle{
+		image.Rect(0, 0, 0, 100),
+		image.Rect(0, 0, 100, 0),
+		image.Rect(0, 0, 0, 0),
+	} {
+		if _, err := thumbnail(image.NewRGBA(bounds)); err == nil {
+			t.Errorf("thumbnail of %v cover succeeded", bounds)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package kindle
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// AutoLevels stretches the tonal range of a page, so that its darkest and
+// lightest values become full black and white, which improves faded scans.
+//
+// The given percentage of pixels is clipped at either end of the range,
+// so that a few stray specks do not prevent stretching, while larger
+// values stretch more aggressively. Pages that already span the full
+// range are returned unchanged.
+func AutoLevels(img image.Image, clip float64) image.Image {
+	b := img.Bounds()
+	histogram := [256]int{}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			histogram[color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y]++
+		}
+	}
+
+	limit := int(float64(b.Dx()*b.Dy()) * clip / 100)
+	low, high := 0, 255
+	for count := 0; low < 255; low++ {
+		if count += histogram[low]; count > limit {
+			break
+		}
+	}
+	for count := 0; high > 0; high-- {
+		if count += histogram[high]; count > limit {
+			break
+		}
+	}
+	if high <= low || (low == 0 && high == 255) {
+		return img
+	}
+
+	levels := [256]uint8{}
+	for v := range levels {
+		stretched := math.Round(float64(v-low) * 255 / float64(high-low))
+		levels[v] = uint8(min(max(stretched, 0), 255))
+	}
+
+	// Grayscale pages stay grayscale, others are stretched per channel
+	if gray, ok := img.(*image.Gray); ok {
+		dst := image.NewGray(b)
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				dst.SetGray(x, y, color.Gray{Y: levels[gray.GrayAt(x, y).Y]})
+			}
+		}
+		return dst
+	}
+
+	dst := image.NewNRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			dst.SetNRGBA(x, y, color.NRGBA{levels[c.R], levels[c.G], levels[c.B], c.A})
+		}
+	}
+
+	return dst
+}
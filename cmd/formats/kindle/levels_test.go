@@ -0,0 +1,57 @@
+package kindle
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func grayRange(img image.Image) (low, high uint8) {
+	low, high = 255, 0
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			v := color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y
+			low, high = min(low, v), max(high, v)
+		}
+	}
+
+	return low, high
+}
+
+func TestAutoLevels(t *testing.T) {
+	// A faded scan with values between 100 and 150
+	faded := image.NewGray(image.Rect(0, 0, 100, 100))
+	for i := range faded.Pix {
+		faded.Pix[i] = uint8(100 + i%51)
+	}
+
+	stretched := AutoLevels(faded, 0)
+	if low, high := grayRange(stretched); low != 0 || high != 255 {
+		t.Errorf("expected range 0-255, got %v-%v", low, high)
+	}
+	if _, ok := stretched.(*image.Gray); !ok {
+		t.Errorf("expected grayscale output, got %T", stretched)
+	}
+
+	// Stray specks are clipped when stretching
+	specked := image.NewGray(faded.Rect)
+	copy(specked.Pix, faded.Pix)
+	specked.Pix[0], specked.Pix[1] = 0, 255
+	if low, high := grayRange(AutoLevels(specked, 0)); low != 0 || high != 255 {
+		t.Errorf("expected range 0-255, got %v-%v", low, high)
+	}
+	clipped := AutoLevels(specked, 1).(*image.Gray)
+	if v := clipped.Pix[faded.PixOffset(10, 0)]; v != 255*10/50 {
+		t.Errorf("expected specks to be clipped, got %v for 110", v)
+	}
+
+	// Scans with full range are left alone
+	good := image.NewGray(image.Rect(0, 0, 256, 1))
+	for i := range good.Pix {
+		good.Pix[i] = uint8(i)
+	}
+	if AutoLevels(good, 0) != image.Image(good) {
+		t.Errorf("expected full range scan to be unchanged")
+	}
+}
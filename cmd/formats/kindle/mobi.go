@@ -32,18 +32,47 @@ img {
 
 var pageTemplate = template.Must(template.New("page").Parse(pageTemplateString))
 
+// Options configures optional aspects of MOBI generation.
+//
+// The zero value generates the same output as GenerateMOBI.
+type Options struct {
+	// Pages configures how pages are cropped, split, scaled and padded.
+	// Its policy, cropping and reading direction are overridden by the
+	// arguments of GenerateMOBIWithOptions.
+	Pages PageOptions
+	// Dither converts pages to the gray levels of Kindle displays using
+	// error diffusion.
+	Dither bool
+	// PageNumbers draws page numbers onto every page.
+	PageNumbers PageNumbers
+}
+
 func GenerateMOBI(manga mangadex.Manga, widepage WidepagePolicy, crop bool, ltr bool) mobi.Book {
+	return GenerateMOBIWithOptions(manga, widepage, crop, ltr, Options{})
+}
+
+// GenerateMOBIWithOptions creates a MOBI book from manga data like
+// GenerateMOBI, but allows customizing the output with the given options.
+func GenerateMOBIWithOptions(manga mangadex.Manga, widepage WidepagePolicy, crop bool, ltr bool, opts Options) mobi.Book {
 	chapters := make([]mobi.Chapter, 0)
 	images := make([]image.Image, 0)
 	pageImageIndex := 1
+	opts.Pages.Policy, opts.Pages.AutoCrop, opts.Pages.LeftToRight = widepage, crop, ltr
 
 	groupNames := make([]string, 0)
 	for _, vol := range manga.Sorted() {
 		for _, chap := range vol.Sorted() {
 			groupNames = append(groupNames, chap.Info.GroupNames...)
+			chapterStart := len(images)
 			pages := make([]string, 0)
 			for _, img := range chap.Sorted() {
-				images = append(images, CropAndSplit(img, widepage, crop, ltr)...)
+				for _, page := range CropAndSplitWithOptions(img, opts.Pages) {
+					page = opts.PageNumbers.Apply(page, len(images)+1, len(images)-chapterStart+1)
+					if opts.Dither {
+						page = Dither(page)
+					}
+					images = append(images, page)
+				}
 				pages = append(pages, templateToString(pageTemplate, records.To32(pageImageIndex)))
 				pageImageIndex++
 			}
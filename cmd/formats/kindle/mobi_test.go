@@ -1,8 +1,10 @@
 package kindle
 
 import (
+	"fmt"
 	"image"
 	"image/color"
+	"slices"
 	"strings"
 	"testing"
 
@@ -153,6 +155,59 @@ func TestGenerateMOBI(t *testing.T) {
 	}
 }
 
+// TestGenerateMOBIDeterministic tests that volumes, chapters and pages
+// keep their reading order regardless of map iteration order
+func TestGenerateMOBIDeterministic(t *testing.T) {
+	languages := []language.Tag{language.Japanese, language.English, language.French}
+	chapters := make(md.ChapterList, 0)
+	for v := 1; v <= 3; v++ {
+		for c := 1; c <= 3; c++ {
+			pages := make(map[int]image.Image)
+			for p := 0; p < 3; p++ {
+				// Page widths encode their position in the reading order
+				pages[p] = createTestImage(100+(v*9+c*3+p), 150, color.White)
+			}
+			chapters = append(chapters, md.Chapter{
+				Info: md.ChapterInfo{
+					Title:            fmt.Sprintf("Chapter %v-%v", v, c),
+					Identifier:       md.NewIdentifier(fmt.Sprint((v-1)*3 + c)),
+					VolumeIdentifier: md.NewIdentifier(fmt.Sprint(v)),
+					Language:         languages[v-1],
+				},
+				Pages: pages,
+			})
+		}
+	}
+	manga := md.Manga{Info: md.MangaInfo{Title: "Test Manga"}}.WithChapters(chapters)
+
+	var want []string
+	for range 10 {
+		book := GenerateMOBI(manga, WidepagePolicyPreserve, false, true)
+		got := []string{book.Language.String()}
+		for _, chapter := range book.Chapters {
+			got = append(got, chapter.Title)
+		}
+		for i, img := range book.Images {
+			if i > 0 && img.Bounds().Dx() <= book.Images[i-1].Bounds().Dx() {
+				t.Fatalf("image %v is out of order", i)
+			}
+			got = append(got, fmt.Sprint(img.Bounds().Dx()))
+		}
+
+		if want == nil {
+			want = got
+		} else if !slices.Equal(got, want) {
+			t.Fatalf("MOBI order differs between runs:\n%v\n%v", got, want)
+		}
+	}
+	if want[0] != language.Japanese.String() {
+		t.Errorf("language is %v, want language of the first chapter", want[0])
+	}
+	if want[1] != "1: Chapter 1-1" || want[9] != "9: Chapter 3-3" {
+		t.Errorf("chapters are out of order: %v", want[1:10])
+	}
+}
+
 // TestMOBIMetadataExtraction tests the metadata extraction functions
 func TestMOBIMetadataExtraction(t *testing.T) {
 	manga := createTestManga()
@@ -0,0 +1,95 @@
+package kindle
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Corner is a corner of a page.
+type Corner int
+
+const (
+	CornerBottomRight Corner = iota
+	CornerBottomLeft
+	CornerTopRight
+	CornerTopLeft
+)
+
+// DefaultPageNumberOpacity is the opacity of page numbers if unset.
+const DefaultPageNumberOpacity = 0.6
+
+// PageNumbers describes page numbers drawn onto page images.
+//
+// The zero value does not draw page numbers.
+type PageNumbers struct {
+	// Enabled draws the page number within the book and within the
+	// chapter, e.g. "12 (3)", onto every page.
+	Enabled bool
+	// Corner is the corner of the page that numbers are drawn in.
+	Corner Corner
+	// Opacity of the page numbers and their background, from zero to one.
+	// DefaultPageNumberOpacity is used if zero.
+	Opacity float64
+}
+
+// Apply draws the given page numbers onto a copy of the page. Pages too
+// small to fit the numbers are returned unchanged.
+func (n PageNumbers) Apply(img image.Image, page, chapterPage int) image.Image {
+	if !n.Enabled {
+		return img
+	}
+
+	// Render the label into a mask with a small padding around the text
+	face := basicfont.Face7x13
+	label := fmt.Sprintf("%d (%d)", page, chapterPage)
+	d := font.Drawer{Face: face, Src: image.Opaque}
+	mask := image.NewAlpha(image.Rect(0, 0, d.MeasureString(label).Ceil()+4, face.Height+4))
+	d.Dst, d.Dot = mask, fixed.P(2, 2+face.Ascent)
+	d.DrawString(label)
+
+	// Scale the label with the page, so it stays legible on large pages
+	b := img.Bounds()
+	scale := max(1, b.Dx()/400)
+	size := mask.Bounds().Size().Mul(scale)
+	gap := 2 * scale
+	var at image.Point
+	switch n.Corner {
+	case CornerTopLeft:
+		at = image.Pt(b.Min.X+gap, b.Min.Y+gap)
+	case CornerTopRight:
+		at = image.Pt(b.Max.X-gap-size.X, b.Min.Y+gap)
+	case CornerBottomLeft:
+		at = image.Pt(b.Min.X+gap, b.Max.Y-gap-size.Y)
+	default:
+		at = image.Pt(b.Max.X-gap-size.X, b.Max.Y-gap-size.Y)
+	}
+	r := image.Rectangle{Min: at, Max: at.Add(size)}
+	if !r.In(b) {
+		return img
+	}
+
+	opacity := n.Opacity
+	if opacity == 0 {
+		opacity = DefaultPageNumberOpacity
+	}
+	alpha := uint8(min(max(opacity, 0), 1) * 255)
+	scaled := image.NewAlpha(image.Rectangle{Max: size})
+	xdraw.NearestNeighbor.Scale(scaled, scaled.Bounds(), mask, mask.Bounds(), draw.Src, nil)
+	for i, a := range scaled.Pix {
+		scaled.Pix[i] = uint8(int(a) * int(alpha) / 255)
+	}
+
+	result := image.NewRGBA(b)
+	draw.Draw(result, b, img, b.Min, draw.Src)
+	draw.DrawMask(result, r, image.White, image.Point{}, image.NewUniform(color.Alpha{A: alpha}), image.Point{}, draw.Over)
+	draw.DrawMask(result, r, image.Black, image.Point{}, scaled, image.Point{}, draw.Over)
+
+	return result
+}
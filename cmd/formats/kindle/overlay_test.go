@@ -0,0 +1,96 @@
+package kindle
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// changedBounds returns the bounds of all pixels that differ between the
+// given images
+func changedBounds(a, b image.Image) image.Rectangle {
+	changed := image.Rectangle{}
+	for y := a.Bounds().Min.Y; y < a.Bounds().Max.Y; y++ {
+		for x := a.Bounds().Min.X; x < a.Bounds().Max.X; x++ {
+			r1, g1, b1, _ := a.At(x, y).RGBA()
+			r2, g2, b2, _ := b.At(x, y).RGBA()
+			if r1 != r2 || g1 != g2 || b1 != b2 {
+				changed = changed.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+
+	return changed
+}
+
+func TestPageNumbers(t *testing.T) {
+	page := createTestImage(800, 1200, color.White)
+
+	if got := (PageNumbers{}).Apply(page, 12, 3); got != page {
+		t.Error("disabled page numbers changed the page")
+	}
+
+	for _, tc := range []struct {
+		corner Corner
+		region image.Rectangle
+	}{
+		{CornerBottomRight, image.Rect(600, 1100, 800, 1200)},
+		{CornerBottomLeft, image.Rect(0, 1100, 200, 1200)},
+		{CornerTopRight, image.Rect(600, 0, 800, 100)},
+		{CornerTopLeft, image.Rect(0, 0, 200, 100)},
+	} {
+		numbered := PageNumbers{Enabled: true, Corner: tc.corner}.Apply(page, 12, 3)
+		if numbered.Bounds() != page.Bounds() {
+			t.Fatalf("corner %v: bounds changed to %v", tc.corner, numbered.Bounds())
+		}
+		changed := changedBounds(page, numbered)
+		if changed.Empty() {
+			t.Errorf("corner %v: page is unchanged", tc.corner)
+		} else if !changed.In(tc.region) {
+			t.Errorf("corner %v: changed region %v is outside of %v", tc.corner, changed, tc.region)
+		}
+	}
+
+	// Lower opacity darkens the text less
+	dark := PageNumbers{Enabled: true, Opacity: 1}.Apply(page, 12, 3)
+	light := PageNumbers{Enabled: true, Opacity: 0.2}.Apply(page, 12, 3)
+	darkest := func(img image.Image) uint32 {
+		result := uint32(0xffff)
+		for y := 1100; y < 1200; y++ {
+			for x := 600; x < 800; x++ {
+				r, _, _, _ := img.At(x, y).RGBA()
+				result = min(result, r)
+			}
+		}
+		return result
+	}
+	if darkest(light) <= darkest(dark) {
+		t.Errorf("opacity 0.2 is not lighter than opacity 1: %v <= %v", darkest(light), darkest(dark))
+	}
+
+	// Pages too small for the numbers are left unchanged
+	tiny := createTestImage(10, 10, color.White)
+	if got := (PageNumbers{Enabled: true}).Apply(tiny, 12, 3); got != tiny {
+		t.Error("page numbers were drawn onto a page too small for them")
+	}
+}
+
+func TestGenerateMOBIPageNumbers(t *testing.T) {
+	manga := createTestManga()
+	plain := GenerateMOBI(manga, WidepagePolicyPreserve, false, true)
+	numbered := GenerateMOBIWithOptions(manga, WidepagePolicyPreserve, false, true, Options{
+		PageNumbers: PageNumbers{Enabled: true},
+	})
+
+	if len(numbered.Images) != len(plain.Images) {
+		t.Fatalf("page numbers changed the number of images from %v to %v", len(plain.Images), len(numbered.Images))
+	}
+	for i := range plain.Images {
+		changed := changedBounds(plain.Images[i], numbered.Images[i])
+		b := plain.Images[i].Bounds()
+		corner := image.Rect(b.Max.X-b.Dx()/4, b.Max.Y-b.Dy()/8, b.Max.X, b.Max.Y)
+		if changed.Empty() || !changed.In(corner) {
+			t.Errorf("image %v: changed region %v, want within %v", i, changed, corner)
+		}
+	}
+}
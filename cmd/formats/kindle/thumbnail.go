@@ -0,0 +1,30 @@
+package kindle
+
+import (
+	"fmt"
+	"image"
+
+	"golang.org/x/image/draw"
+)
+
+// ThumbnailWidth is the width of cover thumbnails shown in the Kindle library.
+const ThumbnailWidth = 330
+
+// thumbnail scales the cover to the width expected by Kindle devices for
+// their library view, preserving the aspect ratio. Empty covers have no
+// aspect ratio and are rejected.
+func thumbnail(cover image.Image) (image.Image, error) {
+	bounds := cover.Bounds()
+	if bounds.Empty() {
+		return nil, fmt.Errorf("empty cover: %v", bounds)
+	}
+	if bounds.Dx() == ThumbnailWidth {
+		return cover, nil
+	}
+
+	height := max(1, bounds.Dy()*ThumbnailWidth/bounds.Dx())
+	dst := image.NewRGBA(image.Rect(0, 0, ThumbnailWidth, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), cover, bounds, draw.Src, nil)
+
+	return dst, nil
+}
@@ -9,17 +9,74 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/leotaku/kojirou/cmd/formats"
+	"github.com/leotaku/kojirou/cmd/formats/progress"
+)
+
+// Level controls which messages are logged
+type Level int
+
+const (
+	// LevelQuiet only logs errors
+	LevelQuiet Level = iota
+	// LevelNormal additionally logs status messages
+	LevelNormal
+	// LevelVerbose additionally logs details about every step
+	LevelVerbose
+	// LevelDebug additionally logs debug information
+	LevelDebug
 )
 
 var (
-	// Log levels for format generation
-	debugMode    = false
+	// Log level for format generation
+	level        = LevelNormal
 	colorEnabled = true
 )
 
+// SetLevel sets the level of messages that are logged
+func SetLevel(l Level) {
+	level = l
+}
+
+// Enabled reports whether messages of the given level are logged
+func Enabled(l Level) bool {
+	return l <= level
+}
+
 // EnableDebug enables debug logging
 func EnableDebug(enable bool) {
-	debugMode = enable
+	if enable {
+		SetLevel(LevelDebug)
+	} else {
+		SetLevel(min(level, LevelVerbose))
+	}
+}
+
+// Errorf logs an error message regardless of the level
+func Errorf(format string, args ...any) {
+	logf(LevelQuiet, format, args...)
+}
+
+// Infof logs a status message
+func Infof(format string, args ...any) {
+	logf(LevelNormal, format, args...)
+}
+
+// Verbosef logs a detail message
+func Verbosef(format string, args ...any) {
+	logf(LevelVerbose, format, args...)
+}
+
+// Debugf logs a debug message
+func Debugf(format string, args ...any) {
+	logf(LevelDebug, format, args...)
+}
+
+// logf writes a message of the given level to standard error, without
+// corrupting any progress bars
+func logf(l Level, format string, args ...any) {
+	if Enabled(l) {
+		progress.Println(os.Stderr, fmt.Sprintf(format, args...))
+	}
 }
 
 // EnableColor enables colored output
@@ -30,24 +87,32 @@ func EnableColor(enable bool) {
 
 // FormatInfo logs information about format generation
 func FormatInfo(format formats.FormatType, message string) {
+	if !Enabled(LevelNormal) {
+		return
+	}
+
 	prefix := ""
 	if colorEnabled {
 		prefix = color.BlueString("[%s]", format)
 	} else {
 		prefix = fmt.Sprintf("[%s]", format)
 	}
-	fmt.Fprintf(os.Stderr, "%s %s\n", prefix, message)
+	progress.Println(os.Stderr, prefix+" "+message)
 }
 
 // FormatSuccess logs a successful format generation
 func FormatSuccess(format formats.FormatType, message string) {
+	if !Enabled(LevelNormal) {
+		return
+	}
+
 	prefix := ""
 	if colorEnabled {
 		prefix = color.GreenString("[%s]", format)
 	} else {
 		prefix = fmt.Sprintf("[%s]", format)
 	}
-	fmt.Fprintf(os.Stderr, "%s %s\n", prefix, message)
+	progress.Println(os.Stderr, prefix+" "+message)
 }
 
 // FormatError logs an error during format generation
@@ -58,12 +123,12 @@ func FormatError(format formats.FormatType, err error) {
 	} else {
 		prefix = fmt.Sprintf("[%s]", format)
 	}
-	fmt.Fprintf(os.Stderr, "%s Error: %v\n", prefix, err)
+	progress.Println(os.Stderr, fmt.Sprintf("%s Error: %v", prefix, err))
 }
 
 // FormatDebug logs debug information if debug mode is enabled
 func FormatDebug(format formats.FormatType, message string) {
-	if !debugMode {
+	if !Enabled(LevelDebug) {
 		return
 	}
 
@@ -73,12 +138,12 @@ func FormatDebug(format formats.FormatType, message string) {
 	} else {
 		prefix = fmt.Sprintf("[%s]", format)
 	}
-	fmt.Fprintf(os.Stderr, "%s DEBUG: %s\n", prefix, message)
+	progress.Println(os.Stderr, prefix+" DEBUG: "+message)
 }
 
 // TimedOperation executes a function and logs the time it took
 func TimedOperation(formatType formats.FormatType, operation string, fn func() error) error {
-	if debugMode {
+	if Enabled(LevelDebug) {
 		FormatDebug(formatType, fmt.Sprintf("Starting %s", operation))
 	}
 
@@ -91,7 +156,7 @@ func TimedOperation(formatType formats.FormatType, operation string, fn func() e
 		return err
 	}
 
-	if debugMode {
+	if Enabled(LevelDebug) {
 		FormatDebug(formatType, fmt.Sprintf("Completed %s in %s", operation, elapsed))
 	}
 
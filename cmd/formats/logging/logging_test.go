@@ -172,6 +172,45 @@ func TestFormatSummary(t *testing.T) {
 	}
 }
 
+func TestLevels(t *testing.T) {
+	defer SetLevel(LevelNormal)
+
+	for _, tc := range []struct {
+		level    Level
+		expected []string
+	}{
+		{LevelQuiet, []string{"error"}},
+		{LevelNormal, []string{"error", "info"}},
+		{LevelVerbose, []string{"error", "info", "verbose"}},
+		{LevelDebug, []string{"error", "info", "verbose", "debug"}},
+	} {
+		oldStderr := os.Stderr
+		r, w, _ := os.Pipe()
+		os.Stderr = w
+
+		SetLevel(tc.level)
+		Errorf("message: %v", "error")
+		Infof("message: %v", "info")
+		Verbosef("message: %v", "verbose")
+		Debugf("message: %v", "debug")
+
+		w.Close()
+		os.Stderr = oldStderr
+		out, _ := io.ReadAll(r)
+
+		lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+		if len(lines) != len(tc.expected) {
+			t.Errorf("level %v: expected %v messages, got %q", tc.level, len(tc.expected), lines)
+			continue
+		}
+		for i, expected := range tc.expected {
+			if lines[i] != "message: "+expected {
+				t.Errorf("level %v: expected %q, got %q", tc.level, "message: "+expected, lines[i])
+			}
+		}
+	}
+}
+
 // Helper function to check if a string contains a substring
 func contains(s, substr string) bool {
 	return len(s) > 0 && s != substr && strings.Contains(s, substr)
@@ -3,6 +3,7 @@ package formats
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -180,6 +181,16 @@ func (e *EPUBFormatOutput) Write() error {
 	return e.epub.Write(e.filePath)
 }
 
+// WriteTo writes the EPUB to a writer
+func (e *EPUBFormatOutput) WriteTo(w io.Writer) (int64, error) {
+	data, err := e.GetBytes()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
 // Extension returns the file extension for this format
 func (e *EPUBFormatOutput) Extension() string {
 	return "epub"
@@ -212,6 +223,16 @@ func (k *KEPUBFormatOutput) Write() error {
 	return os.WriteFile(k.filePath, data, 0644)
 }
 
+// WriteTo writes the KEPUB to a writer
+func (k *KEPUBFormatOutput) WriteTo(w io.Writer) (int64, error) {
+	data, err := k.GetBytes()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
 // Extension returns the file extension for this format
 func (k *KEPUBFormatOutput) Extension() string {
 	return "kepub.epub"
@@ -0,0 +1,33 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+)
+
+// accessibilityMetadata describes image-only manga, where all content is
+// conveyed visually. The generated alternative text of pages only names
+// them, so it is not declared as a textual alternative.
+var accessibilityMetadata = []struct{ property, content string }{
+	{"schema:accessMode", "visual"},
+	{"schema:accessModeSufficient", "visual"},
+	{"schema:accessibilityFeature", "readingOrder"},
+	{"schema:accessibilityFeature", "tableOfContents"},
+	{"schema:accessibilityHazard", "none"},
+	{"schema:accessibilitySummary", "Image-based manga without a textual alternative for page content."},
+}
+
+// injectAccessibilityMetadata adds schema.org accessibility metadata to
+// the given package document.
+func injectAccessibilityMetadata(opf string) string {
+	var insert strings.Builder
+	for _, m := range accessibilityMetadata {
+		tag := fmt.Sprintf(`<meta property="%v">%v</meta>`, m.property, m.content)
+		if !strings.Contains(opf, tag) {
+			insert.WriteString(tag)
+			insert.WriteString("\n    ")
+		}
+	}
+
+	return strings.Replace(opf, "</metadata>", insert.String()+"</metadata>", 1)
+}
@@ -0,0 +1,69 @@
+package output_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/epub"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/output"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+)
+
+func TestEpubAccessibilityMetadata(t *testing.T) {
+	manga := testhelpers.CreateSyntheticManga(1, 2, 200, 300)
+	e, cleanup, err := epub.GenerateEPUB(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true)
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		t.Fatalf("GenerateEPUB() failed: %v", err)
+	}
+
+	data, err := output.EpubOutput{Epub: e}.GetBytes()
+	if err != nil {
+		t.Fatalf("GetBytes() failed: %v", err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("failed to open EPUB: %v", err)
+	}
+	if r.File[0].Name != "mimetype" || r.File[0].Method != zip.Store {
+		t.Errorf("mimetype is not the first stored entry")
+	}
+
+	var opf string
+	for _, f := range r.File {
+		if f.Name == "EPUB/package.opf" {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatalf("failed to open OPF: %v", err)
+			}
+			content, _ := io.ReadAll(rc)
+			rc.Close()
+			opf = string(content)
+		}
+	}
+
+	for _, want := range []string{
+		`<meta property="schema:accessMode">visual</meta>`,
+		`<meta property="schema:accessModeSufficient">visual</meta>`,
+		`<meta property="schema:accessibilityFeature">readingOrder</meta>`,
+		`<meta property="schema:accessibilityFeature">tableOfContents</meta>`,
+		`<meta property="schema:accessibilityHazard">none</meta>`,
+		`<meta property="schema:accessibilitySummary">Image-based manga without a textual alternative for page content.</meta>`,
+	} {
+		if !strings.Contains(opf, want) {
+			t.Errorf("package.opf missing %v:\n%s", want, opf)
+		}
+	}
+
+	// The summary states that there is no textual alternative, so none
+	// may be declared as a feature
+	if strings.Contains(opf, "without a textual alternative") && strings.Contains(opf, ">alternativeText<") {
+		t.Errorf("package.opf declares alternative text that its summary denies:\n%s", opf)
+	}
+}
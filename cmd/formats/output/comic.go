@@ -0,0 +1,41 @@
+package output
+
+import (
+	"fmt"
+
+	"github.com/leotaku/mobi/pdb"
+	"github.com/leotaku/mobi/records"
+	t "github.com/leotaku/mobi/types"
+)
+
+// Realize converts the book into a PalmDB database like mobi.Book does,
+// adding the comic metadata that Kindle Comic Converter books carry when
+// the book is marked as a comic.
+func (m MobiOutput) Realize() pdb.Database {
+	db := m.Book.Realize()
+	if !m.Comic {
+		return db
+	}
+
+	null, ok := db.Records[0].(records.NullRecord)
+	if !ok {
+		return db
+	}
+	width, height := 0, 0
+	for _, img := range m.Images {
+		width = max(width, img.Bounds().Dx())
+		height = max(height, img.Bounds().Dy())
+	}
+	if !m.FixedLayout {
+		null.EXTHSection.AddString(t.EXTHFixedLayout, "true")
+	}
+	null.EXTHSection.AddString(t.EXTHBookType, "comic")
+	null.EXTHSection.AddString(t.EXTHOrientationLock, "portrait")
+	null.EXTHSection.AddString(t.EXTHOrigResolution, fmt.Sprintf("%vx%v", width, height))
+	null.EXTHSection.AddString(t.EXTHZeroGutter, "true")
+	null.EXTHSection.AddString(t.EXTHZeroMargin, "true")
+	null.EXTHSection.AddString(t.EXTHRegionMagnification, "true")
+	db.ReplaceRecord(0, null)
+
+	return db
+}
@@ -0,0 +1,64 @@
+package output_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/output"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+)
+
+// exthEntries returns the EXTH entries of a written MOBI file by type
+func exthEntries(t *testing.T, data []byte) map[uint32][]string {
+	t.Helper()
+
+	start := bytes.Index(data, []byte("EXTH"))
+	if start < 0 {
+		t.Fatalf("missing EXTH header")
+	}
+	count := int(binary.BigEndian.Uint32(data[start+8:]))
+	entries := make(map[uint32][]string)
+	for i, offset := 0, start+12; i < count; i++ {
+		kind := binary.BigEndian.Uint32(data[offset:])
+		length := int(binary.BigEndian.Uint32(data[offset+4:]))
+		entries[kind] = append(entries[kind], string(data[offset+8:offset+length]))
+		offset += length
+	}
+
+	return entries
+}
+
+func TestMobiComicMetadata(t *testing.T) {
+	manga := testhelpers.CreateSyntheticManga(1, 2, 200, 300)
+	book := kindle.GenerateMOBI(manga, kindle.WidepagePolicyPreserve, false, false)
+
+	data, err := output.MobiOutput{Book: &book}.GetBytes()
+	if err != nil {
+		t.Fatalf("GetBytes() failed: %v", err)
+	}
+	if entries := exthEntries(t, data); entries[123] != nil || entries[132] != nil {
+		t.Errorf("comic metadata was added without being requested")
+	}
+
+	data, err = output.MobiOutput{Book: &book, Comic: true}.GetBytes()
+	if err != nil {
+		t.Fatalf("GetBytes() failed: %v", err)
+	}
+	entries := exthEntries(t, data)
+	for kind, expected := range map[uint32]string{
+		122: "true",     // fixed-layout
+		123: "comic",    // book-type
+		124: "portrait", // orientation-lock
+		126: "200x300",  // original-resolution
+		127: "true",     // zero-gutter
+		128: "true",     // zero-margin
+		132: "true",     // region-mag
+		527: "rtl",      // page-progression-direction
+	} {
+		if len(entries[kind]) != 1 || entries[kind][0] != expected {
+			t.Errorf("EXTH %v: expected %q, got %q", kind, expected, entries[kind])
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package output
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/leotaku/kojirou/cmd/formats/util"
+)
+
+// recompressArchive rewrites every entry of an archive that does not use
+// the compression method chosen for it, which go-epub does not allow to
+// choose. The archive is returned as is if all entries already do.
+func recompressArchive(data []byte) ([]byte, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	if !slices.ContainsFunc(r.File, needsRecompression) {
+		return data, nil
+	}
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for _, f := range r.File {
+		if !needsRecompression(f) {
+			if err := zw.Copy(f); err != nil {
+				return nil, fmt.Errorf("copy %v: %w", f.Name, err)
+			}
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("open %v: %w", f.Name, err)
+		}
+		header := f.FileHeader
+		header.Method = util.ZipMethod(f.Name)
+		w, err := zw.CreateHeader(&header)
+		if err != nil {
+			rc.Close()
+			return nil, fmt.Errorf("create %v: %w", f.Name, err)
+		}
+		_, err = io.Copy(w, rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("write %v: %w", f.Name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("close: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// needsRecompression reports whether an entry uses another compression
+// method than chosen for it. Directories and the uncompressed mimetype of
+// EPUB archives always stay as they are.
+func needsRecompression(f *zip.File) bool {
+	return f.Name != "mimetype" && !f.FileInfo().IsDir() && f.Method != util.ZipMethod(f.Name)
+}
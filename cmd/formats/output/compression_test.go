@@ -0,0 +1,61 @@
+package output_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"path"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/epub"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/output"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+	"github.com/leotaku/kojirou/cmd/formats/util"
+)
+
+func TestZipStoreImages(t *testing.T) {
+	manga := testhelpers.CreateSyntheticManga(1, 2, 200, 300)
+	e, cleanup, err := epub.GenerateEPUB(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true)
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		t.Fatalf("GenerateEPUB() failed: %v", err)
+	}
+
+	util.SetZipStoreImages(true)
+	defer util.SetZipStoreImages(false)
+
+	for _, format := range []output.FormatOutput{
+		output.EpubOutput{Epub: e},
+		output.KepubOutput{Epub: e},
+	} {
+		data, err := format.GetBytes()
+		if err != nil {
+			t.Fatalf("GetBytes() failed: %v", err)
+		}
+		r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("%v: failed to open archive: %v", format.Extension(), err)
+		}
+
+		images, texts := 0, 0
+		for _, f := range r.File {
+			switch path.Ext(f.Name) {
+			case ".jpg":
+				images++
+				if f.Method != zip.Store {
+					t.Errorf("%v: image %v is not stored", format.Extension(), f.Name)
+				}
+			case ".xhtml", ".opf", ".css":
+				texts++
+				if f.Method != zip.Deflate {
+					t.Errorf("%v: text %v is not deflated", format.Extension(), f.Name)
+				}
+			}
+		}
+		if images == 0 || texts == 0 {
+			t.Errorf("%v: expected images and text, got %v images and %v texts", format.Extension(), images, texts)
+		}
+	}
+}
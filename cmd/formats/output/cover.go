@@ -0,0 +1,31 @@
+package output
+
+import (
+	"regexp"
+	"strings"
+)
+
+// coverPageRe matches the spine entry of the page that go-epub generates
+// for the cover image
+var coverPageRe = regexp.MustCompile(`\s*<itemref idref="cover\.xhtml"[^>]*?(?:/>|>\s*</itemref>)`)
+
+// spineRe matches the opening tag of the spine
+var spineRe = regexp.MustCompile(`<spine[^>]*>`)
+
+// injectCoverPage moves the cover page to the start of the spine. Unless
+// coverPage is set, the cover page is marked as non-linear, so that the
+// cover is only shown as book metadata and not in the reading flow.
+func injectCoverPage(opf string, coverPage bool) string {
+	if !coverPageRe.MatchString(opf) {
+		return opf
+	}
+
+	tag := `<itemref idref="cover.xhtml"></itemref>`
+	if !coverPage {
+		tag = `<itemref idref="cover.xhtml" linear="no"></itemref>`
+	}
+	opf = coverPageRe.ReplaceAllString(opf, "")
+	spine := spineRe.FindString(opf)
+
+	return strings.Replace(opf, spine, spine+"\n    "+tag, 1)
+}
@@ -0,0 +1,56 @@
+package output_test
+
+import (
+	"image/color"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/epub"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/output"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+)
+
+func TestCoverPage(t *testing.T) {
+	manga := testhelpers.CreateSyntheticManga(1, 1, 200, 300)
+	for id, vol := range manga.Volumes {
+		vol.Cover = testhelpers.CreateTestImage(200, 300, color.Black)
+		manga.Volumes[id] = vol
+	}
+	e, cleanup, err := epub.GenerateEPUB(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true)
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		t.Fatalf("GenerateEPUB() failed: %v", err)
+	}
+
+	itemrefRe := regexp.MustCompile(`<itemref [^>]*>`)
+	for _, tc := range []struct {
+		format output.FormatOutput
+		want   string
+	}{
+		{output.EpubOutput{Epub: e, CoverPage: true}, `<itemref idref="cover.xhtml">`},
+		{output.EpubOutput{Epub: e}, `<itemref idref="cover.xhtml" linear="no">`},
+	} {
+		data, err := tc.format.GetBytes()
+		if err != nil {
+			t.Fatalf("GetBytes() failed: %v", err)
+		}
+		opf := readPackage(t, data)
+
+		if !strings.Contains(opf, `<meta name="cover" content="cover-1.jpg">`) {
+			t.Errorf("%v: cover metadata is missing:\n%s", tc.format.Extension(), opf)
+		}
+		if !regexp.MustCompile(`<item [^>]*href="images/cover-1\.jpg"[^>]*properties="cover-image"`).MatchString(opf) {
+			t.Errorf("%v: cover image is not marked as cover:\n%s", tc.format.Extension(), opf)
+		}
+		if first := itemrefRe.FindString(opf); first != tc.want {
+			t.Errorf("%v: first spine entry is %v, want %v", tc.format.Extension(), first, tc.want)
+		}
+		if count := strings.Count(opf, `idref="cover.xhtml"`); count != 1 {
+			t.Errorf("%v: expected a single cover page, got %v", tc.format.Extension(), count)
+		}
+	}
+}
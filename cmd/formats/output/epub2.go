@@ -0,0 +1,208 @@
+package output
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	// propertyMetaRe matches metadata with a property, which EPUB 2 does
+	// not define, such as rendition and accessibility metadata
+	propertyMetaRe = regexp.MustCompile(`\s*<meta\s[^>]*?property="[^"]*"[^>]*?(?:/>|>[^<]*</meta>)`)
+	// modifiedValueRe matches the last modification date written by
+	// injectModified, capturing its value
+	modifiedValueRe = regexp.MustCompile(`<meta property="dcterms:modified">([^<]*)</meta>`)
+	// propertiesAttrRe matches the EPUB 3 properties of manifest items
+	propertiesAttrRe = regexp.MustCompile(`\s+properties="[^"]*"`)
+	// ncxDepthRe matches the depth of an NCX, where go-epub writes the
+	// identifier of the book instead of the depth, capturing the identifier
+	ncxDepthRe = regexp.MustCompile(`<meta name="dtb:depth" content="([^"]*)"\s*(?:/>|></meta>)`)
+	// navPointTagRe matches the start and end tags of NCX navigation points
+	navPointTagRe = regexp.MustCompile(`</?navPoint\b`)
+)
+
+// convertEPUB2 turns an EPUB 3 archive as written by go-epub into an EPUB 2
+// archive for devices and apps that cannot read EPUB 3. Navigation relies
+// on the NCX and a guide instead of the navigation document, which is
+// removed, and the package document loses all properties that EPUB 2 does
+// not define. Content documents are kept as they are.
+func convertEPUB2(data []byte) ([]byte, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	i := slices.IndexFunc(r.File, func(f *zip.File) bool { return strings.HasSuffix(f.Name, ".opf") })
+	if i < 0 {
+		return nil, fmt.Errorf("no package document")
+	}
+	opf, err := readEntry(r.File[i])
+	if err != nil {
+		return nil, err
+	}
+	opf, nav, err := epub2Package(opf)
+	if err != nil {
+		return nil, fmt.Errorf("package: %w", err)
+	}
+	if nav != "" {
+		nav = path.Join(path.Dir(r.File[i].Name), nav)
+	}
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for _, f := range r.File {
+		switch {
+		case f.Name == nav:
+		case f == r.File[i]:
+			err = writeEntry(zw, f, opf)
+		case strings.HasSuffix(f.Name, ".ncx"):
+			var ncx string
+			if ncx, err = readEntry(f); err == nil {
+				err = writeEntry(zw, f, epub2NCX(ncx))
+			}
+		default:
+			err = zw.Copy(f)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", f.Name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("close: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// epub2Package converts a package document to EPUB 2, returning it along
+// with the path of the removed navigation document relative to it
+func epub2Package(opf string) (string, string, error) {
+	pkg := struct {
+		Items []struct {
+			ID         string `xml:"id,attr"`
+			Href       string `xml:"href,attr"`
+			Properties string `xml:"properties,attr"`
+		} `xml:"manifest>item"`
+		Spine []struct {
+			IDRef  string `xml:"idref,attr"`
+			Linear string `xml:"linear,attr"`
+		} `xml:"spine>itemref"`
+	}{}
+	if err := xml.Unmarshal([]byte(opf), &pkg); err != nil {
+		return "", "", err
+	}
+
+	opf = strings.Replace(opf, `version="3.0"`, `version="2.0"`, 1)
+
+	// Keep the modification date as an EPUB 2 date
+	modified := modifiedValueRe.FindStringSubmatch(opf)
+	opf = propertyMetaRe.ReplaceAllString(opf, "")
+	if modified != nil {
+		if !strings.Contains(opf, "xmlns:opf=") {
+			opf = strings.Replace(opf, "<metadata", `<metadata xmlns:opf="http://www.idpf.org/2007/opf"`, 1)
+		}
+		tag := fmt.Sprintf(`<dc:date opf:event="modification">%v</dc:date>`, modified[1])
+		opf = strings.Replace(opf, "</metadata>", tag+"\n    </metadata>", 1)
+	}
+
+	nav := ""
+	hrefs := make(map[string]string)
+	for _, item := range pkg.Items {
+		hrefs[item.ID] = item.Href
+		if !slices.Contains(strings.Fields(item.Properties), "nav") {
+			continue
+		}
+		nav = item.Href
+		for element, attr := range map[string]string{"item": "id", "itemref": "idref"} {
+			re := regexp.MustCompile(`\s*<` + element + `\s[^>]*?` + attr + `="` + regexp.QuoteMeta(item.ID) + `"[^>]*?(?:/>|>\s*</` + element + `>)`)
+			opf = re.ReplaceAllString(opf, "")
+		}
+	}
+	opf = propertiesAttrRe.ReplaceAllString(opf, "")
+
+	// Point readers to the cover and the start of the reading order
+	if !strings.Contains(opf, "<guide") {
+		var guide strings.Builder
+		cover, text := "", ""
+		for _, ref := range pkg.Spine {
+			href, ok := hrefs[ref.IDRef]
+			switch {
+			case !ok || href == nav:
+			case cover == "" && strings.HasPrefix(strings.ToLower(path.Base(href)), "cover"):
+				cover = href
+			case text == "" && ref.Linear != "no":
+				text = href
+			}
+		}
+		for _, ref := range []struct{ kind, title, href string }{{"cover", "Cover", cover}, {"text", "Start", text}} {
+			if ref.href != "" {
+				fmt.Fprintf(&guide, "\n    "+`<reference type="%v" title="%v" href="%v"/>`, ref.kind, ref.title, html.EscapeString(ref.href))
+			}
+		}
+		if guide.Len() > 0 {
+			opf = strings.Replace(opf, "</spine>", "</spine>\n  <guide>"+guide.String()+"\n  </guide>", 1)
+		}
+	}
+
+	return opf, nav, nil
+}
+
+// epub2NCX names the identifier of the book in the NCX as EPUB 2 requires,
+// which go-epub writes as its depth instead
+func epub2NCX(ncx string) string {
+	depth := fmt.Sprint(ncxDepth(ncx))
+	return ncxDepthRe.ReplaceAllString(ncx, `<meta name="dtb:uid" content="$1"></meta>`+"\n    "+`<meta name="dtb:depth" content="`+depth+`"></meta>`)
+}
+
+// ncxDepth returns how deeply the navigation points of an NCX are nested,
+// at least one
+func ncxDepth(ncx string) int {
+	depth, level := 1, 0
+	for _, tag := range navPointTagRe.FindAllString(ncx, -1) {
+		if tag == "</navPoint" {
+			level--
+			continue
+		}
+		level++
+		depth = max(depth, level)
+	}
+
+	return depth
+}
+
+// readEntry returns the content of an archive entry
+func readEntry(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", fmt.Errorf("open: %w", err)
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return "", fmt.Errorf("read: %w", err)
+	}
+
+	return string(content), nil
+}
+
+// writeEntry writes an archive entry with the given content and the header
+// of the original entry
+func writeEntry(zw *zip.Writer, f *zip.File, content string) error {
+	header := f.FileHeader
+	w, err := zw.CreateHeader(&header)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if _, err := io.WriteString(w, content); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return nil
+}
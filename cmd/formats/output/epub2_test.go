@@ -0,0 +1,102 @@
+package output_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats"
+	"github.com/leotaku/kojirou/cmd/formats/epub"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/output"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+)
+
+func TestEpubVersion2(t *testing.T) {
+	manga := testhelpers.CreateSyntheticManga(2, 1, 200, 300)
+	for volID, vol := range manga.Volumes {
+		vol.Cover = testhelpers.CreateTestImage(200, 300, color.Black)
+		manga.Volumes[volID] = vol
+	}
+	e, cleanup, err := epub.GenerateEPUB(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true)
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		t.Fatalf("GenerateEPUB() failed: %v", err)
+	}
+
+	data, err := output.EpubOutput{Epub: e, Generator: "kojirou 1.2.3", EpubVersion: 2}.GetBytes()
+	if err != nil {
+		t.Fatalf("GetBytes() failed: %v", err)
+	}
+	filename := filepath.Join(t.TempDir(), "book.epub")
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatalf("failed to write EPUB: %v", err)
+	}
+	if err := formats.VerifyFile(filename, formats.FormatEpub); err != nil {
+		t.Errorf("EPUB 2 output is invalid: %v", err)
+	}
+	opf := readPackage(t, data)
+	for _, want := range []string{
+		`version="2.0"`,
+		`<spine toc="ncx">`,
+		`<item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml">`,
+		"<guide>\n    " + `<reference type="cover" title="Cover" href="xhtml/cover.xhtml"/>` +
+			"\n    " + `<reference type="text" title="Start" href="xhtml/volume-1.xhtml"/>` + "\n  </guide>",
+		`<dc:title>Synthetic Manga</dc:title>`,
+		`<meta name="cover" content="cover-1.jpg">`,
+		`<meta name="generator" content="kojirou 1.2.3"/>`,
+		`<dc:date opf:event="modification">`,
+	} {
+		if !strings.Contains(opf, want) {
+			t.Errorf("package.opf missing %v:\n%s", want, opf)
+		}
+	}
+	for _, unwanted := range []string{`version="3.0"`, "property=", "properties=", "rendition:", `href="nav.xhtml"`} {
+		if strings.Contains(opf, unwanted) {
+			t.Errorf("package.opf contains EPUB 3 only %v:\n%s", unwanted, opf)
+		}
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("failed to open EPUB: %v", err)
+	}
+	names := make(map[string]bool)
+	for _, f := range r.File {
+		names[f.Name] = true
+	}
+	if !names["EPUB/toc.ncx"] || names["EPUB/nav.xhtml"] {
+		t.Errorf("expected NCX without navigation document, got %v", names)
+	}
+	rc, err := r.Open("EPUB/toc.ncx")
+	if err != nil {
+		t.Fatalf("failed to open NCX: %v", err)
+	}
+	defer rc.Close()
+	ncx, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("failed to read NCX: %v", err)
+	}
+	// Chapters are nested below their volume
+	for _, want := range []string{`<meta name="dtb:uid" content="` + e.Identifier() + `">`, `<meta name="dtb:depth" content="2">`} {
+		if !strings.Contains(string(ncx), want) {
+			t.Errorf("toc.ncx missing %v:\n%s", want, ncx)
+		}
+	}
+
+	// EPUB 3 is written by default
+	data, err = output.EpubOutput{Epub: e}.GetBytes()
+	if err != nil {
+		t.Fatalf("GetBytes() failed: %v", err)
+	}
+	if opf := readPackage(t, data); !strings.Contains(opf, `version="3.0"`) || strings.Contains(opf, "<guide>") {
+		t.Errorf("expected EPUB 3 package without guide:\n%s", opf)
+	}
+}
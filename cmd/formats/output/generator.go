@@ -0,0 +1,24 @@
+package output
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// generatorRe matches generator metadata of a package document
+var generatorRe = regexp.MustCompile(`\s*<meta name="generator"[^>]*?(?:/>|>[^<]*</meta>)`)
+
+// injectGenerator names the program that generated the book in the package
+// document, replacing any existing generator. An empty generator only
+// removes existing ones.
+func injectGenerator(opf string, generator string) string {
+	opf = generatorRe.ReplaceAllString(opf, "")
+	if generator == "" {
+		return opf
+	}
+	tag := fmt.Sprintf(`<meta name="generator" content="%v"/>`, html.EscapeString(generator))
+
+	return strings.Replace(opf, "</metadata>", tag+"\n    </metadata>", 1)
+}
@@ -0,0 +1,46 @@
+package output_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/epub"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/output"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+)
+
+func TestGenerator(t *testing.T) {
+	manga := testhelpers.CreateSyntheticManga(1, 1, 200, 300)
+	e, cleanup, err := epub.GenerateEPUB(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true)
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		t.Fatalf("GenerateEPUB() failed: %v", err)
+	}
+
+	want := `<meta name="generator" content="kojirou 1.2.3"/>`
+	for _, tc := range []struct {
+		format output.FormatOutput
+		count  int
+	}{
+		{output.EpubOutput{Epub: e, Generator: "kojirou 1.2.3"}, 1},
+		{output.KepubOutput{Epub: e, Generator: "kojirou 1.2.3"}, 1},
+		{output.EpubOutput{Epub: e, Generator: "kojirou 1.2.3", StripMetadata: true}, 0},
+		{output.KepubOutput{Epub: e, Generator: "kojirou 1.2.3", StripMetadata: true}, 0},
+		{output.EpubOutput{Epub: e}, 0},
+	} {
+		data, err := tc.format.GetBytes()
+		if err != nil {
+			t.Fatalf("%v: GetBytes() failed: %v", tc.format.Extension(), err)
+		}
+		opf := readPackage(t, data)
+		if count := strings.Count(opf, want); count != tc.count {
+			t.Errorf("%v: expected %v generators, got %v:\n%s", tc.format.Extension(), tc.count, count, opf)
+		}
+		if count := strings.Count(opf, `name="generator"`); count != tc.count {
+			t.Errorf("%v: expected %v generators in total, got %v", tc.format.Extension(), tc.count, count)
+		}
+	}
+}
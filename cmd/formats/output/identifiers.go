@@ -0,0 +1,37 @@
+package output
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+)
+
+// injectIdentifiers adds the given external identifiers to the package
+// document. ISBNs are written as URNs, all other identifiers are prefixed
+// with their scheme, which is the form e-book managers such as calibre
+// read identifiers from.
+func injectIdentifiers(opf string, identifiers map[string]string) string {
+	schemes := make([]string, 0, len(identifiers))
+	for scheme := range identifiers {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+
+	var insert strings.Builder
+	for _, scheme := range schemes {
+		value := strings.TrimSpace(identifiers[scheme])
+		if value == "" {
+			continue
+		}
+		if scheme == "isbn" {
+			value = "urn:isbn:" + strings.ReplaceAll(value, "-", "")
+		} else {
+			value = scheme + ":" + value
+		}
+		fmt.Fprintf(&insert, `<dc:identifier id="id-%v">%v</dc:identifier>`, html.EscapeString(scheme), html.EscapeString(value))
+		insert.WriteString("\n    ")
+	}
+
+	return strings.Replace(opf, "</metadata>", insert.String()+"</metadata>", 1)
+}
@@ -0,0 +1,74 @@
+package output_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/epub"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/output"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+)
+
+// readPackage returns the package document of the given EPUB data
+func readPackage(t *testing.T, data []byte) string {
+	t.Helper()
+
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("failed to open EPUB: %v", err)
+	}
+	rc, err := r.Open("EPUB/package.opf")
+	if err != nil {
+		t.Fatalf("failed to open OPF: %v", err)
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("failed to read OPF: %v", err)
+	}
+
+	return string(content)
+}
+
+func TestEpubExternalIdentifiers(t *testing.T) {
+	manga := testhelpers.CreateSyntheticManga(1, 1, 200, 300)
+	manga.Info.ExternalIDs = map[string]string{
+		"isbn":    "978-4-08-873011-0",
+		"anilist": "30013",
+	}
+	e, cleanup, err := epub.GenerateEPUB(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true)
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		t.Fatalf("GenerateEPUB() failed: %v", err)
+	}
+
+	data, err := output.EpubOutput{Epub: e, ExternalIDs: manga.Info.ExternalIDs}.GetBytes()
+	if err != nil {
+		t.Fatalf("GetBytes() failed: %v", err)
+	}
+	opf := readPackage(t, data)
+	for _, want := range []string{
+		`<dc:identifier id="id-isbn">urn:isbn:9784088730110</dc:identifier>`,
+		`<dc:identifier id="id-anilist">anilist:30013</dc:identifier>`,
+		`<dc:identifier id="pub-id">`,
+	} {
+		if !strings.Contains(opf, want) {
+			t.Errorf("package.opf missing %v:\n%s", want, opf)
+		}
+	}
+
+	// Books without external identifiers only carry their own
+	data, err = output.EpubOutput{Epub: e}.GetBytes()
+	if err != nil {
+		t.Fatalf("GetBytes() failed: %v", err)
+	}
+	if count := strings.Count(readPackage(t, data), "<dc:identifier"); count != 1 {
+		t.Errorf("expected a single identifier, got %v", count)
+	}
+}
@@ -0,0 +1,57 @@
+package output_test
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/epub"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/output"
+	"github.com/leotaku/kojirou/cmd/formats/progress"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+	md "github.com/leotaku/kojirou/mangadex"
+)
+
+func TestWriteKepub(t *testing.T) {
+	tempDir := t.TempDir()
+	manga := testhelpers.CreateSyntheticManga(1, 2, 200, 300)
+	e, cleanup, err := epub.GenerateEPUB(tempDir, manga, kindle.WidepagePolicyPreserve, false, true)
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		t.Fatalf("GenerateEPUB() failed: %v", err)
+	}
+
+	outDir := t.TempDir()
+	dir := kindle.NewNormalizedDirectory(outDir, "Test Manga", false)
+	if err := dir.WriteKepub(md.NewIdentifier("1"), &output.KepubOutput{Epub: e}, progress.VanishingProgress("Writing...")); err != nil {
+		t.Fatalf("WriteKepub() failed: %v", err)
+	}
+
+	filename := filepath.Join(outDir, "0001.kepub.epub")
+	if entries, err := os.ReadDir(outDir); err != nil || len(entries) != 1 || entries[0].Name() != filepath.Base(filename) {
+		t.Fatalf("got files %v, want only %v", entries, filepath.Base(filename))
+	}
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		t.Fatalf("failed to open KEPUB: %v", err)
+	}
+	defer r.Close()
+
+	mimetype := r.File[0]
+	if mimetype.Name != "mimetype" || mimetype.Method != zip.Store || len(mimetype.Extra) != 0 {
+		t.Fatalf("mimetype is not the first stored entry without extra fields")
+	}
+	rc, err := mimetype.Open()
+	if err != nil {
+		t.Fatalf("failed to open mimetype: %v", err)
+	}
+	defer rc.Close()
+	if data, err := io.ReadAll(rc); err != nil || string(data) != "application/epub+zip" {
+		t.Errorf("got mimetype %q, %v, want application/epub+zip", data, err)
+	}
+}
@@ -0,0 +1,76 @@
+package output
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"image"
+	"path"
+	"strings"
+)
+
+// fixedLayoutMetadata describes books of full page images, which readers
+// should show one page at a time without reflowing them.
+var fixedLayoutMetadata = []struct{ property, content string }{
+	{"rendition:layout", "pre-paginated"},
+	{"rendition:orientation", "portrait"},
+	{"rendition:spread", "none"},
+	{"rendition:flow", "paginated"},
+}
+
+// injectFixedLayout adds package-level fixed-layout rendition properties
+// to the given package document, keeping properties that already exist.
+// Unless empty, the viewport gives the page geometry that readers assume
+// before loading a page.
+func injectFixedLayout(opf string, viewport image.Point) string {
+	var insert strings.Builder
+	for _, m := range fixedLayoutMetadata {
+		if !strings.Contains(opf, fmt.Sprintf(`property="%v"`, m.property)) {
+			fmt.Fprintf(&insert, `<meta property="%v">%v</meta>`, m.property, m.content)
+			insert.WriteString("\n    ")
+		}
+	}
+	if viewport.X > 0 && viewport.Y > 0 && !strings.Contains(opf, `property="rendition:viewport"`) {
+		fmt.Fprintf(&insert, `<meta property="rendition:viewport">width=%v, height=%v</meta>`, viewport.X, viewport.Y)
+		insert.WriteString("\n    ")
+	}
+
+	return strings.Replace(opf, "</metadata>", insert.String()+"</metadata>", 1)
+}
+
+// commonPageSize returns the most common size of the images of an EPUB
+// archive, preferring the size seen first on ties. It is empty for books
+// without images.
+func commonPageSize(data []byte) (image.Point, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return image.Point{}, fmt.Errorf("open: %w", err)
+	}
+
+	counts := make(map[image.Point]int)
+	result := image.Point{}
+	for _, f := range r.File {
+		switch strings.ToLower(path.Ext(f.Name)) {
+		case ".jpg", ".jpeg", ".png", ".gif":
+		default:
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return image.Point{}, fmt.Errorf("open %v: %w", f.Name, err)
+		}
+		config, _, err := image.DecodeConfig(rc)
+		rc.Close()
+		if err != nil {
+			continue
+		}
+
+		size := image.Pt(config.Width, config.Height)
+		counts[size]++
+		if counts[size] > counts[result] {
+			result = size
+		}
+	}
+
+	return result, nil
+}
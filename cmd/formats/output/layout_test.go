@@ -0,0 +1,52 @@
+package output_test
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/epub"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/output"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+	md "github.com/leotaku/kojirou/mangadex"
+)
+
+func TestFixedLayout(t *testing.T) {
+	manga := testhelpers.CreateSyntheticManga(1, 3, 200, 300)
+
+	// A single odd page must not decide the viewport
+	chapter := manga.Volumes[md.NewIdentifier("1")].Chapters[md.NewIdentifier("1")]
+	chapter.Pages[0] = testhelpers.CreateTestImage(600, 300, color.White)
+
+	e, cleanup, err := epub.GenerateEPUB(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true)
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		t.Fatalf("GenerateEPUB() failed: %v", err)
+	}
+
+	for _, format := range []output.FormatOutput{
+		output.EpubOutput{Epub: e},
+		output.KepubOutput{Epub: e},
+	} {
+		data, err := format.GetBytes()
+		if err != nil {
+			t.Fatalf("GetBytes() failed: %v", err)
+		}
+		opf := readPackage(t, data)
+		// The KEPUB conversion already adds rendition properties of its own
+		for _, property := range []string{"rendition:layout", "rendition:orientation", "rendition:spread", "rendition:viewport"} {
+			if count := strings.Count(opf, `property="`+property+`"`); count != 1 {
+				t.Errorf("%v: expected a single %v, got %v:\n%s", format.Extension(), property, count, opf)
+			}
+		}
+		if !strings.Contains(opf, "pre-paginated") {
+			t.Errorf("%v: package.opf is not fixed-layout:\n%s", format.Extension(), opf)
+		}
+		if want := `<meta property="rendition:viewport">width=200, height=300</meta>`; !strings.Contains(opf, want) {
+			t.Errorf("%v: package.opf missing %v:\n%s", format.Extension(), want, opf)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package output
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// modifiedRe matches last modification dates of a package document. The
+// go-epub library adds another one every time a book is written, and the
+// KEPUB conversion may rewrite it as an empty element.
+var modifiedRe = regexp.MustCompile(`\s*<meta property="dcterms:modified"(?:\s+content="[^"]*")?\s*(?:/>|>[^<]*</meta>)`)
+
+// injectModified sets the last modification date of the package document
+// to the given time in UTC with second precision, replacing all existing
+// dates. The zero time is replaced by the current time.
+func injectModified(opf string, modified time.Time) string {
+	if modified.IsZero() {
+		modified = time.Now()
+	}
+	tag := fmt.Sprintf(`<meta property="dcterms:modified">%v</meta>`, modified.UTC().Format("2006-01-02T15:04:05Z"))
+	opf = modifiedRe.ReplaceAllString(opf, "")
+
+	return strings.Replace(opf, "</metadata>", tag+"\n    </metadata>", 1)
+}
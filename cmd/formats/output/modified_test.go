@@ -0,0 +1,53 @@
+package output_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leotaku/kojirou/cmd/formats/epub"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/output"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+)
+
+func TestEpubModified(t *testing.T) {
+	manga := testhelpers.CreateSyntheticManga(1, 1, 200, 300)
+	e, cleanup, err := epub.GenerateEPUB(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true)
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		t.Fatalf("GenerateEPUB() failed: %v", err)
+	}
+
+	pinned := time.Date(2024, 1, 31, 12, 30, 45, 999, time.FixedZone("JST", 9*60*60))
+	want := `<meta property="dcterms:modified">2024-01-31T03:30:45Z</meta>`
+	for _, format := range []output.FormatOutput{
+		output.EpubOutput{Epub: e, Modified: pinned},
+		output.KepubOutput{Epub: e, Modified: pinned},
+		// Writing the same book again must not add another date
+		output.EpubOutput{Epub: e, Modified: pinned},
+	} {
+		data, err := format.GetBytes()
+		if err != nil {
+			t.Fatalf("GetBytes() failed: %v", err)
+		}
+		opf := readPackage(t, data)
+		if !strings.Contains(opf, want) {
+			t.Errorf("%v: package.opf missing %v:\n%s", format.Extension(), want, opf)
+		}
+		if count := strings.Count(opf, "dcterms:modified"); count != 1 {
+			t.Errorf("%v: expected a single modification date, got %v", format.Extension(), count)
+		}
+	}
+
+	// Unpinned books are dated at the time of writing
+	data, err := output.EpubOutput{Epub: e}.GetBytes()
+	if err != nil {
+		t.Fatalf("GetBytes() failed: %v", err)
+	}
+	if opf := readPackage(t, data); !strings.Contains(opf, time.Now().UTC().Format("2006-01-02T")) {
+		t.Errorf("package.opf is not dated today:\n%s", opf)
+	}
+}
@@ -0,0 +1,257 @@
+package output
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"html"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	nethtml "golang.org/x/net/html"
+)
+
+// navigationPage is the page, relative to the package document, that the
+// EPUB generator writes its nested table of contents to. go-epub itself
+// only writes a flat one.
+const navigationPage = "xhtml/nav.xhtml"
+
+var (
+	// navListRe matches the list of the table of contents of a navigation
+	// document, from its first to its last element
+	navListRe = regexp.MustCompile(`(?s)(<nav[^>]*epub:type="toc"[^>]*>.*?)<ol>.*</ol>(\s*</nav>)`)
+	// navMapRe matches the navigation map of an NCX
+	navMapRe = regexp.MustCompile(`(?s)<navMap>.*</navMap>`)
+	// navigationItemRe matches the manifest item of the navigation page,
+	// capturing its identifier
+	navigationItemRe = regexp.MustCompile(`\s*<item\s[^>]*?id="([^"]*)"[^>]*?href="` + regexp.QuoteMeta(navigationPage) + `"[^>]*?(?:/>|>\s*</item>)`)
+)
+
+// navEntry is an entry of a nested table of contents
+type navEntry struct {
+	label string
+	// href is the target of the entry relative to the package document,
+	// empty for entries that only group others
+	href     string
+	children []navEntry
+}
+
+// rewriteNavigation replaces the flat table of contents that go-epub
+// writes to the navigation document and NCX of an EPUB archive with the
+// nested one of the navigation page, which is then removed from the book.
+// Archives without a navigation page are returned unchanged.
+func rewriteNavigation(data []byte) ([]byte, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	i := slices.IndexFunc(r.File, func(f *zip.File) bool { return strings.HasSuffix(f.Name, ".opf") })
+	if i < 0 {
+		return nil, fmt.Errorf("no package document")
+	}
+	root := path.Dir(r.File[i].Name)
+	j := slices.IndexFunc(r.File, func(f *zip.File) bool { return f.Name == path.Join(root, navigationPage) })
+	if j < 0 {
+		return data, nil
+	}
+	page, err := readEntry(r.File[j])
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", r.File[j].Name, err)
+	}
+	entries, err := parseNavigation(page, path.Dir(navigationPage))
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", r.File[j].Name, err)
+	}
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for _, f := range r.File {
+		var content string
+		switch {
+		case f == r.File[j]:
+			continue
+		case f == r.File[i]:
+			if content, err = readEntry(f); err == nil {
+				err = writeEntry(zw, f, removeNavigationPage(content))
+			}
+		case path.Ext(f.Name) == ".ncx":
+			if content, err = readEntry(f); err == nil {
+				err = writeEntry(zw, f, navMapRe.ReplaceAllLiteralString(content, ncxNavMap(entries)))
+			}
+		case f.Name == path.Join(root, "nav.xhtml"):
+			if content, err = readEntry(f); err == nil {
+				err = writeEntry(zw, f, replaceNavList(content, entries))
+			}
+		default:
+			err = zw.Copy(f)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", f.Name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("close: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// removeNavigationPage removes the navigation page from the manifest and
+// spine of a package document
+func removeNavigationPage(opf string) string {
+	item := navigationItemRe.FindStringSubmatch(opf)
+	if item == nil {
+		return opf
+	}
+	itemref := regexp.MustCompile(`\s*<itemref\s[^>]*?idref="` + regexp.QuoteMeta(item[1]) + `"[^>]*?(?:/>|>\s*</itemref>)`)
+
+	return itemref.ReplaceAllString(strings.Replace(opf, item[0], "", 1), "")
+}
+
+// replaceNavList replaces the list of the table of contents of a navigation
+// document with the given entries
+func replaceNavList(nav string, entries []navEntry) string {
+	m := navListRe.FindStringSubmatch(nav)
+	if m == nil {
+		return nav
+	}
+
+	return strings.Replace(nav, m[0], m[1]+navList(entries, "      ")+m[2], 1)
+}
+
+// parseNavigation returns the table of contents of a navigation page, with
+// targets resolved against the given directory
+func parseNavigation(page, dir string) ([]navEntry, error) {
+	doc, err := nethtml.Parse(strings.NewReader(page))
+	if err != nil {
+		return nil, err
+	}
+	nav := findElement(doc, "nav")
+	if nav == nil {
+		return nil, fmt.Errorf("no table of contents")
+	}
+	list := findElement(nav, "ol")
+	if list == nil {
+		return nil, fmt.Errorf("no table of contents")
+	}
+
+	return parseNavList(list, dir), nil
+}
+
+// parseNavList returns the entries of a list of a table of contents
+func parseNavList(list *nethtml.Node, dir string) []navEntry {
+	entries := make([]navEntry, 0)
+	for li := list.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != nethtml.ElementNode || li.Data != "li" {
+			continue
+		}
+		var entry navEntry
+		for c := li.FirstChild; c != nil; c = c.NextSibling {
+			switch {
+			case c.Type == nethtml.TextNode && entry.label == "":
+				entry.label = strings.TrimSpace(c.Data)
+			case c.Type != nethtml.ElementNode:
+			case c.Data == "a":
+				entry.label = strings.TrimSpace(textContent(c))
+				for _, attr := range c.Attr {
+					if attr.Key == "href" {
+						entry.href = path.Join(dir, attr.Val)
+					}
+				}
+			case c.Data == "span":
+				entry.label = strings.TrimSpace(textContent(c))
+			case c.Data == "ol":
+				entry.children = parseNavList(c, dir)
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// navList renders entries as the list of a navigation document
+func navList(entries []navEntry, indent string) string {
+	var b strings.Builder
+	b.WriteString("<ol>\n")
+	for _, entry := range entries {
+		b.WriteString(indent + "  <li>")
+		if entry.href != "" {
+			fmt.Fprintf(&b, `<a href="%v">%v</a>`, html.EscapeString(entry.href), html.EscapeString(entry.label))
+		} else {
+			fmt.Fprintf(&b, "<span>%v</span>", html.EscapeString(entry.label))
+		}
+		if len(entry.children) > 0 {
+			b.WriteString(navList(entry.children, indent+"  "))
+		}
+		b.WriteString("</li>\n")
+	}
+	b.WriteString(indent + "</ol>")
+
+	return b.String()
+}
+
+// ncxNavMap renders entries as the navigation map of an NCX. Entries that
+// only group others point to the target of their first child.
+func ncxNavMap(entries []navEntry) string {
+	var b strings.Builder
+	id := 0
+	var write func(entries []navEntry, indent string)
+	write = func(entries []navEntry, indent string) {
+		for _, entry := range entries {
+			id++
+			fmt.Fprintf(&b, "\n%v<navPoint id=\"navPoint-%v\">", indent, id)
+			fmt.Fprintf(&b, "\n%v  <navLabel>\n%v    <text>%v</text>\n%v  </navLabel>", indent, indent, html.EscapeString(entry.label), indent)
+			fmt.Fprintf(&b, "\n%v  <content src=\"%v\"></content>", indent, html.EscapeString(firstTarget(entry)))
+			write(entry.children, indent+"  ")
+			fmt.Fprintf(&b, "\n%v</navPoint>", indent)
+		}
+	}
+	write(entries, "    ")
+
+	return "<navMap>" + b.String() + "\n  </navMap>"
+}
+
+// firstTarget returns the target of an entry, or that of its first
+// descendant with one
+func firstTarget(entry navEntry) string {
+	if entry.href != "" {
+		return entry.href
+	}
+	for _, child := range entry.children {
+		if target := firstTarget(child); target != "" {
+			return target
+		}
+	}
+
+	return ""
+}
+
+// findElement returns the first element with the given name below a node
+func findElement(n *nethtml.Node, name string) *nethtml.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == nethtml.ElementNode && c.Data == name {
+			return c
+		}
+		if found := findElement(c, name); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+// textContent returns the text below a node
+func textContent(n *nethtml.Node) string {
+	if n.Type == nethtml.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,154 @@
+package output_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/epub"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/output"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+)
+
+// tocEntry is an entry of a navigation document or NCX
+type tocEntry struct {
+	Label    string
+	Href     string
+	Children []tocEntry
+}
+
+// navTree parses the table of contents of a navigation document
+func navTree(t *testing.T, nav string) []tocEntry {
+	t.Helper()
+
+	type li struct {
+		A struct {
+			Href string `xml:"href,attr"`
+			Text string `xml:",chardata"`
+		} `xml:"a"`
+		Span     string `xml:"span"`
+		Children []li   `xml:"ol>li"`
+	}
+	doc := struct {
+		Items []li `xml:"body>nav>ol>li"`
+	}{}
+	if err := xml.Unmarshal([]byte(nav), &doc); err != nil {
+		t.Fatalf("failed to parse navigation document: %v\n%s", err, nav)
+	}
+	var convert func(items []li) []tocEntry
+	convert = func(items []li) []tocEntry {
+		entries := make([]tocEntry, 0)
+		for _, item := range items {
+			entries = append(entries, tocEntry{item.A.Text + item.Span, item.A.Href, convert(item.Children)})
+		}
+		return entries
+	}
+
+	return convert(doc.Items)
+}
+
+// ncxTree parses the navigation map of an NCX
+func ncxTree(t *testing.T, ncx string) []tocEntry {
+	t.Helper()
+
+	type navPoint struct {
+		Label   string `xml:"navLabel>text"`
+		Content struct {
+			Src string `xml:"src,attr"`
+		} `xml:"content"`
+		Children []navPoint `xml:"navPoint"`
+	}
+	doc := struct {
+		Points []navPoint `xml:"navMap>navPoint"`
+	}{}
+	if err := xml.Unmarshal([]byte(ncx), &doc); err != nil {
+		t.Fatalf("failed to parse NCX: %v\n%s", err, ncx)
+	}
+	var convert func(points []navPoint) []tocEntry
+	convert = func(points []navPoint) []tocEntry {
+		entries := make([]tocEntry, 0)
+		for _, point := range points {
+			entries = append(entries, tocEntry{point.Label, point.Content.Src, convert(point.Children)})
+		}
+		return entries
+	}
+
+	return convert(doc.Points)
+}
+
+func TestNestedNavigation(t *testing.T) {
+	manga := testhelpers.CreateTestManga()
+	e, cleanup, err := epub.GenerateEPUB(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true)
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		t.Fatalf("GenerateEPUB() failed: %v", err)
+	}
+	want := []tocEntry{
+		{"Volume 1", "xhtml/volume-1.xhtml", []tocEntry{{"Chapter 1", "xhtml/chapter-1-1-1.xhtml", []tocEntry{}}}},
+		{"Volume 2", "xhtml/volume-2.xhtml", []tocEntry{{"Chapter 2", "xhtml/chapter-2-2-1.xhtml", []tocEntry{}}}},
+	}
+
+	for _, out := range []output.FormatOutput{output.EpubOutput{Epub: e}, output.KepubOutput{Epub: e}} {
+		t.Run(out.Extension(), func(t *testing.T) {
+			data, err := out.GetBytes()
+			if err != nil {
+				t.Fatalf("GetBytes() failed: %v", err)
+			}
+			r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Fatalf("failed to open book: %v", err)
+			}
+			files := make(map[string]string)
+			for _, f := range r.File {
+				rc, err := f.Open()
+				if err != nil {
+					t.Fatalf("failed to open %v: %v", f.Name, err)
+				}
+				content, err := io.ReadAll(rc)
+				rc.Close()
+				if err != nil {
+					t.Fatalf("failed to read %v: %v", f.Name, err)
+				}
+				files[f.Name] = string(content)
+			}
+
+			// KEPUB conversion renders the navigation document as HTML,
+			// which comments out its XML declaration
+			nav := files["EPUB/nav.xhtml"]
+			for name, got := range map[string][]tocEntry{
+				"EPUB/nav.xhtml": navTree(t, nav[strings.Index(nav, "<html"):]),
+				"EPUB/toc.ncx":   ncxTree(t, files["EPUB/toc.ncx"]),
+			} {
+				if !equalEntries(got, want) {
+					t.Errorf("%v has entries %+v, want %+v", name, got, want)
+				}
+			}
+
+			if _, ok := files["EPUB/xhtml/nav.xhtml"]; ok {
+				t.Error("navigation page is written as a page of the book")
+			}
+			if opf := files["EPUB/package.opf"]; strings.Contains(opf, "xhtml/nav.xhtml") || strings.Contains(opf, `idref="nav.xhtml"`) {
+				t.Errorf("package lists the navigation page:\n%s", opf)
+			}
+		})
+	}
+}
+
+func equalEntries(a, b []tocEntry) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Label != b[i].Label || a[i].Href != b[i].Href || !equalEntries(a[i].Children, b[i].Children) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,53 @@
+package output
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// rewritePackage applies the given rewrite to the package document of an
+// EPUB archive, copying all other entries unchanged.
+func rewritePackage(data []byte, rewrite func(opf string) string) ([]byte, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for _, f := range r.File {
+		if !strings.HasSuffix(f.Name, ".opf") {
+			if err := zw.Copy(f); err != nil {
+				return nil, fmt.Errorf("copy %v: %w", f.Name, err)
+			}
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("open %v: %w", f.Name, err)
+		}
+		opf, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read %v: %w", f.Name, err)
+		}
+
+		header := f.FileHeader
+		w, err := zw.CreateHeader(&header)
+		if err != nil {
+			return nil, fmt.Errorf("create %v: %w", f.Name, err)
+		}
+		if _, err := io.WriteString(w, rewrite(string(opf))); err != nil {
+			return nil, fmt.Errorf("write %v: %w", f.Name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("close: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,22 @@
+package output
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// SkippedChaptersMeta is the name of the package metadata that lists the
+// chapters that were left out of a book
+const SkippedChaptersMeta = "kojirou:skipped-chapters"
+
+// injectSkippedChapters lists the chapters that were left out of the book
+// in the package document
+func injectSkippedChapters(opf string, chapters []string) string {
+	if len(chapters) == 0 {
+		return opf
+	}
+	tag := fmt.Sprintf(`<meta name="%v" content="%v"/>`, SkippedChaptersMeta, html.EscapeString(strings.Join(chapters, " ")))
+
+	return strings.Replace(opf, "</metadata>", tag+"\n    </metadata>", 1)
+}
@@ -0,0 +1,48 @@
+package output
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+)
+
+// strippedDate is the modification date written to books with stripped
+// metadata
+var strippedDate = time.Unix(0, 0)
+
+// stripArchive removes the modification times, extra fields and comments
+// of all entries of a ZIP archive, which reveal when and on which system
+// the archive was written
+func stripArchive(data []byte) ([]byte, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for _, f := range r.File {
+		raw, err := f.OpenRaw()
+		if err != nil {
+			return nil, fmt.Errorf("open %v: %w", f.Name, err)
+		}
+		header := f.FileHeader
+		header.Modified = time.Time{}
+		header.ModifiedTime, header.ModifiedDate = 0, 0
+		header.Extra, header.Comment = nil, ""
+		w, err := zw.CreateRaw(&header)
+		if err != nil {
+			return nil, fmt.Errorf("create %v: %w", f.Name, err)
+		}
+		if _, err := io.Copy(w, raw); err != nil {
+			return nil, fmt.Errorf("write %v: %w", f.Name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("close: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
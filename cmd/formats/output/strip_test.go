@@ -0,0 +1,107 @@
+package output_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"os/user"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/leotaku/kojirou/cmd/formats/epub"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/output"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+)
+
+// environmentStrings returns patterns for strings that reveal when and on
+// which system a book was written
+func environmentStrings(t *testing.T, tempDir string) []*regexp.Regexp {
+	t.Helper()
+
+	now := time.Now()
+	words := []string{tempDir, now.Format("2006-01-02"), now.UTC().Format("2006-01-02"), now.Format("20060102")}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		words = append(words, hostname)
+	}
+	if home, err := os.UserHomeDir(); err == nil && home != "/" {
+		words = append(words, home)
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		words = append(words, u.Username)
+	}
+
+	patterns := make([]*regexp.Regexp, 0, len(words))
+	for _, word := range words {
+		// Match whole words only, so that e.g. the user "root" does not
+		// match the "rootfile" element of EPUB containers
+		patterns = append(patterns, regexp.MustCompile(`\b`+regexp.QuoteMeta(word)+`\b`))
+	}
+
+	return patterns
+}
+
+func TestStripMetadata(t *testing.T) {
+	tempDir := t.TempDir()
+	manga := testhelpers.CreateSyntheticManga(2, 2, 200, 300)
+	e, cleanup, err := epub.GenerateEPUB(tempDir, manga, kindle.WidepagePolicyPreserve, false, true)
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		t.Fatalf("GenerateEPUB() failed: %v", err)
+	}
+	mobi := kindle.GenerateMOBI(manga, kindle.WidepagePolicyPreserve, false, true)
+
+	patterns := environmentStrings(t, tempDir)
+	for _, format := range []output.FormatOutput{
+		output.EpubOutput{Epub: e, StripMetadata: true},
+		output.KepubOutput{Epub: e, StripMetadata: true},
+		output.MobiOutput{Book: &mobi},
+	} {
+		data, err := format.GetBytes()
+		if err != nil {
+			t.Fatalf("%v: GetBytes() failed: %v", format.Extension(), err)
+		}
+
+		contents := map[string][]byte{"book": data}
+		if r, err := zip.NewReader(bytes.NewReader(data), int64(len(data))); err == nil {
+			for _, f := range r.File {
+				if f.ModifiedTime != 0 || f.ModifiedDate != 0 || len(f.Extra) != 0 {
+					t.Errorf("%v: %v has a modification time: %v", format.Extension(), f.Name, f.Modified)
+				}
+				rc, err := f.Open()
+				if err != nil {
+					t.Fatalf("%v: failed to open %v: %v", format.Extension(), f.Name, err)
+				}
+				content, err := io.ReadAll(rc)
+				rc.Close()
+				if err != nil {
+					t.Fatalf("%v: failed to read %v: %v", format.Extension(), f.Name, err)
+				}
+				contents[f.Name] = content
+			}
+		}
+
+		for name, content := range contents {
+			for _, pattern := range patterns {
+				if loc := pattern.FindIndex(content); loc != nil {
+					t.Errorf("%v: %v contains %q", format.Extension(), name, content[loc[0]:loc[1]])
+				}
+			}
+		}
+	}
+
+	// Stripped books are dated at the epoch instead of the time of writing
+	pinned := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	data, err := output.EpubOutput{Epub: e, Modified: pinned, StripMetadata: true}.GetBytes()
+	if err != nil {
+		t.Fatalf("GetBytes() failed: %v", err)
+	}
+	want := `<meta property="dcterms:modified">1970-01-01T00:00:00Z</meta>`
+	if opf := readPackage(t, data); !bytes.Contains([]byte(opf), []byte(want)) {
+		t.Errorf("package.opf missing %v:\n%s", want, opf)
+	}
+}
@@ -0,0 +1,67 @@
+package output_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/epub"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/output"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+	"github.com/leotaku/kojirou/cmd/formats/util"
+)
+
+func TestTempDir(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	for _, dir := range []string{file, filepath.Join(t.TempDir(), "missing")} {
+		if err := util.SetTempDir(dir); err == nil {
+			t.Errorf("SetTempDir(%v) succeeded", dir)
+		}
+	}
+
+	dir := t.TempDir()
+	if err := util.SetTempDir(dir); err != nil {
+		t.Fatalf("SetTempDir() failed: %v", err)
+	}
+	defer util.SetTempDir("") //nolint:errcheck
+
+	manga := testhelpers.CreateSyntheticManga(1, 2, 200, 300)
+	e, cleanup, err := epub.GenerateEPUBProd(manga, kindle.WidepagePolicyPreserve, false, true)
+	if err != nil {
+		t.Fatalf("GenerateEPUBProd() failed: %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || !strings.HasPrefix(entries[0].Name(), "epub-prod-") {
+		t.Errorf("expected pages below the temp dir, got %v", entries)
+	}
+
+	for _, format := range []output.FormatOutput{output.EpubOutput{Epub: e}, output.KepubOutput{Epub: e}} {
+		if _, err := format.GetBytes(); err != nil {
+			t.Fatalf("%v: GetBytes() failed: %v", format.Extension(), err)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+			t.Errorf("%v: temporary files were left behind: %v", format.Extension(), entries)
+		}
+	}
+
+	cleanup()
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("temporary files were left behind: %v", entries)
+	}
+
+	// Without the temp dir, nothing can be generated
+	if err := os.Remove(dir); err != nil {
+		t.Fatalf("failed to remove temp dir: %v", err)
+	}
+	if _, err := (output.KepubOutput{Epub: e}).GetBytes(); err == nil {
+		t.Error("KEPUB conversion did not use the temp dir")
+	}
+	if _, _, err := epub.GenerateEPUBProd(manga, kindle.WidepagePolicyPreserve, false, true); err == nil {
+		t.Error("EPUB generation did not use the temp dir")
+	}
+}
@@ -7,8 +7,12 @@ import (
 	"image"
 	"io"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/leotaku/kojirou/cmd/formats/kepubconv"
+	"github.com/leotaku/kojirou/cmd/formats/pdf/document"
+	"github.com/leotaku/kojirou/cmd/formats/util"
 
 	"github.com/bmaupin/go-epub"
 	"github.com/leotaku/mobi"
@@ -20,11 +24,40 @@ type FormatOutput interface {
 	Extension() string
 	// GetBytes returns the bytes of the generated ebook
 	GetBytes() ([]byte, error)
+	// WriteTo streams the generated ebook to a writer, such as a file,
+	// a pipe or standard output
+	WriteTo(w io.Writer) (int64, error)
+}
+
+// countingWriter counts the bytes written to the underlying writer
+type countingWriter struct {
+	io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.Writer.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// writeBytes writes the result of GetBytes for formats that can only be
+// generated as a whole
+func writeBytes(out FormatOutput, w io.Writer) (int64, error) {
+	data, err := out.GetBytes()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
 }
 
 // MobiOutput wraps a mobi.Book to implement FormatOutput
 type MobiOutput struct {
 	*mobi.Book
+	// Comic advertises the book as a fixed-layout comic, which enables
+	// region magnification in Kindle's guided view where supported.
+	Comic bool
 }
 
 func NewMobiOutput(book *mobi.Book) MobiOutput {
@@ -43,6 +76,12 @@ func (m MobiOutput) GetBytes() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+func (m MobiOutput) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{Writer: w}
+	err := m.Realize().Write(cw)
+	return cw.n, err
+}
+
 // GetCoverImage returns the cover image if one exists
 func (m MobiOutput) GetCoverImage() image.Image {
 	return m.CoverImage
@@ -61,6 +100,33 @@ type EpubWriter interface {
 // EpubOutput wraps an epub.Epub to implement FormatOutput
 type EpubOutput struct {
 	*epub.Epub
+	// ExternalIDs are written as additional identifiers of the book,
+	// keyed by their scheme such as "isbn" or "anilist".
+	ExternalIDs map[string]string
+	// Modified is the last modification date written to the book, the
+	// current time if zero. Pinning it makes builds reproducible.
+	Modified time.Time
+	// CoverPage shows the cover image as the first page of the book. By
+	// default the cover is only shown as book metadata.
+	CoverPage bool
+	// Generator names the program that generated the book, such as
+	// "kojirou 1.0", in its metadata.
+	Generator string
+	// StripMetadata removes all timestamps and the generator from the
+	// book, overriding Modified, so that it does not reveal when and by
+	// what it was written.
+	StripMetadata bool
+	// Archive is a book that was already written in memory, which is used
+	// instead of writing the Epub.
+	Archive []byte
+	// EpubVersion is the EPUB version of the book, 3 if zero. Version 2 is
+	// read by older devices and apps, but loses EPUB 3 metadata such as
+	// the fixed layout.
+	EpubVersion int
+	// SkippedChapters names the chapters that were left out of the book,
+	// such as chapters without pages, as "<volume>/<chapter>", so that
+	// the book is not taken to be missing them.
+	SkippedChapters []string
 }
 
 func NewEpubOutput(epub *epub.Epub) EpubOutput {
@@ -72,7 +138,53 @@ func (e EpubOutput) Extension() string {
 }
 
 func (e EpubOutput) GetBytes() ([]byte, error) {
-	tempFile, err := os.CreateTemp("", "epub-*.epub")
+	data := e.Archive
+	if data == nil {
+		written, err := e.write()
+		if err != nil {
+			return nil, err
+		}
+		data = written
+	}
+	data, err := rewriteNavigation(data)
+	if err != nil {
+		return nil, fmt.Errorf("navigation: %w", err)
+	}
+	data, err = recompressArchive(data)
+	if err != nil {
+		return nil, fmt.Errorf("compression: %w", err)
+	}
+
+	viewport, err := commonPageSize(data)
+	if err != nil {
+		return nil, fmt.Errorf("page size: %w", err)
+	}
+	modified, generator := e.Modified, e.Generator
+	if e.StripMetadata {
+		modified, generator = strippedDate, ""
+	}
+	data, err = rewritePackage(data, func(opf string) string {
+		opf = injectIdentifiers(injectAccessibilityMetadata(opf), e.ExternalIDs)
+		opf = injectSkippedChapters(opf, e.SkippedChapters)
+		opf = injectFixedLayout(injectCoverPage(opf, e.CoverPage), viewport)
+		return injectGenerator(injectModified(opf, modified), generator)
+	})
+	if err == nil && e.EpubVersion == 2 {
+		data, err = convertEPUB2(data)
+		if err != nil {
+			return nil, fmt.Errorf("epub2: %w", err)
+		}
+	}
+	if err != nil || !e.StripMetadata {
+		return data, err
+	}
+
+	return stripArchive(data)
+}
+
+// write writes the Epub through a temporary file and returns its data
+func (e EpubOutput) write() ([]byte, error) {
+	tempFile, err := os.CreateTemp(util.TempDir(), "epub-*.epub")
 	if err != nil {
 		return nil, fmt.Errorf("create temp file: %w", err)
 	}
@@ -85,22 +197,106 @@ func (e EpubOutput) GetBytes() ([]byte, error) {
 	}
 
 	// Read back the file
-	return os.ReadFile(tempFile.Name())
+	data, err := os.ReadFile(tempFile.Name())
+	if err != nil {
+		return nil, fmt.Errorf("read epub: %w", err)
+	}
+
+	return data, nil
+}
+
+func (e EpubOutput) WriteTo(w io.Writer) (int64, error) {
+	return writeBytes(e, w)
 }
 
 // KepubOutput wraps an epub.Epub to implement FormatOutput
 type KepubOutput struct {
 	*epub.Epub
+	// Modified is the last modification date written to the book, the
+	// current time if zero.
+	Modified time.Time
+	// Generator names the program that generated the book.
+	Generator string
+	// StripMetadata removes all timestamps and the generator from the
+	// book, overriding Modified.
+	StripMetadata bool
+	// StripMediaOverlays removes SMIL media overlays during conversion.
+	StripMediaOverlays bool
+	// SkippedChapters names the chapters that were left out of the book.
+	SkippedChapters []string
 }
 
 func NewKepubOutput(epub *epub.Epub) KepubOutput {
 	return KepubOutput{Epub: epub}
 }
 
+// Extension returns the double extension that Kobo devices require to
+// treat a book as KEPUB rather than plain EPUB
 func (k KepubOutput) Extension() string {
-	return "kepub.epub"
+	return strings.TrimPrefix(kepubconv.KEPUBExtension, ".")
 }
 
 func (k KepubOutput) GetBytes() ([]byte, error) {
-	return kepubconv.ConvertToKEPUB(k.Epub, "", 0)
+	data, err := kepubconv.ConvertToKEPUBWithOptions(k.Epub, "", 0, kepubconv.Options{
+		StripMediaOverlays: k.StripMediaOverlays,
+	})
+	if err != nil {
+		return nil, err
+	}
+	data, err = rewriteNavigation(data)
+	if err != nil {
+		return nil, fmt.Errorf("navigation: %w", err)
+	}
+
+	viewport, err := commonPageSize(data)
+	if err != nil {
+		return nil, fmt.Errorf("page size: %w", err)
+	}
+	modified, generator := k.Modified, k.Generator
+	if k.StripMetadata {
+		modified, generator = strippedDate, ""
+	}
+	data, err = rewritePackage(data, func(opf string) string {
+		opf = injectModified(injectFixedLayout(opf, viewport), modified)
+		opf = injectSkippedChapters(opf, k.SkippedChapters)
+		return injectGenerator(opf, generator)
+	})
+	if err != nil || !k.StripMetadata {
+		return data, err
+	}
+
+	return stripArchive(data)
+}
+
+func (k KepubOutput) WriteTo(w io.Writer) (int64, error) {
+	return writeBytes(k, w)
+}
+
+// PdfOutput wraps a document.Document to implement FormatOutput
+type PdfOutput struct {
+	*document.Document
+}
+
+func NewPdfOutput(doc *document.Document) PdfOutput {
+	return PdfOutput{Document: doc}
+}
+
+func (p PdfOutput) Extension() string {
+	return "pdf"
+}
+
+func (p PdfOutput) GetBytes() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := p.Write(buf); err != nil {
+		return nil, fmt.Errorf("write pdf: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (p PdfOutput) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{Writer: w}
+	if err := p.Write(cw); err != nil {
+		return cw.n, fmt.Errorf("write pdf: %w", err)
+	}
+	return cw.n, nil
 }
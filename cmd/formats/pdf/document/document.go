@@ -0,0 +1,165 @@
+// Package document writes PDF documents made up of full-page images
+package document
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// Document is a PDF document with one page image per page.
+type Document struct {
+	Title   string
+	Authors []string
+	// RightToLeft marks the document to be displayed right-to-left by
+	// viewers that lay out facing pages.
+	RightToLeft bool
+	// Pages holds the page images in reading order.
+	Pages []image.Image
+}
+
+// Write writes the document as a PDF document. Every page is sized to its
+// image at one point per pixel and images are stored as JPEG.
+func (d Document) Write(w io.Writer) error {
+	pw := &writer{w: bufio.NewWriter(w)}
+	pw.printf("%%PDF-1.4\n%%\xE2\xE3\xCF\xD3\n")
+
+	// Objects 1 to 3 are the catalog, page tree and document information,
+	// followed by the page, contents and image object of every page.
+	pageRefs := make([]string, len(d.Pages))
+	for i := range d.Pages {
+		pageRefs[i] = fmt.Sprintf("%v 0 R", 4+3*i)
+	}
+
+	direction := "L2R"
+	if d.RightToLeft {
+		direction = "R2L"
+	}
+	pw.object(1, fmt.Sprintf("<< /Type /Catalog /Pages 2 0 R /ViewerPreferences << /Direction /%v >> >>", direction))
+	pw.object(2, fmt.Sprintf("<< /Type /Pages /Kids [%v] /Count %v >>", strings.Join(pageRefs, " "), len(d.Pages)))
+	pw.object(3, fmt.Sprintf("<< /Title %v /Author %v /Producer %v >>",
+		textString(d.Title), textString(strings.Join(d.Authors, ", ")), textString("kojirou"),
+	))
+
+	for i, img := range d.Pages {
+		id := 4 + 3*i
+		data, colorSpace, err := encodeJPEG(img)
+		if err != nil {
+			return fmt.Errorf("page %v: %w", i+1, err)
+		}
+		width, height := img.Bounds().Dx(), img.Bounds().Dy()
+		content := fmt.Sprintf("q %v 0 0 %v 0 0 cm /Im0 Do Q", width, height)
+
+		pw.object(id, fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %v %v] /Contents %v 0 R /Resources << /XObject << /Im0 %v 0 R >> >> >>",
+			width, height, id+1, id+2,
+		))
+		pw.stream(id+1, "", []byte(content))
+		pw.stream(id+2, fmt.Sprintf(
+			"/Type /XObject /Subtype /Image /Width %v /Height %v /ColorSpace /%v /BitsPerComponent 8 /Filter /DCTDecode",
+			width, height, colorSpace,
+		), data)
+	}
+
+	pw.trailer(3+3*len(d.Pages), 1, 3)
+	if pw.err != nil {
+		return pw.err
+	}
+
+	return pw.w.Flush()
+}
+
+func encodeJPEG(img image.Image) ([]byte, string, error) {
+	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, img, nil); err != nil {
+		return nil, "", fmt.Errorf("encode: %w", err)
+	}
+	if _, ok := img.(*image.Gray); ok {
+		return buf.Bytes(), "DeviceGray", nil
+	}
+
+	return buf.Bytes(), "DeviceRGB", nil
+}
+
+// textString encodes a PDF text string, using UTF-16 for non-ASCII text
+func textString(s string) string {
+	ascii := true
+	for _, r := range s {
+		if r > 0x7E || r < 0x20 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return "(" + strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s) + ")"
+	}
+
+	hex := new(strings.Builder)
+	hex.WriteString("<FEFF")
+	for _, unit := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(hex, "%04X", unit)
+	}
+	hex.WriteString(">")
+
+	return hex.String()
+}
+
+// writer writes PDF objects while recording their offsets for the
+// cross-reference table. The first error is kept and stops all writes.
+type writer struct {
+	w       *bufio.Writer
+	offset  int
+	offsets []int
+	err     error
+}
+
+func (pw *writer) printf(format string, args ...any) {
+	if pw.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(pw.w, format, args...)
+	pw.offset += n
+	pw.err = err
+}
+
+func (pw *writer) write(data []byte) {
+	if pw.err != nil {
+		return
+	}
+	n, err := pw.w.Write(data)
+	pw.offset += n
+	pw.err = err
+}
+
+func (pw *writer) object(id int, dict string) {
+	pw.begin(id)
+	pw.printf("%v 0 obj\n%v\nendobj\n", id, dict)
+}
+
+func (pw *writer) stream(id int, dict string, data []byte) {
+	pw.begin(id)
+	pw.printf("%v 0 obj\n<< %v /Length %v >>\nstream\n", id, dict, len(data))
+	pw.write(data)
+	pw.printf("\nendstream\nendobj\n")
+}
+
+func (pw *writer) begin(id int) {
+	for len(pw.offsets) < id {
+		pw.offsets = append(pw.offsets, 0)
+	}
+	pw.offsets[id-1] = pw.offset
+}
+
+func (pw *writer) trailer(size, root, info int) {
+	xref := pw.offset
+	pw.printf("xref\n0 %v\n0000000000 65535 f \n", size+1)
+	for _, offset := range pw.offsets {
+		pw.printf("%010d 00000 n \n", offset)
+	}
+	pw.printf("trailer\n<< /Size %v /Root %v 0 R /Info %v 0 R >>\nstartxref\n%v\n%%%%EOF\n", size+1, root, info, xref)
+}
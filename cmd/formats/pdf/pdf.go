@@ -0,0 +1,41 @@
+// Package pdf generates PDF documents containing one manga page per PDF page
+package pdf
+
+import (
+	"image"
+
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/pdf/document"
+	"github.com/leotaku/kojirou/mangadex"
+)
+
+// GeneratePDF creates a PDF document from manga data. Pages are cropped and
+// split like for the other formats and are kept in reading order.
+func GeneratePDF(manga mangadex.Manga, widepage kindle.WidepagePolicy, crop bool, ltr bool) document.Document {
+	return GeneratePDFWithOptions(manga, kindle.PageOptions{
+		Policy:      widepage,
+		AutoCrop:    crop,
+		LeftToRight: ltr,
+	})
+}
+
+// GeneratePDFWithOptions creates a PDF document like GeneratePDF, with
+// pages processed according to the given options.
+func GeneratePDFWithOptions(manga mangadex.Manga, opts kindle.PageOptions) document.Document {
+	ltr := opts.LeftToRight
+	pages := make([]image.Image, 0)
+	for _, vol := range manga.Sorted() {
+		for _, chap := range vol.Sorted() {
+			for _, img := range chap.Sorted() {
+				pages = append(pages, kindle.CropAndSplitWithOptions(img, opts)...)
+			}
+		}
+	}
+
+	return document.Document{
+		Title:       manga.Info.Title,
+		Authors:     manga.Info.Authors,
+		RightToLeft: !ltr,
+		Pages:       pages,
+	}
+}
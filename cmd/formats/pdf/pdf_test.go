@@ -0,0 +1,105 @@
+package pdf
+
+import (
+	"bytes"
+	"image/jpeg"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+)
+
+var (
+	pagesPattern  = regexp.MustCompile(`/Type /Pages /Kids \[[^\]]*\] /Count (\d+)`)
+	pagePattern   = regexp.MustCompile(`/Type /Page /Parent 2 0 R /MediaBox \[0 0 (\d+) (\d+)\]`)
+	imagePattern  = regexp.MustCompile(`/Filter /DCTDecode /Length (\d+) >>\nstream\n`)
+	xrefPattern   = regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`)
+	objectPattern = regexp.MustCompile(`(?m)^(\d+) 0 obj$`)
+)
+
+func TestGeneratePDF(t *testing.T) {
+	manga := testhelpers.CreateWidePageTestManga()
+	expected := 0
+	for _, vol := range manga.Sorted() {
+		for _, chap := range vol.Sorted() {
+			for _, img := range chap.Sorted() {
+				expected += len(kindle.CropAndSplit(img, kindle.WidepagePolicySplit, false, false))
+			}
+		}
+	}
+
+	doc := GeneratePDF(manga, kindle.WidepagePolicySplit, false, false)
+	doc.Title = "テスト (Test)"
+	buf := new(bytes.Buffer)
+	if err := doc.Write(buf); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	data := buf.Bytes()
+
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) {
+		t.Errorf("missing PDF header")
+	}
+	if match := pagesPattern.FindSubmatch(data); match == nil {
+		t.Errorf("missing page tree")
+	} else if count, _ := strconv.Atoi(string(match[1])); count != expected {
+		t.Errorf("page tree counts %v pages, expected %v", count, expected)
+	}
+	if pages := pagePattern.FindAll(data, -1); len(pages) != expected {
+		t.Errorf("expected %v pages, got %v", expected, len(pages))
+	}
+
+	// Pages are sized to their images, which must be valid JPEG data
+	images := imagePattern.FindAllSubmatchIndex(data, -1)
+	if len(images) != expected {
+		t.Fatalf("expected %v images, got %v", expected, len(images))
+	}
+	for i, match := range images {
+		length, _ := strconv.Atoi(string(data[match[2]:match[3]]))
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(data[match[1] : match[1]+length]))
+		if err != nil {
+			t.Fatalf("image %v is invalid: %v", i, err)
+		}
+		size := pagePattern.FindAllSubmatch(data, -1)[i]
+		if strconv.Itoa(cfg.Width) != string(size[1]) || strconv.Itoa(cfg.Height) != string(size[2]) {
+			t.Errorf("page %v is %sx%s, image is %vx%v", i, size[1], size[2], cfg.Width, cfg.Height)
+		}
+	}
+
+	// Metadata is set, non-ASCII text is encoded as UTF-16
+	for _, meta := range []string{
+		"/Title <FEFF30C630B930C80020002800540065007300740029>",
+		"/Author (Test Author)",
+		"/Direction /R2L",
+	} {
+		if !bytes.Contains(data, []byte(meta)) {
+			t.Errorf("missing %q", meta)
+		}
+	}
+
+	// Cross-reference offsets point at their objects
+	match := xrefPattern.FindSubmatch(data)
+	if match == nil {
+		t.Fatalf("missing cross-reference table")
+	}
+	xref, _ := strconv.Atoi(string(match[1]))
+	entries := bytes.Split(data[xref:], []byte("\n"))[3:]
+	for i, object := range objectPattern.FindAllSubmatchIndex(data, -1) {
+		offset, _ := strconv.Atoi(string(entries[i][:10]))
+		if offset != object[0] {
+			t.Errorf("object %s is at %v, cross-reference points to %v", data[object[2]:object[3]], object[0], offset)
+		}
+	}
+}
+
+func TestGeneratePDFDirection(t *testing.T) {
+	doc := GeneratePDF(testhelpers.CreateTestManga(), kindle.WidepagePolicyPreserve, false, true)
+	buf := new(bytes.Buffer)
+	if err := doc.Write(buf); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("/Direction /L2R")) {
+		t.Errorf("left-to-right document is not marked as such")
+	}
+}
@@ -4,8 +4,11 @@ package progress
 import (
 	"fmt"
 	"io"
+	"os"
+	"sync"
 
 	"github.com/cheggaaa/pb/v3"
+	"github.com/mattn/go-isatty"
 )
 
 const (
@@ -21,9 +24,72 @@ const (
 		`{{   string . "message" | printf "%-15v" }}` +
 		`{{ else }}` +
 		`{{   counters . | printf "%-15v" }}` +
-		`{{ end }}` + `{{ " |" }}`
+		`{{ end }}` + `{{ " |" }}` +
+		`{{ with rate . }}{{ printf " %-16v" . }}{{ end }}`
 )
 
+const animatedKey = "animated"
+
+var (
+	staticMu    sync.Mutex
+	staticMode  bool
+	hiddenMode  bool
+	interactive = isatty.IsTerminal(os.Stderr.Fd()) || isatty.IsCygwinTerminal(os.Stderr.Fd())
+	animated    int
+)
+
+// SetStatic switches newly created progress bars to static mode, in which
+// they are not animated and only print their final state once finished.
+// This keeps output coherent while several bars are active concurrently.
+func SetStatic(enabled bool) {
+	staticMu.Lock()
+	defer staticMu.Unlock()
+	staticMode = enabled
+}
+
+// SetHidden discards the output of newly created progress bars.
+func SetHidden(enabled bool) {
+	staticMu.Lock()
+	defer staticMu.Unlock()
+	hiddenMode = enabled
+}
+
+// SetInteractive overrides whether progress bars are drawn on a terminal.
+// It defaults to whether standard error is a terminal. Outside terminals,
+// such as in CI or redirected output, bars are not animated and instead
+// print their final state as a plain line, like in static mode.
+func SetInteractive(enabled bool) {
+	staticMu.Lock()
+	defer staticMu.Unlock()
+	interactive = enabled
+}
+
+// Println writes a line of text to w. Animated progress bars drawn on the
+// same terminal are cleared first, so that they are redrawn below the text
+// instead of being interleaved with it.
+func Println(w io.Writer, line string) {
+	staticMu.Lock()
+	defer staticMu.Unlock()
+	if animated > 0 {
+		fmt.Fprint(w, "\r\x1b[K")
+	}
+	fmt.Fprintln(w, line)
+}
+
+func start(bar *pb.ProgressBar) {
+	staticMu.Lock()
+	static := staticMode || !interactive
+	bar.Set(pb.Static, static || hiddenMode)
+	if hiddenMode {
+		bar.SetWriter(io.Discard)
+	} else if !static {
+		bar.Set(animatedKey, true)
+		animated++
+	}
+	staticMu.Unlock()
+	bar.Start()
+}
+
 type Progress interface {
 	Increase(int)
 	Add(int)
@@ -48,7 +114,19 @@ func (p CliProgress) NewProxyWriter(w io.Writer) io.Writer {
 }
 
 func (p CliProgress) Done() {
+	if p.bar.IsFinished() {
+		return
+	}
 	p.bar.Finish()
+
+	staticMu.Lock()
+	defer staticMu.Unlock()
+	if p.bar.GetBool(animatedKey) {
+		animated--
+	} else if p.bar.GetBool(pb.Static) && !p.bar.GetBool(pb.CleanOnFinish) && !hiddenMode {
+		// Static bars are never rendered automatically
+		fmt.Fprintln(os.Stderr, p.bar.String())
+	}
 }
 
 // SetFormat sets the format indicator in the progress bar
@@ -56,6 +134,12 @@ func (p *CliProgress) SetFormat(format string) {
 	p.bar.Set("format", format)
 }
 
+// ShowRate displays the throughput and estimated remaining time of the
+// progress bar until it is done
+func (p *CliProgress) ShowRate() {
+	p.bar.Set(rateKey, true)
+}
+
 // Cancel cancels the progress bar with a message
 func (p *CliProgress) Cancel(message string) {
 	p.bar.Set("message", message)
@@ -79,7 +163,7 @@ func (p *CliProgress) SetFormatMessage(format, message string) {
 func TitledProgress(title string) CliProgress {
 	bar := pb.New(0).SetTemplate(progressTemplate)
 	bar.Set("prefix", title)
-	bar.Start()
+	start(bar)
 
 	return CliProgress{bar, true}
 }
@@ -89,7 +173,7 @@ func FormatTitledProgress(title string, format string) CliProgress {
 	bar := pb.New(0).SetTemplate(progressTemplate)
 	bar.Set("prefix", title)
 	bar.Set("format", format)
-	bar.Start()
+	start(bar)
 
 	return CliProgress{bar, true}
 }
@@ -99,7 +183,7 @@ func VanishingProgress(title string) CliProgress {
 	bar := pb.New(0).SetTemplate(progressTemplate)
 	bar.Set("prefix", title)
 	bar.Set(pb.CleanOnFinish, true)
-	bar.Start()
+	start(bar)
 
 	return CliProgress{bar, true}
 }
@@ -110,7 +194,7 @@ func FormatVanishingProgress(title string, format string) CliProgress {
 	bar.Set("prefix", title)
 	bar.Set("format", format)
 	bar.Set(pb.CleanOnFinish, true)
-	bar.Start()
+	start(bar)
 
 	return CliProgress{bar, true}
 }
@@ -120,7 +204,7 @@ func FormatVanishingProgress(title string, format string) CliProgress {
 func MultiFormatStatusProgress(title string, formats []string) CliProgress {
 	bar := pb.New(len(formats)).SetTemplate(progressTemplate)
 	bar.Set("prefix", title)
-	bar.Start()
+	start(bar)
 
 	return CliProgress{bar, true}
 }
@@ -1,6 +1,9 @@
 package progress_test
 
 import (
+"bytes"
+"os"
+"strings"
 "testing"
 
 "github.com/leotaku/kojirou/cmd/formats/progress"
@@ -28,3 +31,75 @@ p4 := progress.FormatVanishingProgress("Test Format Vanishing", "mobi")
 p4.Add(10)
 p4.Done()
 }
+
+func TestPrintln(t *testing.T) {
+	buf := new(bytes.Buffer)
+	progress.Println(buf, "plain")
+	if buf.String() != "plain\n" {
+		t.Errorf("expected plain line, got %q", buf.String())
+	}
+
+	// Animated bars are cleared before writing
+	progress.SetInteractive(true)
+	defer progress.SetInteractive(false)
+	p := progress.TitledProgress("Test Println")
+	buf.Reset()
+	progress.Println(buf, "cleared")
+	p.Done()
+	if buf.String() != "\r\x1b[Kcleared\n" {
+		t.Errorf("expected cleared line, got %q", buf.String())
+	}
+
+	// Hidden bars are not drawn, so nothing is cleared
+	progress.SetHidden(true)
+	defer progress.SetHidden(false)
+	p = progress.TitledProgress("Test Println")
+	buf.Reset()
+	progress.Println(buf, "hidden")
+	p.Done()
+	if buf.String() != "hidden\n" {
+		t.Errorf("expected plain line, got %q", buf.String())
+	}
+}
+
+func TestNonInteractive(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	origStderr := os.Stderr
+	defer func() { os.Stderr = origStderr }()
+	os.Stderr = w
+
+	progress.SetInteractive(false)
+	p1 := progress.TitledProgress("Test Title")
+	p1.Increase(2)
+	p1.Add(2)
+	p2 := progress.VanishingProgress("Test Vanishing")
+	p2.Increase(1)
+	p2.Add(1)
+	p3 := progress.MultiFormatStatusProgress("Test Formats", []string{"epub"})
+	p3.FormatCompleted("epub", "Success")
+	progress.Println(os.Stderr, "line")
+	p2.Done()
+	p3.Done()
+	p1.Done()
+	w.Close()
+	os.Stderr = origStderr
+
+	buf := new(bytes.Buffer)
+	if _, err := buf.ReadFrom(r); err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	if strings.ContainsAny(buf.String(), "\r\x1b") {
+		t.Errorf("output contains control sequences: %q", buf.String())
+	}
+	for _, want := range []string{"line\n", "Test Title", "epub: Success"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output is missing %q: %q", want, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "Test Vanishing") {
+		t.Errorf("vanishing progress was printed: %q", buf.String())
+	}
+}
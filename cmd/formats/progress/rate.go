@@ -0,0 +1,64 @@
+package progress
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cheggaaa/pb/v3"
+)
+
+const rateKey = "rate"
+
+func init() {
+	pb.RegisterElement(rateKey, pb.ElementFunc(func(state *pb.State, args ...string) string {
+		if !state.GetBool(rateKey) || state.IsFinished() {
+			return ""
+		}
+
+		return Rate{
+			Completed: state.Value(),
+			Total:     state.Total(),
+			Elapsed:   state.Time().Sub(state.StartTime()),
+		}.String()
+	}), false)
+}
+
+// Rate describes how many of the total items of a task were completed
+// in the time elapsed since it started
+type Rate struct {
+	Completed int64
+	Total     int64
+	Elapsed   time.Duration
+}
+
+// PerSecond returns the average number of items completed per second
+func (r Rate) PerSecond() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+
+	return float64(r.Completed) / r.Elapsed.Seconds()
+}
+
+// Remaining estimates the time until all items are completed from the
+// average throughput so far. It is unknown until an item is completed.
+func (r Rate) Remaining() (time.Duration, bool) {
+	perSecond := r.PerSecond()
+	if perSecond <= 0 {
+		return 0, false
+	}
+	left := max(r.Total-r.Completed, 0)
+
+	return time.Duration(float64(left) / perSecond * float64(time.Second)), true
+}
+
+// String formats the throughput and remaining time, e.g. "4.2/s 1m5s left",
+// or returns an empty string if they are still unknown
+func (r Rate) String() string {
+	remaining, ok := r.Remaining()
+	if !ok {
+		return ""
+	}
+
+	return fmt.Sprintf("%.1f/s %v left", r.PerSecond(), remaining.Round(time.Second))
+}
@@ -0,0 +1,41 @@
+package progress_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/leotaku/kojirou/cmd/formats/progress"
+)
+
+func TestRate(t *testing.T) {
+	// Two of twenty pages are downloaded every second
+	rate := progress.Rate{Total: 20}
+	for second := 1; second <= 4; second++ {
+		rate.Completed += 2
+		rate.Elapsed += time.Second
+	}
+
+	if perSecond := rate.PerSecond(); perSecond != 2 {
+		t.Errorf("PerSecond() = %v, want 2", perSecond)
+	}
+	if remaining, ok := rate.Remaining(); !ok || remaining != 6*time.Second {
+		t.Errorf("Remaining() = %v, %v, want 6s, true", remaining, ok)
+	}
+	if s := rate.String(); s != "2.0/s 6s left" {
+		t.Errorf("String() = %q, want %q", s, "2.0/s 6s left")
+	}
+
+	// Newly discovered pages extend the estimate
+	rate.Total += 8
+	if remaining, _ := rate.Remaining(); remaining != 10*time.Second {
+		t.Errorf("Remaining() = %v after growing total, want 10s", remaining)
+	}
+
+	// Nothing can be estimated before the first page
+	if _, ok := (progress.Rate{Total: 20, Elapsed: time.Second}).Remaining(); ok {
+		t.Error("Remaining() is known without completed pages")
+	}
+	if s := (progress.Rate{Total: 20}).String(); s != "" {
+		t.Errorf("String() = %q without progress, want empty", s)
+	}
+}
@@ -0,0 +1,119 @@
+package formats
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	md "github.com/leotaku/kojirou/mangadex"
+)
+
+// scopedFormat is a format selected for the volumes within a range, where
+// nil bounds leave the range open
+type scopedFormat struct {
+	format     FormatType
+	start, end *md.Identifier
+}
+
+// contains reports whether the format is selected for the volume
+func (s scopedFormat) contains(volume md.Identifier) bool {
+	return (s.start == nil || s.start.LessOrEqual(volume)) && (s.end == nil || volume.LessOrEqual(*s.end))
+}
+
+// ScopedFormats are formats selected for ranges of volumes
+type ScopedFormats []scopedFormat
+
+// ParseScopedFormats converts a comma-separated string of format names
+// into formats scoped to ranges of volumes. Every format may be prefixed
+// with a range of volumes and a colon, such as "1-5:mobi" or "6-:kepub",
+// to only select it for these volumes. Ranges may leave out their start
+// or end, and ".." may be used instead of "-" like for --volumes. Formats
+// without a range are selected for all volumes.
+func ParseScopedFormats(formatStr string) (ScopedFormats, error) {
+	parts := strings.Split(formatStr, ",")
+	scoped := make(ScopedFormats, 0, len(parts))
+
+	for _, part := range parts {
+		scope, name, ok := strings.Cut(part, ":")
+		if !ok {
+			scope, name = "", part
+		}
+		format := FormatType(strings.TrimSpace(strings.ToLower(name)))
+		switch format {
+		case FormatMobi, FormatEpub, FormatKepub, FormatPdf:
+		default:
+			return nil, fmt.Errorf("unsupported format: %s", part)
+		}
+
+		s := scopedFormat{format: format}
+		if ok {
+			start, end, err := parseVolumeRange(strings.TrimSpace(scope))
+			if err != nil {
+				return nil, fmt.Errorf("volume range of %s: %w", part, err)
+			}
+			s.start, s.end = start, end
+		}
+		scoped = append(scoped, s)
+	}
+
+	if len(scoped) == 0 {
+		return nil, fmt.Errorf("no valid formats specified")
+	}
+
+	return scoped, nil
+}
+
+// parseVolumeRange parses a range of volumes such as "1-5", "6-" or "3"
+func parseVolumeRange(s string) (*md.Identifier, *md.Identifier, error) {
+	startStr, endStr, isRange := strings.Cut(s, "..")
+	if !isRange {
+		startStr, endStr, isRange = strings.Cut(s, "-")
+	}
+	if !isRange {
+		endStr = startStr
+	}
+
+	var start, end *md.Identifier
+	if startStr != "" {
+		id := md.NewIdentifier(startStr)
+		start = &id
+	}
+	if endStr != "" {
+		id := md.NewIdentifier(endStr)
+		end = &id
+	}
+	switch {
+	case start == nil && end == nil:
+		return nil, nil, fmt.Errorf("empty range")
+	case start != nil && end != nil && end.Less(*start):
+		return nil, nil, fmt.Errorf("range ends before it starts")
+	}
+
+	return start, end, nil
+}
+
+// For returns the formats selected for the given volume, in the order they
+// were first given
+func (s ScopedFormats) For(volume md.Identifier) []FormatType {
+	formats := make([]FormatType, 0, len(s))
+	for _, scoped := range s {
+		if scoped.contains(volume) && !slices.Contains(formats, scoped.format) {
+			formats = append(formats, scoped.format)
+		}
+	}
+
+	return formats
+}
+
+// All returns the formats selected for any volume, in the order they were
+// first given
+func (s ScopedFormats) All() []FormatType {
+	formats := make([]FormatType, 0, len(s))
+	for _, scoped := range s {
+		if !slices.Contains(formats, scoped.format) {
+			formats = append(formats, scoped.format)
+		}
+	}
+
+	return formats
+}
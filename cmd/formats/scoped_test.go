@@ -0,0 +1,37 @@
+package formats
+
+import (
+	"slices"
+	"testing"
+
+	md "github.com/leotaku/kojirou/mangadex"
+)
+
+func TestParseScopedFormats(t *testing.T) {
+	scoped, err := ParseScopedFormats("1-5:mobi, 6-:kepub, epub, 3..4:pdf, 8:mobi")
+	if err != nil {
+		t.Fatalf("ParseScopedFormats() failed: %v", err)
+	}
+	for volume, want := range map[string][]FormatType{
+		"1":       {FormatMobi, FormatEpub},
+		"3":       {FormatMobi, FormatEpub, FormatPdf},
+		"4.5":     {FormatMobi, FormatEpub},
+		"5":       {FormatMobi, FormatEpub},
+		"6":       {FormatKepub, FormatEpub},
+		"8":       {FormatKepub, FormatEpub, FormatMobi},
+		"Unknown": {FormatKepub, FormatEpub},
+	} {
+		if got := scoped.For(md.NewIdentifier(volume)); !slices.Equal(got, want) {
+			t.Errorf("volume %v: got formats %v, want %v", volume, got, want)
+		}
+	}
+	if got, want := scoped.All(), []FormatType{FormatMobi, FormatKepub, FormatEpub, FormatPdf}; !slices.Equal(got, want) {
+		t.Errorf("got all formats %v, want %v", got, want)
+	}
+
+	for _, invalid := range []string{"", "1-5:", "1-5:cbz", "-:mobi", "5-1:mobi", ":epub"} {
+		if _, err := ParseScopedFormats(invalid); err == nil {
+			t.Errorf("ParseScopedFormats(%q) succeeded, want error", invalid)
+		}
+	}
+}
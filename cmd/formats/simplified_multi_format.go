@@ -3,6 +3,7 @@ package formats
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
@@ -48,6 +49,16 @@ func (e *SimpleEPUBFormatOutput) Write() error {
 	return os.WriteFile(e.filePath, []byte("epub data"), 0644)
 }
 
+// WriteTo writes the EPUB to a writer
+func (e *SimpleEPUBFormatOutput) WriteTo(w io.Writer) (int64, error) {
+	data, err := e.GetBytes()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
 // Extension returns the file extension for this format
 func (e *SimpleEPUBFormatOutput) Extension() string {
 	return "epub"
@@ -71,6 +82,16 @@ func (k *SimpleKEPUBFormatOutput) Write() error {
 	return os.WriteFile(k.filePath, []byte("kepub data"), 0644)
 }
 
+// WriteTo writes the KEPUB to a writer
+func (k *SimpleKEPUBFormatOutput) WriteTo(w io.Writer) (int64, error) {
+	data, err := k.GetBytes()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
 // Extension returns the file extension for this format
 func (k *SimpleKEPUBFormatOutput) Extension() string {
 	return "kepub.epub"
@@ -9,6 +9,7 @@
 //   - CreateTestImage: Generates test images with specified dimensions
 //   - CreateWidePageTestManga: Creates a manga with wide pages for testing splitting functionality
 //   - CreateInvalidImageManga: Creates a manga with nil images to test error handling
+//   - CreateSyntheticManga: Creates a single-volume manga of configurable size
 //
 // Example usage:
 //
@@ -20,6 +21,7 @@
 package testhelpers
 
 import (
+	"fmt"
 	"image"
 	"image/color"
 
@@ -227,3 +229,48 @@ func CreateLargeImageManga() md.Manga {
 
 	return manga
 }
+
+// CreateSyntheticManga creates a single-volume manga of configurable size
+//
+// Parameters:
+//   - chapters: The number of chapters in the volume
+//   - pages: The number of pages in each chapter
+//   - width: The width of each page in pixels
+//   - height: The height of each page in pixels
+//
+// This function is useful for benchmarking format generators with
+// predictable amounts of input data.
+func CreateSyntheticManga(chapters, pages, width, height int) md.Manga {
+	volID := md.NewIdentifier("1")
+	vol := md.Volume{
+		Info: md.VolumeInfo{
+			Identifier: volID,
+		},
+		Chapters: map[md.Identifier]md.Chapter{},
+	}
+
+	for c := 1; c <= chapters; c++ {
+		chapID := md.NewIdentifier(fmt.Sprint(c))
+		chap := md.Chapter{
+			Info: md.ChapterInfo{
+				Identifier:       chapID,
+				Title:            fmt.Sprintf("Chapter %d", c),
+				VolumeIdentifier: volID,
+			},
+			Pages: map[int]image.Image{},
+		}
+		for p := 0; p < pages; p++ {
+			chap.Pages[p] = CreateTestImage(width, height, color.White)
+		}
+		vol.Chapters[chapID] = chap
+	}
+
+	return md.Manga{
+		Info: md.MangaInfo{
+			Title:   "Synthetic Manga",
+			ID:      "synthetic-manga-id",
+			Authors: []string{"Test Author"},
+		},
+		Volumes: map[md.Identifier]md.Volume{volID: vol},
+	}
+}
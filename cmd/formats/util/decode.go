@@ -0,0 +1,73 @@
+package util
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	"image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// gifFrame is the zero-based frame that animated GIF pages are flattened to
+var gifFrame int
+
+// SetGIFFrame changes the frame that animated GIF pages are flattened to,
+// starting at zero. Pages with fewer frames use their last frame.
+func SetGIFFrame(frame int) {
+	gifFrame = max(frame, 0)
+}
+
+// DecodeImage decodes an image of any registered format. GIF images are
+// flattened to a single static frame, so that animated pages enter the
+// pipeline like any other page.
+func DecodeImage(data []byte) (image.Image, string, error) {
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || format != "gif" {
+		return image.Decode(bytes.NewReader(data))
+	}
+
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, format, err
+	}
+
+	return flattenGIF(g, min(gifFrame, len(g.Image)-1)), format, nil
+}
+
+// flattenGIF renders the given frame of a GIF onto a white canvas of the
+// logical screen size, applying the disposal of all earlier frames
+func flattenGIF(g *gif.GIF, frame int) image.Image {
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() {
+		bounds = g.Image[0].Bounds()
+	}
+	canvas := image.NewRGBA(bounds)
+	draw.Draw(canvas, bounds, image.White, image.Point{}, draw.Src)
+
+	for i, img := range g.Image[:frame+1] {
+		var previous *image.RGBA
+		disposal := byte(0)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewRGBA(bounds)
+			draw.Draw(previous, bounds, canvas, bounds.Min, draw.Src)
+		}
+
+		draw.Draw(canvas, img.Bounds(), img, img.Bounds().Min, draw.Over)
+		if i == frame {
+			break
+		}
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, img.Bounds(), image.White, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+
+	return canvas
+}
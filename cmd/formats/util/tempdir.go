@@ -0,0 +1,41 @@
+package util
+
+import (
+	"fmt"
+	"os"
+)
+
+// tempDir is the directory for temporary files, the system default if empty
+var tempDir string
+
+// TempDir returns the directory for temporary files. It is empty for the
+// system default, so that it can be passed to os.MkdirTemp directly.
+func TempDir() string {
+	return tempDir
+}
+
+// SetTempDir changes the directory for temporary files after checking
+// that files can be created in it. Empty restores the system default.
+func SetTempDir(dir string) error {
+	if dir == "" {
+		tempDir = ""
+		return nil
+	}
+
+	if info, err := os.Stat(dir); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("'%v' is not a directory", dir)
+	}
+	f, err := os.CreateTemp(dir, ".kojirou-*")
+	if err != nil {
+		return fmt.Errorf("not writable: %w", err)
+	}
+	f.Close()
+	if err := os.Remove(f.Name()); err != nil {
+		return err
+	}
+
+	tempDir = dir
+	return nil
+}
@@ -0,0 +1,36 @@
+package util
+
+import (
+	"archive/zip"
+	"path"
+	"strings"
+)
+
+// zipStoreImages stores images in archives uncompressed
+var zipStoreImages bool
+
+// SetZipStoreImages changes whether images are stored uncompressed in
+// archives. Images are already compressed, so deflating them again costs
+// time for little or no gain, while text is still deflated.
+func SetZipStoreImages(store bool) {
+	zipStoreImages = store
+}
+
+// ZipMethod returns the compression method for an archive entry of the
+// given name
+func ZipMethod(name string) uint16 {
+	if zipStoreImages && isImage(name) {
+		return zip.Store
+	}
+
+	return zip.Deflate
+}
+
+func isImage(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp", ".bmp":
+		return true
+	default:
+		return false
+	}
+}
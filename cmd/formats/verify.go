@@ -0,0 +1,286 @@
+package formats
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/leotaku/kojirou/cmd/formats/output"
+	md "github.com/leotaku/kojirou/mangadex"
+)
+
+// VerifyFile re-reads a written ebook and checks its structural integrity.
+//
+// EPUB and KEPUB files must be readable archives containing the mimetype,
+// container and package documents, and every image in the manifest must
+// be referenced by some XHTML document and vice versa. MOBI files must carry valid PalmDB
+// type and creator fields. PDF files must carry a PDF header and end
+// with an end-of-file marker.
+func VerifyFile(filename string, format FormatType) error {
+	switch format {
+	case FormatEpub, FormatKepub:
+		return verifyEPUB(filename)
+	case FormatMobi:
+		return verifyMOBI(filename)
+	case FormatPdf:
+		return verifyPDF(filename)
+	default:
+		return fmt.Errorf("unsupported format: %v", format)
+	}
+}
+
+func verifyEPUB(filename string) error {
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		return fmt.Errorf("open archive: %w", err)
+	}
+	defer r.Close()
+
+	if len(r.File) == 0 || r.File[0].Name != "mimetype" {
+		return fmt.Errorf("mimetype is not the first entry")
+	}
+	mimetype, err := readZipFile(r.File[0])
+	if err != nil {
+		return fmt.Errorf("mimetype: %w", err)
+	} else if string(bytes.TrimSpace(mimetype)) != "application/epub+zip" {
+		return fmt.Errorf("mimetype: unexpected value %q", mimetype)
+	}
+
+	files := make(map[string]*zip.File)
+	for _, f := range r.File {
+		files[f.Name] = f
+	}
+
+	containerFile, ok := files["META-INF/container.xml"]
+	if !ok {
+		return fmt.Errorf("container: missing")
+	}
+	data, err := readZipFile(containerFile)
+	if err != nil {
+		return fmt.Errorf("container: %w", err)
+	}
+	container := struct {
+		Rootfiles []struct {
+			FullPath string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}{}
+	if err := xml.Unmarshal(data, &container); err != nil {
+		return fmt.Errorf("container: %w", err)
+	} else if len(container.Rootfiles) == 0 {
+		return fmt.Errorf("container: no rootfile")
+	}
+
+	for _, rootfile := range container.Rootfiles {
+		opfFile, ok := files[rootfile.FullPath]
+		if !ok {
+			return fmt.Errorf("package: missing '%v'", rootfile.FullPath)
+		}
+		data, err := readZipFile(opfFile)
+		if err != nil {
+			return fmt.Errorf("package: %w", err)
+		}
+		if err := verifyImageReferences(files, rootfile.FullPath, data); err != nil {
+			return fmt.Errorf("images: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// imageSourceRe matches the sources of HTML and SVG images
+var imageSourceRe = regexp.MustCompile(`<(?:img|image)\b[^>]*?\s(?:src|xlink:href|href)="([^"]+)"`)
+
+// verifyImageReferences cross-checks the images of a package document
+// against the images referenced by its XHTML documents. Images that are
+// never referenced are reported as orphaned, references to images that
+// are missing from the manifest or archive are reported as dangling. The
+// cover image may be referenced by the package document alone.
+func verifyImageReferences(files map[string]*zip.File, opfPath string, opf []byte) error {
+	pkg := struct {
+		Items []struct {
+			Href       string `xml:"href,attr"`
+			MediaType  string `xml:"media-type,attr"`
+			Properties string `xml:"properties,attr"`
+		} `xml:"manifest>item"`
+	}{}
+	if err := xml.Unmarshal(opf, &pkg); err != nil {
+		return fmt.Errorf("package: %w", err)
+	}
+
+	images := make(map[string]bool)
+	referenced := make(map[string]bool)
+	documents := make([]string, 0)
+	for _, item := range pkg.Items {
+		href := resolveHref(opfPath, item.Href)
+		switch {
+		case strings.HasPrefix(item.MediaType, "image/"):
+			images[href] = true
+			if slices.Contains(strings.Fields(item.Properties), "cover-image") {
+				referenced[href] = true
+			}
+		case item.MediaType == "application/xhtml+xml":
+			documents = append(documents, href)
+		}
+	}
+
+	dangling := make([]string, 0)
+	for _, document := range documents {
+		f, ok := files[document]
+		if !ok {
+			continue
+		}
+		data, err := readZipFile(f)
+		if err != nil {
+			return fmt.Errorf("%v: %w", document, err)
+		}
+		for _, match := range imageSourceRe.FindAllSubmatch(data, -1) {
+			href := resolveHref(document, string(match[1]))
+			if _, ok := files[href]; !images[href] || !ok {
+				dangling = append(dangling, fmt.Sprintf("'%v' in '%v'", href, document))
+			}
+			referenced[href] = true
+		}
+	}
+
+	orphaned := make([]string, 0)
+	for href := range images {
+		if !referenced[href] {
+			orphaned = append(orphaned, fmt.Sprintf("'%v'", href))
+		}
+	}
+	slices.Sort(orphaned)
+
+	var errs []error
+	if len(orphaned) > 0 {
+		errs = append(errs, fmt.Errorf("orphaned %v", strings.Join(orphaned, ", ")))
+	}
+	if len(dangling) > 0 {
+		errs = append(errs, fmt.Errorf("dangling %v", strings.Join(dangling, ", ")))
+	}
+
+	return errors.Join(errs...)
+}
+
+// resolveHref resolves a reference relative to the archive path of the
+// document containing it
+func resolveHref(base, href string) string {
+	href, _, _ = strings.Cut(href, "#")
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+
+	return path.Join(path.Dir(base), href)
+}
+
+// skippedChaptersRe matches the chapters that a package document lists as
+// left out of the book
+var skippedChaptersRe = regexp.MustCompile(`<meta name="` + regexp.QuoteMeta(output.SkippedChaptersMeta) + `" content="([^"]*)"`)
+
+// ChapterKey returns the name that books list the given chapter by when it
+// was left out of them
+func ChapterKey(info md.ChapterInfo) string {
+	return fmt.Sprintf("%v/%v", info.VolumeIdentifier, info.Identifier)
+}
+
+// VerifyChapters checks that an EPUB or KEPUB file has a section for
+// every given chapter, so that books written before all chapters were
+// available are found to be incomplete. Chapters that the book lists as
+// left out, such as chapters without pages, count as present. MOBI and PDF
+// files do not name their chapters and are not checked.
+func VerifyChapters(filename string, format FormatType, chapters md.ChapterList) error {
+	if format != FormatEpub && format != FormatKepub {
+		return nil
+	}
+
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		return fmt.Errorf("open archive: %w", err)
+	}
+	defer r.Close()
+
+	sections := make(map[string]bool)
+	skipped := make(map[string]bool)
+	for _, f := range r.File {
+		sections[path.Base(f.Name)] = true
+		if path.Ext(f.Name) != ".opf" {
+			continue
+		}
+		opf, err := readZipFile(f)
+		if err != nil {
+			return fmt.Errorf("read %v: %w", f.Name, err)
+		}
+		if m := skippedChaptersRe.FindSubmatch(opf); m != nil {
+			for _, key := range strings.Fields(html.UnescapeString(string(m[1]))) {
+				skipped[key] = true
+			}
+		}
+	}
+	missing := make([]string, 0)
+	for _, chapter := range chapters {
+		// Sections are named like the generator names them
+		name := fmt.Sprintf("chapter-%v-%v.xhtml", chapter.Info.VolumeIdentifier, chapter.Info.Identifier)
+		if !sections[name] && !skipped[ChapterKey(chapter.Info)] {
+			missing = append(missing, fmt.Sprintf("'%v'", chapter.Info.Identifier))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing chapters %v", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+func verifyMOBI(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	// PalmDB header with type and creator fields at offset 60
+	header := make([]byte, 78)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return fmt.Errorf("read header: %w", err)
+	}
+	if magic := string(header[60:68]); magic != "BOOKMOBI" {
+		return fmt.Errorf("invalid header magic %q", magic)
+	}
+
+	return nil
+}
+
+func verifyPDF(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return fmt.Errorf("missing PDF header")
+	}
+	if !bytes.HasSuffix(bytes.TrimSpace(data), []byte("%%EOF")) {
+		return fmt.Errorf("missing end-of-file marker")
+	}
+
+	return nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer rc.Close()
+
+	return io.ReadAll(rc)
+}
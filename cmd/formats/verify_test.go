@@ -0,0 +1,204 @@
+package formats
+
+import (
+	"archive/zip"
+	"bytes"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	epubpkg "github.com/leotaku/kojirou/cmd/formats/epub"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/output"
+	"github.com/leotaku/kojirou/cmd/formats/pdf"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+)
+
+func writeOutput(t *testing.T, format FormatType) string {
+	t.Helper()
+
+	manga := testhelpers.CreateSyntheticManga(1, 2, 200, 300)
+	var out output.FormatOutput
+	switch format {
+	case FormatMobi:
+		mobi := kindle.GenerateMOBI(manga, kindle.WidepagePolicyPreserve, false, true)
+		out = &output.MobiOutput{Book: &mobi}
+	case FormatEpub, FormatKepub:
+		e, cleanup, err := epubpkg.GenerateEPUB(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true)
+		if cleanup != nil {
+			defer cleanup()
+		}
+		if err != nil {
+			t.Fatalf("GenerateEPUB() failed: %v", err)
+		}
+		if format == FormatEpub {
+			out = &output.EpubOutput{Epub: e}
+		} else {
+			out = &output.KepubOutput{Epub: e}
+		}
+	case FormatPdf:
+		doc := pdf.GeneratePDF(manga, kindle.WidepagePolicyPreserve, false, true)
+		out = &output.PdfOutput{Document: &doc}
+	}
+
+	data, err := out.GetBytes()
+	if err != nil {
+		t.Fatalf("GetBytes() failed: %v", err)
+	}
+	filename := filepath.Join(t.TempDir(), "book."+out.Extension())
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatalf("failed to write output: %v", err)
+	}
+
+	return filename
+}
+
+func TestVerifyFile(t *testing.T) {
+	for _, format := range []FormatType{FormatMobi, FormatEpub, FormatKepub, FormatPdf} {
+		t.Run(string(format), func(t *testing.T) {
+			filename := writeOutput(t, format)
+			if err := VerifyFile(filename, format); err != nil {
+				t.Fatalf("VerifyFile() failed on valid output: %v", err)
+			}
+
+			data, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatalf("failed to read output: %v", err)
+			}
+			// Corrupt the header region and drop the archive directory
+			corrupted := append([]byte("CORRUPTED"), data[9:len(data)/2]...)
+			for i := 60; i < 68 && i < len(corrupted); i++ {
+				corrupted[i] = 0
+			}
+			if err := os.WriteFile(filename, corrupted, 0644); err != nil {
+				t.Fatalf("failed to corrupt output: %v", err)
+			}
+			if err := VerifyFile(filename, format); err == nil {
+				t.Error("VerifyFile() did not flag corrupted output")
+			}
+		})
+	}
+}
+
+// rewriteEPUB copies an EPUB archive, applying the given rewrite to the
+// contents of each entry and appending the given extra entries
+func rewriteEPUB(t *testing.T, filename string, rewrite func(name, content string) string, extra map[string]string) {
+	t.Helper()
+
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		t.Fatalf("failed to open EPUB: %v", err)
+	}
+	defer r.Close()
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for _, f := range r.File {
+		data, err := readZipFile(f)
+		if err != nil {
+			t.Fatalf("failed to read %v: %v", f.Name, err)
+		}
+		w, err := zw.Create(f.Name)
+		if err != nil {
+			t.Fatalf("failed to create %v: %v", f.Name, err)
+		}
+		w.Write([]byte(rewrite(f.Name, string(data)))) //nolint:errcheck
+	}
+	for name, content := range extra {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("failed to create %v: %v", name, err)
+		}
+		w.Write([]byte(content)) //nolint:errcheck
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to close EPUB: %v", err)
+	}
+	if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("failed to write EPUB: %v", err)
+	}
+}
+
+func TestVerifyFileImageReferences(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		file    string
+		rewrite func(content string) string
+		extra   map[string]string
+		want    string
+	}{
+		{
+			name: "orphaned image",
+			file: "EPUB/package.opf",
+			rewrite: func(content string) string {
+				item := `<item id="orphan.jpg" href="images/orphan.jpg" media-type="image/jpeg"></item>`
+				return strings.Replace(content, "</manifest>", item+"</manifest>", 1)
+			},
+			extra: map[string]string{"EPUB/images/orphan.jpg": "image"},
+			want:  "orphaned 'EPUB/images/orphan.jpg'",
+		},
+		{
+			name: "dangling reference",
+			file: "EPUB/xhtml/chapter-1-1.xhtml",
+			rewrite: func(content string) string {
+				img := `<img src="../images/missing.jpg" alt=""/>`
+				return strings.Replace(content, "</body>", img+"</body>", 1)
+			},
+			want: "dangling 'EPUB/images/missing.jpg'",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := writeOutput(t, FormatEpub)
+			rewriteEPUB(t, filename, func(name, content string) string {
+				if name == tc.file {
+					return tc.rewrite(content)
+				}
+				return content
+			}, tc.extra)
+
+			err := VerifyFile(filename, FormatEpub)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("VerifyFile() error is %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestVerifyFileCombinedCovers(t *testing.T) {
+	manga := testhelpers.CreateTestManga()
+	for _, volID := range manga.Keys() {
+		vol := manga.Volumes[volID]
+		vol.Cover = testhelpers.CreateTestImage(200, 300, color.White)
+		manga.Volumes[volID] = vol
+	}
+
+	for _, format := range []FormatType{FormatEpub, FormatKepub} {
+		t.Run(string(format), func(t *testing.T) {
+			e, cleanup, err := epubpkg.GenerateEPUB(t.TempDir(), manga, kindle.WidepagePolicyPreserve, false, true)
+			if cleanup != nil {
+				defer cleanup()
+			}
+			if err != nil {
+				t.Fatalf("GenerateEPUB() failed: %v", err)
+			}
+			var out output.FormatOutput = &output.EpubOutput{Epub: e}
+			if format == FormatKepub {
+				out = &output.KepubOutput{Epub: e}
+			}
+			data, err := out.GetBytes()
+			if err != nil {
+				t.Fatalf("GetBytes() failed: %v", err)
+			}
+			filename := filepath.Join(t.TempDir(), "book."+out.Extension())
+			if err := os.WriteFile(filename, data, 0644); err != nil {
+				t.Fatalf("failed to write output: %v", err)
+			}
+
+			if err := VerifyFile(filename, format); err != nil {
+				t.Errorf("VerifyFile() failed on combined book with two covers: %v", err)
+			}
+		})
+	}
+}
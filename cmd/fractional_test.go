@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"archive/zip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	md "github.com/leotaku/kojirou/mangadex"
+)
+
+func TestFractionalVolume(t *testing.T) {
+	manga := loadDiskSeries(t, map[string][]string{
+		"7":    {"1"},
+		"7.5":  {"2"},
+		"7.05": {"3"},
+	})
+
+	origFormatsArg := FormatsArg
+	defer func() { FormatsArg = origFormatsArg }()
+	FormatsArg = "epub"
+
+	dir := kindle.NewNormalizedDirectory(t.TempDir(), manga.Info.Title, false)
+	if err := handleVolumes(manga, dir, 1); err != nil {
+		t.Fatalf("handleVolumes() failed: %v", err)
+	}
+
+	paths := make(map[string]bool)
+	for _, volume := range manga.Sorted() {
+		paths[dir.Path(volume.Info.Identifier, "epub")] = true
+	}
+	if len(paths) != 3 {
+		t.Fatalf("volumes do not have distinct filenames: %v", paths)
+	}
+
+	for _, tc := range []struct {
+		volume string
+		file   string
+		title  string
+	}{
+		{"7", "0007.epub", "Volume 7"},
+		{"7.5", "0007.5.epub", "Volume 7.5"},
+		{"7.05", "0007.05.epub", "Volume 7.05"},
+	} {
+		id := md.NewIdentifier(tc.volume)
+		if !strings.HasSuffix(dir.Path(id, "epub"), "/"+tc.file) {
+			t.Errorf("volume %v: path is %v, want %v", tc.volume, dir.Path(id, "epub"), tc.file)
+		}
+
+		r, err := zip.OpenReader(dir.Path(id, "epub"))
+		if err != nil {
+			t.Fatalf("failed to open EPUB: %v", err)
+		}
+		for name, want := range map[string]string{
+			"EPUB/xhtml/volume-" + tc.volume + ".xhtml": "<h1>" + tc.title + "</h1>",
+		} {
+			rc, err := r.Open(name)
+			if err != nil {
+				t.Fatalf("failed to open %v: %v", name, err)
+			}
+			content, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatalf("failed to read %v: %v", name, err)
+			}
+			if !strings.Contains(string(content), want) {
+				t.Errorf("volume %v: %v is missing %q", tc.volume, name, want)
+			}
+		}
+		r.Close()
+	}
+}
@@ -6,13 +6,16 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
 )
 
+// filterAnnotation marks flags that are listed as filters in help output
+const filterAnnotation = "filter"
+
 func writeHelp(cmd *cobra.Command, w io.Writer) {
 	groups := make(map[string][]pflag.Flag)
 	cmd.Flags().VisitAll(func(f *pflag.Flag) {
@@ -20,7 +23,7 @@ func writeHelp(cmd *cobra.Command, w io.Writer) {
 		case f.Hidden:
 		case strings.HasPrefix(f.Name, "help") || f.Name == "version":
 			groups["3Flags"] = append(groups["3Flags"], *f)
-		case strings.HasSuffix(f.Name, "s"):
+		case len(f.Annotations[filterAnnotation]) > 0:
 			groups["2Filters"] = append(groups["2Filters"], *f)
 		default:
 			groups["1Options"] = append(groups["1Options"], *f)
@@ -39,6 +42,14 @@ func writeHelp(cmd *cobra.Command, w io.Writer) {
 		return result
 	}
 
+	// Descriptions are aligned after the longest flag name
+	width := 0
+	for _, flags := range groups {
+		for _, f := range flags {
+			width = max(width, len(f.Name)+2)
+		}
+	}
+
 	fmt.Fprintf(w, "Usage:\n  %v\n", cmd.Use)
 	for _, name := range keys(groups) {
 		fmt.Fprintf(w, "\n%v:\n", name[1:])
@@ -47,7 +58,7 @@ func writeHelp(cmd *cobra.Command, w io.Writer) {
 			if len(f.Shorthand) > 0 {
 				shorthand = "-" + f.Shorthand + ", "
 			}
-			fmt.Fprintf(w, "  %4v--%-20v%v\n", shorthand, f.Name, toSentenceCase(f.Usage))
+			fmt.Fprintf(w, "  %4v--%-*v%v\n", shorthand, width, f.Name, toSentenceCase(f.Usage))
 		}
 	}
 }
@@ -62,8 +73,9 @@ func usage(cmd *cobra.Command) error {
 	return nil
 }
 
+// toSentenceCase capitalizes the first letter of a sentence, leaving the
+// rest alone so that acronyms such as EPUB and HTTP are kept
 func toSentenceCase(sentence string) string {
-	words := strings.Split(sentence, " ")
-	words[0] = cases.Title(language.English).String(words[0])
-	return strings.Join(words, " ")
+	first, size := utf8.DecodeRuneInString(sentence)
+	return string(unicode.ToUpper(first)) + sentence[size:]
 }
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestHelp(t *testing.T) {
+	var buf bytes.Buffer
+	writeHelp(rootCmd, &buf)
+
+	column := -1
+	for _, line := range strings.Split(buf.String(), "\n") {
+		start := strings.Index(line, "--")
+		if start < 0 {
+			continue
+		}
+		end := start + strings.Index(line[start:], " ")
+		description := len(line) - len(strings.TrimLeft(line[end:], " "))
+		if description-end < 2 {
+			t.Errorf("flag runs into its description: %q", line)
+		}
+		if column >= 0 && description != column {
+			t.Errorf("description is not aligned: %q", line)
+		}
+		column = description
+	}
+
+	for _, want := range []string{"EPUB version of EPUB output", "HTTP, HTTPS or SOCKS5 proxy", "MangaDex API client ID"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("help does not contain %q:\n%s", want, buf.String())
+		}
+	}
+}
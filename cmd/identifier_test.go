@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"archive/zip"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	md "github.com/leotaku/kojirou/mangadex"
+)
+
+func TestBookIdentifierTwoVolumes(t *testing.T) {
+	manga := loadDiskSeries(t, map[string][]string{
+		"1": {"1"},
+		"2": {"2"},
+	})
+
+	origFormatsArg, origBookIdentifierArg, origCombineArg := FormatsArg, bookIdentifierArg, combineArg
+	defer func() {
+		FormatsArg, bookIdentifierArg, combineArg = origFormatsArg, origBookIdentifierArg, origCombineArg
+	}()
+	FormatsArg, bookIdentifierArg = "epub", "isbn:9780000000000"
+
+	// Both volume books would share the identifier
+	if err := checkBookIdentifier(manga); err == nil {
+		t.Fatal("checkBookIdentifier() accepted two volumes without --combine")
+	}
+	single := manga
+	single.Volumes = map[md.Identifier]md.Volume{}
+	for _, volume := range manga.Sorted()[:1] {
+		single.Volumes[volume.Info.Identifier] = volume
+	}
+	if err := checkBookIdentifier(single); err != nil {
+		t.Errorf("checkBookIdentifier() rejected a single volume: %v", err)
+	}
+
+	combineArg = true
+	if err := checkBookIdentifier(manga); err != nil {
+		t.Fatalf("checkBookIdentifier() rejected --combine: %v", err)
+	}
+	outDir := t.TempDir()
+	if err := HandleCombined(manga, kindle.NewNormalizedDirectory(outDir, manga.Info.Title, false)); err != nil {
+		t.Fatalf("HandleCombined() failed: %v", err)
+	}
+	r, err := zip.OpenReader(filepath.Join(outDir, manga.Info.Title+".epub"))
+	if err != nil {
+		t.Fatalf("failed to open EPUB: %v", err)
+	}
+	defer r.Close()
+	rc, err := r.Open("EPUB/package.opf")
+	if err != nil {
+		t.Fatalf("failed to open package.opf: %v", err)
+	}
+	opf, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatalf("failed to read package.opf: %v", err)
+	}
+	if !strings.Contains(string(opf), ">isbn:9780000000000</dc:identifier>") {
+		t.Errorf("combined book does not carry the identifier")
+	}
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/leotaku/kojirou/cmd/formats"
+	"github.com/spf13/cobra"
+)
+
+var inspectJSONArg bool
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect FILE [flags..]",
+	Short: "Print the metadata and structure of an EPUB, KEPUB or MOBI file",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		info, err := formats.InspectFile(args[0])
+		if err != nil {
+			return fmt.Errorf("inspect: %w", err)
+		}
+		if inspectJSONArg {
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			return enc.Encode(info)
+		}
+		printBookInfo(cmd.OutOrStdout(), info)
+
+		return nil
+	},
+	// Flags of the root command do not apply to existing files
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return nil
+	},
+	DisableFlagsInUseLine: true,
+}
+
+func printBookInfo(w io.Writer, info formats.BookInfo) {
+	series := info.Series
+	if series != "" && info.SeriesIndex != "" {
+		series = fmt.Sprintf("%v #%v", series, info.SeriesIndex)
+	}
+	spine := fmt.Sprint(info.Spine)
+	if info.Format == formats.FormatMobi {
+		spine = "none"
+	}
+
+	for _, field := range []struct {
+		name  string
+		value string
+	}{
+		{"Format", string(info.Format)},
+		{"Title", info.Title},
+		{"Authors", strings.Join(info.Authors, ", ")},
+		{"Language", info.Language},
+		{"Series", series},
+		{"Spine", spine},
+		{"Images", fmt.Sprint(info.Images)},
+		{"Direction", info.Direction},
+		{"Kobo", describe(info.Kobo, "enhanced", "none")},
+	} {
+		if field.value == "" {
+			field.value = "-"
+		}
+		fmt.Fprintf(w, "%-10v %v\n", field.name+":", field.value)
+	}
+}
+
+func init() {
+	inspectCmd.Flags().BoolVarP(&inspectJSONArg, "json", "", false, "print the results as JSON")
+	inspectCmd.Flags().SortFlags = false
+	rootCmd.AddCommand(inspectCmd)
+}
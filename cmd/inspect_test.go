@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+)
+
+func TestInspectCommand(t *testing.T) {
+	manga := loadDiskSeries(t, map[string][]string{
+		"1": {"1", "2"},
+	})
+
+	origFormatsArg := FormatsArg
+	defer func() { FormatsArg = origFormatsArg }()
+	FormatsArg = "epub"
+
+	dir := kindle.NewNormalizedDirectory(t.TempDir(), manga.Info.Title, false)
+	for _, volume := range manga.Sorted() {
+		if err := HandleVolume(manga, volume, dir); err != nil {
+			t.Fatalf("HandleVolume() failed: %v", err)
+		}
+	}
+	filename := dir.Path(manga.Keys()[0], "epub")
+
+	origJSONArg := inspectJSONArg
+	defer func() { inspectJSONArg = origJSONArg }()
+	out := new(bytes.Buffer)
+	rootCmd.SetOut(out)
+	rootCmd.SetArgs([]string{"inspect", "--json", filename})
+	defer rootCmd.SetOut(nil)
+	defer rootCmd.SetArgs(nil)
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("inspect failed: %v", err)
+	}
+	info := formats.BookInfo{}
+	if err := json.Unmarshal(out.Bytes(), &info); err != nil {
+		t.Fatalf("failed to parse output: %v\n%s", err, out.String())
+	}
+	// Four pages and the cover taken from the first page
+	if info.Format != formats.FormatEpub || info.Title != manga.Info.Title || info.Images != 5 {
+		t.Errorf("unexpected book info: %+v", info)
+	}
+
+	// The same results are printed for humans without --json
+	inspectJSONArg = false
+	out.Reset()
+	rootCmd.SetArgs([]string{"inspect", filename})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("inspect failed: %v", err)
+	}
+	for _, want := range []string{"Format:    epub", "Title:     " + manga.Info.Title, "Images:    5", "Kobo:      none"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+)
+
+func TestKeepEpub(t *testing.T) {
+	manga := loadDiskSeries(t, map[string][]string{"1": {"1"}})
+	volume := manga.Sorted()[0]
+
+	origFormatsArg, origKeepEpubArg := FormatsArg, keepEpubArg
+	defer func() { FormatsArg, keepEpubArg = origFormatsArg, origKeepEpubArg }()
+	FormatsArg = "kepub"
+
+	for _, keep := range []bool{false, true} {
+		keepEpubArg = keep
+		dir := kindle.NewNormalizedDirectory(t.TempDir(), manga.Info.Title, false)
+		if err := HandleVolume(manga, volume, dir); err != nil {
+			t.Fatalf("HandleVolume() failed: %v", err)
+		}
+
+		if _, err := os.Stat(dir.Path(volume.Info.Identifier, "kepub.epub")); err != nil {
+			t.Errorf("keep %v: KEPUB was not written: %v", keep, err)
+		}
+		_, err := os.Stat(dir.Path(volume.Info.Identifier, "epub"))
+		if keep && err != nil {
+			t.Errorf("keep %v: EPUB was not written: %v", keep, err)
+		} else if !keep && err == nil {
+			t.Errorf("keep %v: EPUB was written", keep)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/disk"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/progress"
+	"golang.org/x/text/language"
+)
+
+func TestKindleFolderLayout(t *testing.T) {
+	root := createDiskSeries(t, map[string][]string{
+		"1": {"1"},
+	})
+	skeleton, err := disk.LoadSkeleton(root)
+	if err != nil {
+		t.Fatalf("failed to load skeleton: %v", err)
+	}
+	chapters, err := disk.LoadChapters(root, language.English, progress.VanishingProgress("Disk..."))
+	if err != nil {
+		t.Fatalf("failed to load chapters: %v", err)
+	}
+	manga := skeleton.WithChapters(chapters)
+
+	origFormatsArg := FormatsArg
+	defer func() { FormatsArg = origFormatsArg }()
+	FormatsArg = "mobi"
+
+	kindleRoot := t.TempDir()
+	dir := kindle.NewNormalizedDirectory(kindleRoot, manga.Info.Title, true)
+	if err := dir.Validate(); err != nil {
+		t.Fatalf("Validate() failed: %v", err)
+	}
+	for _, volume := range manga.Sorted() {
+		if err := HandleVolume(manga, volume, dir); err != nil {
+			t.Fatalf("HandleVolume() failed: %v", err)
+		}
+	}
+
+	book := filepath.Join(kindleRoot, "documents", "Disk_Series", "0001.azw3")
+	if _, err := os.Stat(book); err != nil {
+		t.Errorf("book not written to documents folder: %v", err)
+	}
+	thumbnails, err := os.ReadDir(filepath.Join(kindleRoot, "system", "thumbnails"))
+	if err != nil {
+		t.Fatalf("failed to list thumbnails: %v", err)
+	}
+	if len(thumbnails) != 1 ||
+		!strings.HasPrefix(thumbnails[0].Name(), "thumbnail_") ||
+		!strings.HasSuffix(thumbnails[0].Name(), "_EBOK_portrait.jpg") {
+		t.Errorf("expected single cover thumbnail, got %v", thumbnails)
+	}
+}
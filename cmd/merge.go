@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/leotaku/kojirou/cmd/formats/logging"
+	"github.com/leotaku/kojirou/cmd/formats/progress"
+	md "github.com/leotaku/kojirou/mangadex"
+)
+
+// chapterKey identifies a chapter across its sources
+type chapterKey struct {
+	volume  md.Identifier
+	chapter md.Identifier
+}
+
+func keyOf(info md.ChapterInfo) chapterKey {
+	return chapterKey{volume: info.VolumeIdentifier, chapter: info.Identifier}
+}
+
+// diskAlternates are the best ranked remote chapters replaced by chapters
+// from disk, used to fill in pages missing on disk
+var diskAlternates map[chapterKey]md.Chapter
+
+// findDiskAlternates returns the first remote chapter of every chapter
+// that is also available on disk
+func findDiskAlternates(chapters md.ChapterList) map[chapterKey]md.Chapter {
+	local := make(map[chapterKey]bool)
+	for _, chapter := range chapters {
+		if chapter.Info.GroupNames.String() == "Filesystem" {
+			local[keyOf(chapter.Info)] = true
+		}
+	}
+
+	result := make(map[chapterKey]md.Chapter)
+	for _, chapter := range chapters {
+		key := keyOf(chapter.Info)
+		if _, ok := result[key]; !ok && local[key] && chapter.Info.GroupNames.String() != "Filesystem" {
+			result[key] = chapter
+		}
+	}
+
+	return result
+}
+
+// pagePosition returns the zero-based position of a page in its chapter.
+// Pages from disk with numeric filenames such as "03.png" are placed by
+// their number, so that chapters with gaps can be completed.
+func pagePosition(page md.Image) int {
+	stem := strings.TrimSuffix(page.Filename, path.Ext(page.Filename))
+	if n, err := strconv.Atoi(stem); err == nil && n > 0 {
+		return n - 1
+	}
+
+	return page.ImageIdentifier
+}
+
+// mergePages merges the pages of a chapter from disk with the pages of the
+// same chapter from another source, by their position. Pages from disk are
+// preferred, pages only available from the other source fill the gaps.
+func mergePages(local, remote md.ImageList) md.ImageList {
+	positions := make(map[int]bool)
+	result := make(md.ImageList, 0, len(local)+len(remote))
+	for _, page := range local {
+		page.ImageIdentifier = pagePosition(page)
+		positions[page.ImageIdentifier] = true
+		result = append(result, page)
+	}
+	for _, page := range remote {
+		if !positions[page.ImageIdentifier] {
+			result = append(result, page)
+		}
+	}
+
+	return result
+}
+
+// incomplete reports whether the pages from disk of a chapter leave out
+// any pages of its remote alternate
+func incomplete(pages md.ImageList, alternate md.Chapter) bool {
+	count := alternate.Info.Pages
+	positions := make(map[int]bool)
+	for _, page := range pages {
+		positions[pagePosition(page)] = true
+		count = max(count, pagePosition(page)+1)
+	}
+
+	return len(positions) < count
+}
+
+// fillDiskChapters completes chapters from disk with pages of their remote
+// alternate, loaded with the given function. Chapters without gaps are
+// left as they are.
+func fillDiskChapters(pages md.ImageList, load func(md.ChapterList, progress.Progress) (md.ImageList, error), p progress.Progress) (md.ImageList, error) {
+	byChapter := make(map[chapterKey]md.ImageList)
+	order := make([]chapterKey, 0)
+	for _, page := range pages {
+		key := chapterKey{volume: page.VolumeIdentifier, chapter: page.ChapterIdentifier}
+		if _, ok := byChapter[key]; !ok {
+			order = append(order, key)
+		}
+		byChapter[key] = append(byChapter[key], page)
+	}
+
+	result := make(md.ImageList, 0, len(pages))
+	for _, key := range order {
+		local := byChapter[key]
+		alternate, ok := diskAlternates[key]
+		if !ok || !incomplete(local, alternate) {
+			result = append(result, local...)
+			continue
+		}
+
+		remote, err := load(md.ChapterList{alternate}, p)
+		if err != nil {
+			return nil, err
+		}
+		merged := mergePages(local, remote)
+		logging.Infof("Chapter %v: filled %v missing pages from %v", key.chapter, len(merged)-len(local), alternate.Info.GroupNames)
+		result = append(result, merged...)
+	}
+
+	return result, nil
+}
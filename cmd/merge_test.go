@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"image"
+	"slices"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/progress"
+	md "github.com/leotaku/kojirou/mangadex"
+)
+
+func TestFillDiskChapters(t *testing.T) {
+	volume, chapter := md.NewIdentifier("1"), md.NewIdentifier("1")
+	page := func(id int, filename string, width int) md.Image {
+		return md.Image{
+			Image:             image.NewGray(image.Rect(0, 0, width, 10)),
+			Filename:          filename,
+			ImageIdentifier:   id,
+			ChapterIdentifier: chapter,
+			VolumeIdentifier:  volume,
+		}
+	}
+
+	// Pages from disk are 1 pixel wide, pages from MangaDex 2 pixels
+	local := md.ImageList{page(0, "01.png", 1), page(1, "03.png", 1)}
+	online := md.ImageList{page(0, "", 2), page(1, "", 2), page(3, "", 2), page(4, "", 2)}
+
+	origDiskAlternates := diskAlternates
+	defer func() { diskAlternates = origDiskAlternates }()
+	diskAlternates = findDiskAlternates(md.ChapterList{
+		{Info: md.ChapterInfo{Identifier: chapter, VolumeIdentifier: volume, GroupNames: []string{"Filesystem"}}},
+		{Info: md.ChapterInfo{Identifier: chapter, VolumeIdentifier: volume, GroupNames: []string{"Scans"}, Pages: 5}},
+	})
+
+	loaded := 0
+	load := func(cl md.ChapterList, p progress.Progress) (md.ImageList, error) {
+		loaded++
+		if len(cl) != 1 || cl[0].Info.GroupNames.String() != "Scans" {
+			t.Errorf("loaded unexpected chapters %v", cl)
+		}
+		return online, nil
+	}
+	pages, err := fillDiskChapters(local, load, progress.VanishingProgress("Fill..."))
+	if err != nil {
+		t.Fatalf("fillDiskChapters() failed: %v", err)
+	}
+	if loaded != 1 {
+		t.Errorf("expected the alternate to be loaded once, got %v", loaded)
+	}
+
+	manga := md.Manga{Volumes: map[md.Identifier]md.Volume{}}.WithChapters(md.ChapterList{
+		{Info: md.ChapterInfo{Identifier: chapter, VolumeIdentifier: volume}},
+	}).WithPages(pages)
+	widths := make([]int, 0)
+	for _, img := range manga.Volumes[volume].Chapters[chapter].Sorted() {
+		widths = append(widths, img.Bounds().Dx())
+	}
+	if want := []int{1, 2, 1, 2, 2}; !slices.Equal(widths, want) {
+		t.Errorf("expected merged pages of widths %v, got %v", want, widths)
+	}
+
+	// Complete chapters are not loaded again
+	loaded = 0
+	complete := md.ImageList{page(0, "01.png", 1), page(1, "02.png", 1), page(2, "03.png", 1), page(3, "04.png", 1), page(4, "05.png", 1)}
+	if pages, err := fillDiskChapters(complete, load, progress.VanishingProgress("Fill...")); err != nil || len(pages) != 5 {
+		t.Errorf("fillDiskChapters() returned %v pages, %v", len(pages), err)
+	}
+	if loaded != 0 {
+		t.Errorf("complete chapter was loaded %v times", loaded)
+	}
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"archive/zip"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/disk"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/progress"
+	"github.com/leotaku/kojirou/cmd/formats/testhelpers"
+	"golang.org/x/text/language"
+)
+
+func TestReadingOrderSidecar(t *testing.T) {
+	root := createDiskSeries(t, map[string][]string{"1": {"1"}})
+
+	// The second page is wider, so that pages can be told apart
+	f, err := os.Create(filepath.Join(root, "1", "1", "02.png"))
+	if err != nil {
+		t.Fatalf("failed to create page: %v", err)
+	}
+	if err := png.Encode(f, testhelpers.CreateTestImage(220, 300, color.White)); err != nil {
+		t.Fatalf("failed to encode page: %v", err)
+	}
+	f.Close()
+	sidecar := `{"pages": {"1/1": ["02.png", "01.png"]}}`
+	if err := os.WriteFile(filepath.Join(root, disk.ReadingOrderFile), []byte(sidecar), 0644); err != nil {
+		t.Fatalf("failed to write sidecar: %v", err)
+	}
+
+	skeleton, err := disk.LoadSkeleton(root)
+	if err != nil {
+		t.Fatalf("failed to load skeleton: %v", err)
+	}
+	chapters, err := disk.LoadChapters(root, language.English, progress.VanishingProgress("Disk..."))
+	if err != nil {
+		t.Fatalf("failed to load chapters: %v", err)
+	}
+	manga := skeleton.WithChapters(chapters)
+	volume := manga.Sorted()[0]
+
+	origFormatsArg := FormatsArg
+	defer func() { FormatsArg = origFormatsArg }()
+	FormatsArg = "epub"
+
+	dir := kindle.NewNormalizedDirectory(t.TempDir(), manga.Info.Title, false)
+	if err := HandleVolume(manga, volume, dir); err != nil {
+		t.Fatalf("HandleVolume() failed: %v", err)
+	}
+
+	r, err := zip.OpenReader(dir.Path(volume.Info.Identifier, "epub"))
+	if err != nil {
+		t.Fatalf("failed to open EPUB: %v", err)
+	}
+	defer r.Close()
+	for name, width := range map[string]int{
+		"EPUB/images/page-1-1-0.jpg": 220,
+		"EPUB/images/page-1-1-1.jpg": 200,
+	} {
+		rc, err := r.Open(name)
+		if err != nil {
+			t.Fatalf("failed to open %v: %v", name, err)
+		}
+		img, err := jpeg.Decode(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("failed to decode %v: %v", name, err)
+		}
+		if got := img.Bounds().Dx(); got != width {
+			t.Errorf("%v is %v pixels wide, want %v", name, got, width)
+		}
+	}
+}
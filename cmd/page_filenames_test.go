@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+)
+
+func TestPageFilenames(t *testing.T) {
+	manga := loadDiskSeries(t, map[string][]string{"1": {"1"}})
+	volume := manga.Sorted()[0]
+
+	origFormatsArg, origPageFilenamesArg := FormatsArg, pageFilenamesArg
+	defer func() { FormatsArg, pageFilenamesArg = origFormatsArg, origPageFilenamesArg }()
+	FormatsArg = "epub"
+
+	for _, enabled := range []bool{false, true} {
+		pageFilenamesArg = enabled
+		dir := kindle.NewNormalizedDirectory(t.TempDir(), manga.Info.Title, false)
+		if err := HandleVolume(manga, volume, dir); err != nil {
+			t.Fatalf("HandleVolume() failed: %v", err)
+		}
+
+		r, err := zip.OpenReader(dir.Path(volume.Info.Identifier, "epub"))
+		if err != nil {
+			t.Fatalf("failed to open EPUB: %v", err)
+		}
+		xhtml := new(strings.Builder)
+		for _, f := range r.File {
+			if strings.HasPrefix(f.Name, "EPUB/xhtml/chapter-") {
+				rc, err := f.Open()
+				if err != nil {
+					t.Fatalf("failed to open %v: %v", f.Name, err)
+				}
+				io.Copy(xhtml, rc)
+				rc.Close()
+			}
+		}
+		r.Close()
+
+		for _, page := range []string{"01.png", "02.png"} {
+			if got := strings.Contains(xhtml.String(), `title="`+page+`"`); got != enabled {
+				t.Errorf("enabled %v: title of %v shown: %v", enabled, page, got)
+			}
+		}
+
+		sidecar, err := os.ReadFile(dir.Path(volume.Info.Identifier, formats.PageFilenamesExtension))
+		if !enabled {
+			if err == nil {
+				t.Errorf("enabled %v: sidecar was written", enabled)
+			}
+			continue
+		} else if err != nil {
+			t.Fatalf("enabled %v: failed to read sidecar: %v", enabled, err)
+		}
+		var pages []formats.PageFilename
+		if err := json.Unmarshal(sidecar, &pages); err != nil {
+			t.Fatalf("failed to decode sidecar: %v", err)
+		}
+		want := []formats.PageFilename{
+			{Volume: "1", Chapter: "1", Page: 1, File: "01.png"},
+			{Volume: "1", Chapter: "1", Page: 2, File: "02.png"},
+		}
+		if !reflect.DeepEqual(pages, want) {
+			t.Errorf("expected sidecar %+v, got %+v", want, pages)
+		}
+	}
+}
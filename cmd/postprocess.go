@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leotaku/kojirou/cmd/formats/logging"
+)
+
+var (
+	// runContext is the context of the running command
+	runContext = context.Background()
+	// postProcesses tracks running post-process commands, so that they
+	// are cancelled before exiting on interrupts
+	postProcesses sync.WaitGroup
+)
+
+// postProcess runs the given command on a written output file. The command
+// is split into arguments at whitespace and every "{file}" is replaced with
+// the filename, so paths containing spaces stay single arguments. It is
+// not run by a shell.
+func postProcess(ctx context.Context, command, filename string, timeout time.Duration) error {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return fmt.Errorf("empty command")
+	}
+	for i, arg := range args {
+		args[i] = strings.ReplaceAll(arg, "{file}", filename)
+	}
+
+	postProcesses.Add(1)
+	defer postProcesses.Done()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	output := new(bytes.Buffer)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout, cmd.Stderr = output, output
+	err := cmd.Run()
+	logging.Debugf("post-process %v: %v", filename, strings.TrimSpace(output.String()))
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("timed out after %v", timeout)
+	case err != nil && output.Len() > 0:
+		return fmt.Errorf("%w: %v", err, lastLine(output.String()))
+	default:
+		return err
+	}
+}
+
+// lastLine returns the last non-empty line of command output, which
+// usually explains why the command failed
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+)
+
+func TestPostProcess(t *testing.T) {
+	manga := loadDiskSeries(t, map[string][]string{"1": {"1"}})
+	volume := manga.Sorted()[0]
+
+	origFormatsArg, origPostProcessArg := FormatsArg, postProcessArg
+	defer func() { FormatsArg, postProcessArg = origFormatsArg, origPostProcessArg }()
+	FormatsArg = "epub,mobi"
+
+	// The command runs once for every output
+	postProcessArg = "touch {file}.done"
+	dir := kindle.NewNormalizedDirectory(t.TempDir(), manga.Info.Title, false)
+	if err := HandleVolume(manga, volume, dir); err != nil {
+		t.Fatalf("HandleVolume() failed: %v", err)
+	}
+	for _, ext := range []string{"epub", "azw3"} {
+		if _, err := os.Stat(dir.Path(volume.Info.Identifier, ext) + ".done"); err != nil {
+			t.Errorf("post-process did not run on %v output: %v", ext, err)
+		}
+	}
+
+	// Failures are reported for every format
+	postProcessArg = "false {file}"
+	dir = kindle.NewNormalizedDirectory(t.TempDir(), manga.Info.Title, false)
+	err := HandleVolume(manga, volume, dir)
+	if err == nil {
+		t.Fatalf("HandleVolume() succeeded with failing post-process")
+	}
+	for _, format := range []string{"epub", "mobi"} {
+		if !strings.Contains(err.Error(), format+" (Error: post-process: exit status 1)") {
+			t.Errorf("failure of %v post-process is not reported: %v", format, err)
+		}
+	}
+}
+
+func TestPostProcessCommand(t *testing.T) {
+	// Paths with spaces are passed as a single argument
+	filename := filepath.Join(t.TempDir(), "Volume 1.epub")
+	if err := postProcess(context.Background(), "touch {file}", filename, time.Minute); err != nil {
+		t.Fatalf("postProcess() failed: %v", err)
+	}
+	if _, err := os.Stat(filename); err != nil {
+		t.Errorf("command did not receive the path: %v", err)
+	}
+
+	if err := postProcess(context.Background(), "sleep 5", filename, 50*time.Millisecond); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected timeout, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := postProcess(ctx, "sleep 5", filename, time.Minute); err == nil {
+		t.Errorf("command ran despite canceled context")
+	}
+
+	// Interrupts wait for running commands to be canceled
+	ctx, cancel = context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() { errs <- postProcess(ctx, "sleep 5", filename, time.Minute) }()
+	time.Sleep(50 * time.Millisecond)
+	start := time.Now()
+	cancel()
+	postProcesses.Wait()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("waited %v for canceled command", elapsed)
+	}
+	if err := <-errs; err == nil {
+		t.Errorf("canceled command succeeded")
+	}
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+)
+
+func TestPreview(t *testing.T) {
+	manga := loadDiskSeries(t, map[string][]string{"1": {"1", "2"}})
+	volume := manga.Sorted()[0]
+
+	// Chapters get two more pages than created by default
+	for _, chapter := range volume.Sorted() {
+		for _, page := range []string{"03.png", "04.png"} {
+			data, err := os.ReadFile(filepath.Join(chapter.Info.ID, "01.png"))
+			if err != nil {
+				t.Fatalf("failed to read page: %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(chapter.Info.ID, page), data, 0644); err != nil {
+				t.Fatalf("failed to write page: %v", err)
+			}
+		}
+	}
+
+	origFormatsArg, origPreviewArg, origPagesArg := FormatsArg, previewArg, pagesArg
+	defer func() { FormatsArg, previewArg, pagesArg = origFormatsArg, origPreviewArg, origPagesArg }()
+	FormatsArg = "epub"
+
+	for _, tc := range []struct {
+		preview int
+		pages   string
+		want    []string
+	}{
+		{0, "", []string{"0", "1", "2", "3"}},
+		{2, "", []string{"0", "1"}},
+		{0, "2..3", []string{"1", "2"}},
+		{3, "!1", []string{"1", "2"}},
+	} {
+		previewArg, pagesArg = tc.preview, tc.pages
+		dir := kindle.NewNormalizedDirectory(t.TempDir(), manga.Info.Title, false)
+		if err := HandleVolume(manga, volume, dir); err != nil {
+			t.Fatalf("HandleVolume() failed: %v", err)
+		}
+
+		r, err := zip.OpenReader(dir.Path(volume.Info.Identifier, "epub"))
+		if err != nil {
+			t.Fatalf("failed to open EPUB: %v", err)
+		}
+		images := make(map[string][]string)
+		for _, f := range r.File {
+			if name, ok := strings.CutPrefix(f.Name, "EPUB/images/page-1-"); ok {
+				chapter, page, _ := strings.Cut(strings.TrimSuffix(name, ".jpg"), "-")
+				images[chapter] = append(images[chapter], page)
+			}
+		}
+		r.Close()
+
+		for _, chapter := range []string{"1", "2"} {
+			if strings.Join(images[chapter], ",") != strings.Join(tc.want, ",") {
+				t.Errorf("preview %v, pages %q: chapter %v has pages %v, want %v",
+					tc.preview, tc.pages, chapter, images[chapter], tc.want,
+				)
+			}
+		}
+	}
+
+	// Selecting no pages at all is an error
+	previewArg, pagesArg = 0, "9"
+	dir := kindle.NewNormalizedDirectory(t.TempDir(), manga.Info.Title, false)
+	if err := HandleVolume(manga, volume, dir); err == nil {
+		t.Error("HandleVolume() succeeded without pages")
+	}
+}
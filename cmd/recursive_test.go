@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	md "github.com/leotaku/kojirou/mangadex"
+)
+
+func TestRecursive(t *testing.T) {
+	// Series may be grouped in folders of any depth
+	root := t.TempDir()
+	for _, series := range []struct {
+		path   string
+		layout map[string][]string
+	}{
+		{"Series A", map[string][]string{"1": {"1"}, "2": {"2"}}},
+		{filepath.Join("Shelf", "Series B"), map[string][]string{"1": {"1", "2"}}},
+	} {
+		dir := filepath.Join(root, series.path)
+		if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+			t.Fatalf("failed to create shelf: %v", err)
+		}
+		if err := os.Rename(createDiskSeries(t, series.layout), dir); err != nil {
+			t.Fatalf("failed to move series: %v", err)
+		}
+	}
+
+	origFormatsArg, origDiskArg, origRecursiveArg, origOutArg := FormatsArg, diskArg, recursiveArg, outArg
+	defer func() {
+		FormatsArg, diskArg, recursiveArg, outArg = origFormatsArg, origDiskArg, origRecursiveArg, origOutArg
+	}()
+	out := t.TempDir()
+	FormatsArg, diskArg, recursiveArg, outArg = "epub", root, true, out
+
+	if err := runRecursive(); err != nil {
+		t.Fatalf("runRecursive() failed: %v", err)
+	}
+	if diskArg != root {
+		t.Errorf("disk directory was not restored, got %v", diskArg)
+	}
+
+	for _, tc := range []struct {
+		title   string
+		volumes []string
+	}{
+		{"Series A", []string{"1", "2"}},
+		{"Series B", []string{"1"}},
+	} {
+		dir := kindle.NewNormalizedDirectory(out, tc.title, false)
+		for _, volume := range tc.volumes {
+			if !dir.HasWithExtension(md.NewIdentifier(volume), "epub") {
+				t.Errorf("%v: volume %v was not written", tc.title, volume)
+			}
+		}
+	}
+
+	// A tree without any series is an error
+	diskArg = t.TempDir()
+	if err := runRecursive(); err == nil {
+		t.Error("runRecursive() succeeded without series")
+	}
+}
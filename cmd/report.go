@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/leotaku/kojirou/cmd/formats"
+)
+
+// Statuses of outputs listed in a report
+const (
+	statusGenerated = "generated"
+	statusSkipped   = "skipped"
+	statusError     = "error"
+)
+
+// reportEntry describes a single output file of a run
+type reportEntry struct {
+	Volume string `json:"volume"`
+	Format string `json:"format"`
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// report collects the outputs of a run for --report. It is safe for
+// concurrent use by multiple volume workers.
+type report struct {
+	mu      sync.Mutex
+	entries []reportEntry
+}
+
+// runReport is the report of the running command
+var runReport = new(report)
+
+// add records the output of the given format. The size is taken from the
+// file on disk, so skipped outputs report the size of the existing file.
+func (r *report) add(volume string, format formats.FormatType, filename, status string, err error) {
+	entry := reportEntry{
+		Volume: volume,
+		Format: string(format),
+		Path:   filename,
+		Status: status,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if info, err := os.Stat(filename); err == nil && status != statusError {
+		entry.Size = info.Size()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+// sorted returns all recorded entries, ordered by path
+func (r *report) sorted() []reportEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := append([]reportEntry{}, r.entries...)
+	slices.SortStableFunc(entries, func(a, b reportEntry) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	return entries
+}
+
+// reset removes all recorded entries
+func (r *report) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
+
+// write writes the report to the given file, as CSV if its name ends in
+// ".csv" and as JSON otherwise
+func (r *report) write(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	defer f.Close()
+
+	entries := r.sorted()
+	if strings.EqualFold(filepath.Ext(filename), ".csv") {
+		w := csv.NewWriter(f)
+		w.Write([]string{"volume", "format", "path", "size", "status", "error"}) //nolint:errcheck
+		for _, e := range entries {
+			w.Write([]string{e.Volume, e.Format, e.Path, strconv.FormatInt(e.Size, 10), e.Status, e.Error}) //nolint:errcheck
+		}
+		w.Flush()
+		err = w.Error()
+	} else {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(entries)
+	}
+	if err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/disk"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/progress"
+	"golang.org/x/text/language"
+)
+
+func TestReport(t *testing.T) {
+	root := createDiskSeries(t, map[string][]string{
+		"1": {"1"},
+		"2": {"2"},
+		"3": {"3"},
+	})
+	skeleton, err := disk.LoadSkeleton(root)
+	if err != nil {
+		t.Fatalf("failed to load skeleton: %v", err)
+	}
+	chapters, err := disk.LoadChapters(root, language.English, progress.VanishingProgress("Disk..."))
+	if err != nil {
+		t.Fatalf("failed to load chapters: %v", err)
+	}
+	manga := skeleton.WithChapters(chapters)
+	dir := kindle.NewNormalizedDirectory(t.TempDir(), manga.Info.Title, false)
+
+	origFormatsArg, origReportArg := FormatsArg, reportArg
+	defer func() { FormatsArg, reportArg = origFormatsArg, origReportArg }()
+	FormatsArg = "epub,mobi"
+	reportArg = filepath.Join(t.TempDir(), "report.json")
+	runReport.reset()
+
+	// Volume 1 already exists, volume 3 has a broken page
+	volumes := manga.Sorted()
+	if err := HandleVolume(manga, volumes[0], dir); err != nil {
+		t.Fatalf("HandleVolume() failed: %v", err)
+	}
+	runReport.reset()
+	if err := os.WriteFile(filepath.Join(root, "3", "3", "01.png"), []byte("garbage"), 0644); err != nil {
+		t.Fatalf("failed to corrupt page: %v", err)
+	}
+	if err := handleVolumes(manga, dir, 2); err == nil {
+		t.Fatal("expected error for broken volume")
+	}
+
+	want := map[string]string{
+		dir.Path(volumes[0].Info.Identifier, "epub"): statusSkipped,
+		dir.Path(volumes[0].Info.Identifier, "azw3"): statusSkipped,
+		dir.Path(volumes[1].Info.Identifier, "epub"): statusGenerated,
+		dir.Path(volumes[1].Info.Identifier, "azw3"): statusGenerated,
+		dir.Path(volumes[2].Info.Identifier, "epub"): statusError,
+		dir.Path(volumes[2].Info.Identifier, "azw3"): statusError,
+	}
+
+	for _, name := range []string{"report.json", "report.csv"} {
+		t.Run(name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), name)
+			if err := runReport.write(filename); err != nil {
+				t.Fatalf("write() failed: %v", err)
+			}
+			entries := readReport(t, filename)
+			if len(entries) != len(want) {
+				t.Fatalf("got %v entries, want %v", len(entries), len(want))
+			}
+
+			for _, entry := range entries {
+				status, ok := want[entry.Path]
+				if !ok {
+					t.Errorf("unexpected entry for %v", entry.Path)
+					continue
+				}
+				if entry.Status != status {
+					t.Errorf("%v: status is %v, want %v", entry.Path, entry.Status, status)
+				}
+				if status == statusError {
+					if entry.Error == "" {
+						t.Errorf("%v: error is missing", entry.Path)
+					}
+					continue
+				}
+				info, err := os.Stat(entry.Path)
+				if err != nil {
+					t.Fatalf("reported output does not exist: %v", err)
+				}
+				if entry.Size != info.Size() {
+					t.Errorf("%v: size is %v, want %v", entry.Path, entry.Size, info.Size())
+				}
+			}
+		})
+	}
+}
+
+// readReport parses a report written as JSON or CSV
+func readReport(t *testing.T, filename string) []reportEntry {
+	t.Helper()
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("failed to read report: %v", err)
+	}
+
+	entries := make([]reportEntry, 0)
+	if filepath.Ext(filename) == ".json" {
+		if err := json.Unmarshal(data, &entries); err != nil {
+			t.Fatalf("failed to parse report: %v", err)
+		}
+		return entries
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatalf("failed to open report: %v", err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse report: %v", err)
+	}
+	for _, record := range records[1:] {
+		size, err := strconv.ParseInt(record[3], 10, 64)
+		if err != nil {
+			t.Fatalf("failed to parse size: %v", err)
+		}
+		entries = append(entries, reportEntry{
+			Volume: record[0],
+			Format: record[1],
+			Path:   record[2],
+			Size:   size,
+			Status: record[4],
+			Error:  record[5],
+		})
+	}
+
+	return entries
+}
@@ -1,50 +1,152 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"runtime/pprof"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/bmaupin/go-epub"
+	"github.com/leotaku/kojirou/cmd/crop"
 	"github.com/leotaku/kojirou/cmd/formats"
+	"github.com/leotaku/kojirou/cmd/formats/download"
+	epubpkg "github.com/leotaku/kojirou/cmd/formats/epub"
+	"github.com/leotaku/kojirou/cmd/formats/icc"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/logging"
+	"github.com/leotaku/kojirou/cmd/formats/progress"
+	"github.com/leotaku/kojirou/cmd/formats/util"
 	"github.com/spf13/cobra"
 )
 
 var (
-	identifierArg       string
-	languageArg         string
-	rankArg             string
-	autocropArg         bool
-	widepageArg         WidepagePolicyArg
-	kindleFolderModeArg bool
-	koboFolderModeArg   bool
-	dryRunArg           bool
-	outArg              string
-	forceArg            bool
-	leftToRightArg      bool
-	fillVolumeNumberArg int
-	dataSaverArg        DataSaverPolicyArg
-	diskArg             string
-	cpuprofileArg       string
-	memprofileArg       string
-	groupsFilter        string
-	chaptersFilter      string
-	volumesFilter       string
-	helpRankingFlag     bool
-	helpFilterFlag      bool
-	FormatsArg          string
+	identifierArg         string
+	languageArg           string
+	coverLocaleArg        string
+	rankArg               string
+	autocropArg           bool
+	autoLevelsArg         bool
+	autoLevelsClipArg     float64
+	gifFrameArg           int
+	splitGuttersArg       bool
+	splitOverlapArg       int
+	svgPagesArg           bool
+	pagesArg              string
+	previewArg            int
+	ditherArg             bool
+	kindleComicArg        bool
+	widepageArg           WidepagePolicyArg
+	widepageThresholdArg  float64
+	kindleFolderModeArg   bool
+	koboFolderModeArg     bool
+	dryRunArg             bool
+	outArg                string
+	forceArg              bool
+	combineArg            bool
+	coverFromFirstPage    bool
+	coverSpreadArg        CoverSpreadArg
+	noCoversArg           bool
+	coverCacheArg         bool
+	refreshCoversArg      bool
+	skipEmptyChaptersArg  bool
+	minPagesArg           int
+	coverArg              string
+	cssArg                string
+	replaceCSSArg         bool
+	customCSS             string
+	verifyArg             bool
+	writeChecksumsArg     bool
+	postProcessArg        string
+	postProcessTimeoutArg time.Duration
+	reportArg             string
+	stateFileArg          string
+	checkExistingArg      bool
+	overwriteOlderArg     bool
+	chapterTitlePagesArg  bool
+	noVolumeSectionArg    bool
+	navGroupSizeArg       int
+	chapterTitleArg       string
+	coverAsPageArg        bool
+	pageNumbersArg        bool
+	pageNumberCornerArg   CornerArg
+	pageNumberOpacityArg  float64
+	tempDirArg            string
+	diskSpaceCheckArg     DiskSpaceCheckArg
+	stripMetadataArg      bool
+	generatorArg          string
+	keepEpubArg           bool
+	stripMediaOverlaysArg bool
+	inMemoryArg           bool
+	zipStoreImagesArg     bool
+	bookIdentifierArg     string
+	sourceDateArg         SourceDateArg
+	pageMarginArg         PageMarginArg
+	marginColorArg        MarginColorArg
+	verbosityArg          = VerbosityArg(logging.LevelNormal)
+	quietArg              bool
+	volumeWorkersArg      int
+	outputDirPerVolumeArg bool
+	leftToRightArg        bool
+	fillVolumeNumberArg   int
+	dataSaverArg          DataSaverPolicyArg
+	diskArg               string
+	recursiveArg          bool
+	dumpArg               string
+	exportMetadataArg     string
+	pageFilenamesArg      bool
+	pagePadArg            int
+	epubVersionArg        int
+	proxyArg              string
+	clientIDArg           string
+	usernameArg           string
+	convertICCArg         bool
+	cpuprofileArg         string
+	memprofileArg         string
+	groupsFilter          string
+	excludeGroupsFilter   string
+	preferGroupsArg       string
+	chaptersFilter        string
+	volumesFilter         string
+	volumeStrategyArg     VolumeStrategyArg
+	helpRankingFlag       bool
+	helpFilterFlag        bool
+	FormatsArg            string
 )
 
 var rootCmd = &cobra.Command{
 	Use:     "kojirou [flags..] <identifier>",
 	Short:   "Generate e-books from MangaDex in multiple formats",
-	Version: "0.1",
-	Args:    cobra.ExactArgs(1),
+	Version: version,
+	Args: func(cmd *cobra.Command, args []string) error {
+		// Series found on disk are not identified by MangaDex
+		if recursiveArg {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cmd.SilenceUsage = true
+		runContext = cmd.Context()
+		if recursiveArg {
+			return runRecursive()
+		}
 		identifierArg = args[0]
 
 		return run()
 	},
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Quiet output is meant for scripts, so progress is hidden too
+		if quietArg {
+			verbosityArg = VerbosityArg(logging.LevelQuiet)
+		}
+		logging.SetLevel(logging.Level(verbosityArg))
+		progress.SetHidden(!logging.Enabled(logging.LevelNormal))
+
 		if cpuprofileArg != "" {
 			f, err := os.Create(cpuprofileArg)
 			if err != nil {
@@ -55,10 +157,125 @@ var rootCmd = &cobra.Command{
 			}
 		}
 
+		if autoLevelsClipArg < 0 || autoLevelsClipArg >= 50 {
+			return fmt.Errorf("auto-levels-clip: must be a percentage from 0 up to 50")
+		}
+		if pageNumberOpacityArg <= 0 || pageNumberOpacityArg > 1 {
+			return fmt.Errorf("page-number-opacity: must be greater than 0 and at most 1")
+		}
+		if previewArg < 0 {
+			return fmt.Errorf("preview: must not be negative")
+		}
+		if widepageThresholdArg <= 0 {
+			return fmt.Errorf("widepage-threshold: must be positive")
+		}
+		if splitOverlapArg < 0 {
+			return fmt.Errorf("split-overlap: must not be negative")
+		}
+		if pagePadArg < 0 {
+			return fmt.Errorf("page-pad: must not be negative")
+		}
+		if epubVersionArg != 2 && epubVersionArg != 3 {
+			return fmt.Errorf("epub-version: must be 2 or 3")
+		}
+		if gifFrameArg < 1 {
+			return fmt.Errorf("gif-frame: must be at least 1")
+		}
+		if recursiveArg {
+			if diskArg == "" {
+				return fmt.Errorf("recursive: requires --disk")
+			}
+			for _, flag := range []string{"dump", "export-metadata", "report", "identifier"} {
+				if cmd.Flags().Changed(flag) {
+					return fmt.Errorf("recursive: does not support --%v", flag)
+				}
+			}
+			if outArg == stdoutPath {
+				return fmt.Errorf("recursive: does not support streaming to stdout")
+			}
+		}
+		if outArg == stdoutPath {
+			for _, flag := range []string{"kindle-folder-mode", "kobo-folder-mode", "output-dir-per-volume", "write-checksums", "verify", "post-process", "page-filenames", "state-file"} {
+				if cmd.Flags().Changed(flag) {
+					return fmt.Errorf("out: streaming to stdout does not support --%v", flag)
+				}
+			}
+		}
+
 		// Validate formats
-		if _, err := formats.ParseFormats(FormatsArg); err != nil {
+		scoped, err := formats.ParseScopedFormats(FormatsArg)
+		if err != nil {
 			return err
 		}
+		selected := scoped.All()
+		if inMemoryArg && slices.Contains(selected, formats.FormatKepub) {
+			return fmt.Errorf("in-memory: not supported for kepub output")
+		}
+
+		icc.SetConvert(convertICCArg)
+		util.SetGIFFrame(gifFrameArg - 1)
+		util.SetZipStoreImages(zipStoreImagesArg)
+
+		// Keep temporary files of large volumes off small system disks
+		if tempDirArg != "" {
+			if err := util.SetTempDir(tempDirArg); err != nil {
+				return fmt.Errorf("temp-dir: %w", err)
+			}
+			// go-epub writes books below the system temporary directory
+			if err := os.Setenv("TMPDIR", tempDirArg); err != nil {
+				return fmt.Errorf("temp-dir: %w", err)
+			}
+			epub.Use(epub.OsFS)
+		}
+
+		// Pin modification dates for reproducible builds
+		if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" && !cmd.Flags().Changed("source-date") {
+			if err := sourceDateArg.Set(epoch); err != nil {
+				return fmt.Errorf("SOURCE_DATE_EPOCH: %w", err)
+			}
+		}
+
+		if noCoversArg && cmd.Flags().Changed("cover") {
+			return fmt.Errorf("no-covers: cannot be used with --cover")
+		}
+
+		// Keep covers between runs only when asked to, since the cache
+		// is never pruned
+		if coverCacheArg {
+			dir, err := os.UserCacheDir()
+			if err != nil {
+				return fmt.Errorf("cover-cache: %w", err)
+			}
+			download.SetCoverCache(filepath.Join(dir, "kojirou", "covers"), refreshCoversArg)
+		}
+
+		// Configure proxy for downloads
+		if err := download.SetProxy(proxyArg); err != nil {
+			return fmt.Errorf("proxy: %w", err)
+		}
+
+		// Authenticate with MangaDex if credentials are given
+		if credentials, err := getCredentials(); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		} else if credentials != nil {
+			download.SetCredentials(*credentials)
+		}
+
+		if _, err := epubpkg.ParseChapterTitle(chapterTitleArg); err != nil {
+			return fmt.Errorf("chapter-title: %w", err)
+		}
+
+		// Load custom stylesheet
+		if cssArg != "" {
+			css, err := os.ReadFile(cssArg)
+			if err != nil {
+				return fmt.Errorf("css: %w", err)
+			}
+			if len(strings.TrimSpace(string(css))) == 0 {
+				return fmt.Errorf("css: '%v' is empty", cssArg)
+			}
+			customCSS = string(css)
+		}
 
 		return nil
 	},
@@ -112,10 +329,21 @@ Prefer chapters by groups with the most uploaded chapters.
 Prefer chapters by groups with the newest upload.
   newest:
 Prefer chapters that have been uploaded most recently.
+  oldest-total:
+Prefer chapters by groups with the oldest upload.
+  oldest:
+Prefer chapters that have been uploaded first.
   views-total:
 Prefer chapters by groups with the most total views.
   views:
-Prefer chapters with the most views.`,
+Prefer chapters with the most views.
+
+Regardless of the selected algorithm, you can name groups
+whose scantlations should always be preferred when several
+versions of the same chapter exist.  Groups are given in
+order of decreasing priority.
+
+  $ kojirou ID --language LANG --prefer-groups "Group A,Group B"`,
 }
 
 var helpFilterCmd = &cobra.Command{
@@ -141,10 +369,11 @@ chapter to be selected for download.
 The previous command will download chapters one through ten
 as well as the special "Oneshot" chapter of the given manga.
 
-  $ kojirou ID --language LANG --volumes 8,9,Specials
+  $ kojirou ID --language LANG --volumes 8,9,Oneshot
 
 The previous command will download volumes eight, nine and
-the special "Special" volume of the given manga.  You might
+the special "Oneshot" volume of the given manga, which holds
+all chapters that were never assigned a volume.  You might
 want to combine filtering for the last released volume of a
 regularly updated manga with the "--force" flag to download
 volumes that might have changed.
@@ -157,46 +386,154 @@ the given regular expression.  If you remove the "!" prefix
 of the regular expression, Kojirou will instead only download
 chapters by groups that match the regular expression.
 
+  $ kojirou ID --language LANG --exclude-groups "Group A,Group B"
+
+The previous command will download all available chapters of
+the given manga except those uploaded by any of the listed
+groups.  Group names are matched case-insensitively, and
+chapters uploaded jointly by several groups are excluded if
+any one of them is listed.
+
+  $ kojirou ID --language LANG --min-pages 3
+
+The previous command will leave out chapters with fewer than
+three pages, which are often advertisements or placeholders.
+If MangaDex reports the page count of a chapter, short uploads
+are dropped before ranking so that other uploads of the same
+chapter can be chosen instead.
+
   $ kojirou ID --language BCP_47_LANGUAGE_TAG
 
 Technically, the "--language" option is also implemented
 as a filter, however it is non-optional and must always be
-given.  It accepts the format of BCP 47 language tags.`,
+given.  It accepts the format of BCP 47 language tags.
+
+  $ kojirou ID --language en,es
+
+If multiple comma-separated languages are given, each chapter
+is downloaded in the first listed language it is available
+in.  This can be useful to fill gaps in a translation.`,
 }
 
 func Execute() {
+	// Interrupts cancel running post-process commands before exiting
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			stop()
+			postProcesses.Wait()
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+
 	if helpRankingFlag {
 		helpRankingCmd.Help() //nolint:errcheck
 	} else if helpFilterFlag {
 		helpFilterCmd.Help() //nolint:errcheck
-	} else if err := rootCmd.Execute(); err != nil {
+	} else if err := rootCmd.ExecuteContext(ctx); err != nil {
 		os.Exit(1)
 	}
 }
 
 func init() {
-	rootCmd.Flags().StringVarP(&FormatsArg, "file-type", "t", "", "output file type(s), e.g. mobi,epub,kepub")
-	rootCmd.Flags().StringVarP(&languageArg, "language", "l", "en", "language for chapter downloads")
+	rootCmd.Flags().StringVarP(&FormatsArg, "file-type", "t", "", "output file type(s), e.g. mobi,epub,kepub,pdf, optionally per volume range like 1-5:mobi,6-:kepub")
+	rootCmd.Flags().StringVarP(&languageArg, "language", "l", "en", "language(s) for chapter downloads, in order of preference")
+	rootCmd.Flags().StringVarP(&coverLocaleArg, "cover-locale", "", "", "preferred locale of volume covers, e.g. ja or en, defaults to the original language")
 	rootCmd.Flags().StringVarP(&rankArg, "rank", "r", "most", "chapter ranking method to use")
+	rootCmd.Flags().StringVarP(&preferGroupsArg, "prefer-groups", "P", "", "comma-separated scantlation groups to prefer, in order")
 	rootCmd.Flags().BoolVarP(&autocropArg, "autocrop", "a", false, "crop whitespace from pages automatically")
 	rootCmd.Flags().VarP(&widepageArg, "widepage", "w", "split wide pages automatically")
+	rootCmd.Flags().Float64VarP(&widepageThresholdArg, "widepage-threshold", "", crop.DefaultWideThreshold, "aspect ratio, width divided by height, above which pages count as wide")
+	rootCmd.Flags().BoolVarP(&autoLevelsArg, "auto-levels", "", false, "stretch the contrast of faded pages automatically")
+	rootCmd.Flags().Float64VarP(&autoLevelsClipArg, "auto-levels-clip", "", 0.5, "percentage of darkest and lightest pixels ignored by auto-levels")
+	rootCmd.Flags().BoolVarP(&splitGuttersArg, "split-gutters", "", false, "split images of two stitched pages at their gutter, regardless of --widepage")
+	rootCmd.Flags().IntVarP(&splitOverlapArg, "split-overlap", "", 0, "pixels that both halves of split pages extend past the split")
+	rootCmd.Flags().IntVarP(&gifFrameArg, "gif-frame", "", 1, "frame that animated GIF pages are flattened to, starting at 1")
+	rootCmd.Flags().BoolVarP(&ditherArg, "dither", "", false, "dither pages to the 16 gray levels of Kindle displays in MOBI output")
+	rootCmd.Flags().BoolVarP(&kindleComicArg, "kindle-comic", "", false, "mark MOBI output as a comic to enable region magnification on Kindle")
 	rootCmd.Flags().BoolVarP(&kindleFolderModeArg, "kindle-folder-mode", "k", false, "generate folder structure for Kindle devices")
 	rootCmd.Flags().BoolVarP(&koboFolderModeArg, "kobo-folder-mode", "K", false, "generate folder structure for Kobo devices (KoboBooks/<Series Title>/)")
 	rootCmd.Flags().BoolVarP(&leftToRightArg, "left-to-right", "p", false, "make reading direction left to right")
 	rootCmd.Flags().IntVarP(&fillVolumeNumberArg, "fill-volume-number", "n", 0, "fill volume number with leading zeros in title")
 	rootCmd.Flags().VarP(&dataSaverArg, "data-saver", "s", "download lower quality images to save space")
 	rootCmd.Flags().BoolVarP(&dryRunArg, "dry-run", "d", false, "disable writing of any files")
-	rootCmd.Flags().StringVarP(&outArg, "out", "o", "", "output directory")
+	rootCmd.Flags().StringVarP(&outArg, "out", "o", "", "output directory, or - to stream a single book to stdout")
 	rootCmd.Flags().BoolVarP(&forceArg, "force", "f", false, "overwrite existing volumes")
+	rootCmd.Flags().BoolVarP(&overwriteOlderArg, "overwrite-older", "", false, "regenerate volumes that are older than their newest chapter")
+	rootCmd.Flags().BoolVarP(&checkExistingArg, "check-existing", "", false, "regenerate existing volumes that are corrupt or incomplete")
+	rootCmd.Flags().BoolVarP(&combineArg, "combine", "c", false, "combine all volumes into a single book")
+	rootCmd.Flags().StringVarP(&coverArg, "cover", "", "", "custom cover image, directory or {volume} template")
+	rootCmd.Flags().BoolVarP(&outputDirPerVolumeArg, "output-dir-per-volume", "", false, "write each volume into its own subfolder")
+	rootCmd.Flags().IntVarP(&volumeWorkersArg, "volume-workers", "", 1, "number of volumes to process concurrently")
+	rootCmd.Flags().BoolVarP(&writeChecksumsArg, "write-checksums", "", false, "write a .sha256 file next to each generated file")
+	rootCmd.Flags().BoolVarP(&verifyArg, "verify", "", false, "re-read written files and check their integrity")
+	rootCmd.Flags().StringVarP(&postProcessArg, "post-process", "", "", "command to run on each generated file, with {file} replaced by its path")
+	rootCmd.Flags().DurationVarP(&postProcessTimeoutArg, "post-process-timeout", "", 5*time.Minute, "time limit for each run of the post-process command")
+	rootCmd.Flags().StringVarP(&reportArg, "report", "", "", "write a JSON or CSV report of all outputs to this file")
+	rootCmd.Flags().StringVarP(&stateFileArg, "state-file", "", "", "record completed volumes in this file, so an interrupted run resumes where it stopped")
+	rootCmd.Flags().StringVarP(&cssArg, "css", "", "", "custom stylesheet for EPUB output")
+	rootCmd.Flags().BoolVarP(&replaceCSSArg, "replace-css", "", false, "replace default stylesheet instead of appending")
+	rootCmd.Flags().BoolVarP(&chapterTitlePagesArg, "chapter-title-pages", "", false, "insert a title page before every chapter in EPUB output")
+	rootCmd.Flags().BoolVarP(&noVolumeSectionArg, "no-volume-section", "", false, "leave out the volume title page at the start of every volume in EPUB output")
+	rootCmd.Flags().StringVarP(&chapterTitleArg, "chapter-title", "", epubpkg.DefaultChapterTitle, "template for chapter titles in EPUB output, with the fields .Number, .Title and .Volume")
+	rootCmd.Flags().IntVarP(&navGroupSizeArg, "nav-group-size", "", 0, "group table of contents entries of long volumes by this many chapters")
+	rootCmd.Flags().StringVarP(&bookIdentifierArg, "identifier", "", "", "unique identifier of EPUB output, e.g. an ISBN or urn:uuid, for a single volume or --combine")
+	rootCmd.Flags().VarP(&sourceDateArg, "source-date", "", "modification date of EPUB output, defaults to $SOURCE_DATE_EPOCH or now")
+	rootCmd.Flags().BoolVarP(&pageNumbersArg, "page-numbers", "", false, "draw page numbers within the book and chapter onto pages of MOBI and EPUB output")
+	rootCmd.Flags().VarP(&pageNumberCornerArg, "page-number-corner", "", "corner of page numbers: bottom-right, bottom-left, top-right or top-left")
+	rootCmd.Flags().Float64VarP(&pageNumberOpacityArg, "page-number-opacity", "", kindle.DefaultPageNumberOpacity, "opacity of page numbers, from 0 to 1")
+	rootCmd.Flags().VarP(&pageMarginArg, "page-margin", "", "border around pages in EPUB output, in pixels or percent")
+	rootCmd.Flags().VarP(&marginColorArg, "page-margin-color", "", "color of the page border: white or black")
+	rootCmd.Flags().BoolVarP(&skipEmptyChaptersArg, "skip-empty-chapters", "", true, "leave out chapters without pages instead of failing the volume")
+	rootCmd.Flags().BoolVarP(&svgPagesArg, "svg-pages", "", false, "wrap every page of EPUB and KEPUB output in an SVG for crisper scaling on fixed-layout readers")
+	rootCmd.Flags().BoolVarP(&coverAsPageArg, "include-cover-as-page", "", false, "show the cover as the first page of EPUB output")
+	rootCmd.Flags().BoolVarP(&keepEpubArg, "keep-epub", "", false, "also write the EPUB that KEPUB output is converted from")
+	rootCmd.Flags().BoolVarP(&stripMediaOverlaysArg, "strip-media-overlays", "", false, "remove SMIL media overlays and their references during KEPUB conversion")
+	rootCmd.Flags().BoolVarP(&inMemoryArg, "in-memory", "", false, "build EPUB output in memory without temporary image files, uses more memory")
+	rootCmd.Flags().BoolVarP(&zipStoreImagesArg, "zip-store-images", "", false, "store images in EPUB and KEPUB archives uncompressed, only deflating text")
+	rootCmd.Flags().BoolVarP(&stripMetadataArg, "strip-metadata", "", false, "remove timestamps and the generator from EPUB and KEPUB output, overrides --source-date")
+	rootCmd.Flags().StringVarP(&generatorArg, "generator", "", "", "generator written to EPUB and KEPUB metadata, defaults to kojirou and its version")
+	rootCmd.Flags().BoolVarP(&coverFromFirstPage, "cover-from-first-page", "", true, "use the first page as cover for volumes without one")
+	rootCmd.Flags().VarP(&coverSpreadArg, "first-page-is-cover-spread", "", "arrangement of wide wraparound covers: off, use-as-cover, split-front-back or keep-spread")
+	rootCmd.Flags().BoolVarP(&noCoversArg, "no-covers", "", false, "generate books without covers, skipping cover downloads")
+	rootCmd.Flags().BoolVarP(&coverCacheArg, "cover-cache", "", false, "keep downloaded covers in the user cache directory for later runs")
+	rootCmd.Flags().BoolVarP(&refreshCoversArg, "force-redownload-covers", "", false, "download covers again instead of using those cached with --cover-cache")
+	rootCmd.Flags().VarP(&verbosityArg, "verbosity", "v", "amount of output: quiet, normal, verbose or debug")
+	rootCmd.Flags().BoolVarP(&quietArg, "quiet", "q", false, "hide progress and informational output, same as --verbosity quiet")
+	rootCmd.Flags().BoolVarP(&convertICCArg, "convert-icc", "", false, "convert images with embedded color profiles to sRGB")
 	rootCmd.Flags().StringVarP(&diskArg, "disk", "D", "", "load additional content from disk")
+	rootCmd.Flags().BoolVarP(&recursiveArg, "recursive", "", false, "generate every series found below the --disk directory, without MangaDex")
+	rootCmd.Flags().StringVarP(&dumpArg, "dump", "", "", "load manga and chapters from a MangaDex JSON dump instead of downloading")
+	rootCmd.Flags().StringVarP(&exportMetadataArg, "export-metadata", "", "", "write the selected manga and chapters as a MangaDex JSON dump to this directory")
+	rootCmd.Flags().BoolVarP(&pageFilenamesArg, "page-filenames", "", false, "keep the original filenames of pages loaded from disk as image titles and in a .pages.json file")
+	rootCmd.Flags().IntVarP(&epubVersionArg, "epub-version", "", 3, "EPUB version of EPUB output: 2 for older devices and apps, or 3")
+	rootCmd.Flags().IntVarP(&pagePadArg, "page-pad", "", 0, "pad page numbers in image filenames to this many digits, defaults to the digits of the largest page number")
+	rootCmd.Flags().StringVarP(&proxyArg, "proxy", "", "", "HTTP, HTTPS or SOCKS5 proxy URL for downloads")
+	rootCmd.Flags().StringVarP(&clientIDArg, "client-id", "", "", "MangaDex API client ID, secret is read from $KOJIROU_CLIENT_SECRET")
+	rootCmd.Flags().StringVarP(&usernameArg, "username", "", "", "MangaDex username, password is read from $KOJIROU_PASSWORD")
+	rootCmd.Flags().StringVarP(&tempDirArg, "temp-dir", "", "", "directory for temporary files, defaults to $TMPDIR")
+	rootCmd.Flags().VarP(&diskSpaceCheckArg, "disk-space-check", "", "check free disk space before generating books: warn, fail or off")
 	rootCmd.Flags().StringVarP(&cpuprofileArg, "cpuprofile", "", "", "write CPU profile to this file")
 	rootCmd.Flags().StringVarP(&memprofileArg, "memprofile", "", "", "write heap profile to this file")
 	rootCmd.Flags().StringVarP(&volumesFilter, "volumes", "V", "", "volume identifiers for chapter downloads")
+	rootCmd.Flags().VarP(&volumeStrategyArg, "volume-strategy", "", "grouping of chapters into volumes: metadata, by-chapter-range or single")
 	rootCmd.Flags().StringVarP(&chaptersFilter, "chapters", "C", "", "chapter identifiers for chapter downloads")
 	rootCmd.Flags().StringVarP(&groupsFilter, "groups", "G", "", "scantlation groups for chapter downloads")
+	rootCmd.Flags().IntVarP(&minPagesArg, "min-pages", "", 0, "leave out chapters with fewer pages, such as advertisements")
+	rootCmd.Flags().StringVarP(&pagesArg, "pages", "", "", "page numbers within each chapter to include, e.g. for previews")
+	rootCmd.Flags().IntVarP(&previewArg, "preview", "", 0, "include only the first pages of each chapter, same as --pages 1..N")
+	rootCmd.Flags().StringVarP(&excludeGroupsFilter, "exclude-groups", "X", "", "comma-separated scantlation groups to exclude")
 	rootCmd.Flags().BoolVarP(&helpRankingFlag, "help-ranking", "R", false, "Help for chapter ranking")
 	rootCmd.Flags().BoolVarP(&helpFilterFlag, "help-filter", "F", false, "Help for chapter filtering")
 	rootCmd.Flags().SortFlags = false
+	for _, name := range []string{"prefer-groups", "volumes", "volume-strategy", "chapters", "groups", "exclude-groups", "min-pages"} {
+		rootCmd.Flags().SetAnnotation(name, filterAnnotation, []string{"true"}) //nolint:errcheck
+	}
 	rootCmd.Flags().MarkHidden("cpuprofile") //nolint:errcheck
 	rootCmd.Flags().MarkHidden("memprofile") //nolint:errcheck
 	rootCmd.MarkFlagRequired("language")     //nolint:errcheck
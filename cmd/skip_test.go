@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/leotaku/kojirou/cmd/formats"
+	"github.com/leotaku/kojirou/cmd/formats/disk"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/progress"
+	md "github.com/leotaku/kojirou/mangadex"
+	"golang.org/x/text/language"
+)
+
+// loadDiskSeries creates and loads a disk series with the given layout
+func loadDiskSeries(t *testing.T, layout map[string][]string) md.Manga {
+	t.Helper()
+
+	root := createDiskSeries(t, layout)
+	skeleton, err := disk.LoadSkeleton(root)
+	if err != nil {
+		t.Fatalf("failed to load skeleton: %v", err)
+	}
+	chapters, err := disk.LoadChapters(root, language.English, progress.VanishingProgress("Disk..."))
+	if err != nil {
+		t.Fatalf("failed to load chapters: %v", err)
+	}
+
+	return skeleton.WithChapters(chapters)
+}
+
+func TestCheckExistingRegeneratesCorruptOutput(t *testing.T) {
+	manga := loadDiskSeries(t, map[string][]string{"1": {"1"}})
+	volume := manga.Sorted()[0]
+
+	origFormatsArg, origCheckExistingArg := FormatsArg, checkExistingArg
+	defer func() { FormatsArg, checkExistingArg = origFormatsArg, origCheckExistingArg }()
+	FormatsArg = "epub,mobi"
+
+	for _, tc := range []struct {
+		name        string
+		check       bool
+		regenerated bool
+	}{
+		{"fast path skips", false, false},
+		{"content-aware regenerates", true, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			checkExistingArg = tc.check
+			dir := kindle.NewNormalizedDirectory(t.TempDir(), manga.Info.Title, false)
+			for _, ext := range []string{"epub", "azw3"} {
+				if err := os.WriteFile(dir.Path(volume.Info.Identifier, ext), nil, 0644); err != nil {
+					t.Fatalf("failed to create empty output: %v", err)
+				}
+			}
+
+			if err := HandleVolume(manga, volume, dir); err != nil {
+				t.Fatalf("HandleVolume() failed: %v", err)
+			}
+
+			for _, ext := range []string{"epub", "azw3"} {
+				info, err := os.Stat(dir.Path(volume.Info.Identifier, ext))
+				if err != nil {
+					t.Fatalf("failed to stat output: %v", err)
+				}
+				if regenerated := info.Size() > 0; regenerated != tc.regenerated {
+					t.Errorf("%v: regenerated is %v, want %v", ext, regenerated, tc.regenerated)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckExistingRegeneratesIncompleteOutput(t *testing.T) {
+	manga := loadDiskSeries(t, map[string][]string{"1": {"1", "2"}})
+	volume := manga.Sorted()[0]
+
+	origFormatsArg, origCheckExistingArg := FormatsArg, checkExistingArg
+	defer func() { FormatsArg, checkExistingArg = origFormatsArg, origCheckExistingArg }()
+	FormatsArg = "epub"
+
+	for _, tc := range []struct {
+		name        string
+		check       bool
+		regenerated bool
+	}{
+		{"fast path skips", false, false},
+		{"content-aware regenerates", true, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// A well-formed book written when only the first chapter was
+			// available
+			checkExistingArg = false
+			dir := kindle.NewNormalizedDirectory(t.TempDir(), manga.Info.Title, false)
+			partial := volume
+			partial.Chapters = maps.Clone(volume.Chapters)
+			delete(partial.Chapters, md.NewIdentifier("2"))
+			if err := HandleVolume(manga, partial, dir); err != nil {
+				t.Fatalf("HandleVolume() failed: %v", err)
+			}
+			filename := dir.Path(volume.Info.Identifier, "epub")
+			if err := formats.VerifyFile(filename, formats.FormatEpub); err != nil {
+				t.Fatalf("partial output is not valid: %v", err)
+			}
+
+			checkExistingArg = tc.check
+			if err := HandleVolume(manga, volume, dir); err != nil {
+				t.Fatalf("HandleVolume() failed: %v", err)
+			}
+			err := formats.VerifyChapters(filename, formats.FormatEpub, volume.Sorted())
+			if regenerated := err == nil; regenerated != tc.regenerated {
+				t.Errorf("regenerated is %v, want %v", regenerated, tc.regenerated)
+			}
+		})
+	}
+}
+
+func TestOverwriteOlderRegeneratesStaleOutput(t *testing.T) {
+	manga := loadDiskSeries(t, map[string][]string{"1": {"1", "2"}})
+	published := time.Now().Add(-time.Hour)
+	for _, volume := range manga.Volumes {
+		for id, chapter := range volume.Chapters {
+			chapter.Info.Published = published
+			volume.Chapters[id] = chapter
+		}
+	}
+	volume := manga.Sorted()[0]
+
+	origFormatsArg, origOverwriteOlderArg := FormatsArg, overwriteOlderArg
+	defer func() { FormatsArg, overwriteOlderArg = origFormatsArg, origOverwriteOlderArg }()
+	FormatsArg = "epub"
+	overwriteOlderArg = true
+
+	for _, tc := range []struct {
+		name        string
+		modified    time.Time
+		regenerated bool
+	}{
+		{"older output regenerates", published.Add(-time.Hour), true},
+		{"newer output skips", published.Add(time.Minute), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := kindle.NewNormalizedDirectory(t.TempDir(), manga.Info.Title, false)
+			filename := dir.Path(volume.Info.Identifier, "epub")
+			if err := os.WriteFile(filename, nil, 0644); err != nil {
+				t.Fatalf("failed to create output: %v", err)
+			}
+			if err := os.Chtimes(filename, tc.modified, tc.modified); err != nil {
+				t.Fatalf("failed to set output time: %v", err)
+			}
+
+			if err := HandleVolume(manga, volume, dir); err != nil {
+				t.Fatalf("HandleVolume() failed: %v", err)
+			}
+
+			info, err := os.Stat(filename)
+			if err != nil {
+				t.Fatalf("failed to stat output: %v", err)
+			}
+			if regenerated := info.Size() > 0; regenerated != tc.regenerated {
+				t.Errorf("regenerated is %v, want %v", regenerated, tc.regenerated)
+			}
+		})
+	}
+}
+
+func TestCheckExistingSkipsOutputWithoutDroppedChapters(t *testing.T) {
+	root := createDiskSeries(t, map[string][]string{"1": {"1", "2"}})
+	skeleton, err := disk.LoadSkeleton(root)
+	if err != nil {
+		t.Fatalf("failed to load skeleton: %v", err)
+	}
+	chapters, err := disk.LoadChapters(root, language.English, progress.VanishingProgress("Disk..."))
+	if err != nil {
+		t.Fatalf("failed to load chapters: %v", err)
+	}
+	manga := skeleton.WithChapters(chapters)
+	volume := manga.Sorted()[0]
+
+	// Chapter 2 is a single page advertisement
+	if err := os.Remove(filepath.Join(root, "1", "2", "02.png")); err != nil {
+		t.Fatalf("failed to remove page: %v", err)
+	}
+
+	origFormatsArg, origCheckExistingArg, origMinPagesArg := FormatsArg, checkExistingArg, minPagesArg
+	defer func() {
+		FormatsArg, checkExistingArg, minPagesArg = origFormatsArg, origCheckExistingArg, origMinPagesArg
+	}()
+	FormatsArg = "epub,kepub"
+	checkExistingArg = true
+	minPagesArg = 2
+
+	dir := kindle.NewNormalizedDirectory(t.TempDir(), manga.Info.Title, false)
+	if err := HandleVolume(manga, volume, dir); err != nil {
+		t.Fatalf("HandleVolume() failed: %v", err)
+	}
+	written := time.Now().Add(-time.Hour)
+	for _, ext := range []string{"epub", "kepub.epub"} {
+		if err := os.Chtimes(dir.Path(volume.Info.Identifier, ext), written, written); err != nil {
+			t.Fatalf("failed to set output time: %v", err)
+		}
+	}
+
+	if err := HandleVolume(manga, volume, dir); err != nil {
+		t.Fatalf("HandleVolume() failed: %v", err)
+	}
+	for _, ext := range []string{"epub", "kepub.epub"} {
+		info, err := os.Stat(dir.Path(volume.Info.Identifier, ext))
+		if err != nil {
+			t.Fatalf("failed to stat output: %v", err)
+		}
+		if !info.ModTime().Equal(written) {
+			t.Errorf("%v output was regenerated", ext)
+		}
+	}
+}
+
+func TestKoboFolderModeSkipsExistingOutput(t *testing.T) {
+	manga := loadDiskSeries(t, map[string][]string{"1": {"1"}})
+	volume := manga.Sorted()[0]
+
+	origFormatsArg, origKoboFolderModeArg := FormatsArg, koboFolderModeArg
+	defer func() { FormatsArg, koboFolderModeArg = origFormatsArg, origKoboFolderModeArg }()
+	FormatsArg = "kepub"
+	koboFolderModeArg = true
+	t.Chdir(t.TempDir())
+
+	dir := kindle.NewNormalizedDirectory(t.TempDir(), manga.Info.Title, false)
+	if err := HandleVolume(manga, volume, dir); err != nil {
+		t.Fatalf("HandleVolume() failed: %v", err)
+	}
+	outputs, err := filepath.Glob(filepath.Join("KoboBooks", "*", "*.kepub.epub"))
+	if err != nil || len(outputs) != 1 {
+		t.Fatalf("Kobo outputs are %v, want one: %v", outputs, err)
+	}
+	written := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(outputs[0], written, written); err != nil {
+		t.Fatalf("failed to set output time: %v", err)
+	}
+
+	if err := HandleVolume(manga, volume, dir); err != nil {
+		t.Fatalf("HandleVolume() failed: %v", err)
+	}
+	info, err := os.Stat(outputs[0])
+	if err != nil {
+		t.Fatalf("failed to stat output: %v", err)
+	}
+	if !info.ModTime().Equal(written) {
+		t.Error("existing Kobo output was regenerated")
+	}
+}
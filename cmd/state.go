@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/leotaku/kojirou/cmd/formats"
+	md "github.com/leotaku/kojirou/mangadex"
+)
+
+// stateFile is the content of a --state-file. It lists the completed books
+// and formats of every unfinished run, keyed by the series and selection of
+// the run, so that recursive runs keep the state of each series apart.
+type stateFile struct {
+	Runs map[string]map[string][]formats.FormatType `json:"runs"`
+}
+
+// runState records the completed books of a run, so that an interrupted run
+// continues where it stopped when started again with the same --state-file.
+// It is safe for concurrent use by multiple volume workers, and all methods
+// do nothing on a nil state.
+type runState struct {
+	mu       sync.Mutex
+	filename string
+	key      string
+	file     stateFile
+}
+
+// resumeState is the state of the running command, nil without --state-file
+var resumeState *runState
+
+// stateKey identifies a run by its series, the selected chapters and
+// formats and the output directory. Runs with a different selection do not
+// share their state.
+func stateKey(manga md.Manga, selected []formats.FormatType) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%q %q %q\n", manga.Info.ID, manga.Info.Title, outArg)
+	for _, volume := range manga.Sorted() {
+		for _, chapter := range volume.Sorted() {
+			fmt.Fprintf(h, "%q %q %q\n", volume.Info.Identifier, chapter.Info.Identifier, chapter.Info.ID)
+		}
+	}
+	for _, format := range selected {
+		fmt.Fprintf(h, "%q\n", format)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// loadRunState reads the state of the run with the given key from the
+// given file, starting with no completed books if either does not exist
+func loadRunState(filename, key string) (*runState, error) {
+	state := &runState{filename: filename, key: key}
+	data, err := os.ReadFile(filename)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("read: %w", err)
+	default:
+		if err := json.Unmarshal(data, &state.file); err != nil {
+			return nil, fmt.Errorf("parse: %w", err)
+		}
+	}
+	if state.file.Runs == nil {
+		state.file.Runs = make(map[string]map[string][]formats.FormatType)
+	}
+
+	return state, nil
+}
+
+// done reports whether the given format of the named book was completed
+func (s *runState) done(book string, format formats.FormatType) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Contains(s.file.Runs[s.key][book], format)
+}
+
+// complete records the given format of the named book as completed
+func (s *runState) complete(book string, format formats.FormatType) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file.Runs[s.key] == nil {
+		s.file.Runs[s.key] = make(map[string][]formats.FormatType)
+	}
+	s.file.Runs[s.key][book] = append(s.file.Runs[s.key][book], format)
+
+	return s.save()
+}
+
+// finish forgets the run once it has completed, removing the file if no
+// other run is left unfinished
+func (s *runState) finish() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.file.Runs, s.key)
+	if len(s.file.Runs) == 0 {
+		if err := os.Remove(s.filename); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("remove: %w", err)
+		}
+		return nil
+	}
+
+	return s.save()
+}
+
+// save writes the state through a temporary file, so that an interrupt
+// never leaves a partially written state behind
+func (s *runState) save() error {
+	data, err := json.MarshalIndent(s.file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.filename), filepath.Base(s.filename)+".*")
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.filename); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return nil
+}
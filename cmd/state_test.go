@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+)
+
+func TestResumeState(t *testing.T) {
+	manga := loadDiskSeries(t, map[string][]string{"1": {"1"}, "2": {"2"}, "3": {"3"}})
+	volumes := manga.Sorted()
+	dir := kindle.NewNormalizedDirectory(t.TempDir(), manga.Info.Title, false)
+	filename := filepath.Join(t.TempDir(), "state.json")
+	selected := []formats.FormatType{formats.FormatEpub}
+
+	origFormatsArg := FormatsArg
+	defer func() { FormatsArg, resumeState = origFormatsArg, nil }()
+	FormatsArg = "epub"
+
+	// The interrupted run only completes the first volume
+	state, err := loadRunState(filename, stateKey(manga, selected))
+	if err != nil {
+		t.Fatalf("loadRunState() failed: %v", err)
+	}
+	resumeState = state
+	if err := HandleVolume(manga, volumes[0], dir); err != nil {
+		t.Fatalf("HandleVolume() failed: %v", err)
+	}
+	if err := os.Remove(dir.Path(volumes[0].Info.Identifier, "epub")); err != nil {
+		t.Fatalf("failed to remove output: %v", err)
+	}
+
+	// The resumed run continues from the state, even without the output
+	resumeState, err = loadRunState(filename, stateKey(manga, selected))
+	if err != nil {
+		t.Fatalf("loadRunState() failed: %v", err)
+	}
+	if err := handleVolumes(manga, dir, 1); err != nil {
+		t.Fatalf("handleVolumes() failed: %v", err)
+	}
+	for i, volume := range volumes {
+		_, err := os.Stat(dir.Path(volume.Info.Identifier, "epub"))
+		if written := err == nil; written != (i > 0) {
+			t.Errorf("volume %v: written is %v, want %v", volume.Info.Identifier, written, i > 0)
+		}
+	}
+
+	// Runs with another selection do not share the state
+	other, err := loadRunState(filename, stateKey(manga, []formats.FormatType{formats.FormatMobi}))
+	if err != nil {
+		t.Fatalf("loadRunState() failed: %v", err)
+	}
+	if other.done(volumes[0].Info.Identifier.StringFilled(4, 0, false), formats.FormatEpub) {
+		t.Errorf("state of another selection is resumed")
+	}
+
+	if err := resumeState.finish(); err != nil {
+		t.Fatalf("finish() failed: %v", err)
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Errorf("state file remains after the run finished: %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+)
+
+func TestStreamToStdout(t *testing.T) {
+	manga := loadDiskSeries(t, map[string][]string{"1": {"1"}})
+	volume := manga.Sorted()[0]
+
+	origFormatsArg, origOutArg, origStdout := FormatsArg, outArg, stdout
+	defer func() { FormatsArg, outArg, stdout = origFormatsArg, origOutArg, origStdout }()
+	buf := new(bytes.Buffer)
+	FormatsArg, outArg, stdout = "epub", stdoutPath, buf
+
+	root := t.TempDir()
+	dir := kindle.NewNormalizedDirectory(root, manga.Info.Title, false)
+	if err := HandleVolume(manga, volume, dir); err != nil {
+		t.Fatalf("HandleVolume() failed: %v", err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("stream is not a valid archive: %v", err)
+	}
+	if len(r.File) == 0 || r.File[0].Name != "mimetype" {
+		t.Fatalf("stream does not start with the EPUB mimetype")
+	}
+	if _, err := r.Open("META-INF/container.xml"); err != nil {
+		t.Errorf("stream has no container: %v", err)
+	}
+
+	if entries, err := os.ReadDir(root); err != nil || len(entries) != 0 {
+		t.Errorf("expected no files in the output directory, got %v (%v)", len(entries), err)
+	}
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"cmp"
+	"runtime/debug"
+	"strings"
+)
+
+// version is the version of kojirou, used unless a build records another
+const version = "0.1"
+
+// buildVersion returns the version of the module kojirou was built from,
+// falling back to version for development builds
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return strings.TrimPrefix(info.Main.Version, "v")
+	}
+
+	return version
+}
+
+// generator returns the generator written to EPUB and KEPUB output
+func generator() string {
+	return cmp.Or(generatorArg, "kojirou "+buildVersion())
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/leotaku/kojirou/cmd/formats/disk"
+	"github.com/leotaku/kojirou/cmd/formats/kindle"
+	"github.com/leotaku/kojirou/cmd/formats/progress"
+	md "github.com/leotaku/kojirou/mangadex"
+	"golang.org/x/text/language"
+)
+
+func TestHandleVolumesContinuesAfterError(t *testing.T) {
+	for _, workers := range []int{1, 3} {
+		t.Run(fmt.Sprintf("workers=%v", workers), func(t *testing.T) {
+			testHandleVolumes(t, workers)
+		})
+	}
+}
+
+func testHandleVolumes(t *testing.T, workers int) {
+	root := createDiskSeries(t, map[string][]string{
+		"1": {"1"},
+		"2": {"2"},
+		"3": {"3"},
+		"4": {"4"},
+	})
+	skeleton, err := disk.LoadSkeleton(root)
+	if err != nil {
+		t.Fatalf("failed to load skeleton: %v", err)
+	}
+	chapters, err := disk.LoadChapters(root, language.English, progress.VanishingProgress("Disk..."))
+	if err != nil {
+		t.Fatalf("failed to load chapters: %v", err)
+	}
+	manga := skeleton.WithChapters(chapters)
+
+	// Break a page of the second volume so that loading it fails
+	if err := os.WriteFile(filepath.Join(root, "2", "2", "01.png"), []byte("garbage"), 0644); err != nil {
+		t.Fatalf("failed to corrupt page: %v", err)
+	}
+
+	origFormatsArg := FormatsArg
+	defer func() { FormatsArg = origFormatsArg }()
+	FormatsArg = "epub"
+
+	dir := kindle.NewNormalizedDirectory(t.TempDir(), manga.Info.Title, false)
+	err = handleVolumes(manga, dir, workers)
+	if err == nil {
+		t.Fatal("expected error for broken volume")
+	}
+	if !strings.Contains(err.Error(), "volume 2") {
+		t.Errorf("error does not name the broken volume: %v", err)
+	}
+
+	for _, volume := range []string{"1", "3", "4"} {
+		if !dir.HasWithExtension(md.NewIdentifier(volume), "epub") {
+			t.Errorf("volume %v was not written", volume)
+		}
+	}
+	if dir.HasWithExtension(md.NewIdentifier("2"), "epub") {
+		t.Error("broken volume 2 was written")
+	}
+}
+
+func TestEmptyVolumesArePruned(t *testing.T) {
+	manga := loadDiskSeries(t, map[string][]string{"1": {"1"}, "2": {"2"}, "3": {"3"}})
+
+	// Filtering can leave a volume of the skeleton without any chapters
+	empty := md.NewIdentifier("2")
+	volume := manga.Volumes[empty]
+	volume.Chapters = map[md.Identifier]md.Chapter{}
+	manga.Volumes[empty] = volume
+
+	manga = withoutEmptyVolumes(manga)
+	if _, ok := manga.Volumes[empty]; ok {
+		t.Fatal("empty volume 2 was not pruned")
+	}
+	if len(manga.Volumes) != 2 {
+		t.Fatalf("expected 2 volumes, got %v", len(manga.Volumes))
+	}
+
+	origFormatsArg := FormatsArg
+	defer func() { FormatsArg = origFormatsArg }()
+	FormatsArg = "epub"
+
+	dir := kindle.NewNormalizedDirectory(t.TempDir(), manga.Info.Title, false)
+	if err := handleVolumes(manga, dir, 1); err != nil {
+		t.Fatalf("handleVolumes() failed: %v", err)
+	}
+	for _, volume := range []string{"1", "3"} {
+		if !dir.HasWithExtension(md.NewIdentifier(volume), "epub") {
+			t.Errorf("volume %v was not written", volume)
+		}
+	}
+}
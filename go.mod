@@ -8,6 +8,8 @@ require (
 	github.com/fatih/color v1.18.0
 	github.com/hashicorp/go-retryablehttp v0.7.7
 	github.com/leotaku/mobi v0.5.0
+	github.com/mattn/go-isatty v0.0.20
+	github.com/nwaples/rardecode/v2 v2.4.1
 	github.com/spf13/cobra v1.8.1
 	github.com/spf13/pflag v1.0.5
 	go.uber.org/ratelimit v0.3.1
@@ -25,7 +27,6 @@ require (
 	github.com/hashicorp/go-cleanhttp v0.5.2 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
-	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/mattn/go-runewidth v0.0.16 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
 	github.com/stretchr/testify v1.9.0 // indirect
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var AuthURL, _ = url.Parse(`https://auth.mangadex.org/realms/mangadex/protocol/openid-connect/token`)
+
+// Credentials identify a MangaDex personal API client and the user it
+// belongs to.
+type Credentials struct {
+	ClientID     string
+	ClientSecret string
+	Username     string
+	Password     string
+}
+
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+type authenticator struct {
+	credentials Credentials
+	authURL     url.URL
+
+	mu      sync.Mutex
+	token   *Token
+	expires time.Time
+}
+
+func (c *Client) WithCredentials(credentials Credentials) *Client {
+	c.auth = &authenticator{
+		credentials: credentials,
+		authURL:     *AuthURL,
+	}
+	return c
+}
+
+func (c *Client) WithAuthURL(url url.URL) *Client {
+	if c.auth != nil {
+		c.auth.authURL = url
+	}
+	return c
+}
+
+// accessToken returns a valid access token, logging in or refreshing the
+// previous token as needed. The given token is considered expired, which
+// forces a refresh after the server rejected it.
+func (a *authenticator) accessToken(ctx context.Context, client *http.Client, rejected string) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token != nil && a.token.AccessToken != rejected && time.Now().Before(a.expires) {
+		return a.token.AccessToken, nil
+	}
+
+	form := url.Values{
+		"client_id":     {a.credentials.ClientID},
+		"client_secret": {a.credentials.ClientSecret},
+	}
+	if a.token != nil && a.token.RefreshToken != "" {
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", a.token.RefreshToken)
+		if err := a.requestToken(ctx, client, form); err == nil {
+			return a.token.AccessToken, nil
+		}
+	}
+
+	form.Del("refresh_token")
+	form.Set("grant_type", "password")
+	form.Set("username", a.credentials.Username)
+	form.Set("password", a.credentials.Password)
+	if err := a.requestToken(ctx, client, form); err != nil {
+		return "", fmt.Errorf("login: %w", err)
+	}
+
+	return a.token.AccessToken, nil
+}
+
+func (a *authenticator) requestToken(ctx context.Context, client *http.Client, form url.Values) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", a.authURL.String(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("prepare: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status: %v", resp.Status)
+	}
+	token := new(Token)
+	if err := json.NewDecoder(resp.Body).Decode(token); err != nil {
+		return fmt.Errorf("decode: %w", err)
+	} else if token.AccessToken == "" {
+		return fmt.Errorf("missing access token")
+	}
+
+	// Refresh slightly early so that tokens never expire mid-request
+	a.token = token
+	a.expires = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - 30*time.Second)
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestCredentials(t *testing.T) {
+	grants := make([]string, 0)
+	authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("client_id") != "id" || r.FormValue("client_secret") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		grants = append(grants, r.FormValue("grant_type"))
+		json.NewEncoder(w).Encode(Token{ //nolint:errcheck
+			AccessToken:  fmt.Sprint("token-", len(grants)),
+			RefreshToken: "refresh",
+			ExpiresIn:    900,
+		})
+	}))
+	defer authServer.Close()
+
+	headers := make([]string, 0)
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Get("Authorization"))
+		// The first token is revoked by the server
+		if r.Header.Get("Authorization") == "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"errors": []}`)) //nolint:errcheck
+			return
+		}
+		w.Write([]byte(`{"result": "ok"}`)) //nolint:errcheck
+	}))
+	defer apiServer.Close()
+	apiURL, _ := url.Parse(apiServer.URL)
+	authURL, _ := url.Parse(authServer.URL)
+
+	// Anonymous access is the default
+	client := NewClient().WithBaseURL(*apiURL)
+	if _, err := client.GetManga(context.TODO(), "id"); err != nil {
+		t.Fatalf("GetManga() failed: %v", err)
+	}
+	if len(headers) != 1 || headers[0] != "" {
+		t.Errorf("expected no authorization, got %q", headers)
+	}
+
+	headers = headers[:0]
+	client = NewClient().WithBaseURL(*apiURL).WithCredentials(Credentials{
+		ClientID:     "id",
+		ClientSecret: "secret",
+		Username:     "user",
+		Password:     "password",
+	}).WithAuthURL(*authURL)
+	for range 2 {
+		if _, err := client.GetManga(context.TODO(), "id"); err != nil {
+			t.Fatalf("GetManga() failed: %v", err)
+		}
+	}
+
+	expected := []string{"Bearer token-1", "Bearer token-2", "Bearer token-2"}
+	if fmt.Sprint(headers) != fmt.Sprint(expected) {
+		t.Errorf("expected authorization %q, got %q", expected, headers)
+	}
+	if fmt.Sprint(grants) != "[password refresh_token]" {
+		t.Errorf("expected login and one refresh, got %q", grants)
+	}
+}
@@ -23,6 +23,7 @@ var APIBaseURL, _ = url.Parse(`https://api.mangadex.org/`)
 type Client struct {
 	http    *http.Client
 	baseURL url.URL
+	auth    *authenticator
 }
 
 func NewClient() *Client {
@@ -96,24 +97,16 @@ func (c *Client) doJSON(ctx context.Context, method, ref string, result, body in
 		return fmt.Errorf("url: %w", err)
 	}
 
-	rw := io.ReadWriter(nil)
+	buf := new(bytes.Buffer)
 	if body != nil {
-		rw = bytes.NewBuffer(nil)
-		if err := json.NewEncoder(rw).Encode(body); err != nil {
+		if err := json.NewEncoder(buf).Encode(body); err != nil {
 			return fmt.Errorf("encode: %w", err)
 		}
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, url.String(), rw)
+	resp, err := c.do(ctx, method, url.String(), buf.Bytes(), "")
 	if err != nil {
-		return fmt.Errorf("prepare: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	limitGlobal.Take()
-	resp, err := c.http.Do(req)
-	if err != nil {
-		return fmt.Errorf("do: %w", err)
+		return err
 	}
 	defer resp.Body.Close()
 
@@ -133,3 +126,41 @@ func (c *Client) doJSON(ctx context.Context, method, ref string, result, body in
 
 	return nil
 }
+
+// do sends a single request, authenticating it if credentials are
+// configured. Requests rejected as unauthorized are retried once with a
+// refreshed token.
+func (c *Client) do(ctx context.Context, method, url string, body []byte, rejected string) (*http.Response, error) {
+	rw := io.Reader(nil)
+	if len(body) != 0 {
+		rw = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, rw)
+	if err != nil {
+		return nil, fmt.Errorf("prepare: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	token := ""
+	if c.auth != nil {
+		token, err = c.auth.accessToken(ctx, c.http, rejected)
+		if err != nil {
+			return nil, fmt.Errorf("auth: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	limitGlobal.Take()
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && token != "" && rejected == "" {
+		resp.Body.Close()
+		return c.do(ctx, method, url, body, token)
+	}
+
+	return resp, nil
+}
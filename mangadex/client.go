@@ -28,6 +28,11 @@ func (c *Client) WithHTTPClient(http *http.Client) *Client {
 	return c
 }
 
+func (c *Client) WithCredentials(credentials api.Credentials) *Client {
+	c.base.WithCredentials(credentials)
+	return c
+}
+
 func (c *Client) FetchLegacy(ctx context.Context, tp string, legacyID int) (string, error) {
 	mapping, err := c.base.PostIDMapping(ctx, tp, legacyID)
 	if err != nil {
@@ -21,13 +21,39 @@ func convertManga(b *api.Manga, authors, artists *api.AuthorList) MangaInfo {
 	}
 
 	return MangaInfo{
-		Title:   first(b.Data.Attributes.Title),
-		Authors: authorNames,
-		Artists: artistNames,
-		ID:      b.Data.ID,
+		Title:            first(b.Data.Attributes.Title),
+		Authors:          authorNames,
+		Artists:          artistNames,
+		ID:               b.Data.ID,
+		OriginalLanguage: b.Data.Attributes.OriginalLanguage,
+		ExternalIDs:      convertLinks(b.Data.Attributes.Links),
 	}
 }
 
+// linkSchemes maps MangaDex link keys that hold plain identifiers to their
+// identifier schemes. Links that hold URLs are not identifiers.
+var linkSchemes = map[string]string{
+	"isbn": "isbn",
+	"al":   "anilist",
+	"ap":   "anime-planet",
+	"bw":   "bookwalker",
+	"kt":   "kitsu",
+	"mal":  "myanimelist",
+	"mu":   "mangaupdates",
+	"nu":   "novelupdates",
+}
+
+func convertLinks(links map[string]string) map[string]string {
+	result := make(map[string]string)
+	for key, value := range links {
+		if scheme, ok := linkSchemes[key]; ok && value != "" {
+			result[scheme] = value
+		}
+	}
+
+	return result
+}
+
 func convertChapters(ca []api.ChapterData, groupMap map[string]api.GroupData) ChapterList {
 	sorted := make(ChapterList, 0)
 	for _, info := range ca {
@@ -45,8 +71,9 @@ func convertChapters(ca []api.ChapterData, groupMap map[string]api.GroupData) Ch
 				GroupNames:       groups,
 				Published:        info.Attributes.PublishAt,
 				ID:               info.ID,
+				Pages:            info.Attributes.Pages,
 				Identifier:       NewWithFallback(info.Attributes.Chapter, info.Attributes.Title),
-				VolumeIdentifier: NewWithFallback(info.Attributes.Volume, "Special"),
+				VolumeIdentifier: volumeIdentifier(info.Attributes.Volume),
 			},
 			Pages: make(map[int]image.Image),
 		})
@@ -56,6 +83,17 @@ func convertChapters(ca []api.ChapterData, groupMap map[string]api.GroupData) Ch
 	return sorted
 }
 
+// volumeIdentifier returns the identifier of a volume as given by the API.
+// Chapters and covers without a volume are collected in the synthetic
+// oneshot volume.
+func volumeIdentifier(volume string) Identifier {
+	if volume == "" {
+		return OneshotIdentifier()
+	}
+
+	return NewWithFallback(volume, "Special")
+}
+
 func convertCovers(coverBaseURL string, mangaID string, co []api.CoverData) PathList {
 	result := make(PathList, 0)
 	for _, info := range co {
@@ -64,7 +102,8 @@ func convertCovers(coverBaseURL string, mangaID string, co []api.CoverData) Path
 			DataURL:           url,
 			ImageIdentifier:   0,
 			ChapterIdentifier: NewIdentifier("0"),
-			VolumeIdentifier:  NewWithFallback(info.Attributes.Volume, "Special"),
+			VolumeIdentifier:  volumeIdentifier(info.Attributes.Volume),
+			Locale:            info.Attributes.Locale,
 		})
 	}
 
@@ -0,0 +1,30 @@
+package mangadex
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/leotaku/kojirou/mangadex/api"
+)
+
+func TestConvertChaptersWithoutVolume(t *testing.T) {
+	var chapters []api.ChapterData
+	if err := json.Unmarshal([]byte(`[
+		{"id": "a", "attributes": {"volume": "1", "chapter": "1"}},
+		{"id": "b", "attributes": {"volume": null, "chapter": "2"}},
+		{"id": "c", "attributes": {"chapter": "3"}}
+	]`), &chapters); err != nil {
+		t.Fatalf("failed to parse chapters: %v", err)
+	}
+
+	want := map[string]Identifier{
+		"a": NewIdentifier("1"),
+		"b": OneshotIdentifier(),
+		"c": OneshotIdentifier(),
+	}
+	for _, chapter := range convertChapters(chapters, nil).CollectVolumeless() {
+		if got := chapter.Info.VolumeIdentifier; !got.Equal(want[chapter.Info.ID]) {
+			t.Errorf("chapter %v is in volume %v, want %v", chapter.Info.Identifier, got, want[chapter.Info.ID])
+		}
+	}
+}
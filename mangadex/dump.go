@@ -0,0 +1,214 @@
+package mangadex
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/leotaku/kojirou/mangadex/api"
+)
+
+// dumpObject holds the relationships of a dumped object including their
+// attributes, which are only present for included relationships
+type dumpObject struct {
+	Relationships []api.Relationship
+}
+
+// ParseDump builds the manga and its chapters from previously saved
+// MangaDex API responses, without any network access.
+//
+// The manga is the response of the manga endpoint and the chapters are the
+// response of the manga feed endpoint. Author, artist and group names are
+// taken from relationships included with "includes[]", and are otherwise
+// left empty. Unlike downloaded chapters, chapters and volumes that are
+// not numbers keep their names, so that dumps written by ExportDump
+// round-trip.
+func ParseDump(manga, chapters []byte) (*Manga, ChapterList, error) {
+	mangaResponse := new(api.Manga)
+	if err := json.Unmarshal(manga, mangaResponse); err != nil {
+		return nil, nil, fmt.Errorf("manga: %w", err)
+	}
+	if err := validateDumpObject(mangaResponse.Data.Type, "manga", mangaResponse.Data.ID); err != nil {
+		return nil, nil, fmt.Errorf("manga: %w", err)
+	}
+	if len(mangaResponse.Data.Attributes.Title) == 0 {
+		return nil, nil, fmt.Errorf("manga: missing title")
+	}
+	mangaIncludes := new(struct{ Data dumpObject })
+	if err := json.Unmarshal(manga, mangaIncludes); err != nil {
+		return nil, nil, fmt.Errorf("manga: %w", err)
+	}
+
+	feed := new(api.ChapterList)
+	if err := json.Unmarshal(chapters, feed); err != nil {
+		return nil, nil, fmt.Errorf("chapters: %w", err)
+	}
+	attributes := make(map[string]api.ChapterData)
+	for _, chapter := range feed.Data {
+		if err := validateDumpObject(chapter.Type, "chapter", chapter.ID); err != nil {
+			return nil, nil, fmt.Errorf("chapters: %w", err)
+		} else if _, ok := attributes[chapter.ID]; ok {
+			return nil, nil, fmt.Errorf("chapters: duplicate ID %v", chapter.ID)
+		}
+		attributes[chapter.ID] = chapter
+	}
+	feedIncludes := new(struct{ Data []dumpObject })
+	if err := json.Unmarshal(chapters, feedIncludes); err != nil {
+		return nil, nil, fmt.Errorf("chapters: %w", err)
+	}
+
+	authors, artists := new(api.AuthorList), new(api.AuthorList)
+	for _, r := range mangaIncludes.Data.Relationships {
+		author := api.AuthorData{ID: r.ID, Type: r.Type}
+		author.Attributes.Name = dumpName(r)
+		switch {
+		case author.Attributes.Name == "":
+			continue
+		case r.Type == "author":
+			authors.Data = append(authors.Data, author)
+		case r.Type == "artist":
+			artists.Data = append(artists.Data, author)
+		}
+	}
+
+	groupMap := make(map[string]api.GroupData)
+	for _, chapter := range feedIncludes.Data {
+		for _, r := range chapter.Relationships {
+			// Groups may only be included with some of their chapters
+			if r.Type == "scanlation_group" && dumpName(r) != "" {
+				group := api.GroupData{ID: r.ID, Type: r.Type}
+				group.Attributes.Name = dumpName(r)
+				groupMap[r.ID] = group
+			}
+		}
+	}
+
+	cl := convertChapters(feed.Data, groupMap)
+	for i, chapter := range cl {
+		attrs := attributes[chapter.Info.ID].Attributes
+		if attrs.Volume != "" {
+			cl[i].Info.VolumeIdentifier = NewIdentifier(attrs.Volume)
+		}
+		if attrs.Chapter != "" {
+			cl[i].Info.Identifier = NewIdentifier(attrs.Chapter)
+		}
+	}
+
+	return &Manga{
+		Info:    convertManga(mangaResponse, authors, artists),
+		Volumes: make(map[Identifier]Volume),
+	}, cl, nil
+}
+
+// dumpRelationship is a relationship as written by ExportDump
+type dumpRelationship struct {
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+type dumpManga struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	Attributes struct {
+		Title            map[string]string `json:"title"`
+		OriginalLanguage string            `json:"originalLanguage,omitempty"`
+		Links            map[string]string `json:"links"`
+	} `json:"attributes"`
+	Relationships []dumpRelationship `json:"relationships"`
+}
+
+type dumpChapter struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	Attributes struct {
+		Title              string    `json:"title"`
+		Volume             string    `json:"volume"`
+		Chapter            string    `json:"chapter"`
+		Pages              int       `json:"pages"`
+		TranslatedLanguage string    `json:"translatedLanguage"`
+		PublishAt          time.Time `json:"publishAt"`
+	} `json:"attributes"`
+	Relationships []dumpRelationship `json:"relationships"`
+}
+
+// ExportDump writes the manga and its chapters in the format of the
+// MangaDex API responses read by ParseDump. Authors, artists and groups
+// are written as included relationships with made up IDs, as their real
+// IDs are not known.
+func ExportDump(info MangaInfo, chapters ChapterList) (manga, feed []byte, err error) {
+	m := dumpManga{ID: info.ID, Type: "manga", Relationships: make([]dumpRelationship, 0)}
+	m.Attributes.Title = map[string]string{"en": info.Title}
+	m.Attributes.OriginalLanguage = info.OriginalLanguage
+	m.Attributes.Links = make(map[string]string)
+	for key, scheme := range linkSchemes {
+		if id, ok := info.ExternalIDs[scheme]; ok {
+			m.Attributes.Links[key] = id
+		}
+	}
+	for i, name := range info.Authors {
+		m.Relationships = append(m.Relationships, dumpRelationship{
+			ID: fmt.Sprintf("author-%v", i+1), Type: "author", Attributes: map[string]string{"name": name},
+		})
+	}
+	for i, name := range info.Artists {
+		m.Relationships = append(m.Relationships, dumpRelationship{
+			ID: fmt.Sprintf("artist-%v", i+1), Type: "artist", Attributes: map[string]string{"name": name},
+		})
+	}
+
+	groupIDs := make(map[string]string)
+	data := make([]dumpChapter, 0, len(chapters))
+	// The feed is ordered oldest first, while chapter lists are newest first
+	for i := len(chapters) - 1; i >= 0; i-- {
+		chapter := chapters[i].Info
+		c := dumpChapter{ID: chapter.ID, Type: "chapter", Relationships: make([]dumpRelationship, 0)}
+		c.Attributes.Title = chapter.Title
+		c.Attributes.Volume = chapter.VolumeIdentifier.String()
+		c.Attributes.Chapter = chapter.Identifier.String()
+		c.Attributes.Pages = chapter.Pages
+		c.Attributes.TranslatedLanguage = chapter.Language.String()
+		c.Attributes.PublishAt = chapter.Published
+		for _, name := range chapter.GroupNames {
+			if _, ok := groupIDs[name]; !ok {
+				groupIDs[name] = fmt.Sprintf("group-%v", len(groupIDs)+1)
+			}
+			c.Relationships = append(c.Relationships, dumpRelationship{
+				ID: groupIDs[name], Type: "scanlation_group", Attributes: map[string]string{"name": name},
+			})
+		}
+		data = append(data, c)
+	}
+
+	manga, err = json.MarshalIndent(map[string]any{
+		"result": "ok", "response": "entity", "data": m,
+	}, "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("manga: %w", err)
+	}
+	feed, err = json.MarshalIndent(map[string]any{
+		"result": "ok", "response": "collection", "data": data,
+		"limit": len(data), "offset": 0, "total": len(data),
+	}, "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("chapters: %w", err)
+	}
+
+	return manga, feed, nil
+}
+
+func validateDumpObject(tp, want, id string) error {
+	switch {
+	case tp != want:
+		return fmt.Errorf("expected type '%v', got '%v'", want, tp)
+	case id == "":
+		return fmt.Errorf("%v without ID", want)
+	default:
+		return nil
+	}
+}
+
+func dumpName(r api.Relationship) string {
+	name, _ := r.Attributes["name"].(string)
+	return name
+}
@@ -11,6 +11,7 @@ type Identifier struct {
 	special  bool
 	major    int
 	minor    int
+	digits   int
 	fallback string
 }
 
@@ -25,13 +26,23 @@ func UnknownIdentifier() Identifier {
 	}
 }
 
+// OneshotIdentifier returns the identifier of the synthetic volume
+// that collects chapters without any volume assignment.
+func OneshotIdentifier() Identifier {
+	return Identifier{
+		special:  true,
+		fallback: "Oneshot",
+	}
+}
+
 func NewWithFallback(id string, fallback string) Identifier {
-	major, minor, ok := parseTwoPart(id)
+	major, minor, digits, ok := parseTwoPart(id)
 	switch {
 	case ok:
 		return Identifier{
-			major: major,
-			minor: minor,
+			major:  major,
+			minor:  minor,
+			digits: digits,
 		}
 	case fallback == "Unknown":
 		return Identifier{
@@ -59,15 +70,26 @@ func (n Identifier) StringFilled(before, after int, forceAfter bool) string {
 		f := fmt.Sprintf("%%0%dd", before)
 		return fmt.Sprintf(f, n.major)
 	default:
-		f := fmt.Sprintf("%%0%dd.%%0%dd", before, after)
-		return fmt.Sprintf(f, n.major, n.minor)
+		f := fmt.Sprintf("%%0%dd.%%s", before)
+		return fmt.Sprintf(f, n.major, n.fraction(max(after, n.digits, 1)))
 	}
 }
 
+// fraction returns the digits of the fractional part, padded on the right
+// to the given number of digits, so that e.g. 7.5 and 7.05 stay distinct
+// and fractions of the same length compare as decimals
+func (n Identifier) fraction(digits int) string {
+	if n.minor == 0 {
+		return strings.Repeat("0", digits)
+	}
+	f := fmt.Sprintf("%0*d", n.digits, n.minor)
+	return f + strings.Repeat("0", max(digits-n.digits, 0))
+}
+
 func (n Identifier) Equal(o Identifier) bool {
 	switch {
 	case !n.IsSpecial() && !o.IsSpecial():
-		return n.major == o.major && n.minor == o.minor
+		return n.major == o.major && n.minor == o.minor && n.digits == o.digits
 	case !n.IsUnknown() && !o.IsUnknown():
 		return n.fallback == o.fallback
 	default:
@@ -86,7 +108,8 @@ func (n Identifier) Less(o Identifier) bool {
 	case n.IsSpecial() && o.IsSpecial():
 		return n.fallback < o.fallback
 	case n.major == o.major:
-		return n.minor < o.minor
+		digits := max(n.digits, o.digits)
+		return n.fraction(digits) < o.fraction(digits)
 	default:
 		return n.major < o.major
 	}
@@ -108,7 +131,7 @@ func (n Identifier) IsNext(o Identifier) bool {
 	switch {
 	case n.IsSpecial() || o.IsSpecial():
 		return true
-	case n.major == o.major && n.minor < o.minor:
+	case n.major == o.major && n.Less(o):
 		return true
 	case n.major+1 == o.major && o.minor == 0:
 		return true
@@ -139,25 +162,31 @@ func (n *Identifier) UnmarshalJSON(data []byte) error {
 	return n.UnmarshalText([]byte(text))
 }
 
-func parseTwoPart(s string) (before, after int, ok bool) {
+// parseTwoPart parses identifiers such as "7" or "7.5". Along with the
+// fractional part, the number of its digits without trailing zeros is
+// returned, so that "7.5" and "7.05" stay distinct but "7.5" and "7.50" are
+// the same.
+func parseTwoPart(s string) (before, after, digits int, ok bool) {
 	split := strings.Split(s, ".")
 	if len(split) == 0 || len(split) > 2 {
-		return 0, 0, false
+		return 0, 0, 0, false
 	} else if len(split) == 1 {
 		split = append(split, "0")
 	}
 
 	if parsed, err := strconv.ParseUint(split[0], 10, 0); err != nil {
-		return 0, 0, false
+		return 0, 0, 0, false
 	} else {
 		before = int(parsed)
 	}
 
-	if parsed, err := strconv.ParseUint(split[1], 10, 0); err != nil {
-		return 0, 0, false
-	} else {
+	if _, err := strconv.ParseUint(split[1], 10, 0); err != nil {
+		return 0, 0, 0, false
+	} else if fraction := strings.TrimRight(split[1], "0"); fraction != "" {
+		parsed, _ := strconv.ParseUint(fraction, 10, 0)
 		after = int(parsed)
+		digits = len(fraction)
 	}
 
-	return before, after, true
+	return before, after, digits, true
 }
@@ -0,0 +1,87 @@
+package mangadex
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestIdentifierDecimal(t *testing.T) {
+	for _, tc := range []struct {
+		less, greater string
+	}{
+		{"7.25", "7.5"},
+		{"7.10", "7.9"},
+		{"7.05", "7.5"},
+		{"7.5", "7.55"},
+		{"7", "7.01"},
+	} {
+		a, b := NewIdentifier(tc.less), NewIdentifier(tc.greater)
+		if !a.Less(b) || b.Less(a) || a.Equal(b) {
+			t.Errorf("%v is not ordered before %v", tc.less, tc.greater)
+		}
+	}
+
+	for _, tc := range []struct {
+		a, b string
+	}{
+		{"7.5", "7.50"},
+		{"1.1", "1.10"},
+		{"7", "7.0"},
+	} {
+		a, b := NewIdentifier(tc.a), NewIdentifier(tc.b)
+		if a != b || !a.Equal(b) || a.Less(b) || b.Less(a) {
+			t.Errorf("%v and %v are not equal", tc.a, tc.b)
+		}
+	}
+}
+
+func TestIdentifierOrder(t *testing.T) {
+	want := []string{"1", "1.1", "1.2", "1.25", "1.9", "2", "7", "7.05", "7.5", "Oneshot"}
+	ids := make([]Identifier, 0, len(want))
+	for _, id := range slices.Backward(want) {
+		ids = append(ids, NewIdentifier(id))
+	}
+	slices.SortFunc(ids, func(a, b Identifier) int {
+		switch {
+		case a.Less(b):
+			return -1
+		case b.Less(a):
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	got := make([]string, 0, len(ids))
+	for _, id := range ids {
+		got = append(got, id.String())
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("sorted identifiers are %v, want %v", got, want)
+	}
+}
+
+func TestIdentifierString(t *testing.T) {
+	for _, tc := range []struct {
+		id     string
+		before int
+		want   string
+	}{
+		{"7", 4, "0007"},
+		{"7.5", 4, "0007.5"},
+		{"7.05", 4, "0007.05"},
+		{"1.10", 0, "1.1"},
+		{"7.50", 0, "7.5"},
+		{"7.0", 0, "7"},
+		{"Oneshot", 4, "Oneshot"},
+	} {
+		if got := NewIdentifier(tc.id).StringFilled(tc.before, 0, false); got != tc.want {
+			t.Errorf("StringFilled(%v) of %v is %v, want %v", tc.before, tc.id, got, tc.want)
+		}
+		text, _ := NewIdentifier(tc.id).MarshalText()
+		var id Identifier
+		if err := id.UnmarshalText(text); err != nil || !id.Equal(NewIdentifier(tc.id)) {
+			t.Errorf("%v does not survive MarshalText: %v", tc.id, id)
+		}
+	}
+}
@@ -38,6 +38,20 @@ func (m ChapterList) FilterBy(f func(ChapterInfo) bool) ChapterList {
 	return sorted
 }
 
+// CollectVolumeless moves chapters without a known volume into the
+// synthetic oneshot volume, so they are grouped and output together.
+func (m ChapterList) CollectVolumeless() ChapterList {
+	result := make(ChapterList, 0)
+	for _, val := range m {
+		if val.Info.VolumeIdentifier.IsUnknown() {
+			val.Info.VolumeIdentifier = OneshotIdentifier()
+		}
+		result = append(result, val)
+	}
+
+	return result
+}
+
 // this function currently also affects the contents of the original slice
 func (m ChapterList) SortBy(f func(ChapterInfo, ChapterInfo) bool) ChapterList {
 	sort.SliceStable(m, func(i, j int) bool {
@@ -3,6 +3,7 @@ package mangadex
 import (
 	"image"
 	"sort"
+	"strings"
 )
 
 type Manga struct {
@@ -19,6 +20,9 @@ type Volume struct {
 type Chapter struct {
 	Info  ChapterInfo
 	Pages map[int]image.Image
+	// Filenames are the original filenames of pages loaded from disk,
+	// keyed like Pages. Downloaded pages have no filename.
+	Filenames map[int]string
 }
 
 func (m Manga) Sorted() []Volume {
@@ -30,12 +34,11 @@ func (m Manga) Sorted() []Volume {
 	return result
 }
 
+// Chapters returns the chapters of all volumes in reading order
 func (m Manga) Chapters() ChapterList {
 	result := make(ChapterList, 0)
-	for _, vol := range m.Volumes {
-		for _, chap := range vol.Chapters {
-			result = append(result, chap)
-		}
+	for _, vol := range m.Sorted() {
+		result = append(result, vol.Sorted()...)
 	}
 
 	return result
@@ -128,12 +131,20 @@ func (m Manga) WithPages(pages ImageList) Manga {
 		vols[Idx] = vol
 		for idx, chap := range vol.Chapters {
 			chap.Pages = make(map[int]image.Image)
+			chap.Filenames = nil
 			m.Volumes[Idx].Chapters[idx] = chap
 		}
 	}
 	for _, it := range pages {
-		if _, ok := vols[it.VolumeIdentifier].Chapters[it.ChapterIdentifier]; ok {
-			vols[it.VolumeIdentifier].Chapters[it.ChapterIdentifier].Pages[it.ImageIdentifier] = it.Image
+		if chap, ok := vols[it.VolumeIdentifier].Chapters[it.ChapterIdentifier]; ok {
+			chap.Pages[it.ImageIdentifier] = it.Image
+			if it.Filename != "" {
+				if chap.Filenames == nil {
+					chap.Filenames = make(map[int]string)
+				}
+				chap.Filenames[it.ImageIdentifier] = it.Filename
+				vols[it.VolumeIdentifier].Chapters[it.ChapterIdentifier] = chap
+			}
 		}
 	}
 
@@ -143,6 +154,44 @@ func (m Manga) WithPages(pages ImageList) Manga {
 	}
 }
 
+// SelectCovers returns a single cover for every volume of the manga.
+// Covers in the preferred locale are chosen over covers in the original
+// language of the manga, which are chosen over any other cover. An empty
+// locale prefers the original language.
+func (m Manga) SelectCovers(covers PathList, locale string) PathList {
+	rank := func(cover Path) int {
+		switch {
+		case locale != "" && strings.EqualFold(cover.Locale, locale):
+			return 2
+		case strings.EqualFold(cover.Locale, m.Info.OriginalLanguage):
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	selected := make(map[Identifier]Path)
+	for _, cover := range covers {
+		if _, ok := m.Volumes[cover.VolumeIdentifier]; !ok {
+			continue
+		}
+		if prev, ok := selected[cover.VolumeIdentifier]; !ok || rank(cover) > rank(prev) {
+			selected[cover.VolumeIdentifier] = cover
+		}
+	}
+
+	result := make(PathList, 0, len(selected))
+	for _, id := range m.Keys() {
+		if cover, ok := selected[id]; ok {
+			result = append(result, cover)
+		}
+	}
+
+	return result
+}
+
+// WithCovers returns the manga with the given covers assigned to their
+// volumes. Later covers for the same volume replace earlier ones.
 func (m Manga) WithCovers(covers ImageList) Manga {
 	vols := make(map[Identifier]Volume)
 	for idx, vol := range m.Volumes {
@@ -150,6 +199,9 @@ func (m Manga) WithCovers(covers ImageList) Manga {
 		vols[idx] = vol
 	}
 	for _, it := range covers {
+		if it.Image == nil {
+			continue
+		}
 		if vol, ok := vols[it.VolumeIdentifier]; ok {
 			vol.Cover = it.Image
 			vols[it.VolumeIdentifier] = vol
@@ -162,6 +214,114 @@ func (m Manga) WithCovers(covers ImageList) Manga {
 	}
 }
 
+// WithFallbackCovers returns the manga where every volume without a
+// cover uses the first page of its first chapter as cover instead.
+func (m Manga) WithFallbackCovers() Manga {
+	vols := make(map[Identifier]Volume)
+	for idx, vol := range m.Volumes {
+		if vol.Cover == nil {
+			vol.Cover = firstPage(vol)
+		}
+		vols[idx] = vol
+	}
+
+	return Manga{
+		Info:    m.Info,
+		Volumes: vols,
+	}
+}
+
+// WithoutShortChapters returns the manga without chapters that have fewer
+// than the given number of pages, such as chapters that were deleted from
+// MangaDex, along with the removed chapters. Volumes left without chapters
+// are removed as well.
+func (m Manga) WithoutShortChapters(minPages int) (Manga, ChapterList) {
+	vols := make(map[Identifier]Volume)
+	removed := make(ChapterList, 0)
+	for _, volID := range m.Keys() {
+		vol := m.Volumes[volID]
+		chapters := make(map[Identifier]Chapter)
+		for _, chapID := range vol.Keys() {
+			if chap := vol.Chapters[chapID]; len(chap.Pages) >= minPages {
+				chapters[chapID] = chap
+			} else {
+				removed = append(removed, chap)
+			}
+		}
+		if len(chapters) > 0 {
+			vol.Chapters = chapters
+			vols[volID] = vol
+		}
+	}
+
+	return Manga{
+		Info:    m.Info,
+		Volumes: vols,
+	}, removed
+}
+
+// WithoutEmptyVolumes returns the manga without volumes that have no
+// chapters, and the identifiers of the removed volumes in order.
+func (m Manga) WithoutEmptyVolumes() (Manga, []Identifier) {
+	vols := make(map[Identifier]Volume)
+	removed := make([]Identifier, 0)
+	for _, volID := range m.Keys() {
+		if vol := m.Volumes[volID]; len(vol.Chapters) > 0 {
+			vols[volID] = vol
+		} else {
+			removed = append(removed, volID)
+		}
+	}
+
+	return Manga{
+		Info:    m.Info,
+		Volumes: vols,
+	}, removed
+}
+
+// WithPagesFiltered returns the manga with only the pages that keep returns
+// true for, given their position in their chapter starting at one.
+// Chapters and volumes left without pages are removed.
+func (m Manga) WithPagesFiltered(keep func(page int) bool) Manga {
+	vols := make(map[Identifier]Volume)
+	for volID, vol := range m.Volumes {
+		chapters := make(map[Identifier]Chapter)
+		for chapID, chap := range vol.Chapters {
+			pages := make(map[int]image.Image)
+			for i, key := range chap.Keys() {
+				if keep(i + 1) {
+					pages[key] = chap.Pages[key]
+				}
+			}
+			if len(pages) > 0 {
+				chap.Pages = pages
+				chapters[chapID] = chap
+			}
+		}
+		if len(chapters) > 0 {
+			vol.Chapters = chapters
+			vols[volID] = vol
+		}
+	}
+
+	return Manga{
+		Info:    m.Info,
+		Volumes: vols,
+	}
+}
+
+func firstPage(vol Volume) image.Image {
+	for _, chap := range vol.Sorted() {
+		for _, page := range chap.Sorted() {
+			if page != nil {
+				return page
+			}
+		}
+	}
+
+	return nil
+}
+
 func cleanVolume(old Chapter) Volume {
 	chapters := make(map[Identifier]Chapter)
 	chapters[old.Info.Identifier] = cleanChapter(old)
@@ -12,6 +12,12 @@ type MangaInfo struct {
 	Authors multiple
 	Artists multiple
 	ID      string
+	// OriginalLanguage is the locale the manga was first published in,
+	// e.g. "ja" or "ko"
+	OriginalLanguage string
+	// ExternalIDs maps identifier schemes such as "isbn" or "anilist" to
+	// the identifier of the manga in that scheme
+	ExternalIDs map[string]string
 }
 
 type VolumeInfo struct {
@@ -25,6 +31,8 @@ type ChapterInfo struct {
 	GroupNames multiple
 	Published  time.Time
 	ID         string
+	// Pages is the number of pages reported by MangaDex, zero if unknown
+	Pages int
 
 	// identifiers
 	Identifier       Identifier
@@ -33,6 +41,8 @@ type ChapterInfo struct {
 
 type Image struct {
 	Image image.Image
+	// Filename is the original filename of pages loaded from disk
+	Filename string
 
 	// identifiers
 	ImageIdentifier   int
@@ -43,6 +53,8 @@ type Image struct {
 type Path struct {
 	DataURL      string
 	DataSaverURL string
+	// Locale is the language of cover art, empty for pages
+	Locale string
 
 	// identifiers
 	ImageIdentifier   int